@@ -0,0 +1,368 @@
+/*
+Package agent provides the unlock agent, which keeps the unwrapped encryption keys
+of profiles in memory and serves them to the client processes over a Unix socket,
+so the master password is asked once per session.
+
+The socket directory must be owned by the user and closed for others,
+the socket itself is created with 0600 mode, and on Linux the uid of the peer is checked.
+Keys are dropped after the idle timeout and by the lock request.
+
+Every request is one JSON line, the response is one JSON line too.
+
+The ssh agent serves the private keys of ssh records over the standard ssh agent protocol
+at its own socket with the same permissions.
+*/
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// SocketEnv environment variable with the socket path of the running agent
+	SocketEnv = "GOPHKEEPER_AGENT_SOCK"
+	// DefaultTimeout idle timeout of the agent keys
+	DefaultTimeout = 15 * time.Minute
+
+	opGet    = "get"
+	opPut    = "put"
+	opLock   = "lock"
+	opStatus = "status"
+	opStop   = "stop"
+
+	requestTimeout = 5 * time.Second
+	maxRequestLen  = 64 * 1024
+)
+
+var (
+	ErrNotRunning  = errors.New("agent is not running")
+	ErrRunning     = errors.New("agent is already running")
+	ErrNoKey       = errors.New("agent has no key")
+	ErrPermissions = errors.New("unsafe agent socket permissions")
+	ErrPeer        = errors.New("agent peer is not the owner")
+	ErrUnsupported = errors.New("agent is not supported on this platform")
+)
+
+type request struct {
+	Op  string `json:"op"`
+	ID  string `json:"id,omitempty"`
+	Key []byte `json:"key,omitempty"`
+}
+
+type response struct {
+	Error  string  `json:"error,omitempty"`
+	Key    []byte  `json:"key,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// Status of the running agent
+type Status struct {
+	Pid     int           `json:"pid"`
+	Keys    int           `json:"keys"`
+	Timeout time.Duration `json:"timeout"`
+	// LockAt the time of the idle lock, zero if there are no keys or no timeout
+	LockAt time.Time `json:"lock_at,omitempty"`
+}
+
+// SocketPath returns the agent socket path:
+// from SocketEnv, then at XDG_RUNTIME_DIR, otherwise at the temp directory of the user
+func SocketPath() string {
+	if path := os.Getenv(SocketEnv); path != "" {
+		return path
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "gophkeeper", "agent.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("gophkeeper-%d", os.Getuid()), "agent.sock")
+}
+
+// Agent
+// in memory store of the unwrapped keys
+type Agent struct {
+	mu      sync.Mutex
+	keys    map[string][]byte
+	timeout time.Duration
+	timer   *time.Timer
+	lockAt  time.Time
+	stop    context.CancelFunc
+}
+
+// New returns the agent, keys are dropped after timeout without requests, zero timeout keeps them
+func New(timeout time.Duration) *Agent {
+	return &Agent{
+		keys:    make(map[string][]byte),
+		timeout: timeout,
+	}
+}
+
+// Lock
+// drop all keys, the memory of keys is cleared
+func (a *Agent) Lock() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lock()
+}
+
+func (a *Agent) lock() {
+	for id, key := range a.keys {
+		clear(key)
+		delete(a.keys, id)
+	}
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	a.lockAt = time.Time{}
+}
+
+// touch
+// restart the idle timeout, must be called under the lock
+func (a *Agent) touch() {
+	if a.timeout <= 0 || len(a.keys) == 0 {
+		return
+	}
+	if a.timer == nil {
+		a.timer = time.AfterFunc(a.timeout, a.Lock)
+	} else {
+		a.timer.Reset(a.timeout)
+	}
+	a.lockAt = time.Now().Add(a.timeout)
+}
+
+func (a *Agent) handle(req request) (resp response) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch req.Op {
+	case opGet:
+		key, ok := a.keys[req.ID]
+		if !ok {
+			resp.Error = ErrNoKey.Error()
+			return
+		}
+		resp.Key = bytes.Clone(key)
+		a.touch()
+	case opPut:
+		if req.ID == "" || len(req.Key) == 0 {
+			resp.Error = "empty id or key"
+			return
+		}
+		if old, ok := a.keys[req.ID]; ok {
+			clear(old)
+		}
+		a.keys[req.ID] = req.Key
+		a.touch()
+	case opLock:
+		a.lock()
+	case opStatus:
+		resp.Status = &Status{
+			Pid:     os.Getpid(),
+			Keys:    len(a.keys),
+			Timeout: a.timeout,
+			LockAt:  a.lockAt,
+		}
+	case opStop:
+		a.lock()
+		if a.stop != nil {
+			a.stop()
+		}
+	default:
+		resp.Error = fmt.Sprintf("unknown request %q", req.Op)
+	}
+	return
+}
+
+// Serve
+// listen the socket at path and serve the client requests until ctx is done or the stop request,
+// the socket is removed at exit and the keys are dropped
+func (a *Agent) Serve(ctx context.Context, path string) (err error) {
+	var ln *net.UnixListener
+	if ln, err = listen(path); err != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	a.mu.Lock()
+	a.stop = cancel
+	a.mu.Unlock()
+	defer func() {
+		cancel()
+		a.Lock()
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		var conn *net.UnixConn
+		if conn, err = ln.AcceptUnix(); err != nil {
+			if ctx.Err() != nil {
+				err = nil
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.serveConn(conn)
+		}()
+	}
+}
+
+func (a *Agent) serveConn(conn *net.UnixConn) {
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+	var resp response
+	if err := checkPeer(conn); err != nil {
+		resp.Error = err.Error()
+	} else {
+		var req request
+		line, err := bufio.NewReaderSize(conn, maxRequestLen).ReadSlice('\n')
+		if err == nil {
+			err = json.Unmarshal(line, &req)
+		}
+		if err != nil {
+			resp.Error = fmt.Sprintf("bad request: %v", err)
+		} else {
+			resp = a.handle(req)
+		}
+	}
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	_, _ = conn.Write(append(b, '\n'))
+}
+
+// listen
+// create the socket at the private directory, the stale socket of not running agent is replaced
+func listen(path string) (ln *net.UnixListener, err error) {
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return
+	}
+	if err = checkDir(dir); err != nil {
+		return
+	}
+	if _, err = os.Lstat(path); err == nil {
+		if c, er := net.DialTimeout("unix", path, time.Second); er == nil {
+			_ = c.Close()
+			err = fmt.Errorf("%w: %s", ErrRunning, path)
+			return
+		}
+		if err = os.Remove(path); err != nil {
+			return
+		}
+	}
+	if ln, err = net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"}); err != nil {
+		return
+	}
+	ln.SetUnlinkOnClose(true)
+	if err = os.Chmod(path, 0600); err != nil {
+		_ = ln.Close()
+		ln = nil
+	}
+	return
+}
+
+// Client
+// requests to the agent at the socket path
+type Client struct {
+	path string
+}
+
+// NewClient returns the client of the agent listening at path
+func NewClient(path string) *Client {
+	return &Client{path: path}
+}
+
+func (c *Client) do(req request) (resp response, err error) {
+	if _, err = os.Lstat(c.path); err != nil {
+		if os.IsNotExist(err) {
+			err = ErrNotRunning
+		}
+		return
+	}
+	// the key must not be sent to the socket of other user
+	if err = checkSocket(c.path); err != nil {
+		return
+	}
+	var conn net.Conn
+	if conn, err = net.DialTimeout("unix", c.path, requestTimeout); err != nil {
+		err = errors.Join(ErrNotRunning, err)
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+	var b []byte
+	if b, err = json.Marshal(req); err != nil {
+		return
+	}
+	if _, err = conn.Write(append(b, '\n')); err != nil {
+		return
+	}
+	var line []byte
+	if line, err = bufio.NewReaderSize(conn, maxRequestLen).ReadSlice('\n'); err != nil {
+		return
+	}
+	if err = json.Unmarshal(line, &resp); err != nil {
+		return
+	}
+	switch resp.Error {
+	case "":
+	case ErrNoKey.Error():
+		err = ErrNoKey
+	default:
+		err = errors.New(resp.Error)
+	}
+	return
+}
+
+// GetKey returns the key of id, ErrNoKey if the agent has not it
+func (c *Client) GetKey(id string) (key []byte, err error) {
+	var resp response
+	if resp, err = c.do(request{Op: opGet, ID: id}); err != nil {
+		return
+	}
+	key = resp.Key
+	return
+}
+
+// PutKey stores the key of id at the agent
+func (c *Client) PutKey(id string, key []byte) (err error) {
+	_, err = c.do(request{Op: opPut, ID: id, Key: key})
+	return
+}
+
+// Lock drops all keys of the agent
+func (c *Client) Lock() (err error) {
+	_, err = c.do(request{Op: opLock})
+	return
+}
+
+// Status returns the status of the agent
+func (c *Client) Status() (s Status, err error) {
+	var resp response
+	if resp, err = c.do(request{Op: opStatus}); err != nil {
+		return
+	}
+	if resp.Status != nil {
+		s = *resp.Status
+	}
+	return
+}
+
+// Stop drops all keys and stops the agent
+func (c *Client) Stop() (err error) {
+	_, err = c.do(request{Op: opStop})
+	return
+}
@@ -0,0 +1,89 @@
+//go:build unix
+
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, timeout time.Duration) (path string, done chan error) {
+	dir, err := os.MkdirTemp("", "gk")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path = filepath.Join(dir, "agent", "agent.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	done = make(chan error, 1)
+	go func() { done <- New(timeout).Serve(ctx, path) }()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	return
+}
+
+func TestAgent(t *testing.T) {
+	path, done := serve(t, time.Minute)
+	c := NewClient(path)
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	_, err = c.GetKey("profile")
+	require.ErrorIs(t, err, ErrNoKey)
+
+	require.NoError(t, c.PutKey("profile", []byte("some key")))
+	key, err := c.GetKey("profile")
+	require.NoError(t, err)
+	require.Equal(t, []byte("some key"), key)
+
+	status, err := c.Status()
+	require.NoError(t, err)
+	require.Equal(t, 1, status.Keys)
+	require.Equal(t, time.Minute, status.Timeout)
+	require.False(t, status.LockAt.IsZero())
+
+	err = New(time.Minute).Serve(context.Background(), path)
+	require.ErrorIs(t, err, ErrRunning)
+
+	require.NoError(t, c.Lock())
+	_, err = c.GetKey("profile")
+	require.ErrorIs(t, err, ErrNoKey)
+
+	require.NoError(t, c.Stop())
+	require.NoError(t, <-done)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+	_, err = c.GetKey("profile")
+	require.ErrorIs(t, err, ErrNotRunning)
+}
+
+func TestAgentIdle(t *testing.T) {
+	path, _ := serve(t, 100*time.Millisecond)
+	c := NewClient(path)
+	require.NoError(t, c.PutKey("profile", []byte("some key")))
+	// status does not restart the idle timeout
+	require.Eventually(t, func() bool {
+		status, err := c.Status()
+		return err == nil && status.Keys == 0
+	}, 2*time.Second, 20*time.Millisecond)
+	_, err := c.GetKey("profile")
+	require.ErrorIs(t, err, ErrNoKey)
+}
+
+func TestAgentPermissions(t *testing.T) {
+	path, _ := serve(t, time.Minute)
+	c := NewClient(path)
+	require.NoError(t, os.Chmod(filepath.Dir(path), 0755))
+	err := c.PutKey("profile", []byte("some key"))
+	require.ErrorIs(t, err, ErrPermissions)
+	require.NoError(t, os.Chmod(filepath.Dir(path), 0700))
+	require.NoError(t, c.PutKey("profile", []byte("some key")))
+}
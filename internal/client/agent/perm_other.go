@@ -0,0 +1,22 @@
+//go:build !unix
+
+package agent
+
+// checkDir
+// the socket permissions can not be checked here, the agent is not supported
+func checkDir(_ string) error {
+	return ErrUnsupported
+}
+
+// checkSocket
+// the socket permissions can not be checked here, the agent is not used
+func checkSocket(_ string) error {
+	return ErrNotRunning
+}
+
+// Detach
+// the agent is not supported here
+func Detach(_ string, _ ...string) (pid int, err error) {
+	err = ErrUnsupported
+	return
+}
@@ -0,0 +1,69 @@
+//go:build unix
+
+package agent
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// checkOwner
+// the file must be owned by the current user
+func checkOwner(fi os.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("%w: %s is not owned by the user", ErrPermissions, fi.Name())
+	}
+	return nil
+}
+
+// checkDir
+// the socket directory must be owned by the user and closed for others
+func checkDir(dir string) (err error) {
+	var fi os.FileInfo
+	if fi, err = os.Lstat(dir); err != nil {
+		return
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrPermissions, dir)
+	}
+	if err = checkOwner(fi); err != nil {
+		return
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%w: %s is open for others, %s", ErrPermissions, dir, fi.Mode().Perm())
+	}
+	return
+}
+
+// checkSocket
+// the socket and its directory must be owned by the user
+func checkSocket(path string) (err error) {
+	var fi os.FileInfo
+	if fi, err = os.Lstat(path); err != nil {
+		return
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%w: %s is not a socket", ErrPermissions, path)
+	}
+	if err = checkOwner(fi); err != nil {
+		return
+	}
+	return checkDir(filepath.Dir(path))
+}
+
+// Detach
+// start the agent by the command at the new session without terminal, returns its pid
+func Detach(name string, args ...string) (pid int, err error) {
+	cmd := exec.Command(name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err = cmd.Start(); err != nil {
+		return
+	}
+	pid = cmd.Process.Pid
+	err = cmd.Process.Release()
+	return
+}
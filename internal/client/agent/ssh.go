@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	sshagent "golang.org/x/crypto/ssh/agent"
+)
+
+// SSHSocketEnv the environment variable of the ssh agent socket, used by ssh
+const SSHSocketEnv = "SSH_AUTH_SOCK"
+
+var ErrNotConfirmed = errors.New("key use is not confirmed")
+
+// SSHSocketPath returns the default ssh agent socket path, next to the unlock agent socket
+func SSHSocketPath() string {
+	return filepath.Join(filepath.Dir(SocketPath()), "ssh-agent.sock")
+}
+
+// SSHAgent
+// the keyring of ssh agent protocol. The keyring drops the keys after their lifetime,
+// the keys with confirm constraint sign only if the use is confirmed by the confirm function
+type SSHAgent struct {
+	sshagent.ExtendedAgent
+	mu        sync.Mutex
+	confirmed map[string]string
+	confirmMu sync.Mutex
+	confirm   func(comment string) bool
+}
+
+// NewSSH returns the empty ssh agent, confirm asks the user to allow the use of key with the comment,
+// nil confirm denies the use of such keys
+func NewSSH(confirm func(comment string) bool) *SSHAgent {
+	return &SSHAgent{
+		ExtendedAgent: sshagent.NewKeyring().(sshagent.ExtendedAgent),
+		confirmed:     make(map[string]string),
+		confirm:       confirm,
+	}
+}
+
+// Add
+// add the key to keyring, the confirm constraint is kept by the agent
+func (a *SSHAgent) Add(key sshagent.AddedKey) (err error) {
+	signer, err := ssh.NewSignerFromKey(key.PrivateKey)
+	if err != nil {
+		return
+	}
+	blobs := []string{string(signer.PublicKey().Marshal())}
+	if key.Certificate != nil {
+		blobs = append(blobs, string(key.Certificate.Marshal()))
+	}
+	confirm := key.ConfirmBeforeUse
+	key.ConfirmBeforeUse = false
+	if err = a.ExtendedAgent.Add(key); err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, blob := range blobs {
+		if confirm {
+			a.confirmed[blob] = key.Comment
+		} else {
+			delete(a.confirmed, blob)
+		}
+	}
+	return
+}
+
+// Remove
+// remove the key from keyring
+func (a *SSHAgent) Remove(key ssh.PublicKey) error {
+	a.mu.Lock()
+	delete(a.confirmed, string(key.Marshal()))
+	a.mu.Unlock()
+	return a.ExtendedAgent.Remove(key)
+}
+
+// RemoveAll
+// remove all keys from keyring
+func (a *SSHAgent) RemoveAll() error {
+	a.mu.Lock()
+	clear(a.confirmed)
+	a.mu.Unlock()
+	return a.ExtendedAgent.RemoveAll()
+}
+
+// Sign
+// sign the data by the key, the use of key with confirm constraint is asked before
+func (a *SSHAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags
+// sign the data by the key with the signature flags, the use of key with confirm constraint is asked before
+func (a *SSHAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags sshagent.SignatureFlags) (*ssh.Signature, error) {
+	if err := a.confirmUse(key); err != nil {
+		return nil, err
+	}
+	return a.ExtendedAgent.SignWithFlags(key, data, flags)
+}
+
+// confirmUse
+// ask the use of key with confirm constraint, the questions are asked one by one
+func (a *SSHAgent) confirmUse(key ssh.PublicKey) error {
+	a.mu.Lock()
+	comment, ok := a.confirmed[string(key.Marshal())]
+	a.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	a.confirmMu.Lock()
+	defer a.confirmMu.Unlock()
+	if a.confirm == nil || !a.confirm(comment) {
+		return ErrNotConfirmed
+	}
+	return nil
+}
+
+// Serve
+// listen the socket at path and serve the ssh agent protocol until ctx is done,
+// the socket is removed at exit and the keys are dropped
+func (a *SSHAgent) Serve(ctx context.Context, path string) (err error) {
+	var ln *net.UnixListener
+	if ln, err = listen(path); err != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		_ = a.RemoveAll()
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		var conn *net.UnixConn
+		if conn, err = ln.AcceptUnix(); err != nil {
+			if ctx.Err() != nil {
+				err = nil
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { _ = conn.Close() }()
+			if checkPeer(conn) != nil {
+				return
+			}
+			// the connection is closed on stop, the client may keep it
+			stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+			defer stop()
+			_ = sshagent.ServeAgent(a, conn)
+		}()
+	}
+}
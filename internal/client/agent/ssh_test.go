@@ -0,0 +1,89 @@
+//go:build unix
+
+package agent
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	sshagent "golang.org/x/crypto/ssh/agent"
+)
+
+func TestSSHAgent(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gk")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "agent", "ssh-agent.sock")
+
+	var allow atomic.Bool
+	var asked atomic.Int32
+	a := NewSSH(func(comment string) bool {
+		asked.Add(1)
+		require.Equal(t, "confirmed", comment)
+		return allow.Load()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	done := make(chan error, 1)
+	go func() { done <- a.Serve(ctx, path) }()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	c := sshagent.NewClient(conn)
+
+	_, plainKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, confirmKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, shortKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, c.Add(sshagent.AddedKey{PrivateKey: plainKey, Comment: "plain"}))
+	require.NoError(t, c.Add(sshagent.AddedKey{PrivateKey: confirmKey, Comment: "confirmed", ConfirmBeforeUse: true}))
+	require.NoError(t, c.Add(sshagent.AddedKey{PrivateKey: shortKey, Comment: "short", LifetimeSecs: 1}))
+
+	keys, err := c.List()
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+
+	data := []byte("data to sign")
+	pub, err := ssh.NewPublicKey(plainKey.Public())
+	require.NoError(t, err)
+	sig, err := c.Sign(pub, data)
+	require.NoError(t, err)
+	require.NoError(t, pub.Verify(data, sig))
+	require.Zero(t, asked.Load())
+
+	pub, err = ssh.NewPublicKey(confirmKey.Public())
+	require.NoError(t, err)
+	_, err = c.Sign(pub, data)
+	require.Error(t, err)
+	allow.Store(true)
+	sig, err = c.Sign(pub, data)
+	require.NoError(t, err)
+	require.NoError(t, pub.Verify(data, sig))
+	require.Equal(t, int32(2), asked.Load())
+
+	require.Eventually(t, func() bool {
+		keys, err := c.List()
+		return err == nil && len(keys) == 2
+	}, 3*time.Second, 100*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+}
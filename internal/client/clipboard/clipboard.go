@@ -0,0 +1,205 @@
+/*
+Package clipboard puts the secret values to the clipboard and clears them after the timeout.
+
+Two backends are supported:
+  - osc52 - the OSC 52 escape sequence is written to the terminal, the terminal puts the value
+    to the system clipboard, so it works over SSH too. The terminal must allow it,
+    the value can not be read back.
+  - command - the external program of the user config gets the value at stdin, as wl-copy,
+    xclip -selection clipboard or pbcopy. The paste program, as wl-paste or pbpaste,
+    allows to clear the clipboard only if it still holds the copied value.
+
+The clear timer is run by the detached helper process, so the client exits right away.
+The helper gets the sha256 of the value, the value itself is never passed to it.
+*/
+package clipboard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Backends of clipboard
+const (
+	// BackendAuto the command backend if the copy program is set, osc52 otherwise
+	BackendAuto    = "auto"
+	BackendOSC52   = "osc52"
+	BackendCommand = "command"
+
+	// DefaultClearAfter the clipboard is cleared after the time by default
+	DefaultClearAfter = 30 * time.Second
+
+	// TTYFd the file descriptor of the terminal passed to the clear helper
+	TTYFd = 3
+)
+
+var (
+	ErrBackend   = errors.New("unknown clipboard backend")
+	ErrNoCommand = errors.New("clipboard copy program is not set")
+	ErrNoPaste   = errors.New("clipboard can not be read")
+)
+
+// Clipboard
+// the clipboard backend
+type Clipboard interface {
+	// Copy put the value to the clipboard, the empty value clears it
+	Copy(value string) error
+	// Paste the value of the clipboard, ErrNoPaste if it can not be read
+	Paste() (string, error)
+}
+
+// Config
+// the clipboard settings of the user config
+type Config struct {
+	Backend string
+	// CopyCmd the program with arguments reading the value from stdin
+	CopyCmd string
+	// PasteCmd the program with arguments writing the value to stdout
+	PasteCmd string
+	// ClearAfter the clipboard is cleared after the time, 0 keeps the value
+	ClearAfter time.Duration
+}
+
+// Kind
+// the backend used by the config, the auto one is resolved
+func (c Config) Kind() (string, error) {
+	switch b := strings.ToLower(c.Backend); b {
+	case "", BackendAuto:
+		if c.CopyCmd != "" {
+			return BackendCommand, nil
+		}
+		return BackendOSC52, nil
+	case BackendOSC52:
+		return b, nil
+	case BackendCommand:
+		if c.CopyCmd == "" {
+			return "", ErrNoCommand
+		}
+		return b, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrBackend, c.Backend)
+}
+
+// New
+// the clipboard of the config, the osc52 sequences are written to the terminal w
+func (c Config) New(w io.Writer) (Clipboard, error) {
+	kind, err := c.Kind()
+	if err != nil {
+		return nil, err
+	}
+	if kind == BackendCommand {
+		return Command{CopyCmd: c.CopyCmd, PasteCmd: c.PasteCmd}, nil
+	}
+	return OSC52{W: w, Tmux: os.Getenv("TMUX") != ""}, nil
+}
+
+// OSC52
+// the clipboard of the terminal set by the OSC 52 escape sequence
+type OSC52 struct {
+	W io.Writer
+	// Tmux the sequence is wrapped to pass through tmux to the outer terminal
+	Tmux bool
+}
+
+func (c OSC52) Copy(value string) (err error) {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(value)) + "\a"
+	if c.Tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	_, err = io.WriteString(c.W, seq)
+	return
+}
+
+// Paste
+// the terminal clipboard is not read, terminals do not allow it usually
+func (c OSC52) Paste() (string, error) {
+	return "", ErrNoPaste
+}
+
+// Command
+// the clipboard of the external programs, the arguments are separated by spaces
+type Command struct {
+	CopyCmd  string
+	PasteCmd string
+}
+
+func (c Command) Copy(value string) error {
+	cmd, err := command(c.CopyCmd)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = strings.NewReader(value)
+	return run(cmd)
+}
+
+func (c Command) Paste() (value string, err error) {
+	if c.PasteCmd == "" {
+		return "", ErrNoPaste
+	}
+	cmd, err := command(c.PasteCmd)
+	if err != nil {
+		return
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err = run(cmd); err != nil {
+		return
+	}
+	return out.String(), nil
+}
+
+// command
+// the command of the program line
+func command(line string) (*exec.Cmd, error) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return nil, ErrNoCommand
+	}
+	return exec.Command(args[0], args[1:]...), nil
+}
+
+// run
+// the stderr of program is added to the error
+func run(cmd *exec.Cmd) (err error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil && stderr.Len() > 0 {
+		err = fmt.Errorf("%s: %w: %s", cmd.Path, err, strings.TrimSpace(stderr.String()))
+	}
+	return
+}
+
+// Hash
+// the hex sha256 of the value, it is passed to the clear helper instead of the value
+func Hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Clear
+// clear the clipboard if it still holds the value of the hash.
+// If the clipboard can not be read, it is cleared anyway.
+// The trailing line end added by some paste programs is ignored
+func Clear(c Clipboard, hash string) (cleared bool, err error) {
+	value, err := c.Paste()
+	switch {
+	case errors.Is(err, ErrNoPaste):
+	case err != nil:
+		return
+	case Hash(value) != hash && Hash(strings.TrimSuffix(value, "\n")) != hash:
+		return false, nil
+	}
+	if err = c.Copy(""); err != nil {
+		return
+	}
+	return true, nil
+}
@@ -0,0 +1,54 @@
+package clipboard
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigKind(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+		err    error
+	}{
+		{"auto osc52", Config{}, BackendOSC52, nil},
+		{"auto command", Config{Backend: BackendAuto, CopyCmd: "wl-copy"}, BackendCommand, nil},
+		{"osc52", Config{Backend: "OSC52", CopyCmd: "wl-copy"}, BackendOSC52, nil},
+		{"command", Config{Backend: BackendCommand, CopyCmd: "pbcopy"}, BackendCommand, nil},
+		{"command without program", Config{Backend: BackendCommand}, "", ErrNoCommand},
+		{"unknown", Config{Backend: "x11"}, "", ErrBackend},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.Kind()
+			require.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOSC52(t *testing.T) {
+	var w bytes.Buffer
+	c := OSC52{W: &w}
+	require.NoError(t, c.Copy("secret"))
+	assert.Equal(t, "\x1b]52;c;c2VjcmV0\a", w.String())
+
+	w.Reset()
+	c.Tmux = true
+	require.NoError(t, c.Copy(""))
+	assert.Equal(t, "\x1bPtmux;\x1b\x1b]52;c;\a\x1b\\", w.String())
+
+	_, err := c.Paste()
+	require.ErrorIs(t, err, ErrNoPaste)
+
+	// the terminal clipboard is cleared anyway
+	w.Reset()
+	cleared, err := Clear(OSC52{W: &w}, Hash("secret"))
+	require.NoError(t, err)
+	assert.True(t, cleared)
+	assert.Equal(t, "\x1b]52;c;\a", w.String())
+}
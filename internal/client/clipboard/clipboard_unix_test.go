@@ -0,0 +1,42 @@
+//go:build unix
+
+package clipboard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "clipboard")
+	c := Command{CopyCmd: "tee " + file, PasteCmd: "cat " + file}
+	require.NoError(t, c.Copy("secret"))
+	value, err := c.Paste()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+
+	// the clipboard changed by the user is kept
+	require.NoError(t, c.Copy("other"))
+	cleared, err := Clear(c, Hash("secret"))
+	require.NoError(t, err)
+	assert.False(t, cleared)
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "other", string(b))
+
+	require.NoError(t, c.Copy("secret\n"))
+	cleared, err = Clear(c, Hash("secret"))
+	require.NoError(t, err)
+	assert.True(t, cleared)
+	b, err = os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Empty(t, b)
+
+	_, err = Command{CopyCmd: "tee " + file}.Paste()
+	require.ErrorIs(t, err, ErrNoPaste)
+	require.Error(t, Command{CopyCmd: "false"}.Copy("secret"))
+}
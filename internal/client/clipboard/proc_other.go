@@ -0,0 +1,27 @@
+//go:build !unix
+
+package clipboard
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Terminal
+// the console opened for writing of the osc52 sequences
+func Terminal() (*os.File, error) {
+	return os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+}
+
+// Detach
+// start the command, it is not stopped with the client here.
+// The files can not be passed, so the osc52 clipboard is not cleared by the helper
+func Detach(_ *os.File, name string, args ...string) (pid int, err error) {
+	cmd := exec.Command(name, args...)
+	if err = cmd.Start(); err != nil {
+		return
+	}
+	pid = cmd.Process.Pid
+	err = cmd.Process.Release()
+	return
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package clipboard
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Terminal
+// the controlling terminal opened for writing of the osc52 sequences
+func Terminal() (*os.File, error) {
+	return os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+}
+
+// Detach
+// start the command at the new session, so it is not stopped with the terminal,
+// the terminal tty is passed as the file descriptor TTYFd if it is not nil
+func Detach(tty *os.File, name string, args ...string) (pid int, err error) {
+	cmd := exec.Command(name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if tty != nil {
+		cmd.ExtraFiles = []*os.File{tty}
+	}
+	if err = cmd.Start(); err != nil {
+		return
+	}
+	pid = cmd.Process.Pid
+	err = cmd.Process.Release()
+	return
+}
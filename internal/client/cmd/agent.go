@@ -0,0 +1,128 @@
+/*
+This package provides the commands of the unlock agent, which keeps the unwrapped
+encryption key in memory, so the master password is asked once per session.
+
+Main functionalities include:
+
+- Running the agent in foreground or detached.
+- Showing the agent status and stopping it.
+- Locking the agent, all keys are dropped.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gophKeeper/internal/client/agent"
+	cfg "gophKeeper/internal/client/config"
+
+	"github.com/spf13/cobra"
+)
+
+// addAgentCmd adds the agent command with its subcommands and the lock command to the root command.
+func (a *app) addAgentCmd() *app {
+	var (
+		timeout time.Duration
+		socket  string
+		detach  bool
+	)
+	agentCmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Run the unlock agent",
+		Long: `The agent keeps the unwrapped encryption keys in memory and serves them
+to the client commands over a Unix socket, so the master password is asked once per session.
+Keys are dropped after the idle timeout and by the lock command.
+The socket path is taken from ` + agent.SocketEnv + ` environment variable, if set.`,
+		Example: `  agent --detach
+  agent --timeout 1h
+  agent status
+  lock`,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer func() { timeout, socket, detach = agent.DefaultTimeout, "", false }()
+			if socket == "" {
+				socket = agent.SocketPath()
+			}
+			if socket != agent.SocketPath() {
+				cmd.Printf("export %s=%s\n", agent.SocketEnv, socket)
+			}
+			if detach {
+				exe, err := os.Executable()
+				if err != nil {
+					cmd.PrintErrf("failed to start agent: %v\n", err)
+					return
+				}
+				pid, err := agent.Detach(exe, "agent", "--timeout", timeout.String(), "--socket", socket)
+				if err != nil {
+					cmd.PrintErrf("failed to start agent: %v\n", err)
+					return
+				}
+				cmd.Printf("Agent is started, pid %d\n", pid)
+				return
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			cmd.Printf("Agent is listening at %s, idle timeout %s\n", socket, timeout)
+			if err := agent.New(timeout).Serve(ctx, socket); err != nil {
+				cmd.PrintErrf("agent failed: %v\n", err)
+				return
+			}
+			cmd.Println("Agent is stopped")
+		},
+	}
+	agentCmd.Flags().DurationVar(&timeout, "timeout", agent.DefaultTimeout, "drop keys after the idle time, 0 keeps them")
+	agentCmd.Flags().StringVar(&socket, "socket", "", "socket path")
+	agentCmd.Flags().BoolVarP(&detach, "detach", "d", false, "run the agent in background")
+	agentCmd.AddCommand(
+		&cobra.Command{
+			Use:   "status",
+			Short: "Show the agent status",
+			Run: func(cmd *cobra.Command, args []string) {
+				s, err := agent.NewClient(agent.SocketPath()).Status()
+				if err != nil {
+					cmd.PrintErrf("failed to get agent status: %v\n", err)
+					return
+				}
+				cmd.Printf("Agent pid %d, keys %d, idle timeout %s\n", s.Pid, s.Keys, s.Timeout)
+				if !s.LockAt.IsZero() {
+					cmd.Println("Keys are dropped at", s.LockAt.Format(time.DateTime))
+				}
+			},
+		},
+		&cobra.Command{
+			Use:   "stop",
+			Short: "Drop keys and stop the agent",
+			Run: func(cmd *cobra.Command, args []string) {
+				if err := agent.NewClient(agent.SocketPath()).Stop(); err != nil {
+					cmd.PrintErrf("failed to stop agent: %v\n", err)
+					return
+				}
+				cmd.Println("Agent is stopped")
+			},
+		})
+	a.root.AddCommand(agentCmd,
+		&cobra.Command{
+			Use:   "lock",
+			Short: "Drop the unlocked keys",
+			Long:  `The keys of the agent are dropped, the master password is asked again.`,
+			Run: func(cmd *cobra.Command, args []string) {
+				if cfg.User.Viper != nil && cfg.User.GetString("encryption_key") != "" {
+					cfg.User.Set("encryption_key", nil)
+				}
+				err := agent.NewClient(agent.SocketPath()).Lock()
+				switch {
+				case errors.Is(err, agent.ErrNotRunning):
+					cmd.Println("Agent is not running")
+				case err != nil:
+					cmd.PrintErrf("failed to lock agent: %v\n", err)
+				default:
+					cmd.Println("Agent is locked")
+				}
+			},
+		})
+	return a
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"gophKeeper/internal/client/agent"
 	cfg "gophKeeper/internal/client/config"
 	clMigrate "gophKeeper/internal/client/migrate"
 	"gophKeeper/internal/client/service"
@@ -29,6 +30,9 @@ type app struct {
 	db   *sqlx.DB
 	srv  service.Service
 	root *cobra.Command
+	save *cobra.Command
+	// templateCmds the save commands of profile templates
+	templateCmds []*cobra.Command
 }
 
 func NewApp(b BuildMetadata) (a *app) {
@@ -37,10 +41,20 @@ func NewApp(b BuildMetadata) (a *app) {
 		addConfigCmd().
 		addSaveCmd().
 		addViewCmd().
+		addEditCmd().
+		addCopyCmd().
+		addGenerateCmd().
 		addDeleteCmd().
+		addHistoryCmd().
+		addTrashCmd().
 		addListCmd().
+		addAuditCmd().
+		addImportCmd().
+		addExportCmd().
 		addProfileCmd().
-		addSyncCmd()
+		addSyncCmd().
+		addAgentCmd().
+		addSSHAgentCmd()
 	return
 }
 
@@ -76,7 +90,8 @@ func (a *app) Srv() service.Service {
 		if err != nil {
 			return service.NewServiceError(fmt.Errorf("usrCfgDir error: %s \n", err))
 		}
-		a.srv = service.NewService(storage.NewStorage(a.db, storePath))
+		a.srv = service.NewService(storage.NewStorage(a.db, storePath),
+			service.WithKeyCache(agent.NewClient(agent.SocketPath())))
 	}
 	return a.srv
 }
@@ -117,6 +132,7 @@ func (a *app) Execute() (err error) {
 		err = errors.Join(err, a.Close())
 	}()
 
+	a.addTemplateSaveCmds()
 	err = a.root.Execute()
 	if err != nil {
 		a.root.Println(err)
@@ -0,0 +1,63 @@
+/*
+This package provides the audit command, which decrypts the kept data
+and reports the records needing attention.
+
+Main functionalities include:
+
+- Reporting the certificates and identity documents expired or expiring soon.
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/helper"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultExpiring the time of the expiring records reported by audit
+const defaultExpiring = "30d"
+
+// addAuditCmd adds the audit command to the root command.
+// The command decrypts the records and reports the ones needing attention by sections.
+func (a *app) addAuditCmd() *app {
+	expiring := defaultExpiring
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Report records needing attention",
+		Long: `Decrypt the kept data and report the records needing attention.
+Expiring: the certificates and identity documents expired or expiring in the --expiring time.`,
+		Example: `  audit
+  audit --expiring 90d`,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer func() { expiring = defaultExpiring }()
+			within, err := helper.ParseDuration(expiring)
+			if err != nil {
+				cmd.PrintErrf("wrong expiring time: %v\n", err)
+				return
+			}
+			items, skipped, err := a.Srv().Expiring(within)
+			if err != nil {
+				cmd.PrintErrf("audit error: %v\n", err)
+				return
+			}
+			printSkipped(cmd, skipped)
+			cmd.Printf("Expiring in %s: %d\n", expiring, len(items))
+			now := time.Now()
+			for _, item := range items {
+				at := item.Data.(model.Expiring).ExpiresAt()
+				state := "expired"
+				if at.After(now) {
+					state = fmt.Sprintf("%d days left", int(at.Sub(now)/(24*time.Hour)))
+				}
+				cmd.Printf("%s\t%s\t%s\t%s\n", item.Key, model.GetName(item.Data), at.Local().Format(time.DateTime), state)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&expiring, "expiring", defaultExpiring, "report the records expiring in the time, 30d or 12h")
+	a.root.AddCommand(cmd)
+	return a
+}
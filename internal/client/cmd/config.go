@@ -108,6 +108,25 @@ func (a *app) addConfigCmd() *app {
 	updUserCmd.Flags().DurationP("sync.timeout.sync", "t", 0, "synchronization timeout")
 	updUserCmd.Flags().DurationP("sync.timeout.register", "", 0, "register at server timeout")
 	updUserCmd.Flags().StringP("email", "e", "", "User email")
+	updUserCmd.Flags().StringP("crypt.algorithm", "", "", "encryption algorithm for saved data: aes-256-gcm (default) or xchacha20-poly1305")
+	updUserCmd.Flags().StringP("passphrase.file", "", "", "read the master password from the file, empty to unset")
+	updUserCmd.Flags().StringP("passphrase.env", "", "", "read the master password from the environment variable of the name, empty to unset")
+	updUserCmd.Flags().StringP("passphrase.pinentry", "", "", "ask the master password by the pinentry program, empty to unset")
+	updUserCmd.Flags().StringP("clipboard.backend", "", "", "clipboard backend: auto (default), osc52 or command")
+	updUserCmd.Flags().StringP("clipboard.copy", "", "", "clipboard copy program reading stdin, as wl-copy, xclip -selection clipboard or pbcopy")
+	updUserCmd.Flags().StringP("clipboard.paste", "", "", "clipboard paste program, as wl-paste or pbpaste, the clipboard is cleared only if it still holds the copied value")
+	updUserCmd.Flags().DurationP("clipboard.clear_after", "", 0, "clear the copied value from clipboard after the time, 0 keeps it")
+	updUserCmd.Flags().IntP("generate.length", "", 0, "length of generated passwords")
+	updUserCmd.Flags().BoolP("generate.lower", "", true, "use lower case letters in generated passwords")
+	updUserCmd.Flags().BoolP("generate.upper", "", true, "use upper case letters in generated passwords")
+	updUserCmd.Flags().BoolP("generate.digits", "", true, "use digits in generated passwords")
+	updUserCmd.Flags().BoolP("generate.symbols", "", true, "use symbols in generated passwords")
+	updUserCmd.Flags().BoolP("generate.exclude_similar", "", false, "exclude look-alike characters from generated passwords")
+	updUserCmd.Flags().BoolP("generate.diceware", "", false, "generate diceware passphrases instead of passwords")
+	updUserCmd.Flags().IntP("generate.words", "", 0, "words of generated passphrases")
+	updUserCmd.Flags().StringP("generate.separator", "", "", "separator of words of generated passphrases")
+	updUserCmd.Flags().IntP("history.keep", "", 0, "number of the kept previous versions of a record, 0 keeps none")
+	updUserCmd.Flags().StringP("history.max_age", "", "", "remove the previous versions older than the time, as 90d or 720h, empty keeps them")
 	updUserCmd.Flags().BoolP("autosave", "a", true, "Auto save user config")
 
 	saveCmd := &cobra.Command{
@@ -0,0 +1,190 @@
+/*
+This package provides the copy command, which puts the secret value of a record to the clipboard
+instead of stdout, and the hidden helper clearing the clipboard after the timeout.
+
+Main functionalities include:
+
+- Copying the secret field of data, the field by name or the current one-time password code.
+- Copying by the OSC52 terminal sequences or by the clipboard programs of the user config.
+- Clearing the clipboard by the detached helper, if it still holds the copied value.
+*/
+
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gophKeeper/internal/client/clipboard"
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/model/out"
+	"gophKeeper/internal/client/model/type/otp"
+
+	"github.com/spf13/cobra"
+)
+
+// clipboardClearCmd the name of the hidden helper command clearing the clipboard
+const clipboardClearCmd = "clipboard-clear"
+
+// addCopyCmd adds the copy command and the hidden clipboard clear helper to the root command.
+func (a *app) addCopyCmd() *app {
+	cmd := &cobra.Command{
+		Use:   "copy <key name> [field]",
+		Short: "Copy secret to clipboard",
+		Long: `Decrypt data and put the value of the field to the clipboard instead of stdout.
+The field is the data field by its name or the extra field,
+the first secret field is copied by default, the current code for one-time passwords.
+The clipboard is set by the OSC52 terminal sequences, they work over SSH too,
+or by the clipboard programs of the user config:
+  config user --clipboard.copy "wl-copy" --clipboard.paste "wl-paste -n"
+The clipboard is cleared after clipboard.clear_after of the user config, 30s by default,
+if it still holds the copied value. The terminal clipboard can not be read, so it is cleared anyway.`,
+		Example: `  copy <key name>
+  copy <key name> login
+  copy <otp key name>`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 || len(args) > 2 {
+				_ = cmd.Help()
+				return
+			}
+			data, err := a.Srv().Get(args[0])
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					cmd.Printf("Record not exist: %s\n", args[0])
+				} else {
+					cmd.Println("Data get error:", err)
+				}
+				return
+			}
+			var field string
+			if len(args) > 1 {
+				field = args[1]
+			}
+			a.copyField(cmd, data, field)
+		},
+	}
+
+	var (
+		after                                    time.Duration
+		hash, backend, copyProgram, pasteProgram string
+	)
+	clearCmd := &cobra.Command{
+		Use:    clipboardClearCmd,
+		Short:  "Clear the clipboard after the time",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			time.Sleep(after)
+			c := clipboard.Config{Backend: backend, CopyCmd: copyProgram, PasteCmd: pasteProgram}
+			// the terminal is passed by the client for the osc52 sequences
+			cb, err := c.New(os.NewFile(clipboard.TTYFd, "tty"))
+			if err != nil {
+				cmd.PrintErrf("clipboard error: %v\n", err)
+				return
+			}
+			if _, err = clipboard.Clear(cb, hash); err != nil {
+				cmd.PrintErrf("clipboard clear error: %v\n", err)
+			}
+		},
+	}
+	clearCmd.Flags().DurationVar(&after, "after", clipboard.DefaultClearAfter, "clear after the time")
+	clearCmd.Flags().StringVar(&hash, "hash", "", "sha256 of the copied value")
+	clearCmd.Flags().StringVar(&backend, "backend", clipboard.BackendOSC52, "clipboard backend")
+	clearCmd.Flags().StringVar(&copyProgram, "copy", "", "clipboard copy program")
+	clearCmd.Flags().StringVar(&pasteProgram, "paste", "", "clipboard paste program")
+
+	a.root.AddCommand(cmd, clearCmd)
+	return a
+}
+
+// copyField puts the value of the data field to the clipboard and prints the result.
+// The first secret field is copied if the field is empty, the current code for one-time passwords.
+func (a *app) copyField(cmd *cobra.Command, data out.Item, field string) {
+	var (
+		value string
+		ok    bool
+	)
+	if d, isOTP := data.Data.(*otp.Data); isOTP && (field == "" || field == "code") {
+		var (
+			code string
+			err  error
+		)
+		if d.Type == otp.TypeHOTP {
+			// the counter is advanced past the copied code
+			code, _, err = a.Srv().NextHOTP(data.Key)
+		} else {
+			code, _, err = d.Code(time.Now())
+		}
+		if err != nil {
+			cmd.PrintErrf("otp code error: %v\n", err)
+			return
+		}
+		value, ok, field = code, true, "code"
+	} else {
+		if field == "" {
+			if field, ok = data.SecretField(); !ok {
+				cmd.PrintErrln("No secret field to copy, set the field name")
+				return
+			}
+		}
+		value, ok = data.Field(field)
+	}
+	if !ok {
+		cmd.PrintErrf("Field not exist: %s\n", field)
+		return
+	}
+	clearAfter, err := copyToClipboard(value)
+	if err != nil {
+		cmd.PrintErrf("clipboard error: %v\n", err)
+		return
+	}
+	if clearAfter > 0 {
+		cmd.Printf("Copied %s to clipboard, it is cleared in %s\n", field, clearAfter)
+	} else {
+		cmd.Printf("Copied %s to clipboard\n", field)
+	}
+}
+
+// copyToClipboard puts the value to the clipboard of the user config
+// and starts the detached helper clearing it, clearAfter is 0 if it is kept.
+func copyToClipboard(value string) (clearAfter time.Duration, err error) {
+	if err = cfg.UserLoad(); err != nil {
+		return
+	}
+	c := cfg.GetClipboard()
+	kind, err := c.Kind()
+	if err != nil {
+		return
+	}
+	var tty *os.File
+	if kind == clipboard.BackendOSC52 {
+		if tty, err = clipboard.Terminal(); err != nil {
+			return 0, fmt.Errorf("the terminal is required for %s: %w", kind, err)
+		}
+		defer func() { _ = tty.Close() }()
+	}
+	cb, err := c.New(tty)
+	if err != nil {
+		return
+	}
+	if err = cb.Copy(value); err != nil || c.ClearAfter <= 0 {
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	_, err = clipboard.Detach(tty, exe, clipboardClearCmd,
+		"--after", c.ClearAfter.String(),
+		"--hash", clipboard.Hash(value),
+		"--backend", kind,
+		"--copy", c.CopyCmd,
+		"--paste", c.PasteCmd)
+	if err != nil {
+		return 0, fmt.Errorf("the clipboard is not cleared: %w", err)
+	}
+	return c.ClearAfter, nil
+}
@@ -4,7 +4,7 @@ It uses the Cobra library to define commands for deleting records by their keys.
 
 Main functionalities include:
 
-- Deleting one or more records by specifying their keys.
+- Deleting one or more records by specifying their keys, the deleted records are kept at the trash until purged.
 */
 package cmd
 
@@ -24,7 +24,8 @@ func (a *app) addDeleteCmd() *app {
 	cmd := &cobra.Command{
 		Use:   "delete [flags] record_key [...record_key]",
 		Short: "delete records",
-		Long:  `delete records by their keys`,
+		Long: `delete records by their keys.
+The deleted records are moved to the trash, see trash list, trash restore and trash purge`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) < 1 {
 				cmd.PrintErrln("You must specify a record key")
@@ -43,7 +44,7 @@ func (a *app) addDeleteCmd() *app {
 						cmd.PrintErrf("Delete error: %s\n", err)
 					}
 				} else {
-					cmd.Printf("%s successfully deleted \n", key)
+					cmd.Printf("%s successfully deleted, it is kept at the trash\n", key)
 				}
 			}
 		},
@@ -0,0 +1,355 @@
+/*
+This package provides the edit command, which changes the fields of an existing record.
+
+Main functionalities include:
+
+- Changing only the fields given by the flags of the record type, the other fields are kept.
+- Editing the decrypted JSON of the record in $EDITOR, it is validated by the model before saving.
+- Saving the changed record with the new updated time, so it is synchronized.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/out"
+	"gophKeeper/internal/client/model/type/bin"
+	"gophKeeper/internal/client/model/type/template"
+	"gophKeeper/internal/helper"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// defaultEditor the editor used if neither EDITOR nor VISUAL is set
+const defaultEditor = "vi"
+
+// editDoc the decrypted record edited in the editor
+type editDoc struct {
+	Description string          `json:"description"`
+	Data        json.RawMessage `json:"data"`
+	model.Extra
+}
+
+// addEditCmd adds the edit command to the root command.
+// The flags of the command are the save flags of the record type, so they are parsed by the command itself.
+func (a *app) addEditCmd() *app {
+	cmd := &cobra.Command{
+		Use:   "edit <key name> [flags of the record type]",
+		Short: "Edit data",
+		Long: `Decrypt the record and change only the fields of the given flags, the flags are of save of the record type.
+The extra field and url flags replace the lists of fields and urls, the key can not be changed.
+Without flags the decrypted JSON of the record is opened in $EDITOR, it is validated before saving.
+The record gets the new updated time, so the change is synchronized.
+The key name goes before the flags of the record type, they are known by the type of the record.
+edit <key name> --help shows the flags of the record type.`,
+		Example: `  edit github -p newpass
+  edit my-card --exp 10/30 -d "new card"
+  edit github
+  EDITOR=nano edit github`,
+		DisableFlagParsing: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			key, err := editKey(cmd, args)
+			if err != nil {
+				cmd.PrintErrln(err)
+				return
+			}
+			if key == "" {
+				_ = cmd.Help()
+				return
+			}
+			// the root flags are parsed by editKey, they are applied as if they are parsed by cobra
+			if root := cmd.Root(); root.PersistentPreRun != nil {
+				root.PersistentPreRun(cmd, args)
+			}
+			data, err := a.Srv().Get(key)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					cmd.Printf("Record not exist: %s\n", key)
+				} else {
+					cmd.Println("Data get error:", err)
+				}
+				return
+			}
+			m, fs, err := a.editModel(cmd, data)
+			if err != nil {
+				cmd.PrintErrln(err)
+				return
+			}
+			if err = fs.Parse(args); err != nil {
+				cmd.PrintErrln(err)
+				return
+			}
+			if fs.NArg() > 1 {
+				cmd.PrintErrf("unexpected arguments: %s\n", strings.Join(fs.Args()[1:], " "))
+				return
+			}
+			if help, _ := fs.GetBool("help"); help {
+				cmd.Printf("Flags of %s records:\n%s", model.GetName(data.Data), fs.FlagUsages())
+				return
+			}
+			if fs.Changed("key") {
+				cmd.PrintErrln("The key can not be changed")
+				return
+			}
+			if d, ok := data.Data.(*bin.Data); ok && d.Size > 0 && len(d.Bin) == 0 && !fs.Changed("file") {
+				cmd.PrintErrln("The content of large file is not edited, set the new file by -f")
+				return
+			}
+			if editFlags(cmd, fs) == 0 {
+				err = a.editInEditor(cmd, m, data)
+			} else {
+				err = a.saveEdited(m)
+			}
+			if err != nil {
+				cmd.PrintErrln(err)
+				return
+			}
+		},
+	}
+	a.root.AddCommand(cmd)
+	return a
+}
+
+// editKey returns the key of the edit arguments, it is the first argument after the root flags.
+// The flags of the record type are not known before the key, so they must follow it.
+// The root flags after the key are parsed too, the flags of the record type are skipped then.
+// The key is empty for the help flag without key.
+func editKey(cmd *cobra.Command, args []string) (key string, err error) {
+	fs := editRootFlags(cmd)
+	fs.SetInterspersed(false)
+	if err = fs.Parse(args); err != nil {
+		return "", fmt.Errorf("%w, the key name must precede the flags of the record type", err)
+	}
+	if fs.NArg() == 0 {
+		return
+	}
+	rest := editRootFlags(cmd)
+	rest.ParseErrorsWhitelist.UnknownFlags = true
+	if err = rest.Parse(fs.Args()[1:]); err != nil {
+		return
+	}
+	return fs.Arg(0), nil
+}
+
+// editRootFlags returns the flag set of the root flags and the help flag of the edit command.
+func editRootFlags(cmd *cobra.Command) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	fs.AddFlagSet(cmd.InheritedFlags())
+	fs.BoolP("help", "h", false, "help")
+	fs.SetOutput(&bytes.Buffer{})
+	return fs
+}
+
+// editFlags returns the number of the changed flags of the record, the root flags are not counted.
+func editFlags(cmd *cobra.Command, fs *pflag.FlagSet) (n int) {
+	fs.Visit(func(f *pflag.Flag) {
+		if f.Name != "help" && cmd.InheritedFlags().Lookup(f.Name) == nil {
+			n++
+		}
+	})
+	return
+}
+
+// newModel returns the empty model of the record type, the template records get the model of their template.
+func newModel(data model.Data) (m model.Model, err error) {
+	d, ok := data.(*template.Data)
+	if !ok {
+		if m, err = model.GetNewModel(model.GetName(data)); err != nil {
+			err = fmt.Errorf("the record of type %s can not be edited", model.GetName(data))
+		}
+		return
+	}
+	var dir string
+	if dir, err = cfg.UsrCfgDir(); err != nil {
+		return
+	}
+	var templates []template.Template
+	if templates, _, err = template.Load(dir); err != nil {
+		return
+	}
+	for _, t := range templates {
+		if t.Name == d.Template {
+			return template.New(t), nil
+		}
+	}
+	err = fmt.Errorf("%w: %s is not found", template.ErrTemplate, d.Template)
+	return
+}
+
+// editModel returns the model of the record type set by the record data, and the flags of the model.
+// The flags are added before the data is set, so their defaults do not change the record.
+func (a *app) editModel(cmd *cobra.Command, item out.Item) (m model.Model, fs *pflag.FlagSet, err error) {
+	if m, err = newModel(item.Data); err != nil {
+		return
+	}
+	fs = pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	fs.SetOutput(cmd.ErrOrStderr())
+	err = errors.Join(helper.GenerateFlags(m.GetBase(), fs), helper.GenerateFlags(m.GetDst(), fs))
+	if t, ok := m.(*template.Model); ok {
+		t.GenerateFlags(fs)
+	}
+	if err != nil {
+		return
+	}
+	fs.BoolP("help", "h", false, "flags of the record type")
+	fs.AddFlagSet(cmd.InheritedFlags())
+	err = setModelData(m, item)
+	return
+}
+
+// setModelData sets the key, description, extra fields and data of the item to the model.
+func setModelData(m model.Model, item out.Item) (err error) {
+	if reflect.TypeOf(m.GetDst()) != reflect.TypeOf(item.Data) {
+		return fmt.Errorf("the record of type %s does not match %s", model.GetName(item.Data), model.GetName(m))
+	}
+	base := m.GetBase()
+	base.Key = item.Key
+	base.Description = item.Description
+	base.Extra = item.Extra
+	if t, ok := m.(*template.Model); ok {
+		t.SetData(item.Data.(*template.Data))
+		return
+	}
+	reflect.ValueOf(m.GetDst()).Elem().Set(reflect.ValueOf(item.Data).Elem())
+	return
+}
+
+// saveEdited sanitizes and saves the edited model.
+func (a *app) saveEdited(m model.Model) (err error) {
+	if d, ok := m.GetDst().(model.Sanitisable); ok {
+		d.Sanitize()
+	}
+	if err = a.Srv().Save(m); err != nil {
+		return
+	}
+	a.root.Println("Data saved successfully")
+	return
+}
+
+// editInEditor opens the decrypted JSON of the record in the editor and saves the edited record.
+// The file is in the private temporary directory, memory backed if available, it is removed at exit
+// and on interrupt or termination, the editor is stopped then.
+// If the edited record is not valid, the editor is opened again on confirmation.
+func (a *app) editInEditor(cmd *cobra.Command, m model.Model, item out.Item) (err error) {
+	d, err := json.Marshal(item.Data)
+	if err != nil {
+		return
+	}
+	doc, err := json.MarshalIndent(editDoc{Description: item.Description, Data: d, Extra: item.Extra}, "", "  ")
+	if err != nil {
+		return
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	dir, err := os.MkdirTemp(editTempDir(), "gophkeeper-edit-")
+	if err != nil {
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	// the key is not a part of the name, it may have path separators
+	fileName := filepath.Join(dir, "record.json")
+	if err = os.WriteFile(fileName, append(doc, '\n'), 0600); err != nil {
+		return
+	}
+	cmd.Printf("The decrypted record is written to %s, it is removed after editing\n", fileName)
+	for {
+		if err = runEditor(ctx, fileName); err != nil {
+			if ctx.Err() != nil {
+				err = errors.New("edit interrupted, not saved")
+			}
+			return
+		}
+		var edited []byte
+		if edited, err = os.ReadFile(fileName); err != nil {
+			return
+		}
+		if bytes.Equal(bytes.TrimSpace(edited), doc) {
+			cmd.Println("Not changed")
+			return
+		}
+		if err = setEditedDoc(m, item, edited); err == nil {
+			if err = a.saveEdited(m); err == nil {
+				return
+			}
+		}
+		cmd.PrintErrln(err)
+		if !confirm(cmd, "Edit again? [y/N] ") {
+			return errors.New("not saved")
+		}
+	}
+}
+
+// setEditedDoc sets the edited JSON document of the record to the model.
+func setEditedDoc(m model.Model, item out.Item, b []byte) (err error) {
+	var doc editDoc
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&doc); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	data, err := model.GetNewDataModel(model.GetName(item.Data))
+	if err != nil {
+		return
+	}
+	dec = json.NewDecoder(bytes.NewReader(doc.Data))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(data); err != nil {
+		return fmt.Errorf("invalid data: %w", err)
+	}
+	item.Data, item.Description, item.Extra = data, doc.Description, doc.Extra
+	return setModelData(m, item)
+}
+
+// editTempDir returns the directory for the decrypted record, the memory backed one of XDG_RUNTIME_DIR or /dev/shm
+// if available, the default temporary directory otherwise.
+func editTempDir() string {
+	for _, dir := range []string{os.Getenv("XDG_RUNTIME_DIR"), "/dev/shm"} {
+		if fi, err := os.Stat(dir); dir != "" && err == nil && fi.IsDir() {
+			return dir
+		}
+	}
+	return ""
+}
+
+// runEditor runs the editor of EDITOR or VISUAL with the file at the terminal, the editor is killed when ctx is done.
+func runEditor(ctx context.Context, fileName string) error {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = os.Getenv("VISUAL")
+	}
+	if editor == "" {
+		editor = defaultEditor
+	}
+	args := strings.Fields(editor)
+	c := exec.CommandContext(ctx, args[0], append(args[1:], fileName)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("editor %s: %w", editor, err)
+	}
+	return nil
+}
+
+// confirm asks the question at the terminal, the answer is yes for y or yes.
+func confirm(cmd *cobra.Command, question string) bool {
+	cmd.Print(question)
+	answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
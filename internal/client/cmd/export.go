@@ -0,0 +1,241 @@
+/*
+This package provides the export command, which writes the profile to the encrypted vault archive
+or the decrypted records to the plain csv, json or yaml file.
+
+Main functionalities include:
+
+- Writing the records with the contents of the file store, the templates and the packed key
+  to the single .gkx archive encrypted by the separate export passphrase.
+- Keeping the records encrypted by the profile key, so the master passphrase is not asked.
+- Writing the decrypted records by --format with --plaintext, after the confirmation
+  and the master passphrase, filtered by --type and --key.
+- Replacing the output file only when it is completely written.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/exporter"
+	"gophKeeper/internal/client/input/password"
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/vault"
+
+	"github.com/spf13/cobra"
+)
+
+// addExportCmd adds the export command to the root command.
+func (a *app) addExportCmd() *app {
+	var (
+		out          string
+		passwordFile string
+		format       string
+		plaintext    bool
+		kind         string
+		key          string
+	)
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the profile to the encrypted vault archive or the records to plain csv, json or yaml",
+		Long: `Export the records, the contents of the file store, the templates and the wrapped encryption key
+of the profile to the single archive encrypted by the export passphrase, the deleted records are not exported.
+The records stay encrypted by the profile key, the archive is restored by "import <file>` + vault.Ext + `"
+into a new or existing profile, the master passphrase of the exported profile is asked there if needed.
+The export passphrase is asked with confirmation, or read from --export-passphrase-file.
+
+With --format csv|json|yaml and --plaintext the records are decrypted and written unencrypted
+to --out or to stdout, one row or object per record with the key, type, description, timestamps,
+data fields, extra fields and urls. The contents of the file store are not written, only their size.
+The export is confirmed and the master passphrase is asked always, the key cache is not used.
+The records are selected by --type and by the part of the key by --key.`,
+		Example: `  export --out vault.gkx
+  export --out /media/backup/vault.gkx --export-passphrase-file ~/.export-pass
+  export --format csv --plaintext --out records.csv
+  export --format json --plaintext --type auth --key mail > auth.json`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer func() {
+				out, passwordFile, format, plaintext, kind, key = "", "", "", false, "", ""
+			}()
+			if format != "" || plaintext {
+				a.exportPlain(cmd, out, format, plaintext, kind, key)
+				return
+			}
+			if kind != "" || key != "" {
+				cmd.PrintErrln("--type and --key select the records of the plaintext export only")
+				return
+			}
+			if out == "" {
+				cmd.PrintErrln("the archive file is not set by --out")
+				return
+			}
+			if _, err := os.Stat(out); err == nil && !confirm(cmd, out+" exists, replace it? [y/N] ") {
+				return
+			}
+			var src password.Source
+			if passwordFile != "" {
+				src = password.FileSource(passwordFile)
+			}
+			pass, err := password.GetPassphrase(src, true, cfg.PromptExportPs, cfg.PromptConfirmExportPs)
+			if err != nil {
+				cmd.PrintErrf("password error: %v\n", err)
+				return
+			}
+			n, err := a.exportVault(out, pass)
+			if err != nil {
+				cmd.PrintErrf("export error: %v\n", err)
+				return
+			}
+			cmd.Printf("Exported records: %d to %s\n", n, out)
+		},
+	}
+	cmd.Flags().StringVarP(&out, "out", "o", "", "file of the vault archive, "+vault.Ext+", or of the plaintext export, stdout if not set")
+	cmd.Flags().StringVar(&passwordFile, "export-passphrase-file", "", "file of the export passphrase")
+	cmd.Flags().StringVar(&format, "format", "", "plaintext format: "+strings.Join(exporter.Formats, ", "))
+	cmd.Flags().BoolVar(&plaintext, "plaintext", false, "confirm writing the decrypted records by --format")
+	cmd.Flags().StringVar(&kind, "type", "", "export the records of the type only")
+	cmd.Flags().StringVarP(&key, "key", "k", "", "export the records with the key containing it only")
+	a.root.AddCommand(cmd)
+	return a
+}
+
+// exportVault writes the archive to the out file.
+func (a *app) exportVault(out, pass string) (n int, err error) {
+	err = writeFile(out, func(w io.Writer) (err error) {
+		n, err = a.Srv().ExportVault(w, pass)
+		return
+	})
+	return
+}
+
+// writeFile writes to the temporary file next to the out file and renames it on success.
+func writeFile(out string, write func(w io.Writer) error) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(out), "."+filepath.Base(out)+"-*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+	err = errors.Join(write(f), f.Close())
+	if err != nil {
+		return
+	}
+	err = os.Rename(f.Name(), out)
+	return
+}
+
+// exportPlain checks the flags, confirms the export and asks the master passphrase,
+// then writes the decrypted records to the out file or to stdout.
+func (a *app) exportPlain(cmd *cobra.Command, out, format string, plaintext bool, kind, key string) {
+	if !slices.Contains(exporter.Formats, format) {
+		cmd.PrintErrf("%v: %q, supported: %s\n", exporter.ErrFormat, format, strings.Join(exporter.Formats, ", "))
+		return
+	}
+	if !plaintext {
+		cmd.PrintErrln("the records are written decrypted, confirm it by --plaintext")
+		return
+	}
+	if kind != "" {
+		if _, err := model.GetNewDataModel(kind); err != nil {
+			cmd.PrintErrf("type error: %v\n", err)
+			return
+		}
+	}
+	question := "The records will be written decrypted to " + out + ", continue? [y/N] "
+	if out == "" {
+		question = "The records will be written decrypted to stdout, continue? [y/N] "
+	} else if _, err := os.Stat(out); err == nil {
+		question = "The records will be written decrypted to " + out + ", it exists and will be replaced, continue? [y/N] "
+	}
+	if !confirm(cmd, question) {
+		return
+	}
+	if err := a.Srv().CheckPassphrase(); err != nil {
+		cmd.PrintErrf("password error: %v\n", err)
+		return
+	}
+	var (
+		n   int
+		err error
+	)
+	if out == "" {
+		n, err = a.exportRecords(cmd.OutOrStdout(), format, kind, key)
+	} else {
+		err = writeFile(out, func(w io.Writer) (err error) {
+			n, err = a.exportRecords(w, format, kind, key)
+			return
+		})
+	}
+	if err != nil {
+		cmd.PrintErrf("export error: %v\n", err)
+		return
+	}
+	if out == "" {
+		cmd.PrintErrf("Exported records: %d\n", n)
+		return
+	}
+	cmd.Printf("Exported records: %d to %s\n", n, out)
+}
+
+// exportRecords writes the decrypted records of the type and key to w one by one.
+// The csv columns are known before its first row, so the records are decrypted twice for csv:
+// to collect the data fields and to write them.
+func (a *app) exportRecords(w io.Writer, format, kind, key string) (n int, err error) {
+	list, err := a.Srv().List(model.ListQuery{Key: key, OrderBy: "key"})
+	if err != nil {
+		return
+	}
+	each := func(fn func(r exporter.Record) error) error {
+		for _, item := range list.Items {
+			data, err := a.Srv().Get(item.Key)
+			if err != nil {
+				return fmt.Errorf("%s: %w", item.Key, err)
+			}
+			r, err := exporter.Normalize(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", item.Key, err)
+			}
+			if kind != "" && r.Type != kind {
+				continue
+			}
+			if err = fn(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var columns []string
+	if format == exporter.FormatCSV {
+		if err = each(func(r exporter.Record) error {
+			for _, c := range r.Columns() {
+				if !slices.Contains(columns, c) {
+					columns = append(columns, c)
+				}
+			}
+			return nil
+		}); err != nil {
+			return
+		}
+	}
+	ew, err := exporter.NewWriter(format, w, columns)
+	if err != nil {
+		return
+	}
+	err = each(func(r exporter.Record) error {
+		n++
+		return ew.Write(r)
+	})
+	err = errors.Join(err, ew.Close())
+	return
+}
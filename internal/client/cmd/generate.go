@@ -0,0 +1,128 @@
+/*
+This package provides the generate command, which creates random passwords and diceware passphrases.
+
+Main functionalities include:
+
+- Generating passwords of the chosen length and character classes, without look-alike characters optionally.
+- Generating diceware passphrases of the embedded wordlist.
+- Printing the entropy estimate of the generated secret.
+- Taking the default policy of the profile from the user config, the flags override it.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/generate"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// addGenerateCmd adds the generate command to the root command.
+// The secret is printed to stdout, the entropy estimate is printed to stderr.
+func (a *app) addGenerateCmd() *app {
+	var (
+		flags  = generate.DefaultPolicy()
+		copyTo bool
+	)
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate password",
+		Long: `Generate a random password or diceware passphrase by crypto/rand and print it to stdout.
+The passwords have at least one character of every chosen class,
+the passphrases are the words of the embedded EFF large wordlist.
+The default policy of the profile is set by config user --generate.*, the flags override it.
+The entropy estimate is printed to stderr.`,
+		Example: `  generate
+  generate --length 32 --symbols=false --exclude-similar
+  generate --words 6 --separator " "
+  generate --copy`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer func() { flags, copyTo = generate.DefaultPolicy(), false }()
+			policy, err := generatePolicy(cmd.Flags(), flags)
+			if err != nil {
+				cmd.PrintErrf("failed to load config: %v\n", err)
+				return
+			}
+			secret, err := policy.Generate()
+			if err != nil {
+				cmd.PrintErrln(err)
+				return
+			}
+			if copyTo {
+				clearAfter, err := copyToClipboard(secret)
+				if err != nil {
+					cmd.PrintErrf("clipboard error: %v\n", err)
+					return
+				}
+				if clearAfter > 0 {
+					cmd.Printf("Copied to clipboard, it is cleared in %s\n", clearAfter)
+				} else {
+					cmd.Println("Copied to clipboard")
+				}
+			} else {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), secret)
+			}
+			cmd.Println(entropyInfo(policy))
+		},
+	}
+	addPolicyFlags(cmd.Flags(), &flags)
+	cmd.Flags().BoolVar(&copyTo, "copy", false, "copy to the clipboard instead of printing")
+	a.root.AddCommand(cmd)
+	return a
+}
+
+// addPolicyFlags adds the flags of the generate policy p,
+// the changed ones override the profile policy, see generatePolicy.
+func addPolicyFlags(fs *pflag.FlagSet, p *generate.Policy) {
+	fs.IntVar(&p.Length, "length", p.Length, "password length")
+	fs.BoolVar(&p.Lower, "lower", p.Lower, "use lower case letters")
+	fs.BoolVar(&p.Upper, "upper", p.Upper, "use upper case letters")
+	fs.BoolVar(&p.Digits, "digits", p.Digits, "use digits")
+	fs.BoolVar(&p.Symbols, "symbols", p.Symbols, "use symbols")
+	fs.BoolVar(&p.ExcludeSimilar, "exclude-similar", p.ExcludeSimilar, "exclude look-alike characters "+generate.Similar)
+	fs.BoolVar(&p.Diceware, "diceware", p.Diceware, "generate the diceware passphrase")
+	fs.IntVar(&p.Words, "words", p.Words, "words of the passphrase, it sets --diceware")
+	fs.StringVar(&p.Separator, "separator", p.Separator, "separator of the passphrase words")
+}
+
+// generatePolicy returns the profile policy overridden by the changed flags of the generate policy.
+func generatePolicy(fs *pflag.FlagSet, flags generate.Policy) (p generate.Policy, err error) {
+	if err = cfg.UserLoad(); err != nil {
+		return
+	}
+	p = cfg.GetGeneratePolicy()
+	fs.Visit(func(f *pflag.Flag) {
+		switch f.Name {
+		case "length":
+			p.Length = flags.Length
+		case "lower":
+			p.Lower = flags.Lower
+		case "upper":
+			p.Upper = flags.Upper
+		case "digits":
+			p.Digits = flags.Digits
+		case "symbols":
+			p.Symbols = flags.Symbols
+		case "exclude-similar":
+			p.ExcludeSimilar = flags.ExcludeSimilar
+		case "diceware":
+			p.Diceware = flags.Diceware
+		case "words":
+			p.Words, p.Diceware = flags.Words, true
+		case "separator":
+			p.Separator = flags.Separator
+		}
+	})
+	return
+}
+
+// entropyInfo returns the entropy estimate of the policy secrets.
+func entropyInfo(p generate.Policy) string {
+	entropy := p.Entropy()
+	return fmt.Sprintf("Entropy: ~%.0f bits, %s", entropy, generate.Strength(entropy))
+}
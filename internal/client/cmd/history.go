@@ -0,0 +1,88 @@
+/*
+This package provides the commands for the versions of a record kept on overwrite.
+
+Main functionalities include:
+
+- Listing the current version of a record and its kept previous versions.
+- Restoring a kept version, the restore is saved as a new update, so it is synchronized.
+
+A version is viewed by view <key> --version N.
+*/
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// addHistoryCmd adds the history and restore commands to the root command.
+func (a *app) addHistoryCmd() *app {
+	historyCmd := &cobra.Command{
+		Use:   "history <key name>",
+		Short: "List the versions of a record",
+		Long: `List the current version of a record, numbered 0, and its previous versions kept on overwrite,
+by save, edit, import or synchronization, the newest first. The time of version is the time it was saved,
+the replaced one is the time it was overwritten.
+The number of kept versions and their age are set by config user --history.keep and --history.max_age.`,
+		Example: `  history <key name>
+  view <key name> --version 2
+  restore <key name> --version 2`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			versions, err := a.Srv().History(args[0])
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					cmd.Printf("Record not exist: %s\n", args[0])
+				} else {
+					cmd.PrintErrf("History error: %v\n", err)
+				}
+				return
+			}
+			cmd.Println("Version\tSaved\tReplaced\tDescription")
+			for _, v := range versions {
+				date := v.UpdatedAt
+				if date == nil {
+					date = &v.CreatedAt
+				}
+				replaced := "current"
+				if v.Version != 0 {
+					replaced = v.SavedAt.Format(time.DateTime)
+				}
+				cmd.Printf("%d\t%s\t%s\t%s\n", v.Version, date.Format(time.DateTime), replaced, v.Description)
+			}
+		},
+	}
+	a.root.AddCommand(historyCmd)
+
+	var version int
+	restoreCmd := &cobra.Command{
+		Use:   "restore <key name> --version N",
+		Short: "Restore a version of a record",
+		Long: `Replace a record by its previous version listed by history.
+The restore is saved as a new update, so it is synchronized, the replaced record is kept as a version too.`,
+		Example: `  restore <key name> --version 2`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer func() { version = 0 }()
+			if version < 1 {
+				cmd.PrintErrln("the version to restore is not set by --version")
+				return
+			}
+			if err := a.Srv().RestoreVersion(args[0], version); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					cmd.Printf("Version not exist: %s %d\n", args[0], version)
+				} else {
+					cmd.PrintErrf("Restore error: %v\n", err)
+				}
+				return
+			}
+			cmd.Printf("%s restored from version %d\n", args[0], version)
+		},
+	}
+	restoreCmd.Flags().IntVar(&version, "version", 0, "the version to restore, see history")
+	a.root.AddCommand(restoreCmd)
+	return a
+}
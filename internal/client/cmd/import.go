@@ -0,0 +1,282 @@
+/*
+This package provides the import command, which reads the exports of other password managers.
+
+Main functionalities include:
+
+- Importing KeePass 2.x XML exports and KDBX 4 databases, Bitwarden JSON, 1Password 1PUX and CSV,
+  Chrome and Firefox CSV exports into the auth, card, text and bin records.
+- Resolving the keys of existing records by the skip, rename or overwrite strategy.
+- Restoring the encrypted vault archive of the export command with the original times of the records.
+- Reporting what would be created without saving by the dry run.
+*/
+
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/importer"
+	"gophKeeper/internal/client/input/password"
+	"gophKeeper/internal/client/vault"
+
+	"github.com/spf13/cobra"
+)
+
+// addImportCmd adds the import command to the root command.
+// The entries are planned by the collision strategy first, the plan is the dry run report.
+func (a *app) addImportCmd() *app {
+	var (
+		format       string
+		strategy     = importer.Skip
+		dryRun       bool
+		passwordFile string
+	)
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import data of other password managers",
+		Long: `Import the export file of other password manager, the format is detected by the file extension or set by --format.
+The formats: ` + strings.Join(importer.Formats(), ", ") + `, ` + vault.Format + `.
+The logins are imported as auth records, the cards as card, the notes and the other items as text,
+the attachments and documents as bin. The folders are the descriptions, the notes, custom fields and TOTP secrets
+are the extra fields, the urls are the record urls.
+The keys are the titles, the duplicate keys of the file get the " (2)" suffix.
+--on-collision is the strategy of the keys of existing records: skip, rename or overwrite.
+The KDBX database password is asked, or read from --source-password-file.
+--dry-run reports what would be created, nothing is saved.
+The ` + vault.Ext + ` vault archive of the export command is restored with the original times of the records,
+its export passphrase is asked, or read from --source-password-file. The archive is checked as a whole,
+nothing is restored from the damaged one. The profile without encryption key takes the key of the archive,
+the records of another key are re-encrypted with the profile key, the master passphrase of the exported profile
+is asked then. The vault records are bound to their keys, so the collision strategy is skip or overwrite.`,
+		Example: `  import passwords.csv --dry-run
+  import bitwarden.json --on-collision rename
+  import keepass.kdbx
+  import export.xml --format keepass-xml --on-collision overwrite
+  import vault.gkx --on-collision overwrite`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer func() { format, strategy, dryRun, passwordFile = "", importer.Skip, false, "" }()
+			var err error
+			if format == "" && strings.EqualFold(filepath.Ext(args[0]), vault.Ext) {
+				format = vault.Format
+			}
+			if format == vault.Format {
+				a.importVault(cmd, args[0], strategy, dryRun, passwordFile)
+				return
+			}
+			if format == "" {
+				if format, err = importer.Detect(args[0]); err != nil {
+					cmd.PrintErrln(err)
+					return
+				}
+			}
+			var opts importer.Options
+			if format == importer.FormatKDBX {
+				var src password.Source
+				if passwordFile != "" {
+					src = password.FileSource(passwordFile)
+				}
+				if opts.Password, err = password.GetPassphrase(src, false, "KeePass database password: "); err != nil {
+					cmd.PrintErrf("password error: %v\n", err)
+					return
+				}
+			}
+			entries, err := parseImport(args[0], format, opts)
+			if err != nil {
+				cmd.PrintErrf("import error: %v\n", err)
+				return
+			}
+			actions, err := importer.Plan(entries, strategy, a.recordExists)
+			if err != nil {
+				cmd.PrintErrf("import error: %v\n", err)
+				return
+			}
+			if dryRun {
+				a.importReport(cmd, actions)
+				return
+			}
+			if err = a.importActions(cmd, actions); err != nil {
+				cmd.PrintErrf("import error: %v\n", err)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "", "import format: "+strings.Join(append(importer.Formats(), vault.Format), ", "))
+	cmd.Flags().StringVar(&strategy, "on-collision", importer.Skip, "strategy of the keys of existing records: "+strings.Join(importer.Strategies, ", "))
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "report what would be created, nothing is saved")
+	cmd.Flags().StringVar(&passwordFile, "source-password-file", "", "file of the KDBX database password or of the vault archive passphrase")
+	a.root.AddCommand(cmd)
+	return a
+}
+
+// parseImport returns the entries of the file of the format.
+func parseImport(fileName, format string, opts importer.Options) (entries []importer.Entry, err error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	return importer.Parse(format, f, opts)
+}
+
+// recordExists checks the record of the key is kept, the deleted records are not.
+func (a *app) recordExists(key string) (bool, error) {
+	r, err := a.Srv().GetRaw(key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !r.IsDeleted(), nil
+}
+
+// printAction prints the action of the import report.
+func printAction(cmd *cobra.Command, action importer.Action, state string) {
+	cmd.Printf("%s\t%s\t%s\t%s\n", state, action.Key, action.Entry.Kind, action.Entry.Folder)
+}
+
+// importReport prints the planned actions, the entries are validated, nothing is saved.
+func (a *app) importReport(cmd *cobra.Command, actions []importer.Action) {
+	counts := make(map[string]int)
+	for _, action := range actions {
+		state := action.Op
+		if action.Op != importer.OpSkip {
+			m, err := action.Entry.Model(action.Key, "")
+			if err == nil {
+				if err = m.GetBase().SetExtra(); err == nil {
+					err = m.Validate()
+				}
+			}
+			if err != nil {
+				state = fmt.Sprintf("invalid: %v", err)
+			}
+		}
+		printAction(cmd, action, state)
+		if strings.HasPrefix(state, "invalid") {
+			state = "invalid"
+		}
+		counts[state]++
+	}
+	cmd.Printf("Dry run, nothing is saved. Would create: %d, rename: %d, overwrite: %d, skip: %d, invalid: %d\n",
+		counts[importer.OpCreate], counts[importer.OpRename], counts[importer.OpOverwrite], counts[importer.OpSkip], counts["invalid"])
+}
+
+// importActions saves the entries of the actions, the failed entries are reported and skipped.
+// The contents of bin entries are written to the private temporary directory, it is removed at exit.
+func (a *app) importActions(cmd *cobra.Command, actions []importer.Action) (err error) {
+	dir, err := os.MkdirTemp("", "gophkeeper-import-")
+	if err != nil {
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	counts := make(map[string]int)
+	for _, action := range actions {
+		state := action.Op
+		if action.Op != importer.OpSkip {
+			if err = a.importEntry(action, dir); err != nil {
+				state = "failed"
+				cmd.PrintErrf("%s: %v\n", action.Key, err)
+			}
+		}
+		printAction(cmd, action, state)
+		counts[state]++
+	}
+	cmd.Printf("Imported. Created: %d, renamed: %d, overwritten: %d, skipped: %d, failed: %d\n",
+		counts[importer.OpCreate], counts[importer.OpRename], counts[importer.OpOverwrite], counts[importer.OpSkip], counts["failed"])
+	return nil
+}
+
+// importEntry saves the entry of the action by its key.
+func (a *app) importEntry(action importer.Action, dir string) (err error) {
+	m, err := action.Entry.Model(action.Key, dir)
+	if err != nil {
+		return
+	}
+	if fileName := m.GetFileName(); fileName != "" {
+		defer func() { _ = os.Remove(fileName) }()
+	}
+	return a.Srv().Save(m)
+}
+
+// importVault restores the vault archive, the dry run reads the whole archive and reports its records.
+func (a *app) importVault(cmd *cobra.Command, fileName, strategy string, dryRun bool, passwordFile string) {
+	if strategy != importer.Skip && strategy != importer.Overwrite {
+		cmd.PrintErrf("import error: the vault archive records are bound to their keys, %s is not supported\n", strategy)
+		return
+	}
+	var src password.Source
+	if passwordFile != "" {
+		src = password.FileSource(passwordFile)
+	}
+	pass, err := password.GetPassphrase(src, false, cfg.PromptVaultPs)
+	if err != nil {
+		cmd.PrintErrf("password error: %v\n", err)
+		return
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		cmd.PrintErrf("import error: %v\n", err)
+		return
+	}
+	defer func() { _ = f.Close() }()
+	if dryRun {
+		if err = a.vaultReport(cmd, f, pass, strategy); err != nil {
+			cmd.PrintErrf("import error: %v\n", err)
+		}
+		return
+	}
+	res, err := a.Srv().RestoreVault(f, pass, strategy == importer.Overwrite)
+	if err != nil {
+		cmd.PrintErrf("import error: %v\n", err)
+		return
+	}
+	if res.ReKeyed {
+		cmd.Println("The records are re-encrypted with the encryption key of the profile")
+	}
+	cmd.Printf("Restored. Created: %d, overwritten: %d, skipped: %d\n", res.Created, res.Overwritten, res.Skipped)
+}
+
+// vaultReport prints the records of the archive by the strategy, nothing is saved.
+func (a *app) vaultReport(cmd *cobra.Command, r io.Reader, pass, strategy string) (err error) {
+	vr, err := vault.NewReader(r, pass)
+	if err != nil {
+		return
+	}
+	cmd.Printf("Vault archive of profile %s, created %s, records: %d\n", vr.Manifest.Profile,
+		vr.Header.CreatedAt.Format(time.DateTime), vr.Manifest.Records)
+	counts := make(map[string]int)
+	for {
+		var rec vault.Record
+		if rec, _, err = vr.Next(); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return
+		}
+		state := importer.OpCreate
+		var exists bool
+		if exists, err = a.recordExists(rec.Key); err != nil {
+			return
+		}
+		if exists {
+			state = strategy
+		}
+		updated := ""
+		if rec.UpdatedAt != nil {
+			updated = rec.UpdatedAt.Format(time.DateTime)
+		}
+		cmd.Printf("%s\t%s\t%s\t%s\n", state, rec.Key, rec.CreatedAt.Format(time.DateTime), updated)
+		counts[state]++
+	}
+	cmd.Printf("Dry run, nothing is saved. Would create: %d, overwrite: %d, skip: %d\n",
+		counts[importer.OpCreate], counts[importer.OpOverwrite], counts[importer.OpSkip])
+	return nil
+}
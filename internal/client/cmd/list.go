@@ -6,10 +6,12 @@ a list of stored data.
 Main functionalities include:
 
 - Displaying a list of kept data with details such as key, date, and description.
+- Searching the decrypted records by their extra fields, urls and expiry time.
 */
 package cmd
 
 import (
+	"strings"
 	"time"
 
 	"gophKeeper/internal/client/model"
@@ -27,12 +29,14 @@ func (a *app) addListCmd() *app {
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "list kept data",
-		Long:  `display list of kept data`,
+		Long: `display list of kept data,
+the search by --field, --url and --expiring decrypts the records to match their extra fields, urls and expiry time`,
 		Run: func(cmd *cobra.Command, args []string) {
 			dataList, err := a.Srv().List(query)
 			if err != nil {
 				cmd.Printf("Get list error: %s\n", err)
 			}
+			printSkipped(cmd, dataList.Skipped)
 			cmd.Printf("Total: %d\n", dataList.Total)
 			for _, item := range dataList.Items {
 				date := item.UpdatedAt
@@ -50,3 +54,10 @@ func (a *app) addListCmd() *app {
 	a.root.AddCommand(cmd)
 	return a
 }
+
+// printSkipped reports the records failed to decrypt by the search of the decrypted data.
+func printSkipped(cmd *cobra.Command, keys []string) {
+	if len(keys) > 0 {
+		cmd.PrintErrf("Failed to decrypt, skipped: %s\n", strings.Join(keys, ", "))
+	}
+}
@@ -8,11 +8,14 @@ Main functionalities include:
 - Listing available profiles.
 - Switching to another profile.
 - Changing the password for the current profile.
+- Rotating the encryption key of the current profile.
+- Encrypting the descriptions of the current profile.
 */
 package cmd
 
 import (
 	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/crypt"
 
 	"github.com/spf13/cobra"
 )
@@ -94,23 +97,303 @@ You can also create a new profile by the command
 				}
 			},
 		},
+		a.profilePasswordCmd(),
+		a.profileRecoveryCmd(),
+		a.profileKeyFileCmd(),
+		a.profileDescriptionCmd(),
 		&cobra.Command{
-			Use:   "password",
-			Short: "set new password",
+			Use:   "rotate-key",
+			Short: "replace the encryption key",
+			Long: `A new random encryption key is created and the data keys of all records are re-wrapped with it
+in one transaction, records saved by older versions are re-encrypted with own data keys.
+Rotated records are sent to the server at the next synchronization.`,
 			Run: func(cmd *cobra.Command, args []string) {
 				err := cfg.UserLoad()
 				if err != nil {
 					cmd.PrintErrf("failed to load config: %v\n", err)
 				}
 				cmd.Println("Current profile", cfg.GetUserName())
-				cmd.Println("Change password:.. ")
-				err = a.Srv().ChangePasswd()
+				var total int
+				err = a.Srv().RotateKey(func(done, n int) {
+					total = n
+					cmd.Printf("\rRe-wrapping data keys: %d/%d", done, n)
+				})
+				if total > 0 {
+					cmd.Println()
+				}
 				if err != nil {
-					cmd.PrintErrf("failed to change password: %v\n", err)
+					cmd.PrintErrf("failed to rotate encryption key: %v\n", err)
+					return
 				}
+				cmd.Println("Encryption key is rotated, records:", total)
 			},
 		},
 	)
 	a.root.AddCommand(cmd)
 	return a
 }
+
+// profilePasswordCmd returns a command for changing the master password of the current profile.
+// The costs of the key derivation can be raised by flags, not set costs are kept.
+func (a *app) profilePasswordCmd() *cobra.Command {
+	var costs crypt.KDFParams
+	cmd := &cobra.Command{
+		Use:   "password",
+		Short: "set new password",
+		Example: `  profile password
+  profile password --kdf-time 4 --kdf-memory 262144 --kdf-threads 4`,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer func() { costs = crypt.KDFParams{} }()
+			err := cfg.UserLoad()
+			if err != nil {
+				cmd.PrintErrf("failed to load config: %v\n", err)
+			}
+			cmd.Println("Current profile", cfg.GetUserName())
+			cmd.Println("Change password:.. ")
+			err = a.Srv().ChangePasswd(costs)
+			if err != nil {
+				cmd.PrintErrf("failed to change password: %v\n", err)
+			}
+		},
+	}
+	cmd.Flags().Uint32Var(&costs.Time, "kdf-time", 0, "argon2id passes (iterations)")
+	cmd.Flags().Uint32Var(&costs.Memory, "kdf-memory", 0, "argon2id memory in KiB")
+	cmd.Flags().Uint8Var(&costs.Threads, "kdf-threads", 0, "argon2id parallelism")
+	return cmd
+}
+
+// profileRecoveryCmd returns a command for the offline recovery of the encryption key.
+// The recovery code can be split into shares, any threshold of them replaces the code.
+func (a *app) profileRecoveryCmd() *cobra.Command {
+	var shares, threshold int
+	cmd := &cobra.Command{
+		Use:   "recovery",
+		Short: "recovery code of the encryption key",
+		Long: `The recovery code is the second wrapping of the encryption key,
+it resets a forgotten master password. Without arguments the recovery code status is shown.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := cfg.UserLoad()
+			if err != nil {
+				cmd.PrintErrf("failed to load config: %v\n", err)
+			}
+			cmd.Println("Current profile", cfg.GetUserName())
+			r, ok, err := cfg.GetRecovery()
+			if err != nil {
+				cmd.PrintErrf("failed to get recovery: %v\n", err)
+				return
+			}
+			if !ok {
+				cmd.Println("Recovery code is not created")
+				return
+			}
+			cmd.Println("Recovery code created at", r.CreatedAt)
+			if r.Shares > 0 {
+				cmd.Printf("Split into %d shares, %d of them are needed\n", r.Shares, r.Threshold)
+			}
+		},
+	}
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "create a new recovery code",
+		Long: `A new recovery code is created, the previous one is no longer valid.
+With --shares the code is split into shares, any --threshold of them restore the key.`,
+		Example: `  profile recovery create
+  profile recovery create --shares 5 --threshold 3`,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer func() { shares, threshold = 0, 0 }()
+			err := cfg.UserLoad()
+			if err != nil {
+				cmd.PrintErrf("failed to load config: %v\n", err)
+			}
+			cmd.Println("Current profile", cfg.GetUserName())
+			codes, err := a.Srv().CreateRecovery(shares, threshold)
+			if err != nil {
+				cmd.PrintErrf("failed to create recovery code: %v\n", err)
+				return
+			}
+			if len(codes) == 1 {
+				cmd.Println("Recovery code:")
+			} else {
+				cmd.Printf("Recovery shares, %d of them are needed:\n", threshold)
+			}
+			for _, code := range codes {
+				cmd.Println(" ", code)
+			}
+			cmd.Println("Write it down and keep offline, it is not shown again.")
+		},
+	}
+	create.Flags().IntVar(&shares, "shares", 0, "split the recovery code into the number of shares")
+	create.Flags().IntVar(&threshold, "threshold", 0, "number of shares to restore the key")
+	cmd.AddCommand(create,
+		&cobra.Command{
+			Use:   "use",
+			Short: "set new password by the recovery code",
+			Long:  `The recovery code or enough of its shares are asked, then the new master password.`,
+			Run: func(cmd *cobra.Command, args []string) {
+				err := cfg.UserLoad()
+				if err != nil {
+					cmd.PrintErrf("failed to load config: %v\n", err)
+				}
+				cmd.Println("Current profile", cfg.GetUserName())
+				if err = a.Srv().UseRecovery(); err != nil {
+					cmd.PrintErrf("failed to recover: %v\n", err)
+					return
+				}
+				cmd.Println("New password is set")
+			},
+		})
+	return cmd
+}
+
+// profileKeyFileCmd returns a command for the key file, the second factor of the encryption key.
+func (a *app) profileKeyFileCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keyfile",
+		Short: "key file of the encryption key",
+		Long: `The key file is random bytes stored outside the profile directory,
+it is needed together with the master password to unlock the encryption key.
+Without arguments the key file status is shown.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := cfg.UserLoad()
+			if err != nil {
+				cmd.PrintErrf("failed to load config: %v\n", err)
+			}
+			cmd.Println("Current profile", cfg.GetUserName())
+			params, _, err := cfg.GetKDFParams()
+			if err != nil {
+				cmd.PrintErrf("failed to get key derivation parameters: %v\n", err)
+				return
+			}
+			path := cfg.User.GetString("key_file")
+			switch {
+			case params.KeyFile != "" && path != "":
+				cmd.Println("Key file is required:", path)
+			case params.KeyFile != "":
+				cmd.Println("Key file is required, but its path is not set")
+			case path != "":
+				cmd.Println("Key file will be required by the new encryption key:", path)
+			default:
+				cmd.Println("Key file is not used")
+			}
+		},
+	}
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "set <path>",
+			Short: "set the key file",
+			Long: `The not existing key file is created with random content.
+The key file with the same content, as a copy on another device, only updates its path,
+other one replaces the current key file, the master password is asked.`,
+			Args: cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				err := cfg.UserLoad()
+				if err != nil {
+					cmd.PrintErrf("failed to load config: %v\n", err)
+				}
+				cmd.Println("Current profile", cfg.GetUserName())
+				created, err := a.Srv().SetKeyFile(args[0])
+				if err != nil {
+					cmd.PrintErrf("failed to set key file: %v\n", err)
+					return
+				}
+				if created {
+					cmd.Println("New key file is created:", args[0])
+					cmd.Println("Keep a copy of it, the encryption key can not be unlocked without it.")
+				}
+				cmd.Println("Key file is set")
+			},
+		},
+		&cobra.Command{
+			Use:   "remove",
+			Short: "do not use the key file",
+			Run: func(cmd *cobra.Command, args []string) {
+				err := cfg.UserLoad()
+				if err != nil {
+					cmd.PrintErrf("failed to load config: %v\n", err)
+				}
+				cmd.Println("Current profile", cfg.GetUserName())
+				if err = a.Srv().RemoveKeyFile(); err != nil {
+					cmd.PrintErrf("failed to remove key file: %v\n", err)
+					return
+				}
+				cmd.Println("Key file is not used anymore")
+			},
+		})
+	return cmd
+}
+
+// profileDescriptionCmd returns a command for the description mode, encrypted or plain.
+func (a *app) profileDescriptionCmd() *cobra.Command {
+	var serverIndex bool
+	cmd := &cobra.Command{
+		Use:   "description",
+		Short: "encryption of descriptions",
+		Long: `The encrypted descriptions are not readable at server, they are searched by list --description
+through the blind index: keyed tokens of the description words. The encrypted description is found
+by whole words only. Without arguments the description mode is shown.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := cfg.UserLoad()
+			if err != nil {
+				cmd.PrintErrf("failed to load config: %v\n", err)
+			}
+			cmd.Println("Current profile", cfg.GetUserName())
+			switch {
+			case cfg.User.GetBool("description.server_index"):
+				cmd.Println("Descriptions are encrypted, the search index is kept at server too")
+			case cfg.User.GetBool("description.encrypt"):
+				cmd.Println("Descriptions are encrypted, the search index is kept locally")
+			default:
+				cmd.Println("Descriptions are not encrypted")
+			}
+			n, err := a.Srv().CountEncryptedDescriptions()
+			if err != nil {
+				cmd.PrintErrf("failed to count encrypted descriptions: %v\n", err)
+				return
+			}
+			cmd.Println("Encrypted descriptions:", n)
+		},
+	}
+	encrypt := &cobra.Command{
+		Use:   "encrypt",
+		Short: "encrypt descriptions",
+		Long: `The descriptions of all records and the new ones are encrypted and indexed.
+With --server-index the search tokens are synchronized to server, without it they are removed from there.`,
+		Example: `  profile description encrypt
+  profile description encrypt --server-index`,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer func() { serverIndex = false }()
+			err := cfg.UserLoad()
+			if err != nil {
+				cmd.PrintErrf("failed to load config: %v\n", err)
+			}
+			cmd.Println("Current profile", cfg.GetUserName())
+			n, err := a.Srv().SetDescriptionMode(true, serverIndex)
+			if err != nil {
+				cmd.PrintErrf("failed to encrypt descriptions: %v\n", err)
+				return
+			}
+			cmd.Printf("Descriptions are encrypted, %d records are updated\n", n)
+		},
+	}
+	encrypt.Flags().BoolVar(&serverIndex, "server-index", false, "keep the search index at server")
+	cmd.AddCommand(encrypt,
+		&cobra.Command{
+			Use:   "plain",
+			Short: "decrypt descriptions",
+			Long:  `The descriptions of all records are decrypted, the new ones are saved as is.`,
+			Run: func(cmd *cobra.Command, args []string) {
+				err := cfg.UserLoad()
+				if err != nil {
+					cmd.PrintErrf("failed to load config: %v\n", err)
+				}
+				cmd.Println("Current profile", cfg.GetUserName())
+				n, err := a.Srv().SetDescriptionMode(false, false)
+				if err != nil {
+					cmd.PrintErrf("failed to decrypt descriptions: %v\n", err)
+					return
+				}
+				cmd.Printf("Descriptions are not encrypted, %d records are updated\n", n)
+			},
+		})
+	return cmd
+}
@@ -17,6 +17,8 @@ import (
 	"os"
 	"path/filepath"
 
+	cfg "gophKeeper/internal/client/config"
+
 	shell "github.com/brianstrauch/cobra-shell"
 	"github.com/spf13/cobra"
 )
@@ -56,6 +58,27 @@ func (a *app) addRootCmd() *app {
 		}
 	}
 
+	// the passphrase sources of this run, they take precedence over the profile ones
+	var (
+		passphraseFD   int
+		passphraseFile string
+	)
+	c.PersistentFlags().IntVar(&passphraseFD, "passphrase-fd", -1, "read the master password from the file descriptor")
+	c.PersistentFlags().StringVar(&passphraseFile, "passphrase-file", "", "read the master password from the file")
+	c.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if passphraseFD >= 0 {
+			cfg.Glob.Viper.Set("passphrase_fd", passphraseFD)
+		}
+		if passphraseFile != "" {
+			cfg.Glob.Viper.Set("passphrase_file", passphraseFile)
+		}
+	}
+	c.PersistentPostRun = func(cmd *cobra.Command, args []string) {
+		passphraseFD, passphraseFile = -1, ""
+		cfg.Glob.Viper.Set("passphrase_fd", nil)
+		cfg.Glob.Viper.Set("passphrase_file", nil)
+	}
+
 	c.AddCommand(shell.New(c, nil))
 	a.root = c
 	return a
@@ -5,20 +5,37 @@ It uses the Cobra library to define commands and subcommands.
 
 Main functionalities include:
 
-- Saving authentication data.
+- Saving authentication data, the password can be generated.
 - Saving text data.
 - Saving binary data.
 - Saving card data.
+- Saving one-time password seeds.
+- Saving ssh private keys.
+- Saving certificates.
+- Saving identity documents, bank accounts and crypto wallet seed phrases.
+- Saving records of the user defined templates.
 */
 package cmd
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/generate"
 	"gophKeeper/internal/client/model"
 	"gophKeeper/internal/client/model/type/auth"
+	"gophKeeper/internal/client/model/type/bank"
 	"gophKeeper/internal/client/model/type/bin"
 	"gophKeeper/internal/client/model/type/card"
+	"gophKeeper/internal/client/model/type/cert"
+	"gophKeeper/internal/client/model/type/identity"
+	"gophKeeper/internal/client/model/type/otp"
+	"gophKeeper/internal/client/model/type/ssh"
+	"gophKeeper/internal/client/model/type/template"
 	"gophKeeper/internal/client/model/type/text"
+	"gophKeeper/internal/client/model/type/wallet"
 	"gophKeeper/internal/helper"
 
 	"github.com/spf13/cobra"
@@ -61,35 +78,73 @@ func (a *app) saveDataRun(data model.Model) func(cmd *cobra.Command, args []stri
 }
 
 // saveAuthCmd returns a Cobra command for saving the authentication model.
-// The command encrypts login/password pairs and saves them.
+// The command encrypts login/password pairs and saves them,
+// the password is generated by --generate with the profile policy overridden by the policy flags.
 // Examples of using the command include:
 //
 //	save auth -l login -p password
 //	save auth -l login -p password -d site.com
 //	save auth -l login -p password -k "my-key-name" -d site.com
+//	save auth -l login --generate --length 32
 func (a *app) saveAuthCmd() (cmd *cobra.Command) {
-	debug := false
-	data := auth.New()
+	var (
+		debug    = false
+		data     = auth.New()
+		gen      bool
+		policy   = generate.DefaultPolicy()
+		saveData = a.saveDataRun(data)
+	)
 
 	cmd = &cobra.Command{
 		Use:   "auth",
 		Short: "Save auth data",
 		// ValidArgs: validSaveArgs(&auth.Data{}),
-		Long: `Encrypts login/password pairs.`,
+		Long: `Encrypts login/password pairs.
+--generate creates the password by the default policy of the profile, the policy flags override it, see generate.
+The generated password is not printed, view --field password or copy shows it.`,
 		Example: `  save auth -l login -p password
   save auth -l login -p password -d site.com
   save auth -l login -p password -k "my-key-name" -d site.com
+  save auth -l login -k "my-key-name" --generate --length 32 --exclude-similar
 `,
-		Run: a.saveDataRun(data),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer func() { gen, policy = false, generate.DefaultPolicy() }()
+			if gen {
+				if err := a.generatePassword(cmd, data, policy); err != nil {
+					data.Reset()
+					cmd.PrintErrln(err)
+					return
+				}
+			}
+			saveData(cmd, args)
+		},
 	}
 	err := generateSaveFlags(data, cmd, &debug)
 	if err != nil {
 		cmd.Printf("save auth generateSaveFlags error: %s\n", err)
 	}
+	cmd.Flags().BoolVar(&gen, "generate", false, "generate the password")
+	addPolicyFlags(cmd.Flags(), &policy)
 
 	return
 }
 
+// generatePassword sets the generated password of the auth model, the password must not be set.
+func (a *app) generatePassword(cmd *cobra.Command, data *auth.Model, flags generate.Policy) (err error) {
+	if data.Data.Password != "" {
+		return errors.New("the password is set, it is not generated")
+	}
+	p, err := generatePolicy(cmd.Flags(), flags)
+	if err != nil {
+		return
+	}
+	if data.Data.Password, err = p.Generate(); err != nil {
+		return
+	}
+	cmd.Printf("Password is generated. %s\n", entropyInfo(p))
+	return
+}
+
 // saveTextCmd returns a Cobra command for saving the text model.
 // The command encrypts text data and saves it.
 // Examples of using the command include:
@@ -168,9 +223,174 @@ func (a *app) saveCardCmd() (cmd *cobra.Command) {
 	return
 }
 
+// saveOTPCmd returns a Cobra command for saving the one-time password model.
+// The command encrypts TOTP/HOTP seeds and saves them.
+// Examples of using the command include:
+//
+//	save otp -u "otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example"
+//	save otp -s JBSWY3DPEHPK3PXP -i Example -a alice@example.com
+func (a *app) saveOTPCmd() (cmd *cobra.Command) {
+	debug := false
+	data := otp.New()
+
+	cmd = &cobra.Command{
+		Use:   "otp [flags]",
+		Short: "Save one-time password seed",
+		Long: `Encrypts TOTP/HOTP seeds, view of the record prints the current code.
+The otpauth:// uri of the QR code sets all fields.`,
+		Example: `  save otp -u "otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example"
+  save otp -s JBSWY3DPEHPK3PXP -i Example -a alice@example.com
+  save otp -t hotp -s JBSWY3DPEHPK3PXP --counter 1`,
+		Run: a.saveDataRun(data),
+	}
+	err := generateSaveFlags(data, cmd, &debug)
+	if err != nil {
+		cmd.Printf("save otp generateSaveFlags error: %s\n", err)
+	}
+
+	return
+}
+
+// saveSSHCmd returns a Cobra command for saving the ssh key model.
+// The command validates the private key, derives its public key and fingerprint, and saves it.
+// Examples of using the command include:
+//
+//	save ssh -f ~/.ssh/id_ed25519 -c alice@laptop
+//	save ssh -f ~/.ssh/id_rsa -p "key passphrase" --confirm --lifetime 1h
+func (a *app) saveSSHCmd() (cmd *cobra.Command) {
+	debug := false
+	data := ssh.New()
+
+	cmd = &cobra.Command{
+		Use:   "ssh [flags]",
+		Short: "Save ssh private key",
+		Long: `Encrypts ssh private keys in OpenSSH or PEM format, the key is validated before saving.
+The public key and fingerprint are derived from the private key.
+The passphrase of encrypted key can be saved with it, otherwise it is asked by ssh-agent.`,
+		Example: `  save ssh -f ~/.ssh/id_ed25519 -c alice@laptop
+  save ssh -f ~/.ssh/id_rsa -p "key passphrase" --confirm --lifetime 1h`,
+		Run: a.saveDataRun(data),
+	}
+	err := generateSaveFlags(data, cmd, &debug)
+	if err != nil {
+		cmd.Printf("save ssh generateSaveFlags error: %s\n", err)
+	}
+
+	return
+}
+
+// saveCertCmd returns a Cobra command for saving the certificate model.
+// The command parses the certificate chain and private key, checks that the key matches the leaf certificate,
+// and saves them with the details of the leaf certificate.
+// Examples of using the command include:
+//
+//	save cert -f bundle.pem
+//	save cert -f cert.der --key-file key.pem
+func (a *app) saveCertCmd() (cmd *cobra.Command) {
+	debug := false
+	data := cert.New()
+
+	cmd = &cobra.Command{
+		Use:   "cert [flags]",
+		Short: "Save certificate",
+		Long: `Encrypts TLS certificate chains with the private key, PEM bundles and DER certificates are read by -f.
+The private key must match the leaf certificate, the first one of the chain.
+The subject, SANs, issuer and expiry time of the leaf certificate are kept with the record,
+list --expiring and audit report the certificates about to expire.`,
+		Example: `  save cert -f bundle.pem
+  save cert -f cert.der --key-file key.pem`,
+		Run: a.saveDataRun(data),
+	}
+	err := generateSaveFlags(data, cmd, &debug)
+	if err != nil {
+		cmd.Printf("save cert generateSaveFlags error: %s\n", err)
+	}
+
+	return
+}
+
+// saveIdentityCmd returns a Cobra command for saving the identity document model.
+// The command encrypts passports and ID cards and saves them.
+// Example of using the command:
+//
+//	save identity -t passport -n C01X00T47 -c DEU -o "Erika Mustermann" --issued 2020-01-31 --expires 2030-01-30
+func (a *app) saveIdentityCmd() (cmd *cobra.Command) {
+	debug := false
+	data := identity.New()
+
+	cmd = &cobra.Command{
+		Use:   "identity [flags]",
+		Short: "Save identity document",
+		Long: `Encrypts passports and ID cards, the number is masked by view.
+The expiry date is reported by list --expiring and audit.`,
+		Example: `  save identity -t passport -n C01X00T47 -c DEU -o "Erika Mustermann" --issued 2020-01-31 --expires 2030-01-30`,
+		Run:     a.saveDataRun(data),
+	}
+	err := generateSaveFlags(data, cmd, &debug)
+	if err != nil {
+		cmd.Printf("save identity generateSaveFlags error: %s\n", err)
+	}
+
+	return
+}
+
+// saveBankCmd returns a Cobra command for saving the bank account model.
+// The command validates the IBAN check digits and the BIC, encrypts the account and saves it.
+// Example of using the command:
+//
+//	save bank --iban "DE89 3704 0044 0532 0130 00" --bic COBADEFFXXX -o "Max Space"
+func (a *app) saveBankCmd() (cmd *cobra.Command) {
+	debug := false
+	data := bank.New()
+
+	cmd = &cobra.Command{
+		Use:   "bank [flags]",
+		Short: "Save bank account",
+		Long: `Encrypts bank accounts, the IBAN check digits and the BIC format are validated.
+The IBAN is masked by view.`,
+		Example: `  save bank --iban "DE89 3704 0044 0532 0130 00" --bic COBADEFFXXX -o "Max Space"`,
+		Run:     a.saveDataRun(data),
+	}
+	err := generateSaveFlags(data, cmd, &debug)
+	if err != nil {
+		cmd.Printf("save bank generateSaveFlags error: %s\n", err)
+	}
+
+	return
+}
+
+// saveWalletCmd returns a Cobra command for saving the crypto wallet model.
+// The command validates the BIP-39 seed phrase by the wordlist and checksum, encrypts it and saves it.
+// Examples of using the command include:
+//
+//	save wallet -f seed.txt -c BTC --path "m/84'/0'/0'"
+//	save wallet -m "abandon abandon ... about"
+func (a *app) saveWalletCmd() (cmd *cobra.Command) {
+	debug := false
+	data := wallet.New()
+
+	cmd = &cobra.Command{
+		Use:   "wallet [flags]",
+		Short: "Save crypto wallet seed phrase",
+		Long: `Encrypts BIP-39 seed phrases, the words and the checksum are validated.
+The seed phrase is better read from file by -f, so it is not kept in the shell history.
+The seed phrase and passphrase are masked by view.`,
+		Example: `  save wallet -f seed.txt -c BTC --path "m/84'/0'/0'"
+  save wallet -m "abandon abandon ... about"`,
+		Run: a.saveDataRun(data),
+	}
+	err := generateSaveFlags(data, cmd, &debug)
+	if err != nil {
+		cmd.Printf("save wallet generateSaveFlags error: %s\n", err)
+	}
+
+	return
+}
+
 // addSaveCmd adds commands for the data saving operation to the root command.
 // Subcommands include commands for saving authentication data,
-// text data, binary data, and card data.
+// text data, binary data, card data, one-time password seeds, ssh keys, certificates,
+// identity documents, bank accounts and crypto wallets.
 func (a *app) addSaveCmd() *app {
 	var saveCmd = &cobra.Command{
 		Use:   "save [command]",
@@ -180,8 +400,69 @@ func (a *app) addSaveCmd() *app {
 		Long: `Encrypts and save data`,
 	}
 
-	saveCmd.AddCommand(a.saveAuthCmd(), a.saveTextCmd(), a.saveBinCmd(), a.saveCardCmd())
+	saveCmd.AddCommand(a.saveAuthCmd(), a.saveTextCmd(), a.saveBinCmd(), a.saveCardCmd(), a.saveOTPCmd(), a.saveSSHCmd(), a.saveCertCmd(),
+		a.saveIdentityCmd(), a.saveBankCmd(), a.saveWalletCmd())
 
 	a.root.AddCommand(saveCmd)
+	a.save = saveCmd
 	return a
 }
+
+// saveTemplateCmd returns a Cobra command for saving the record of user defined template.
+// The flags are generated by the template fields, the values are validated by their kinds and validators.
+func (a *app) saveTemplateCmd(t template.Template) (cmd *cobra.Command) {
+	debug := false
+	data := template.New(t)
+
+	short := t.Description
+	if short == "" {
+		short = fmt.Sprintf("Save %s data", t.Name)
+	}
+	var fields strings.Builder
+	for _, f := range t.Fields {
+		name, _ := f.FlagNames()
+		fields.WriteString(fmt.Sprintf("\n  --%-20s %s", name, f.GetKind()))
+		if f.Validate != "" {
+			fields.WriteString(", " + f.Validate)
+		}
+	}
+	cmd = &cobra.Command{
+		Use:   t.Name + " [flags]",
+		Short: short,
+		Long: fmt.Sprintf(`Encrypts %s data of the profile template.
+The file of -f is the value of the first empty multiline field.
+Fields:%s`, t.Name, fields.String()),
+		Run: a.saveDataRun(data),
+	}
+	err := generateSaveFlags(data, cmd, &debug)
+	if err != nil {
+		cmd.Printf("save %s generateSaveFlags error: %s\n", t.Name, err)
+	}
+	data.GenerateFlags(cmd.Flags())
+
+	return
+}
+
+// addTemplateSaveCmds adds the save commands of the templates file of profile,
+// the commands of the previous run are replaced. The templates file error is printed,
+// the built-in save commands are kept.
+func (a *app) addTemplateSaveCmds() {
+	a.save.RemoveCommand(a.templateCmds...)
+	a.templateCmds = nil
+	if err := cfg.GlobalLoad(); err != nil {
+		return
+	}
+	dir, err := cfg.UsrCfgDir()
+	if err != nil {
+		return
+	}
+	templates, _, err := template.Load(dir)
+	if err != nil {
+		a.root.PrintErrf("templates error: %v\n", err)
+		return
+	}
+	for _, t := range templates {
+		a.templateCmds = append(a.templateCmds, a.saveTemplateCmd(t))
+	}
+	a.save.AddCommand(a.templateCmds...)
+}
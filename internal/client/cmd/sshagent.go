@@ -0,0 +1,174 @@
+/*
+This package provides the ssh-agent command, which serves the private keys of ssh records
+over the standard ssh agent protocol, so the keys exist decrypted in memory only.
+
+Main functionalities include:
+
+- Loading the selected ssh keys, the passphrase of encrypted key is asked if not saved.
+- Serving the keys on a Unix socket with confirm and lifetime constraints.
+- Asking the confirmation of the key use by pinentry or at the terminal.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"gophKeeper/internal/client/agent"
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/input/password"
+	"gophKeeper/internal/client/model/type/ssh"
+
+	"github.com/spf13/cobra"
+	sshagent "golang.org/x/crypto/ssh/agent"
+	"golang.org/x/term"
+)
+
+// addSSHAgentCmd adds the ssh-agent command serving the keys of ssh records to the root command.
+func (a *app) addSSHAgentCmd() *app {
+	var (
+		socket   string
+		confirm  bool
+		lifetime time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "ssh-agent <key name>...",
+		Short: "Serve ssh keys to ssh",
+		Long: `Serve the private keys of ssh records over the ssh agent protocol on a Unix socket,
+the keys are decrypted in memory only and dropped at exit.
+The confirm and lifetime constraints are taken from the records, the flags set them for all keys.
+The use of confirmed keys is asked by the profile pinentry program or at the terminal.
+Set ` + agent.SSHSocketEnv + ` to the printed socket path for ssh.`,
+		Example: `  ssh-agent github-key work-key
+  ssh-agent github-key --confirm --lifetime 1h
+  SSH_AUTH_SOCK=<socket path> ssh git@github.com`,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer func() { socket, confirm, lifetime = "", false, 0 }()
+			if len(args) == 0 {
+				_ = cmd.Help()
+				return
+			}
+			if socket == "" {
+				socket = agent.SSHSocketPath()
+			}
+			keys, err := a.sshAgentKeys(args, confirm, lifetime)
+			if err != nil {
+				cmd.PrintErrf("failed to load ssh keys: %v\n", err)
+				return
+			}
+			sshAgent := agent.NewSSH(confirmKeyUse(cmd))
+			for _, key := range keys {
+				if err = sshAgent.Add(key); err != nil {
+					cmd.PrintErrf("failed to add ssh key %s: %v\n", key.Comment, err)
+					return
+				}
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			cmd.Printf("%s=%s; export %s;\n", agent.SSHSocketEnv, socket, agent.SSHSocketEnv)
+			cmd.Printf("SSH agent is listening at %s, keys %d\n", socket, len(keys))
+			if err = sshAgent.Serve(ctx, socket); err != nil {
+				cmd.PrintErrf("ssh agent failed: %v\n", err)
+				return
+			}
+			cmd.Println("SSH agent is stopped")
+		},
+	}
+	cmd.Flags().StringVar(&socket, "socket", "", "socket path")
+	cmd.Flags().BoolVar(&confirm, "confirm", false, "ask to confirm each use of all keys")
+	cmd.Flags().DurationVar(&lifetime, "lifetime", 0, "drop all keys after the time, the record lifetime is used if not set")
+	a.root.AddCommand(cmd)
+	return a
+}
+
+// sshAgentKeys returns the keys of ssh records with their constraints,
+// the passphrase of encrypted key is asked if it is not saved with the key.
+func (a *app) sshAgentKeys(names []string, confirm bool, lifetime time.Duration) (keys []sshagent.AddedKey, err error) {
+	for _, name := range names {
+		item, er := a.Srv().Get(name)
+		if er != nil {
+			err = fmt.Errorf("%s: %w", name, er)
+			return
+		}
+		d, ok := item.Data.(*ssh.Data)
+		if !ok {
+			err = fmt.Errorf("%s is not an ssh key", name)
+			return
+		}
+		key, er := d.RawKey("")
+		if errors.Is(er, ssh.ErrPassphrase) {
+			var pass string
+			if pass, er = password.GetPassphrase(keyPassphraseSource(), false,
+				fmt.Sprintf("Please enter passphrase of ssh key %s: ", name)); er == nil {
+				key, er = d.RawKey(pass)
+			}
+		}
+		if er != nil {
+			err = fmt.Errorf("%s: %w", name, er)
+			return
+		}
+		added := sshagent.AddedKey{
+			PrivateKey:       key,
+			Comment:          d.Comment,
+			ConfirmBeforeUse: d.Confirm || confirm,
+			LifetimeSecs:     uint32(d.Lifetime / time.Second),
+		}
+		if added.Comment == "" {
+			added.Comment = name
+		}
+		if lifetime > 0 {
+			added.LifetimeSecs = uint32(lifetime / time.Second)
+		}
+		keys = append(keys, added)
+	}
+	return
+}
+
+// keyPassphraseSource the passphrase of ssh key is asked by the profile pinentry program or at the terminal,
+// the other master password sources are not used for it
+func keyPassphraseSource() password.Source {
+	if cfg.User.Viper != nil {
+		if program := cfg.User.GetString("passphrase.pinentry"); program != "" {
+			return password.PinentrySource(program)
+		}
+	}
+	return nil
+}
+
+// confirmKeyUse returns the function asking the use of ssh key by the profile pinentry program,
+// or at the terminal of agent. The use is denied if there is no way to ask.
+func confirmKeyUse(cmd *cobra.Command) func(comment string) bool {
+	var program string
+	if cfg.User.Viper != nil {
+		program = cfg.User.GetString("passphrase.pinentry")
+	}
+	in := bufio.NewReader(os.Stdin)
+	return func(comment string) bool {
+		desc := fmt.Sprintf("Allow use of ssh key %s?", comment)
+		if program != "" {
+			ok, err := password.PinentryConfirm(program, desc)
+			if err != nil {
+				cmd.PrintErrf("confirm error: %v\n", err)
+			}
+			return ok
+		}
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			cmd.PrintErrf("use of ssh key %s is denied, there is no terminal to confirm\n", comment)
+			return false
+		}
+		cmd.Printf("\n%s [y/N] ", desc)
+		answer, err := in.ReadString('\n')
+		if err != nil {
+			return false
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	}
+}
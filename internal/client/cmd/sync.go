@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	cfg "gophKeeper/internal/client/config"
 	"gophKeeper/internal/client/crypt"
 	"gophKeeper/internal/client/input/password"
@@ -106,12 +107,15 @@ You can run synchronization by command
 			cmd.Println("Synchronization status:", string(syncInfo))
 		},
 	}
+	var keyFile string
+	registerCmd := &cobra.Command{
+		Use:   "register",
+		Short: "Register at remote server",
+		Run:   a.syncRegisterCmd(&keyFile),
+	}
+	registerCmd.Flags().StringVar(&keyFile, "keyfile", "", "key file of the encryption key, if the profile needs it")
 	syncCmd.AddCommand(
-		&cobra.Command{
-			Use:   "register",
-			Short: "Register at remote server",
-			Run:   a.syncRegisterCmd(),
-		},
+		registerCmd,
 		&cobra.Command{
 			Use:   "now",
 			Short: "Sync now",
@@ -136,8 +140,10 @@ You can run synchronization by command
 // with the remote server. It prompts the user for their email and synchronization password,
 // sends a registration request, and handles the response, including synchronization token
 // storage and user data synchronization if necessary.
-func (a *app) syncRegisterCmd() func(cmd *cobra.Command, args []string) {
+// The key file, if the profile needs it, is checked before the registration request.
+func (a *app) syncRegisterCmd(keyFile *string) func(cmd *cobra.Command, args []string) {
 	return func(cmd *cobra.Command, args []string) {
+		defer func() { *keyFile = "" }()
 		err := cfg.UserLoad()
 		if err != nil {
 			cmd.PrintErrf("failed to load config: %v\n", err)
@@ -161,6 +167,20 @@ The email is not specified in the configuration, please set it by command
 			return
 		}
 
+		if *keyFile != "" {
+			if _, err = a.Srv().SetKeyFile(*keyFile); err != nil {
+				cmd.PrintErrf("failed to set key file: %v\n", err)
+				return
+			}
+		}
+		if params, ok, er := cfg.GetKDFParams(); er == nil && ok && params.KeyFile != "" {
+			// the key file is required to store the synchronization token
+			if _, err = a.Srv().GetToken(); err != nil {
+				cmd.PrintErrf("failed to get encryption key: %v\n", err)
+				return
+			}
+		}
+
 		cmd.Printf(`
 Registering this client at server with email %s. 
 If you have not yet registered on the server with your email, come up with a new synchronization password, a new account will be created for you, after which this client will be able to synchronize`, cfg.User.Get("email"))
@@ -222,6 +242,13 @@ please come up with a password for this..`)
 		}
 
 		cryptToken, err := a.Srv().GetToken()
+		if errors.Is(err, crypt.ErrKeyFileMissing) {
+			cmd.PrintErrf(`failed to get encryption key: %v
+The profile received from server needs the key file, please run
+  sync register --keyfile <path>
+`, err)
+			return
+		}
 		if err != nil {
 			cmd.PrintErrf("failed to get synchronization token: %v\n", err)
 			return
@@ -0,0 +1,111 @@
+/*
+This package provides the commands for the records moved to the trash by delete.
+
+Main functionalities include:
+
+- Listing the deleted records kept with their data.
+- Restoring a deleted record, the restore is saved as a new update, so it is synchronized.
+- Purging the deleted records, all or the ones deleted earlier than the given time, their data and versions are wiped.
+*/
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"gophKeeper/internal/helper"
+
+	"github.com/spf13/cobra"
+)
+
+// addTrashCmd adds the trash command with the list, restore and purge subcommands to the root command.
+func (a *app) addTrashCmd() *app {
+	trashCmd := &cobra.Command{
+		Use:   "trash",
+		Short: "Manage the deleted records",
+		Long: `The deleted records are kept at the trash with their data and versions until purged.
+The deletion is synchronized, the other devices keep their copy at the trash too.`,
+		Example: `  trash list
+  trash restore <key name>
+  trash purge --older-than 30d`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the deleted records",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			items, err := a.Srv().Trash()
+			if err != nil {
+				cmd.PrintErrf("Trash error: %v\n", err)
+				return
+			}
+			cmd.Println("Key\tDeleted\tDescription")
+			for _, item := range items {
+				cmd.Printf("%s\t%s\t%s\n", item.Key, item.DeletedAt.Format(time.DateTime), item.Description)
+			}
+		},
+	}
+
+	restoreCmd := &cobra.Command{
+		Use:     "restore <key name> [...key name]",
+		Short:   "Restore the deleted records",
+		Long:    `Bring the deleted records back from the trash, the restore is saved as a new update, so it is synchronized.`,
+		Example: `  trash restore <key name>`,
+		Args:    cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, key := range args {
+				if err := a.Srv().RestoreTrash(key); err != nil {
+					if errors.Is(err, sql.ErrNoRows) {
+						cmd.Printf("Record not exist at the trash: %s\n", key)
+					} else {
+						cmd.PrintErrf("Restore error: %v\n", err)
+					}
+					continue
+				}
+				cmd.Printf("%s restored from the trash\n", key)
+			}
+		},
+	}
+
+	var olderThan string
+	purgeCmd := &cobra.Command{
+		Use:   "purge [--older-than 30d]",
+		Short: "Wipe the deleted records",
+		Long: `Wipe the data and versions of the deleted records, all of them or the ones deleted earlier than --older-than.
+The purged records can not be restored.`,
+		Example: `  trash purge
+  trash purge --older-than 30d`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer func() { olderThan = "" }()
+			var (
+				age time.Duration
+				err error
+			)
+			question := "All the deleted records will be wiped, continue? [y/N] "
+			if olderThan != "" {
+				if age, err = helper.ParseDuration(olderThan); err != nil {
+					cmd.PrintErrf("wrong older-than time: %v\n", err)
+					return
+				}
+				question = "The records deleted earlier than " + olderThan + " ago will be wiped, continue? [y/N] "
+			}
+			if !confirm(cmd, question) {
+				return
+			}
+			n, err := a.Srv().PurgeTrash(age)
+			if err != nil {
+				cmd.PrintErrf("Purge error: %v\n", err)
+				return
+			}
+			cmd.Printf("Purged: %d\n", n)
+		},
+	}
+	purgeCmd.Flags().StringVar(&olderThan, "older-than", "", "purge the records deleted earlier than the time ago, 30d or 12h")
+
+	trashCmd.AddCommand(listCmd, restoreCmd, purgeCmd)
+	a.root.AddCommand(trashCmd)
+	return a
+}
@@ -7,14 +7,29 @@ Main functionalities include:
 
 - Viewing data associated with a specific key.
 - Decrypting the data and printing it to standard output.
+- Extracting the file content of data to a file, large files are decrypted by chunks.
+- Printing the current one-time password code, refreshed live by --watch.
+- Masking the secret values marked by the struct tags unless revealed by --reveal.
+- Printing the value of a single field by --field.
+- Copying the value of a single field to the clipboard by --copy.
+- Viewing a previous version of the record kept on overwrite by --version.
 - Handling errors related to data retrieval and formatting.
 */
 package cmd
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"gophKeeper/internal/client/model/out"
+	"gophKeeper/internal/client/model/type/bin"
+	"gophKeeper/internal/client/model/type/otp"
 
 	"github.com/spf13/cobra"
 )
@@ -26,16 +41,51 @@ import (
 // In case of an error during data retrieval, it prints an appropriate error message.
 // If the record does not exist, it indicates that as well.
 func (a *app) addViewCmd() *app {
-	a.root.AddCommand(&cobra.Command{
+	var (
+		outFile string
+		watch   bool
+		reveal  bool
+		field   string
+		copyTo  string
+		version int
+	)
+	cmd := &cobra.Command{
 		Use:   "view <key name>",
 		Short: "View data",
-		Long:  `Decrypt data and print it to stdout.`,
+		Long: `Decrypt data and print it to stdout.
+The file content of data can be extracted to a file by --out,
+the content of large files is not printed, it is only extracted.
+The current code of one-time password is printed with the seconds left, --watch refreshes it.
+The secret values are masked: passwords, keys, seed phrases, CVV and the secret extra fields are hidden,
+card numbers and IBAN show the last 4 characters. --reveal shows them.
+--field prints only the value of the data field by its name or of the extra field, as is.
+--copy puts the value of the field to the clipboard instead, it is cleared after the timeout, see copy.
+--version shows the previous version of the record, listed by history.`,
+		Example: `  view <key name>
+  view <key name> --out filename
+  view <otp key name> --watch
+  view <key name> --reveal
+  view <key name> --field password
+  view <key name> --copy password
+  view <key name> --version 2`,
 		Run: func(cmd *cobra.Command, args []string) {
+			defer func() { outFile, watch, reveal, field, copyTo, version = "", false, false, "", "", 0 }()
 			if len(args) == 0 {
 				_ = cmd.Help()
 				return
 			}
-			data, err := a.Srv().Get(args[0])
+			var (
+				data out.Item
+				err  error
+			)
+			switch {
+			case outFile != "":
+				data, err = a.extractData(args[0], version, outFile)
+			case version != 0:
+				data, err = a.Srv().GetVersion(args[0], version, nil)
+			default:
+				data, err = a.Srv().Get(args[0])
+			}
 			if err != nil {
 				if errors.Is(err, sql.ErrNoRows) {
 					cmd.Printf("Record not exist: %s\n", args[0])
@@ -44,13 +94,113 @@ func (a *app) addViewCmd() *app {
 				}
 				return
 			}
-			out, err := json.MarshalIndent(data, "", " ")
+			if outFile != "" {
+				cmd.Printf("Data content extracted to %s\n", outFile)
+				return
+			}
+			if copyTo != "" {
+				a.copyField(cmd, data, copyTo)
+				return
+			}
+			if field != "" {
+				value, ok := data.Field(field)
+				if !ok {
+					cmd.PrintErrf("Field not exist: %s\n", field)
+					return
+				}
+				// the value is printed to stdout, so it can be piped
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), value)
+				return
+			}
+			shown := data
+			if !reveal {
+				shown = data.Masked()
+			}
+			out, err := json.MarshalIndent(shown, "", " ")
 			if err != nil {
 				cmd.Printf("Data format output error %s %v", err, data)
 				return
 			}
 			cmd.Println(string(out))
+			if d, ok := data.Data.(*bin.Data); ok && d.Size > 0 && len(d.Bin) == 0 {
+				if version != 0 {
+					cmd.Printf("The content is not shown, extract it by\n  view %s --version %d --out <filename>\n", args[0], version)
+				} else {
+					cmd.Printf("The content is not shown, extract it by\n  view %s --out <filename>\n", args[0])
+				}
+			}
+			// the hotp code of a previous version is not shown, its counter is not the stored one
+			if d, ok := data.Data.(*otp.Data); ok && (version == 0 || d.Type != otp.TypeHOTP) {
+				if err = a.viewOTP(cmd, data.Key, d, watch); err != nil {
+					cmd.PrintErrf("otp code error: %v\n", err)
+				}
+			}
 		},
-	})
+	}
+	cmd.Flags().StringVarP(&outFile, "out", "o", "", "extract the file content of data to the file")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "refresh the one-time password code until interrupted")
+	cmd.Flags().BoolVar(&reveal, "reveal", false, "show the masked secret values")
+	cmd.Flags().StringVar(&field, "field", "", "print only the value of the field")
+	cmd.Flags().StringVar(&copyTo, "copy", "", "copy the value of the field to the clipboard")
+	cmd.Flags().IntVar(&version, "version", 0, "view the previous version of the record, see history")
+	a.root.AddCommand(cmd)
 	return a
 }
+
+// extractData writes the file content of data with the key to the file, of the previous version if it is set,
+// the file is removed if the content can not be extracted.
+func (a *app) extractData(key string, version int, fileName string) (data out.Item, err error) {
+	var f *os.File
+	if f, err = os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600); err != nil {
+		return
+	}
+	if version != 0 {
+		data, err = a.Srv().GetVersion(key, version, f)
+	} else {
+		data, err = a.Srv().Extract(key, f)
+	}
+	err = errors.Join(err, f.Close())
+	if err != nil {
+		_ = os.Remove(fileName)
+	}
+	return
+}
+
+// viewOTP prints the current code of one-time password with the seconds left,
+// with watch the code is refreshed every second until interrupted.
+// The hotp code is of the stored counter, the counter is advanced and saved.
+func (a *app) viewOTP(cmd *cobra.Command, key string, d *otp.Data, watch bool) (err error) {
+	if d.Type == otp.TypeHOTP {
+		code, counter, err := a.Srv().NextHOTP(key)
+		if err != nil {
+			return err
+		}
+		cmd.Printf("Code: %s (counter %d)\n", code, counter)
+		return nil
+	}
+	code, left, err := d.Code(time.Now())
+	if err != nil {
+		return
+	}
+	if !watch {
+		cmd.Printf("Code: %s (%ds left)\n", code, int(left.Round(time.Second)/time.Second))
+		return
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		cmd.Printf("\rCode: %s (%2ds left)", code, int(left.Round(time.Second)/time.Second))
+		select {
+		case <-ctx.Done():
+			cmd.Println()
+			return
+		case now := <-ticker.C:
+			if code, left, err = d.Code(now); err != nil {
+				cmd.Println()
+				return
+			}
+		}
+	}
+}
@@ -15,6 +15,9 @@ const (
 	AppName = "GophKeeper"
 
 	PageSize = 1000
+
+	// HistoryKeep the default number of the kept previous versions of a record
+	HistoryKeep = 10
 )
 
 type config struct {
@@ -30,11 +33,11 @@ func (d duration) MarshalJSON() ([]byte, error) {
 }
 
 var (
-	excludeSaveKeys          = []string{"config_path", "loaded_at", "changed_at", "encryption_key", "sync_password"}
+	excludeSaveKeys          = []string{"config_path", "loaded_at", "changed_at", "encryption_key", "sync_password", "passphrase_fd", "passphrase_file"}
 	excludeViewKeys          = []string{"encryption_key", "sync_password"}
-	durationViewKeys         = []string{"sync.timeout.sync", "sync.timeout.register"}
+	durationViewKeys         = []string{"sync.timeout.sync", "sync.timeout.register", "clipboard.clear_after"}
 	clearAfterSave           = []string{"changed_at"}
-	syncUpdatedTriggerFields = []string{"email", "packed_key", "sync.token"}
+	syncUpdatedTriggerFields = []string{"email", "packed_key", "kdf", "recovery", "sync.token", "templates_hash"}
 	User                     config
 	Glob                     = config{Viper: viper.New()}
 )
@@ -120,7 +123,12 @@ func (c *config) Save() error {
 	_ = os.MkdirAll(c.path, 0750)
 
 	if isNew {
-		return c.Viper.SafeWriteConfig()
+		if err := c.Viper.SafeWriteConfig(); err != nil {
+			return err
+		}
+		// the next save rewrites the created file
+		c.excluded["loaded_at"] = time.Now()
+		return nil
 	}
 	return c.Viper.WriteConfig()
 }
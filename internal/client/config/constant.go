@@ -7,4 +7,10 @@ const (
 	PromptSyncPs          = "Please enter server synchronization password: "
 	PromptNewSyncPs       = "Please enter new server synchronization password: "
 	PromptSyncConfirmPs   = "Please confirm you new server synchronization password: "
+	PromptRecoveryCode    = "Please enter recovery code or one of its shares: "
+	PromptRecoveryShare   = "Please enter next recovery share (entered %d of %d): "
+	PromptVaultMasterPs   = "Please enter master password of the exported profile: "
+	PromptExportPs        = "Please enter export passphrase: "
+	PromptConfirmExportPs = "Please confirm export passphrase: "
+	PromptVaultPs         = "Please enter vault archive passphrase: "
 )
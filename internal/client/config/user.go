@@ -1,12 +1,18 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"gophKeeper/internal/client/clipboard"
+	"gophKeeper/internal/client/crypt"
+	"gophKeeper/internal/client/generate"
+	"gophKeeper/internal/helper"
+
 	"github.com/spf13/viper"
 )
 
@@ -78,11 +84,134 @@ func UserLoad(reload ...bool) (err error) {
 	}
 	err = User.Load(AppName, usrCfgDir,
 		map[string]any{
-			"name":                  userName,
-			"autosave":              true,
-			"db_file":               path.Join(usrCfgDir, userName+".db"),
-			"sync.timeout.register": time.Minute * 1,
-			"sync.timeout.sync":     time.Hour * 3,
+			"name":                     userName,
+			"autosave":                 true,
+			"db_file":                  path.Join(usrCfgDir, userName+".db"),
+			"sync.timeout.register":    time.Minute * 1,
+			"sync.timeout.sync":        time.Hour * 3,
+			"clipboard.backend":        clipboard.BackendAuto,
+			"clipboard.clear_after":    clipboard.DefaultClearAfter,
+			"generate.length":          generate.DefaultLength,
+			"generate.lower":           true,
+			"generate.upper":           true,
+			"generate.digits":          true,
+			"generate.symbols":         true,
+			"generate.exclude_similar": false,
+			"generate.diceware":        false,
+			"generate.words":           generate.DefaultWords,
+			"generate.separator":       generate.DefaultSeparator,
+			"history.keep":             HistoryKeep,
 		})
 	return
 }
+
+// GetKDFParams
+// passphrase key derivation parameters of the profile,
+// ok is false for profiles with the packed key still wrapped by the sha256 of the passphrase
+func GetKDFParams() (p crypt.KDFParams, ok bool, err error) {
+	if User.Get("kdf") == nil {
+		return
+	}
+	if err = User.UnmarshalKey("kdf", &p); err != nil {
+		return
+	}
+	ok = true
+	return
+}
+
+// SetKDFParams
+// store key derivation parameters next to the packed_key
+func SetKDFParams(p crypt.KDFParams) {
+	m := map[string]any{
+		"algorithm": p.Algorithm,
+		"salt":      p.Salt,
+		"time":      p.Time,
+		"memory":    p.Memory,
+		"threads":   p.Threads,
+	}
+	if p.KeyFile != "" {
+		m["key_file"] = p.KeyFile
+	}
+	User.Set("kdf", m)
+}
+
+// GetClipboard
+// the clipboard settings of the profile
+func GetClipboard() clipboard.Config {
+	return clipboard.Config{
+		Backend:    User.GetString("clipboard.backend"),
+		CopyCmd:    User.GetString("clipboard.copy"),
+		PasteCmd:   User.GetString("clipboard.paste"),
+		ClearAfter: User.GetDuration("clipboard.clear_after"),
+	}
+}
+
+// GetGeneratePolicy
+// the default policy of generated passwords of the profile
+func GetGeneratePolicy() generate.Policy {
+	return generate.Policy{
+		Length:         User.GetInt("generate.length"),
+		Lower:          User.GetBool("generate.lower"),
+		Upper:          User.GetBool("generate.upper"),
+		Digits:         User.GetBool("generate.digits"),
+		Symbols:        User.GetBool("generate.symbols"),
+		ExcludeSimilar: User.GetBool("generate.exclude_similar"),
+		Diceware:       User.GetBool("generate.diceware"),
+		Words:          User.GetInt("generate.words"),
+		Separator:      User.GetString("generate.separator"),
+	}
+}
+
+// Recovery
+// the second wrapping of the encryption key, under the recovery code
+type Recovery struct {
+	// PackedKey the encryption key wrapped by the recovery code
+	PackedKey string `json:"packed_key" mapstructure:"packed_key"`
+	// CodeKey the recovery code secret wrapped by the encryption key, to re-wrap a rotated key
+	CodeKey   string `json:"code_key" mapstructure:"code_key"`
+	Shares    int    `json:"shares,omitempty" mapstructure:"shares"`
+	Threshold int    `json:"threshold,omitempty" mapstructure:"threshold"`
+	CreatedAt string `json:"created_at" mapstructure:"created_at"`
+}
+
+// GetRecovery
+// recovery wrapping of the encryption key, ok is false if recovery code is not created
+func GetRecovery() (r Recovery, ok bool, err error) {
+	if User.Get("recovery") == nil {
+		return
+	}
+	if err = User.UnmarshalKey("recovery", &r); err != nil {
+		return
+	}
+	ok = r.PackedKey != ""
+	return
+}
+
+// SetRecovery
+// store the recovery wrapping next to the packed_key, nil removes it
+func SetRecovery(r *Recovery) {
+	if r == nil {
+		User.Set("recovery", nil)
+		return
+	}
+	User.Set("recovery", map[string]any{
+		"packed_key": r.PackedKey,
+		"code_key":   r.CodeKey,
+		"shares":     r.Shares,
+		"threshold":  r.Threshold,
+		"created_at": r.CreatedAt,
+	})
+}
+
+// GetHistoryRetention
+// the number of the kept previous versions of a record and the age of the removed ones,
+// the zero age keeps the versions of any age
+func GetHistoryRetention() (keep int, maxAge time.Duration, err error) {
+	keep = User.GetInt("history.keep")
+	if s := User.GetString("history.max_age"); s != "" {
+		if maxAge, err = helper.ParseDuration(s); err != nil {
+			err = fmt.Errorf("history.max_age: %w", err)
+		}
+	}
+	return
+}
@@ -0,0 +1,51 @@
+package crypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// The blind index makes encrypted descriptions searchable: every normalized word
+// of the description is stored as a keyed HMAC token, the search words are turned
+// into tokens by the same key. The tokens tell equal words only, not their content.
+
+const blindTokenLen = 16
+
+// BlindIndexKey derives the key of blind index tokens from the encryption token
+func BlindIndexKey(token string) []byte {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte("gophkeeper/blind-index"))
+	return mac.Sum(nil)
+}
+
+// IndexWords returns the sorted unique normalized words of text:
+// lower case sequences of letters and digits
+func IndexWords(text string) (words []string) {
+	seen := make(map[string]struct{})
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if _, ok := seen[w]; ok {
+			continue
+		}
+		seen[w] = struct{}{}
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return
+}
+
+// BlindTokens returns the hex tokens of words by the index key, in order of words
+func BlindTokens(key []byte, words []string) (tokens []string) {
+	tokens = make([]string, 0, len(words))
+	for _, w := range words {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(w))
+		tokens = append(tokens, hex.EncodeToString(mac.Sum(nil)[:blindTokenLen]))
+	}
+	return
+}
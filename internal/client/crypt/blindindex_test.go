@@ -0,0 +1,41 @@
+package crypt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexWords(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "empty", text: "", want: nil},
+		{name: "punctuation only", text: " -- !? ", want: nil},
+		{name: "site", text: "Mail.example.COM login", want: []string{"com", "example", "login", "mail"}},
+		{name: "duplicates", text: "bank Bank BANK card", want: []string{"bank", "card"}},
+		{name: "unicode", text: "Банк, карта №2", want: []string{"2", "банк", "карта"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, IndexWords(tt.text))
+		})
+	}
+}
+
+func TestBlindTokens(t *testing.T) {
+	key := BlindIndexKey("token")
+	tokens := BlindTokens(key, []string{"bank", "card", "bank"})
+	require.Len(t, tokens, 3)
+	require.Len(t, tokens[0], blindTokenLen*2)
+	require.Equal(t, tokens[0], tokens[2])
+	require.NotEqual(t, tokens[0], tokens[1])
+	require.NotContains(t, tokens[0], "bank")
+
+	// the tokens depend on the key
+	other := BlindTokens(BlindIndexKey("other token"), []string{"bank"})
+	require.NotEqual(t, tokens[0], other[0])
+	require.Equal(t, tokens[:1], BlindTokens(key, []string{"bank"}))
+}
@@ -7,19 +7,191 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
-	"github.com/zenazn/pkcs7pad"
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
-// Encode encrypt plain text into cipher text
-func Encode(plainText []byte, key string) (cipherText []byte, err error) {
-	bKey := sha256.Sum256([]byte(key))
+// Envelope layout:
+//
+//	version 1: magic "GKE" | 1 | algorithm id | kdf id | nonce | sealed gzip payload
+//	version 2: magic "GKE" | 2 | algorithm id | kdf id | content type length | content type | nonce | sealed gzip payload
+//	version 3: magic "GKE" | 3 | algorithm id | kdf id | content type length | content type |
+//	           wrapped data key length (uint16) | wrapped data key | nonce | sealed gzip payload
+//	version 4: the header of version 3 | chunk size (uint32) | nonce prefix | sealed chunks of gzip payload, see NewEncryptWriter
+//
+// The header (everything before the nonce, except the wrapped data key) is authenticated together with
+// the caller associated data, so neither the algorithm, the kdf nor the content
+// type can be switched without detection. Encode writes version 2, or version 3 WithDataKey.
+// In version 3 the payload is sealed with a random data key, the data key itself is
+// an envelope sealed with the given key, so it can be re-wrapped by Rewrap without touching the payload.
+// Blobs without the magic are the legacy AES-CBC format: iv | pkcs7 padded gzip payload.
 
-	compressed := new(bytes.Buffer)
-	w := gzip.NewWriter(compressed)
+type Algorithm byte
+
+const (
+	AlgAES256GCM Algorithm = iota + 1
+	AlgXChaCha20Poly1305
+)
+
+type KDF byte
+
+const (
+	// KDFSHA256 the cipher key is sha256 of the given key string
+	KDFSHA256 KDF = iota + 1
+	// KDFArgon2id the given key is already derived by DeriveKey and used as is
+	KDFArgon2id
+)
+
+const (
+	envelopeVersion1 = 1
+	envelopeVersion2 = 2
+	envelopeVersion3 = 3
+	envelopeVersion4 = 4
+	// envelopeHeaderLen fixed part of header
+	envelopeHeaderLen = 6
+	maxContentTypeLen = 255
+	dataKeyLen        = 32
+)
+
+var (
+	envelopeMagic = []byte("GKE")
+
+	// DefaultAlgorithm used by Encode if no other set by option
+	DefaultAlgorithm = AlgAES256GCM
+)
+
+var (
+	ErrAuthentication     = errors.New("message authentication failed")
+	ErrUnknownAlgorithm   = errors.New("unknown encryption algorithm")
+	ErrUnknownKDF         = errors.New("unknown key derivation function")
+	ErrUnsupportedVersion = errors.New("unsupported envelope version")
+	ErrShortCipherText    = errors.New("cipherText too short")
+	ErrContentType        = errors.New("content type too long")
+	ErrNoDataKey          = errors.New("cipherText has no data key")
+)
+
+var algorithmNames = map[Algorithm]string{
+	AlgAES256GCM:         "aes-256-gcm",
+	AlgXChaCha20Poly1305: "xchacha20-poly1305",
+}
+
+func (a Algorithm) String() string {
+	if n, ok := algorithmNames[a]; ok {
+		return n
+	}
+	return fmt.Sprintf("unknown(%d)", byte(a))
+}
+
+// ParseAlgorithm
+// get algorithm by its name, empty name is DefaultAlgorithm
+func ParseAlgorithm(name string) (Algorithm, error) {
+	if name == "" {
+		return DefaultAlgorithm, nil
+	}
+	for a, n := range algorithmNames {
+		if strings.EqualFold(n, name) {
+			return a, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, name)
+}
+
+type options struct {
+	alg         Algorithm
+	kdf         KDF
+	contentType string
+	ad          []byte
+	dataKey     bool
+	stream      bool
+	chunkSize   int
+}
+
+type Option func(o *options)
+
+// WithAlgorithm set aead algorithm for Encode
+func WithAlgorithm(alg Algorithm) Option {
+	return func(o *options) {
+		o.alg = alg
+	}
+}
+
+// WithKDF set how the key for Encode was derived
+func WithKDF(kdf KDF) Option {
+	return func(o *options) {
+		o.kdf = kdf
+	}
+}
+
+// WithContentType set the type label of the payload for Encode,
+// it is kept in clear at the authenticated header, see ContentType
+func WithContentType(t string) Option {
+	return func(o *options) {
+		o.contentType = t
+	}
+}
+
+// WithAssociatedData set the data authenticated but not stored with the cipher text,
+// the same data must be given to Decode
+func WithAssociatedData(ad []byte) Option {
+	return func(o *options) {
+		o.ad = ad
+	}
+}
+
+// WithDataKey seal the payload of Encode with a random data key wrapped by the given key
+func WithDataKey() Option {
+	return func(o *options) {
+		o.dataKey = true
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{alg: DefaultAlgorithm, kdf: KDFSHA256, chunkSize: DefaultChunkSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func newAEAD(alg Algorithm, key []byte) (aead cipher.AEAD, err error) {
+	switch alg {
+	case AlgAES256GCM:
+		var block cipher.Block
+		if block, err = aes.NewCipher(key); err != nil {
+			return
+		}
+		aead, err = cipher.NewGCM(block)
+	case AlgXChaCha20Poly1305:
+		aead, err = chacha20poly1305.NewX(key)
+	default:
+		err = fmt.Errorf("%w: %d", ErrUnknownAlgorithm, alg)
+	}
+	return
+}
+
+func deriveKey(kdf KDF, key string) ([]byte, error) {
+	switch kdf {
+	case KDFSHA256:
+		bKey := sha256.Sum256([]byte(key))
+		return bKey[:], nil
+	case KDFArgon2id:
+		if len(key) != argon2KeyLen {
+			return nil, ErrAuthentication
+		}
+		return []byte(key), nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownKDF, kdf)
+	}
+}
+
+func compress(plainText []byte) (compressed []byte, err error) {
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
 	_, err = w.Write(plainText)
 	if err != nil {
 		err = fmt.Errorf("error writing to gzip: %v", err)
@@ -30,80 +202,293 @@ func Encode(plainText []byte, key string) (cipherText []byte, err error) {
 		err = fmt.Errorf("error close writing to gzip: %v", err)
 		return
 	}
+	compressed = buf.Bytes()
+	return
+}
 
-	paddedText := pkcs7pad.Pad(compressed.Bytes(), aes.BlockSize)
-
-	if len(paddedText)%aes.BlockSize != 0 {
-		err = fmt.Errorf("after pkcs7pad it has the wrong block size")
+func decompress(compressed []byte) (plainText []byte, err error) {
+	var r *gzip.Reader
+	r, err = gzip.NewReader(bytes.NewBuffer(compressed))
+	if err != nil {
+		err = fmt.Errorf("ungzip newReader error: %w", err)
 		return
 	}
-	var block cipher.Block
-
-	block, err = aes.NewCipher(bKey[:])
+	plainText, err = io.ReadAll(r)
 	if err != nil {
+		err = fmt.Errorf("ungzip readAll error: %w", err)
 		return
 	}
+	err = r.Close()
+	if err != nil {
+		err = fmt.Errorf("ungzip close error: %w", err)
+		return
+	}
+	return
+}
+
+// IsEnvelope checks if cipher text is in the versioned envelope format
+func IsEnvelope(cipherText []byte) bool {
+	return len(cipherText) >= envelopeHeaderLen && bytes.Equal(cipherText[:len(envelopeMagic)], envelopeMagic)
+}
+
+type header struct {
+	version     byte
+	alg         Algorithm
+	kdf         KDF
+	contentType string
+	wrappedKey  []byte
+	// raw header bytes, authenticated as part of associated data
+	raw []byte
+	// size of the whole header, the nonce follows
+	size int
+}
 
-	cipherText = make([]byte, aes.BlockSize+len(paddedText))
-	iv := cipherText[:aes.BlockSize]
-	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
+func newHeader(o *options) (h header, err error) {
+	if len(o.contentType) > maxContentTypeLen {
+		err = ErrContentType
 		return
 	}
+	h = header{version: envelopeVersion2, alg: o.alg, kdf: o.kdf, contentType: o.contentType}
+	if o.dataKey {
+		h.version = envelopeVersion3
+	}
+	if o.stream {
+		h.version = envelopeVersion4
+	}
+	h.raw = append(append(make([]byte, 0, envelopeHeaderLen+1+len(h.contentType)), envelopeMagic...),
+		h.version, byte(h.alg), byte(h.kdf), byte(len(h.contentType)))
+	h.raw = append(h.raw, h.contentType...)
+	h.size = len(h.raw)
+	return
+}
 
-	encrypt := cipher.NewCBCEncrypter(block, iv)
-	encrypt.CryptBlocks(cipherText[aes.BlockSize:], paddedText)
+// bytes returns the header as it is written before the nonce
+func (h header) bytes() []byte {
+	if h.version < envelopeVersion3 {
+		return bytes.Clone(h.raw)
+	}
+	b := make([]byte, len(h.raw)+2, len(h.raw)+2+len(h.wrappedKey))
+	copy(b, h.raw)
+	binary.BigEndian.PutUint16(b[len(h.raw):], uint16(len(h.wrappedKey)))
+	return append(b, h.wrappedKey...)
+}
 
+// readHeader reads the envelope header from r, r is left at the end of header
+func readHeader(r io.Reader) (h header, err error) {
+	raw := make([]byte, envelopeHeaderLen)
+	if _, err = io.ReadFull(r, raw); err != nil || !bytes.Equal(raw[:len(envelopeMagic)], envelopeMagic) {
+		err = ErrShortCipherText
+		return
+	}
+	h.version, h.alg, h.kdf = raw[3], Algorithm(raw[4]), KDF(raw[5])
+	switch h.version {
+	case envelopeVersion1:
+	case envelopeVersion2, envelopeVersion3, envelopeVersion4:
+		var n [1]byte
+		if _, err = io.ReadFull(r, n[:]); err != nil {
+			err = ErrShortCipherText
+			return
+		}
+		contentType := make([]byte, n[0])
+		if _, err = io.ReadFull(r, contentType); err != nil {
+			err = ErrShortCipherText
+			return
+		}
+		h.contentType = string(contentType)
+		raw = append(append(raw, n[0]), contentType...)
+	default:
+		err = fmt.Errorf("%w: %d", ErrUnsupportedVersion, h.version)
+		return
+	}
+	h.raw = raw
+	h.size = len(raw)
+	if h.version >= envelopeVersion3 {
+		var n [2]byte
+		if _, err = io.ReadFull(r, n[:]); err != nil {
+			err = ErrShortCipherText
+			return
+		}
+		h.wrappedKey = make([]byte, binary.BigEndian.Uint16(n[:]))
+		if _, err = io.ReadFull(r, h.wrappedKey); err != nil {
+			err = ErrShortCipherText
+			return
+		}
+		h.size += 2 + len(h.wrappedKey)
+	}
 	return
 }
 
-// Decode decrypt cipher text into plain text
-func Decode(cipherText []byte, key string) (plainText []byte, err error) {
-	bKey := sha256.Sum256([]byte(key))
+func parseHeader(cipherText []byte) (h header, err error) {
+	return readHeader(bytes.NewReader(cipherText))
+}
+
+// HasDataKey checks if cipher text is sealed with a wrapped data key
+func HasDataKey(cipherText []byte) bool {
+	h, err := parseHeader(cipherText)
+	return err == nil && h.version >= envelopeVersion3
+}
 
-	var block cipher.Block
-	block, err = aes.NewCipher(bKey[:])
+// ContentType returns the type label of the envelope payload,
+// it is empty for cipher texts encoded without it and for legacy ones
+func ContentType(cipherText []byte) string {
+	h, err := parseHeader(cipherText)
 	if err != nil {
-		return
+		return ""
 	}
+	return h.contentType
+}
 
-	if len(cipherText) < aes.BlockSize {
-		err = errors.New("cipherText too short")
+// Encode compress plain text and encrypt it into the versioned envelope
+func Encode(plainText []byte, key string, opts ...Option) (cipherText []byte, err error) {
+	o := newOptions(opts)
+
+	var h header
+	if h, err = newHeader(o); err != nil {
+		return
+	}
+	var bKey, compressed []byte
+	if o.dataKey {
+		bKey = make([]byte, dataKeyLen)
+		if _, err = io.ReadFull(rand.Reader, bKey); err != nil {
+			return
+		}
+		if h.wrappedKey, err = wrapKey(bKey, key, o); err != nil {
+			return
+		}
+	} else if bKey, err = deriveKey(h.kdf, key); err != nil {
+		return
+	}
+	var aead cipher.AEAD
+	if aead, err = newAEAD(h.alg, bKey); err != nil {
 		return
 	}
-	iv := cipherText[:aes.BlockSize]
-	if len(cipherText[aes.BlockSize:])%aes.BlockSize != 0 {
-		err = errors.New("cipherText has wrong block size")
+	if compressed, err = compress(plainText); err != nil {
 		return
 	}
-	paddedText := make([]byte, len(cipherText)-aes.BlockSize)
-	decrypt := cipher.NewCBCDecrypter(block, iv)
-	decrypt.CryptBlocks(paddedText, cipherText[aes.BlockSize:])
 
-	var compressed []byte
-	compressed, err = pkcs7pad.Unpad(paddedText)
+	prefix := h.bytes()
+	cipherText = make([]byte, len(prefix)+aead.NonceSize(), len(prefix)+aead.NonceSize()+len(compressed)+aead.Overhead())
+	copy(cipherText, prefix)
+	nonce := cipherText[len(prefix):]
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return
+	}
+	cipherText = aead.Seal(cipherText, nonce, compressed, append(bytes.Clone(h.raw), o.ad...))
+
+	return
+}
 
+// Decode decrypt cipher text into plain text
+// both envelope and legacy AES-CBC cipher texts are accepted,
+// wrong key, wrong associated data or modified cipher text returns ErrAuthentication
+func Decode(cipherText []byte, key string, opts ...Option) (plainText []byte, err error) {
+	if !IsEnvelope(cipherText) {
+		return decodeLegacy(cipherText, key)
+	}
+	plainText, err = decodeEnvelope(cipherText, key, newOptions(opts))
 	if err != nil {
-		err = fmt.Errorf("unpad error: %w", err)
+		// legacy cipher text can start with the magic by chance
+		if legacy, lErr := decodeLegacy(cipherText, key); lErr == nil {
+			return legacy, nil
+		}
+	}
+	return
+}
+
+func decodeEnvelope(cipherText []byte, key string, o *options) (plainText []byte, err error) {
+	var h header
+	if h, err = parseHeader(cipherText); err != nil {
+		return
+	}
+	if h.version == envelopeVersion4 {
+		var r io.Reader
+		if r, err = newDecryptReader(h, bytes.NewReader(cipherText[h.size:]), key, o); err != nil {
+			return
+		}
+		return io.ReadAll(r)
+	}
+	var bKey []byte
+	if h.version == envelopeVersion3 {
+		if bKey, err = unwrapKey(h.wrappedKey, key, o); err != nil {
+			return
+		}
+	} else if bKey, err = deriveKey(h.kdf, key); err != nil {
 		return
 	}
+	var aead cipher.AEAD
+	if aead, err = newAEAD(h.alg, bKey); err != nil {
+		return
+	}
+	if len(cipherText) < h.size+aead.NonceSize()+aead.Overhead() {
+		err = ErrShortCipherText
+		return
+	}
+	nonce := cipherText[h.size : h.size+aead.NonceSize()]
 
-	var r *gzip.Reader
-	b := bytes.NewBuffer(compressed)
-	r, err = gzip.NewReader(b)
+	var compressed []byte
+	compressed, err = aead.Open(nil, nonce, cipherText[h.size+aead.NonceSize():], append(bytes.Clone(h.raw), o.ad...))
 	if err != nil {
-		err = fmt.Errorf("ungzip newReader error: %w", err)
+		err = ErrAuthentication
 		return
 	}
-	plainText, err = io.ReadAll(r)
-	if err != nil {
-		err = fmt.Errorf("ungzip readAll error: %w", err)
+	return decompress(compressed)
+}
+
+// wrapKey seal the data key with the key, the associated data of payload is bound to it too
+func wrapKey(dataKey []byte, key string, o *options) ([]byte, error) {
+	return Encode(dataKey, key, WithAlgorithm(o.alg), WithKDF(o.kdf), WithAssociatedData(o.ad))
+}
+
+func unwrapKey(wrapped []byte, key string, o *options) (dataKey []byte, err error) {
+	if !IsEnvelope(wrapped) {
+		err = ErrAuthentication
 		return
 	}
-	err = r.Close()
-	if err != nil {
-		err = fmt.Errorf("ungzip close error: %w", err)
+	if dataKey, err = decodeEnvelope(wrapped, key, o); err != nil {
 		return
 	}
+	if len(dataKey) != dataKeyLen {
+		err = ErrAuthentication
+	}
+	return
+}
 
+// Rewrap
+// re-wrap the data key of cipher text sealed WithDataKey from the old key to the new one,
+// the payload is kept as is. Options are applied to the new wrapping,
+// the same associated data must be given as at Encode
+func Rewrap(cipherText []byte, oldKey, newKey string, opts ...Option) (reWrapped []byte, err error) {
+	buf := new(bytes.Buffer)
+	if err = RewrapStream(bytes.NewReader(cipherText), buf, oldKey, newKey, opts...); err != nil {
+		return
+	}
+	reWrapped = buf.Bytes()
+	return
+}
+
+// RewrapStream
+// the same as Rewrap, cipher text is read from src and written to dst by parts,
+// nothing is written if the cipher text has no data key
+func RewrapStream(src io.Reader, dst io.Writer, oldKey, newKey string, opts ...Option) (err error) {
+	var h header
+	if h, err = readHeader(src); err != nil {
+		return
+	}
+	if h.version < envelopeVersion3 {
+		err = ErrNoDataKey
+		return
+	}
+	o := newOptions(opts)
+	var dataKey []byte
+	if dataKey, err = unwrapKey(h.wrappedKey, oldKey, o); err != nil {
+		return
+	}
+	if h.wrappedKey, err = wrapKey(dataKey, newKey, o); err != nil {
+		return
+	}
+	if _, err = dst.Write(h.bytes()); err != nil {
+		return
+	}
+	_, err = io.Copy(dst, src)
 	return
 }
@@ -1,11 +1,17 @@
 package crypt
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/zenazn/pkcs7pad"
 )
 
 func TestEncodeDecode(t *testing.T) {
@@ -79,3 +85,181 @@ func TestEncodeDecode(t *testing.T) {
 		})
 	}
 }
+
+// encodeLegacy is the AES-CBC encoder used before the envelope format
+func encodeLegacy(t *testing.T, plainText []byte, key string) []byte {
+	bKey := sha256.Sum256([]byte(key))
+	compressed, err := compress(plainText)
+	require.NoError(t, err)
+	paddedText := pkcs7pad.Pad(compressed, aes.BlockSize)
+	block, err := aes.NewCipher(bKey[:])
+	require.NoError(t, err)
+	cipherText := make([]byte, aes.BlockSize+len(paddedText))
+	_, err = rand.Read(cipherText[:aes.BlockSize])
+	require.NoError(t, err)
+	cipher.NewCBCEncrypter(block, cipherText[:aes.BlockSize]).CryptBlocks(cipherText[aes.BlockSize:], paddedText)
+	return cipherText
+}
+
+func TestEncodeAlgorithms(t *testing.T) {
+	plainText := []byte("some secret text")
+	for _, alg := range []Algorithm{AlgAES256GCM, AlgXChaCha20Poly1305} {
+		t.Run(alg.String(), func(t *testing.T) {
+			cipherText, err := Encode(plainText, "someKey", WithAlgorithm(alg))
+			require.NoError(t, err)
+			require.True(t, IsEnvelope(cipherText))
+			require.Equal(t, byte(alg), cipherText[4])
+
+			got, err := Decode(cipherText, "someKey")
+			require.NoError(t, err)
+			require.Equal(t, plainText, got)
+		})
+	}
+
+	_, err := Encode(plainText, "someKey", WithAlgorithm(Algorithm(100)))
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+}
+
+func TestDecodeAuthentication(t *testing.T) {
+	plainText := []byte("some secret text")
+	cipherText, err := Encode(plainText, "someKey")
+	require.NoError(t, err)
+
+	t.Run("wrong key", func(t *testing.T) {
+		_, err := Decode(cipherText, "wrongKey")
+		require.ErrorIs(t, err, ErrAuthentication)
+	})
+
+	t.Run("modified payload", func(t *testing.T) {
+		modified := bytes.Clone(cipherText)
+		modified[len(modified)-1] ^= 1
+		_, err := Decode(modified, "someKey")
+		require.ErrorIs(t, err, ErrAuthentication)
+	})
+
+	t.Run("modified header", func(t *testing.T) {
+		modified := bytes.Clone(cipherText)
+		modified[4] = byte(AlgXChaCha20Poly1305)
+		_, err := Decode(modified, "someKey")
+		require.ErrorIs(t, err, ErrAuthentication)
+	})
+
+	t.Run("too short", func(t *testing.T) {
+		_, err := Decode(cipherText[:envelopeHeaderLen+2], "someKey")
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeLegacy(t *testing.T) {
+	plainText := []byte("some legacy text")
+	cipherText := encodeLegacy(t, plainText, "someKey")
+	require.False(t, IsEnvelope(cipherText))
+
+	got, err := Decode(cipherText, "someKey")
+	require.NoError(t, err)
+	require.Equal(t, plainText, got)
+
+	_, err = Decode(cipherText, "wrongKey")
+	require.ErrorIs(t, err, ErrAuthentication)
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	alg, err := ParseAlgorithm("")
+	require.NoError(t, err)
+	require.Equal(t, DefaultAlgorithm, alg)
+
+	alg, err = ParseAlgorithm("XChaCha20-Poly1305")
+	require.NoError(t, err)
+	require.Equal(t, AlgXChaCha20Poly1305, alg)
+
+	_, err = ParseAlgorithm("des")
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+}
+
+func TestAssociatedData(t *testing.T) {
+	plainText := []byte("some record data")
+	cipherText, err := Encode(plainText, "someKey",
+		WithContentType("text"), WithAssociatedData([]byte("record-1")))
+	require.NoError(t, err)
+	require.Equal(t, "text", ContentType(cipherText))
+
+	got, err := Decode(cipherText, "someKey", WithAssociatedData([]byte("record-1")))
+	require.NoError(t, err)
+	require.Equal(t, plainText, got)
+
+	_, err = Decode(cipherText, "someKey", WithAssociatedData([]byte("record-2")))
+	require.ErrorIs(t, err, ErrAuthentication)
+
+	_, err = Decode(cipherText, "someKey")
+	require.ErrorIs(t, err, ErrAuthentication)
+
+	modified := bytes.Clone(cipherText)
+	modified[envelopeHeaderLen+1] = 'T'
+	require.Equal(t, "Text", ContentType(modified))
+	_, err = Decode(modified, "someKey", WithAssociatedData([]byte("record-1")))
+	require.ErrorIs(t, err, ErrAuthentication)
+
+	_, err = Encode(plainText, "someKey", WithContentType(string(make([]byte, maxContentTypeLen+1))))
+	require.ErrorIs(t, err, ErrContentType)
+}
+
+func TestDecodeVersion1(t *testing.T) {
+	plainText := []byte("some text of version 1")
+	compressed, err := compress(plainText)
+	require.NoError(t, err)
+	bKey := sha256.Sum256([]byte("someKey"))
+	block, err := aes.NewCipher(bKey[:])
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	header := append(bytes.Clone(envelopeMagic), envelopeVersion1, byte(AlgAES256GCM), byte(KDFSHA256))
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	require.NoError(t, err)
+	cipherText := aead.Seal(append(bytes.Clone(header), nonce...), nonce, compressed, header)
+
+	require.Equal(t, "", ContentType(cipherText))
+	got, err := Decode(cipherText, "someKey")
+	require.NoError(t, err)
+	require.Equal(t, plainText, got)
+}
+
+func TestDataKey(t *testing.T) {
+	plainText := []byte("some record data with own data key")
+	ad := WithAssociatedData([]byte("record-1"))
+	cipherText, err := Encode(plainText, "oldKey", WithDataKey(), WithContentType("text"), ad)
+	require.NoError(t, err)
+	require.True(t, HasDataKey(cipherText))
+	require.Equal(t, "text", ContentType(cipherText))
+
+	got, err := Decode(cipherText, "oldKey", ad)
+	require.NoError(t, err)
+	require.Equal(t, plainText, got)
+
+	_, err = Rewrap(cipherText, "oldKey", "newKey", WithAssociatedData([]byte("record-2")))
+	require.ErrorIs(t, err, ErrAuthentication)
+	_, err = Rewrap(cipherText, "wrongKey", "newKey", ad)
+	require.ErrorIs(t, err, ErrAuthentication)
+
+	reWrapped, err := Rewrap(cipherText, "oldKey", "newKey", ad, WithAlgorithm(AlgXChaCha20Poly1305))
+	require.NoError(t, err)
+	// the sealed payload is not changed
+	h, err := parseHeader(cipherText)
+	require.NoError(t, err)
+	rh, err := parseHeader(reWrapped)
+	require.NoError(t, err)
+	require.Equal(t, cipherText[h.size:], reWrapped[rh.size:])
+	require.NotEqual(t, h.wrappedKey, rh.wrappedKey)
+
+	got, err = Decode(reWrapped, "newKey", ad)
+	require.NoError(t, err)
+	require.Equal(t, plainText, got)
+	_, err = Decode(reWrapped, "oldKey", ad)
+	require.ErrorIs(t, err, ErrAuthentication)
+
+	plain, err := Encode(plainText, "oldKey")
+	require.NoError(t, err)
+	require.False(t, HasDataKey(plain))
+	_, err = Rewrap(plain, "oldKey", "newKey")
+	require.ErrorIs(t, err, ErrNoDataKey)
+}
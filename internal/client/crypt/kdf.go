@@ -0,0 +1,130 @@
+package crypt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	KDFNameArgon2id = "argon2id"
+
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+var (
+	// DefaultKDFParams argon2id costs for new profiles, RFC 9106 second recommended option
+	DefaultKDFParams = KDFParams{
+		Algorithm: KDFNameArgon2id,
+		Time:      3,
+		Memory:    64 * 1024,
+		Threads:   4,
+	}
+	// MinKDFParams lowest accepted costs
+	MinKDFParams = KDFParams{
+		Algorithm: KDFNameArgon2id,
+		Time:      1,
+		Memory:    8 * 1024,
+		Threads:   1,
+	}
+	// MaxKDFParams highest accepted costs, the params of the profile come from the server too
+	MaxKDFParams = KDFParams{
+		Algorithm: KDFNameArgon2id,
+		Time:      100,
+		Memory:    4 * 1024 * 1024,
+		Threads:   64,
+	}
+
+	ErrKDFParams = errors.New("wrong key derivation parameters")
+	ErrKDFWeaker = errors.New("key derivation parameters can not be lower than current")
+)
+
+// KDFParams
+// parameters of the passphrase key derivation, stored at the profile next to the packed key
+type KDFParams struct {
+	Algorithm string `json:"algorithm" mapstructure:"algorithm"`
+	// Salt hex encoded random salt
+	Salt string `json:"salt" mapstructure:"salt"`
+	// Time number of passes
+	Time uint32 `json:"time" mapstructure:"time"`
+	// Memory in KiB
+	Memory  uint32 `json:"memory" mapstructure:"memory"`
+	Threads uint8  `json:"threads" mapstructure:"threads"`
+	// KeyFile fingerprint of the key file mixed into the derivation, empty if not used
+	KeyFile string `json:"key_file,omitempty" mapstructure:"key_file"`
+}
+
+// NewKDFParams returns copy of params with the new random salt
+// zero costs are taken from DefaultKDFParams
+func NewKDFParams(p KDFParams) (KDFParams, error) {
+	p.Algorithm = KDFNameArgon2id
+	if p.Time == 0 {
+		p.Time = DefaultKDFParams.Time
+	}
+	if p.Memory == 0 {
+		p.Memory = DefaultKDFParams.Memory
+	}
+	if p.Threads == 0 {
+		p.Threads = DefaultKDFParams.Threads
+	}
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return p, err
+	}
+	p.Salt = hex.EncodeToString(salt)
+	return p, p.Validate()
+}
+
+func (p KDFParams) Validate() error {
+	if p.Algorithm != KDFNameArgon2id {
+		return fmt.Errorf("%w: algorithm %q", ErrKDFParams, p.Algorithm)
+	}
+	if p.Time < MinKDFParams.Time || p.Memory < MinKDFParams.Memory || p.Threads < MinKDFParams.Threads {
+		return fmt.Errorf("%w: minimal time %d, memory %d KiB, threads %d", ErrKDFParams,
+			MinKDFParams.Time, MinKDFParams.Memory, MinKDFParams.Threads)
+	}
+	if p.Time > MaxKDFParams.Time || p.Memory > MaxKDFParams.Memory || p.Threads > MaxKDFParams.Threads {
+		return fmt.Errorf("%w: maximal time %d, memory %d KiB, threads %d", ErrKDFParams,
+			MaxKDFParams.Time, MaxKDFParams.Memory, MaxKDFParams.Threads)
+	}
+	if salt, err := hex.DecodeString(p.Salt); err != nil || len(salt) < argon2SaltLen {
+		return fmt.Errorf("%w: salt", ErrKDFParams)
+	}
+	if fp, err := hex.DecodeString(p.KeyFile); err != nil || p.KeyFile != "" && len(fp) != keyFileFingerprintLen {
+		return fmt.Errorf("%w: key file fingerprint", ErrKDFParams)
+	}
+	return nil
+}
+
+// Weaker checks if any of the costs is lower than at other params
+func (p KDFParams) Weaker(other KDFParams) bool {
+	return p.Time < other.Time || p.Memory < other.Memory || p.Threads < other.Threads
+}
+
+// DeriveKey derive the key from passphrase with argon2id,
+// result is used as key for Encode with WithKDF(KDFArgon2id).
+// If params have the key file fingerprint, the key file content is mixed in,
+// nil content gives ErrKeyFileMissing, other one ErrKeyFileWrong
+func DeriveKey(pass string, p KDFParams, keyFile []byte) (key string, err error) {
+	if err = p.Validate(); err != nil {
+		return
+	}
+	var salt []byte
+	if salt, err = hex.DecodeString(p.Salt); err != nil {
+		return
+	}
+	input := []byte(pass)
+	if p.KeyFile != "" {
+		var secret []byte
+		if secret, err = keyFileSecret(keyFile, p); err != nil {
+			return
+		}
+		input = append(append(input, 0), secret...)
+	}
+	key = string(argon2.IDKey(input, salt, p.Time, p.Memory, p.Threads, argon2KeyLen))
+	return
+}
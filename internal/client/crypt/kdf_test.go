@@ -0,0 +1,101 @@
+package crypt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveKey(t *testing.T) {
+	params, err := NewKDFParams(MinKDFParams)
+	require.NoError(t, err)
+	params2, err := NewKDFParams(MinKDFParams)
+	require.NoError(t, err)
+	require.NotEqual(t, params.Salt, params2.Salt, "salt must be random")
+
+	key, err := DeriveKey("somePass", params, nil)
+	require.NoError(t, err)
+	require.Len(t, key, argon2KeyLen)
+
+	keyAgain, err := DeriveKey("somePass", params, nil)
+	require.NoError(t, err)
+	require.Equal(t, key, keyAgain)
+
+	key2, err := DeriveKey("somePass", params2, nil)
+	require.NoError(t, err)
+	require.NotEqual(t, key, key2)
+
+	cipherText, err := Encode([]byte("token"), key, WithKDF(KDFArgon2id))
+	require.NoError(t, err)
+	require.Equal(t, byte(KDFArgon2id), cipherText[5])
+
+	plainText, err := Decode(cipherText, key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("token"), plainText)
+
+	_, err = Decode(cipherText, key2)
+	require.ErrorIs(t, err, ErrAuthentication)
+
+	_, err = Decode(cipherText, "somePass")
+	require.ErrorIs(t, err, ErrAuthentication)
+}
+
+func TestKDFParams(t *testing.T) {
+	params, err := NewKDFParams(KDFParams{})
+	require.NoError(t, err)
+	require.Equal(t, DefaultKDFParams.Time, params.Time)
+	require.Equal(t, DefaultKDFParams.Memory, params.Memory)
+	require.Equal(t, DefaultKDFParams.Threads, params.Threads)
+
+	_, err = NewKDFParams(KDFParams{Memory: 1024})
+	require.ErrorIs(t, err, ErrKDFParams)
+	_, err = NewKDFParams(KDFParams{Memory: MaxKDFParams.Memory + 1})
+	require.ErrorIs(t, err, ErrKDFParams)
+	_, err = NewKDFParams(KDFParams{Time: MaxKDFParams.Time + 1})
+	require.ErrorIs(t, err, ErrKDFParams)
+	_, err = NewKDFParams(KDFParams{Threads: MaxKDFParams.Threads + 1})
+	require.ErrorIs(t, err, ErrKDFParams)
+	_, err = NewKDFParams(MaxKDFParams)
+	require.NoError(t, err)
+
+	bad := params
+	bad.Salt = "not hex"
+	require.ErrorIs(t, bad.Validate(), ErrKDFParams)
+	_, err = DeriveKey("somePass", bad, nil)
+	require.ErrorIs(t, err, ErrKDFParams)
+
+	raised := params
+	raised.Memory *= 2
+	require.False(t, raised.Weaker(params))
+	require.True(t, params.Weaker(raised))
+}
+
+func TestDeriveKeyFile(t *testing.T) {
+	content, err := NewKeyFile()
+	require.NoError(t, err)
+	require.Len(t, content, KeyFileLen)
+	params, err := NewKDFParams(MinKDFParams)
+	require.NoError(t, err)
+	key, err := DeriveKey("somePass", params, nil)
+	require.NoError(t, err)
+
+	params.KeyFile = KeyFileFingerprint(content)
+	require.NoError(t, params.Validate())
+	withFile, err := DeriveKey("somePass", params, content)
+	require.NoError(t, err)
+	require.NotEqual(t, key, withFile)
+
+	_, err = DeriveKey("somePass", params, nil)
+	require.ErrorIs(t, err, ErrKeyFileMissing)
+
+	other, err := NewKeyFile()
+	require.NoError(t, err)
+	_, err = DeriveKey("somePass", params, other)
+	require.ErrorIs(t, err, ErrKeyFileWrong)
+
+	_, err = DeriveKey("somePass", params, content[:MinKeyFileLen-1])
+	require.ErrorIs(t, err, ErrKeyFileSize)
+
+	params.KeyFile = "not hex"
+	require.ErrorIs(t, params.Validate(), ErrKDFParams)
+}
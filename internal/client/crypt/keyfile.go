@@ -0,0 +1,63 @@
+package crypt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// The key file is the second factor of the passphrase key derivation:
+// the secret derived from its content is mixed into the argon2id input,
+// the fingerprint kept at KDFParams tells a wrong key file from a wrong passphrase.
+
+const (
+	// KeyFileLen bytes of the new key file
+	KeyFileLen = 64
+	// MinKeyFileLen the shortest content accepted as key file
+	MinKeyFileLen = 32
+	// MaxKeyFileLen the longest content accepted as key file
+	MaxKeyFileLen = 1024 * 1024
+
+	keyFileFingerprintLen = 16
+)
+
+var (
+	ErrKeyFileMissing = errors.New("key file is required but not found")
+	ErrKeyFileWrong   = errors.New("wrong key file")
+	ErrKeyFileSize    = errors.New("wrong key file size")
+)
+
+// NewKeyFile returns the random content of a new key file
+func NewKeyFile() (content []byte, err error) {
+	content = make([]byte, KeyFileLen)
+	_, err = io.ReadFull(rand.Reader, content)
+	return
+}
+
+// KeyFileFingerprint returns the hex fingerprint of key file content, stored at KDFParams.KeyFile
+func KeyFileFingerprint(content []byte) string {
+	sum := sha256.Sum256(append([]byte("gophkeeper/keyfile-id\x00"), content...))
+	return hex.EncodeToString(sum[:keyFileFingerprintLen])
+}
+
+// keyFileSecret
+// checks the key file content against the params and returns the secret to mix into the derivation
+func keyFileSecret(content []byte, p KDFParams) (secret []byte, err error) {
+	switch {
+	case content == nil:
+		err = ErrKeyFileMissing
+		return
+	case len(content) < MinKeyFileLen || len(content) > MaxKeyFileLen:
+		err = ErrKeyFileSize
+		return
+	case subtle.ConstantTimeCompare([]byte(KeyFileFingerprint(content)), []byte(p.KeyFile)) != 1:
+		err = ErrKeyFileWrong
+		return
+	}
+	sum := sha256.Sum256(append([]byte("gophkeeper/keyfile\x00"), content...))
+	secret = sum[:]
+	return
+}
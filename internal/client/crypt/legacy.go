@@ -0,0 +1,50 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/zenazn/pkcs7pad"
+)
+
+// decodeLegacy decrypt cipher text of the AES-CBC format without authentication,
+// used before the versioned envelope, kept to read old stores
+func decodeLegacy(cipherText []byte, key string) (plainText []byte, err error) {
+	bKey := sha256.Sum256([]byte(key))
+
+	var block cipher.Block
+	block, err = aes.NewCipher(bKey[:])
+	if err != nil {
+		return
+	}
+
+	if len(cipherText) < aes.BlockSize {
+		err = ErrShortCipherText
+		return
+	}
+	iv := cipherText[:aes.BlockSize]
+	if len(cipherText[aes.BlockSize:])%aes.BlockSize != 0 {
+		err = errors.New("cipherText has wrong block size")
+		return
+	}
+	paddedText := make([]byte, len(cipherText)-aes.BlockSize)
+	decrypt := cipher.NewCBCDecrypter(block, iv)
+	decrypt.CryptBlocks(paddedText, cipherText[aes.BlockSize:])
+
+	var compressed []byte
+	compressed, err = pkcs7pad.Unpad(paddedText)
+	if err != nil {
+		// no mac at legacy format, broken padding is the only sign of wrong key
+		err = fmt.Errorf("%w: unpad error: %v", ErrAuthentication, err)
+		return
+	}
+
+	plainText, err = decompress(compressed)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrAuthentication, err)
+	}
+	return
+}
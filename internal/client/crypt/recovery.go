@@ -0,0 +1,87 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"io"
+	"strings"
+)
+
+// The recovery code and its shares are written as base32 groups with a checksum,
+// so a typo is detected before the unwrapping of the key:
+//
+//	base32(payload | first bytes of sha256(payload)) split by groups of recoveryGroupLen
+
+const (
+	// RecoverySecretLen bytes of the recovery code secret
+	RecoverySecretLen = 32
+	recoveryCheckLen  = 2
+	recoveryGroupLen  = 5
+)
+
+var (
+	ErrRecoveryCode  = errors.New("wrong recovery code")
+	ErrRecoveryShare = errors.New("wrong recovery share")
+
+	recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// NewRecoverySecret returns the random secret of recovery code
+func NewRecoverySecret() (secret []byte, err error) {
+	secret = make([]byte, RecoverySecretLen)
+	_, err = io.ReadFull(rand.Reader, secret)
+	return
+}
+
+func formatRecovery(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	text := recoveryEncoding.EncodeToString(append(bytes.Clone(payload), sum[:recoveryCheckLen]...))
+	groups := make([]string, 0, len(text)/recoveryGroupLen+1)
+	for len(text) > recoveryGroupLen {
+		groups = append(groups, text[:recoveryGroupLen])
+		text = text[recoveryGroupLen:]
+	}
+	return strings.Join(append(groups, text), "-")
+}
+
+func parseRecovery(text string, payloadLen int) (payload []byte, ok bool) {
+	text = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(text)))
+	b, err := recoveryEncoding.DecodeString(text)
+	if err != nil || len(b) != payloadLen+recoveryCheckLen {
+		return
+	}
+	payload = b[:payloadLen]
+	sum := sha256.Sum256(payload)
+	return payload, bytes.Equal(sum[:recoveryCheckLen], b[payloadLen:])
+}
+
+// FormatRecoveryCode returns the text of recovery code for the secret
+func FormatRecoveryCode(secret []byte) string {
+	return formatRecovery(secret)
+}
+
+// ParseRecoveryCode returns the secret of the recovery code text
+func ParseRecoveryCode(code string) (secret []byte, err error) {
+	var ok bool
+	if secret, ok = parseRecovery(code, RecoverySecretLen); !ok {
+		err = ErrRecoveryCode
+	}
+	return
+}
+
+// FormatRecoveryShare returns the text of recovery code share of SplitSecret
+func FormatRecoveryShare(share []byte) string {
+	return formatRecovery(share)
+}
+
+// ParseRecoveryShare returns the share of the recovery share text
+func ParseRecoveryShare(text string) (share []byte, err error) {
+	var ok bool
+	if share, ok = parseRecovery(text, shareHeaderLen+RecoverySecretLen); !ok || ShareThreshold(share) < 2 {
+		err = ErrRecoveryShare
+	}
+	return
+}
@@ -0,0 +1,146 @@
+package crypt
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Shamir's secret sharing over GF(256) with the AES polynomial x^8 + x^4 + x^3 + x + 1.
+// Every byte of the secret is the constant term of its own random polynomial
+// of threshold-1 degree, a share is the value of polynomials at the share x.
+//
+// Share layout: threshold | x | values of polynomials at x
+
+const (
+	maxShares      = 255
+	shareHeaderLen = 2
+)
+
+var (
+	ErrShareParams = errors.New("wrong shares number or threshold")
+	ErrShares      = errors.New("wrong or not enough shares")
+)
+
+var gfExp, gfLog [256]byte
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfLog[x] = byte(i)
+		// multiply by the generator 3
+		x ^= gfMulSlow(x, 2)
+	}
+	gfExp[255] = gfExp[0]
+}
+
+func gfMulSlow(a, b byte) (p byte) {
+	for b > 0 {
+		if b&1 == 1 {
+			p ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[(int(gfLog[a])+int(gfLog[b]))%255]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[(int(gfLog[a])-int(gfLog[b])+255)%255]
+}
+
+// SplitSecret
+// split the secret into n shares, any threshold of them restore the secret by CombineShares
+func SplitSecret(secret []byte, n, threshold int) (shares [][]byte, err error) {
+	if threshold < 2 || threshold > n || n > maxShares || len(secret) == 0 {
+		err = fmt.Errorf("%w: %d of %d", ErrShareParams, threshold, n)
+		return
+	}
+	shares = make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, shareHeaderLen+len(secret))
+		shares[i][0], shares[i][1] = byte(threshold), byte(i+1)
+	}
+	coefficients := make([]byte, threshold-1)
+	for j, s := range secret {
+		if _, err = io.ReadFull(rand.Reader, coefficients); err != nil {
+			return
+		}
+		for i := range shares {
+			x := byte(i + 1)
+			// Horner's method from the highest coefficient
+			var y byte
+			for c := len(coefficients) - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ coefficients[c]
+			}
+			shares[i][shareHeaderLen+j] = gfMul(y, x) ^ s
+		}
+	}
+	return
+}
+
+// ShareThreshold returns the number of shares needed to restore the secret
+func ShareThreshold(share []byte) int {
+	if len(share) <= shareHeaderLen {
+		return 0
+	}
+	return int(share[0])
+}
+
+// CombineShares
+// restore the secret from the threshold shares of SplitSecret, extra shares are ignored
+func CombineShares(shares [][]byte) (secret []byte, err error) {
+	if len(shares) == 0 || ShareThreshold(shares[0]) < 2 || len(shares) < ShareThreshold(shares[0]) {
+		err = ErrShares
+		return
+	}
+	threshold := ShareThreshold(shares[0])
+	shares = shares[:threshold]
+	xs := make([]byte, threshold)
+	for i, share := range shares {
+		if len(share) != len(shares[0]) || int(share[0]) != threshold || share[1] == 0 {
+			err = ErrShares
+			return
+		}
+		for _, x := range xs[:i] {
+			if x == share[1] {
+				err = fmt.Errorf("%w: duplicate share %d", ErrShares, x)
+				return
+			}
+		}
+		xs[i] = share[1]
+	}
+	// lagrange basis at zero
+	basis := make([]byte, threshold)
+	for i := range xs {
+		basis[i] = 1
+		for j := range xs {
+			if i != j {
+				basis[i] = gfMul(basis[i], gfDiv(xs[j], xs[i]^xs[j]))
+			}
+		}
+	}
+	secret = make([]byte, len(shares[0])-shareHeaderLen)
+	for j := range secret {
+		for i, share := range shares {
+			secret[j] ^= gfMul(basis[i], share[shareHeaderLen+j])
+		}
+	}
+	return
+}
@@ -0,0 +1,100 @@
+package crypt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGF(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			p := gfMul(byte(a), byte(b))
+			require.Equal(t, gfMulSlow(byte(a), byte(b)), p)
+			require.Equal(t, byte(a), gfDiv(p, byte(b)))
+		}
+	}
+}
+
+func TestSplitSecret(t *testing.T) {
+	secret, err := NewRecoverySecret()
+	require.NoError(t, err)
+
+	shares, err := SplitSecret(secret, 5, 3)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+	for _, share := range shares {
+		require.Equal(t, 3, ShareThreshold(share))
+		require.NotEqual(t, secret, share[shareHeaderLen:])
+	}
+
+	// any 3 shares restore the secret
+	for i := 0; i < 5; i++ {
+		for j := i + 1; j < 5; j++ {
+			for k := j + 1; k < 5; k++ {
+				got, err := CombineShares([][]byte{shares[k], shares[i], shares[j]})
+				require.NoError(t, err)
+				require.True(t, bytes.Equal(secret, got))
+			}
+		}
+	}
+
+	got, err := CombineShares(shares)
+	require.NoError(t, err)
+	require.Equal(t, secret, got)
+
+	_, err = CombineShares(shares[:2])
+	require.ErrorIs(t, err, ErrShares)
+	_, err = CombineShares([][]byte{shares[0], shares[0], shares[1]})
+	require.ErrorIs(t, err, ErrShares)
+
+	// a modified share gives other secret
+	modified := bytes.Clone(shares[2])
+	modified[shareHeaderLen] ^= 1
+	got, err = CombineShares([][]byte{shares[0], shares[1], modified})
+	require.NoError(t, err)
+	require.NotEqual(t, secret, got)
+
+	for _, p := range [][2]int{{1, 1}, {3, 4}, {256, 2}, {3, 1}} {
+		_, err = SplitSecret(secret, p[0], p[1])
+		require.ErrorIs(t, err, ErrShareParams)
+	}
+}
+
+func TestRecoveryCode(t *testing.T) {
+	secret, err := NewRecoverySecret()
+	require.NoError(t, err)
+
+	code := FormatRecoveryCode(secret)
+	got, err := ParseRecoveryCode(code)
+	require.NoError(t, err)
+	require.Equal(t, secret, got)
+
+	got, err = ParseRecoveryCode(" " + strings.ToLower(strings.ReplaceAll(code, "-", " ")) + "\n")
+	require.NoError(t, err)
+	require.Equal(t, secret, got)
+
+	typo := []byte(code)
+	if typo[3] == 'A' {
+		typo[3] = 'B'
+	} else {
+		typo[3] = 'A'
+	}
+	_, err = ParseRecoveryCode(string(typo))
+	require.ErrorIs(t, err, ErrRecoveryCode)
+
+	shares, err := SplitSecret(secret, 3, 2)
+	require.NoError(t, err)
+	text := FormatRecoveryShare(shares[1])
+	share, err := ParseRecoveryShare(text)
+	require.NoError(t, err)
+	require.Equal(t, shares[1], share)
+
+	// share is not a code and vice versa
+	_, err = ParseRecoveryCode(text)
+	require.ErrorIs(t, err, ErrRecoveryCode)
+	_, err = ParseRecoveryShare(code)
+	require.ErrorIs(t, err, ErrRecoveryShare)
+}
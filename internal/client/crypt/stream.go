@@ -0,0 +1,310 @@
+package crypt
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Stream layout (envelope version 4), after the header of version 3:
+//
+//	chunk size (uint32) | nonce prefix | sealed chunk | sealed chunk | ... | sealed last chunk
+//
+// The gzip payload is cut into chunks of chunk size, the last one can be shorter or empty.
+// The nonce of a chunk is nonce prefix | chunk counter (uint32) | last chunk flag,
+// so chunks can't be reordered, dropped or truncated without detection.
+
+const (
+	// DefaultChunkSize size of the plain chunk of stream
+	DefaultChunkSize = 64 * 1024
+	maxChunkSize     = 16 * 1024 * 1024
+	// nonceTailLen chunk counter and last chunk flag at the end of nonce
+	nonceTailLen = 5
+)
+
+var (
+	ErrNotStream  = errors.New("cipherText is not a stream")
+	ErrTruncated  = fmt.Errorf("%w: stream is truncated", ErrAuthentication)
+	ErrChunkSize  = errors.New("wrong chunk size")
+	errTooManyChk = errors.New("too many chunks")
+)
+
+// WithChunkSize set the plain chunk size for NewEncryptWriter
+func WithChunkSize(n int) Option {
+	return func(o *options) {
+		o.chunkSize = n
+	}
+}
+
+// Info the clear part of the envelope header
+type Info struct {
+	Version     byte
+	Algorithm   Algorithm
+	ContentType string
+}
+
+// IsStream checks if the envelope is written by NewEncryptWriter
+func (i Info) IsStream() bool {
+	return i.Version == envelopeVersion4
+}
+
+// HasDataKey checks if the envelope is sealed with a wrapped data key
+func (i Info) HasDataKey() bool {
+	return i.Version >= envelopeVersion3
+}
+
+// ReadInfo reads the envelope header from r,
+// for legacy cipher text ErrShortCipherText is returned
+func ReadInfo(r io.Reader) (info Info, err error) {
+	var h header
+	if h, err = readHeader(r); err != nil {
+		return
+	}
+	info = Info{Version: h.version, Algorithm: h.alg, ContentType: h.contentType}
+	return
+}
+
+func setChunkNonce(nonce []byte, counter uint32, last bool) {
+	tail := nonce[len(nonce)-nonceTailLen:]
+	binary.BigEndian.PutUint32(tail, counter)
+	tail[4] = 0
+	if last {
+		tail[4] = 1
+	}
+}
+
+type streamWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	ad      []byte
+	nonce   []byte
+	counter uint32
+	buf     []byte
+	sealed  []byte
+	closed  bool
+	err     error
+}
+
+func (w *streamWriter) Write(p []byte) (n int, err error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	for len(p) > 0 {
+		// the full chunk is sealed only when more data comes, the last one is sealed by Close
+		if len(w.buf) == cap(w.buf) {
+			if err = w.seal(false); err != nil {
+				w.err = err
+				return
+			}
+		}
+		m := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return
+}
+
+func (w *streamWriter) seal(last bool) (err error) {
+	if w.counter == math.MaxUint32 && !last {
+		return errTooManyChk
+	}
+	setChunkNonce(w.nonce, w.counter, last)
+	w.sealed = w.aead.Seal(w.sealed[:0], w.nonce, w.buf, w.ad)
+	if _, err = w.w.Write(w.sealed); err != nil {
+		return
+	}
+	w.buf = w.buf[:0]
+	w.counter++
+	return
+}
+
+func (w *streamWriter) Close() (err error) {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	return w.seal(true)
+}
+
+type encryptWriter struct {
+	*gzip.Writer
+	sw *streamWriter
+}
+
+func (w *encryptWriter) Close() error {
+	return errors.Join(w.Writer.Close(), w.sw.Close())
+}
+
+// NewEncryptWriter
+// returns the writer compressing and encrypting the written data into w by chunks,
+// the data is sealed with a random data key wrapped by the key, as Encode WithDataKey does.
+// Close must be called to write the last chunk, w itself is not closed
+func NewEncryptWriter(w io.Writer, key string, opts ...Option) (wc io.WriteCloser, err error) {
+	o := newOptions(opts)
+	o.dataKey, o.stream = true, true
+	if o.chunkSize <= 0 || o.chunkSize > maxChunkSize {
+		err = fmt.Errorf("%w: %d", ErrChunkSize, o.chunkSize)
+		return
+	}
+	var h header
+	if h, err = newHeader(o); err != nil {
+		return
+	}
+	dataKey := make([]byte, dataKeyLen)
+	if _, err = io.ReadFull(rand.Reader, dataKey); err != nil {
+		return
+	}
+	if h.wrappedKey, err = wrapKey(dataKey, key, o); err != nil {
+		return
+	}
+	var aead cipher.AEAD
+	if aead, err = newAEAD(h.alg, dataKey); err != nil {
+		return
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce[:len(nonce)-nonceTailLen]); err != nil {
+		return
+	}
+	prefix := binary.BigEndian.AppendUint32(h.bytes(), uint32(o.chunkSize))
+	if _, err = w.Write(append(prefix, nonce[:len(nonce)-nonceTailLen]...)); err != nil {
+		return
+	}
+	sw := &streamWriter{
+		w:      w,
+		aead:   aead,
+		ad:     append(append([]byte{}, h.raw...), o.ad...),
+		nonce:  nonce,
+		buf:    make([]byte, 0, o.chunkSize),
+		sealed: make([]byte, 0, o.chunkSize+aead.Overhead()),
+	}
+	wc = &encryptWriter{Writer: gzip.NewWriter(sw), sw: sw}
+	return
+}
+
+type streamReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	ad      []byte
+	nonce   []byte
+	counter uint32
+	sealed  []byte
+	opened  []byte
+	plain   []byte
+	last    bool
+	err     error
+}
+
+func (r *streamReader) Read(p []byte) (n int, err error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.next()
+	}
+	n = copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return
+}
+
+func (r *streamReader) next() (err error) {
+	if r.last {
+		return io.EOF
+	}
+	var n int
+	n, err = io.ReadFull(r.r, r.sealed[:cap(r.sealed)])
+	switch {
+	case errors.Is(err, io.EOF):
+		return ErrTruncated
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		r.last = true
+	case err != nil:
+		return
+	default:
+		if _, err = r.r.Peek(1); errors.Is(err, io.EOF) {
+			r.last = true
+		} else if err != nil {
+			return
+		}
+	}
+	if r.counter == math.MaxUint32 && !r.last {
+		return errTooManyChk
+	}
+	setChunkNonce(r.nonce, r.counter, r.last)
+	if r.plain, err = r.aead.Open(r.opened[:0], r.nonce, r.sealed[:n], r.ad); err != nil {
+		if r.last && n == cap(r.sealed) {
+			// the full chunk at the end can be the not last one of cut stream
+			setChunkNonce(r.nonce, r.counter, false)
+			if _, oErr := r.aead.Open(nil, r.nonce, r.sealed[:n], r.ad); oErr == nil {
+				return ErrTruncated
+			}
+		}
+		return ErrAuthentication
+	}
+	r.counter++
+	return
+}
+
+func newDecryptReader(h header, r io.Reader, key string, o *options) (dr io.Reader, err error) {
+	var dataKey []byte
+	if dataKey, err = unwrapKey(h.wrappedKey, key, o); err != nil {
+		return
+	}
+	var aead cipher.AEAD
+	if aead, err = newAEAD(h.alg, dataKey); err != nil {
+		return
+	}
+	params := make([]byte, 4+aead.NonceSize()-nonceTailLen)
+	if _, err = io.ReadFull(r, params); err != nil {
+		err = ErrShortCipherText
+		return
+	}
+	chunkSize := binary.BigEndian.Uint32(params)
+	if chunkSize == 0 || chunkSize > maxChunkSize {
+		err = fmt.Errorf("%w: %d", ErrChunkSize, chunkSize)
+		return
+	}
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, params[4:])
+	sr := &streamReader{
+		r:      bufio.NewReader(r),
+		aead:   aead,
+		ad:     append(append([]byte{}, h.raw...), o.ad...),
+		nonce:  nonce,
+		sealed: make([]byte, 0, int(chunkSize)+aead.Overhead()),
+		opened: make([]byte, 0, chunkSize),
+	}
+	var gz *gzip.Reader
+	if gz, err = gzip.NewReader(sr); err != nil {
+		if !errors.Is(err, ErrAuthentication) {
+			err = fmt.Errorf("ungzip newReader error: %w", err)
+		}
+		return
+	}
+	dr = gz
+	return
+}
+
+// NewDecryptReader
+// returns the reader of data written by NewEncryptWriter to r.
+// A modified stream makes Read return ErrAuthentication, a cut one ErrTruncated
+func NewDecryptReader(r io.Reader, key string, opts ...Option) (dr io.Reader, err error) {
+	var h header
+	if h, err = readHeader(r); err != nil {
+		return
+	}
+	if h.version != envelopeVersion4 {
+		err = ErrNotStream
+		return
+	}
+	return newDecryptReader(h, r, key, newOptions(opts))
+}
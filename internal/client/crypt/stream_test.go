@@ -0,0 +1,138 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encodeStream(t *testing.T, plainText []byte, key string, opts ...Option) []byte {
+	buf := new(bytes.Buffer)
+	w, err := NewEncryptWriter(buf, key, opts...)
+	require.NoError(t, err)
+	// write by parts of not chunk size
+	for p := plainText; len(p) > 0; {
+		n := min(len(p), 1000)
+		_, err = w.Write(p[:n])
+		require.NoError(t, err)
+		p = p[n:]
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func decodeStream(cipherText []byte, key string, opts ...Option) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(cipherText), key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestStream(t *testing.T) {
+	random := make([]byte, 10000)
+	_, err := rand.Read(random)
+	require.NoError(t, err)
+
+	for _, alg := range []Algorithm{AlgAES256GCM, AlgXChaCha20Poly1305} {
+		for _, plainText := range [][]byte{{}, []byte("short"), random, random[:4096], bytes.Repeat([]byte("abc"), 100000)} {
+			opts := []Option{WithAlgorithm(alg), WithChunkSize(1024), WithContentType("bin"), WithAssociatedData([]byte("rec"))}
+			cipherText := encodeStream(t, plainText, "someKey", opts...)
+
+			info, err := ReadInfo(bytes.NewReader(cipherText))
+			require.NoError(t, err)
+			require.True(t, info.IsStream())
+			require.True(t, info.HasDataKey())
+			require.Equal(t, "bin", info.ContentType)
+			require.Equal(t, alg, info.Algorithm)
+
+			got, err := decodeStream(cipherText, "someKey", WithAssociatedData([]byte("rec")))
+			require.NoError(t, err)
+			require.Equal(t, len(plainText), len(got))
+			require.True(t, bytes.Equal(plainText, got))
+
+			// the stream blob is readable by Decode too
+			got, err = Decode(cipherText, "someKey", WithAssociatedData([]byte("rec")))
+			require.NoError(t, err)
+			require.True(t, bytes.Equal(plainText, got))
+
+			_, err = decodeStream(cipherText, "otherKey", WithAssociatedData([]byte("rec")))
+			require.ErrorIs(t, err, ErrAuthentication)
+			_, err = decodeStream(cipherText, "someKey", WithAssociatedData([]byte("other")))
+			require.ErrorIs(t, err, ErrAuthentication)
+		}
+	}
+
+	_, err = NewEncryptWriter(io.Discard, "someKey", WithChunkSize(0))
+	require.ErrorIs(t, err, ErrChunkSize)
+
+	plain, err := Encode([]byte("not stream"), "someKey", WithDataKey())
+	require.NoError(t, err)
+	_, err = NewDecryptReader(bytes.NewReader(plain), "someKey")
+	require.ErrorIs(t, err, ErrNotStream)
+}
+
+func TestStreamModified(t *testing.T) {
+	plainText := make([]byte, 20000)
+	_, err := rand.Read(plainText)
+	require.NoError(t, err)
+	const chunkSize = 1024
+	cipherText := encodeStream(t, plainText, "someKey", WithChunkSize(chunkSize))
+	h, err := parseHeader(cipherText)
+	require.NoError(t, err)
+	// chunk size and nonce prefix of aes-gcm
+	start := h.size + 4 + 12 - nonceTailLen
+	sealedSize := chunkSize + 16
+	require.Greater(t, len(cipherText)-start, 3*sealedSize)
+
+	t.Run("modified chunk", func(t *testing.T) {
+		modified := bytes.Clone(cipherText)
+		modified[start+sealedSize+10] ^= 1
+		_, err := decodeStream(modified, "someKey")
+		require.ErrorIs(t, err, ErrAuthentication)
+	})
+
+	t.Run("swapped chunks", func(t *testing.T) {
+		modified := bytes.Clone(cipherText)
+		copy(modified[start:], cipherText[start+sealedSize:start+2*sealedSize])
+		copy(modified[start+sealedSize:], cipherText[start:start+sealedSize])
+		_, err := decodeStream(modified, "someKey")
+		require.ErrorIs(t, err, ErrAuthentication)
+	})
+
+	t.Run("truncated at chunk boundary", func(t *testing.T) {
+		_, err := decodeStream(cipherText[:start+2*sealedSize], "someKey")
+		require.ErrorIs(t, err, ErrAuthentication)
+		require.ErrorIs(t, err, ErrTruncated)
+	})
+
+	t.Run("truncated last chunk", func(t *testing.T) {
+		_, err := decodeStream(cipherText[:len(cipherText)-1], "someKey")
+		require.ErrorIs(t, err, ErrAuthentication)
+	})
+
+	t.Run("appended data", func(t *testing.T) {
+		_, err := decodeStream(append(bytes.Clone(cipherText), 0), "someKey")
+		require.ErrorIs(t, err, ErrAuthentication)
+	})
+
+	t.Run("rewrap", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		err := RewrapStream(bytes.NewReader(cipherText), buf, "someKey", "newKey")
+		require.NoError(t, err)
+		got, err := decodeStream(buf.Bytes(), "newKey")
+		require.NoError(t, err)
+		require.Equal(t, plainText, got)
+		_, err = decodeStream(buf.Bytes(), "someKey")
+		require.ErrorIs(t, err, ErrAuthentication)
+
+		legacy := encodeLegacy(t, plainText, "someKey")
+		buf.Reset()
+		err = RewrapStream(bytes.NewReader(legacy), buf, "someKey", "newKey")
+		require.Error(t, err)
+		require.Zero(t, buf.Len())
+	})
+}
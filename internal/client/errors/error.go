@@ -3,8 +3,14 @@ package errors
 import "errors"
 
 var (
-	ErrLoadProfile     = errors.New("error get profile")
-	ErrDecode          = errors.New("decode error, check passphrase")
-	ErrPassword        = errors.New("wrong password")
-	ErrPasswordConfirm = errors.New("password confirm error")
+	ErrLoadProfile      = errors.New("error get profile")
+	ErrDecode           = errors.New("decode error, check passphrase")
+	ErrPassword         = errors.New("wrong password")
+	ErrPasswordConfirm  = errors.New("password confirm error")
+	ErrRecordBinding    = errors.New("record data does not belong to this key")
+	ErrNoEncryptionKey  = errors.New("encryption key is not created yet")
+	ErrNoContent        = errors.New("record has no content to extract")
+	ErrNoRecovery       = errors.New("recovery code is not created yet")
+	ErrKeyFileInProfile = errors.New("key file must be outside the profile directory")
+	ErrNotHOTP          = errors.New("record is not a counter based one-time password")
 )
@@ -0,0 +1,271 @@
+/*
+Package exporter writes the decrypted records in the plain formats, to migrate them to other applications.
+
+The formats are:
+
+- csv, one row per record: the key, type, description, created and updated time, the data fields
+  as the "data.<name>" columns, the extra fields as "name=value" lines and the urls as "match=url" lines.
+- json, the array of the record objects.
+- yaml, the sequence of the record objects.
+
+The records are written one by one, so the output is streamed.
+*/
+
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/out"
+	"gophKeeper/internal/client/model/type/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+var ErrFormat = errors.New("unknown export format")
+
+// Formats the export formats
+var Formats = []string{FormatCSV, FormatJSON, FormatYAML}
+
+// csvColumns the columns of csv before the data fields, and after them,
+// the data field columns are prefixed by csvDataPrefix to not be taken for them
+var (
+	csvColumns     = []string{"key", "type", "description", "created_at", "updated_at"}
+	csvTailColumns = []string{"fields", "urls"}
+)
+
+const csvDataPrefix = "data."
+
+// Record
+// the normalized record, the data fields are by their JSON names,
+// the template record has the template name and the values of template fields
+type Record struct {
+	Key         string         `json:"key" yaml:"key"`
+	Type        string         `json:"type" yaml:"type"`
+	Description string         `json:"description" yaml:"description"`
+	CreatedAt   time.Time      `json:"created_at" yaml:"created_at"`
+	UpdatedAt   *time.Time     `json:"updated_at" yaml:"updated_at"`
+	Data        map[string]any `json:"data" yaml:"data"`
+	Fields      []model.Field  `json:"fields,omitempty" yaml:"fields,omitempty"`
+	URLs        []model.URL    `json:"urls,omitempty" yaml:"urls,omitempty"`
+	// columns the data field names by the order of data
+	columns []string
+}
+
+// Normalize
+// the record of the decrypted item
+func Normalize(item out.Item) (r Record, err error) {
+	r = Record{
+		Key:         item.Key,
+		Description: item.Description,
+		CreatedAt:   item.CreatedAt,
+		UpdatedAt:   item.UpdatedAt,
+		Data:        make(map[string]any),
+		Fields:      item.Fields,
+		URLs:        item.URLs,
+	}
+	if item.Data == nil {
+		return
+	}
+	r.Type = model.GetName(item.Data)
+	if d, ok := item.Data.(*template.Data); ok {
+		r.set("template", d.Template)
+		for _, f := range d.Fields {
+			r.set(f.Name, f.Value)
+		}
+		return
+	}
+	var b []byte
+	if b, err = json.Marshal(item.Data); err != nil {
+		return
+	}
+	// the order of the data fields is kept for the columns
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if _, err = dec.Token(); err != nil {
+		return
+	}
+	for dec.More() {
+		var (
+			t     json.Token
+			value any
+		)
+		if t, err = dec.Token(); err != nil {
+			return
+		}
+		if err = dec.Decode(&value); err != nil {
+			return
+		}
+		r.set(t.(string), value)
+	}
+	return
+}
+
+// set
+// set the data field value, the first one of the name is kept.
+// The JSON number is the integer or the float, so it is a number in all formats
+func (r *Record) set(name string, value any) {
+	if _, ok := r.Data[name]; ok {
+		return
+	}
+	if n, ok := value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			value = i
+		} else if f, err := n.Float64(); err == nil {
+			value = f
+		}
+	}
+	r.Data[name] = value
+	r.columns = append(r.columns, name)
+}
+
+// Columns
+// the data field names of the record by the order of data
+func (r Record) Columns() []string {
+	return r.columns
+}
+
+// Writer
+// writes the records, Close completes the output
+type Writer interface {
+	Write(r Record) error
+	Close() error
+}
+
+// NewWriter
+// the writer of the format to w, columns are the data field columns of csv
+func NewWriter(format string, w io.Writer, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	case FormatYAML:
+		return &yamlWriter{w: w}, nil
+	}
+	return nil, fmt.Errorf("%w: %s, supported: %s", ErrFormat, format, strings.Join(Formats, ", "))
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), columns: columns}
+	header := append([]string{}, csvColumns...)
+	for _, c := range columns {
+		header = append(header, csvDataPrefix+c)
+	}
+	return cw, cw.w.Write(append(header, csvTailColumns...))
+}
+
+func (w *csvWriter) Write(r Record) error {
+	row := []string{r.Key, r.Type, r.Description, r.CreatedAt.Format(time.DateTime), ""}
+	if r.UpdatedAt != nil {
+		row[4] = r.UpdatedAt.Format(time.DateTime)
+	}
+	for _, name := range w.columns {
+		value, err := csvValue(r.Data[name])
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", r.Key, name, err)
+		}
+		row = append(row, value)
+	}
+	fields := make([]string, len(r.Fields))
+	for i, f := range r.Fields {
+		fields[i] = f.Name + "=" + f.Value
+	}
+	urls := make([]string, len(r.URLs))
+	for i, u := range r.URLs {
+		urls[i] = u.URL
+		if u.Match != "" {
+			urls[i] = u.Match + "=" + u.URL
+		}
+	}
+	return w.w.Write(append(row, strings.Join(fields, "\n"), strings.Join(urls, "\n")))
+}
+
+// csvValue
+// the string is written as is, the other values are in JSON, nil is empty
+func csvValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type jsonWriter struct {
+	w io.Writer
+	n int
+}
+
+func (w *jsonWriter) Write(r Record) (err error) {
+	var b []byte
+	if b, err = json.MarshalIndent(r, "  ", "  "); err != nil {
+		return
+	}
+	sep := ",\n  "
+	if w.n == 0 {
+		sep = "[\n  "
+	}
+	w.n++
+	_, err = w.w.Write(append([]byte(sep), b...))
+	return
+}
+
+func (w *jsonWriter) Close() (err error) {
+	if w.n == 0 {
+		_, err = io.WriteString(w.w, "[]\n")
+		return
+	}
+	_, err = io.WriteString(w.w, "\n]\n")
+	return
+}
+
+type yamlWriter struct {
+	w io.Writer
+	n int
+}
+
+// Write
+// the record is written as the one item sequence, so the items make one sequence
+func (w *yamlWriter) Write(r Record) (err error) {
+	var b []byte
+	if b, err = yaml.Marshal([]Record{r}); err != nil {
+		return
+	}
+	w.n++
+	_, err = w.w.Write(b)
+	return
+}
+
+func (w *yamlWriter) Close() (err error) {
+	if w.n == 0 {
+		_, err = io.WriteString(w.w, "[]\n")
+	}
+	return
+}
@@ -0,0 +1,170 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/out"
+	"gophKeeper/internal/client/model/type/auth"
+	"gophKeeper/internal/client/model/type/otp"
+	"gophKeeper/internal/client/model/type/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func testRecords(t *testing.T) []Record {
+	updated := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	items := []out.Item{
+		{
+			Data: &auth.Data{Login: "user", Password: "pa,ss\nword"},
+			Extra: model.Extra{
+				Fields: []model.Field{{Name: "pin", Value: "1234", Secret: true}},
+				URLs:   []model.URL{{URL: "https://example.com", Match: "host"}, {URL: "https://example.org"}},
+			},
+			DBItem: model.DBItem{
+				Key:         "mail",
+				Description: "mail account",
+				CreatedAt:   time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+				UpdatedAt:   &updated,
+			},
+		},
+		{
+			Data:   &otp.Data{Type: "totp", Secret: "JBSWY3DPEHPK3PXP", Digits: 6},
+			DBItem: model.DBItem{Key: "code", CreatedAt: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)},
+		},
+		{
+			Data: &template.Data{Template: "wifi", Fields: []template.Value{
+				{Name: "ssid", Kind: "text", Value: "home"},
+				{Name: "password", Kind: "secret", Value: "wifipass"},
+			}},
+			DBItem: model.DBItem{Key: "wifi", CreatedAt: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)},
+		},
+	}
+	records := make([]Record, len(items))
+	for i, item := range items {
+		r, err := Normalize(item)
+		require.NoError(t, err)
+		records[i] = r
+	}
+	return records
+}
+
+func TestNormalize(t *testing.T) {
+	records := testRecords(t)
+
+	t.Run("auth", func(t *testing.T) {
+		r := records[0]
+		assert.Equal(t, "mail", r.Key)
+		assert.Equal(t, "auth", r.Type)
+		assert.Equal(t, "mail account", r.Description)
+		assert.Equal(t, []string{"login", "password"}, r.Columns())
+		assert.Equal(t, map[string]any{"login": "user", "password": "pa,ss\nword"}, r.Data)
+		assert.Len(t, r.Fields, 1)
+		assert.Len(t, r.URLs, 2)
+	})
+
+	t.Run("number", func(t *testing.T) {
+		r := records[1]
+		assert.Equal(t, "otp", r.Type)
+		assert.Equal(t, int64(6), r.Data["digits"])
+		assert.Equal(t, "JBSWY3DPEHPK3PXP", r.Data["secret"])
+	})
+
+	t.Run("template", func(t *testing.T) {
+		r := records[2]
+		assert.Equal(t, "template", r.Type)
+		assert.Equal(t, []string{"template", "ssid", "password"}, r.Columns())
+		assert.Equal(t, map[string]any{"template": "wifi", "ssid": "home", "password": "wifipass"}, r.Data)
+	})
+
+	t.Run("no data", func(t *testing.T) {
+		r, err := Normalize(out.Item{DBItem: model.DBItem{Key: "empty"}})
+		require.NoError(t, err)
+		assert.Empty(t, r.Type)
+		assert.Empty(t, r.Columns())
+	})
+}
+
+// write the records by the format, the csv columns are collected as the export command does
+func write(t *testing.T, format string, records []Record) []byte {
+	var columns []string
+	for _, r := range records {
+		for _, c := range r.Columns() {
+			if !slices.Contains(columns, c) {
+				columns = append(columns, c)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, columns)
+	require.NoError(t, err)
+	for _, r := range records {
+		require.NoError(t, w.Write(r))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestWriter(t *testing.T) {
+	records := testRecords(t)
+
+	t.Run("csv", func(t *testing.T) {
+		rows, err := csv.NewReader(bytes.NewReader(write(t, FormatCSV, records))).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 4)
+		assert.Equal(t, []string{"key", "type", "description", "created_at", "updated_at",
+			"data.login", "data.password", "data.type", "data.secret", "data.algorithm", "data.digits",
+			"data.template", "data.ssid", "fields", "urls"}, rows[0])
+		assert.Equal(t, []string{"mail", "auth", "mail account", "2023-01-02 03:04:05", "2024-05-06 07:08:09",
+			"user", "pa,ss\nword", "", "", "", "", "", "", "pin=1234", "host=https://example.com\nhttps://example.org"}, rows[1])
+		assert.Equal(t, "6", rows[2][10])
+		assert.Equal(t, "", rows[2][4])
+		assert.Equal(t, "wifipass", rows[3][6])
+		assert.Equal(t, "wifi", rows[3][11])
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var got []map[string]any
+		require.NoError(t, json.Unmarshal(write(t, FormatJSON, records), &got))
+		require.Len(t, got, 3)
+		assert.Equal(t, "mail", got[0]["key"])
+		assert.Equal(t, "2024-05-06T07:08:09Z", got[0]["updated_at"])
+		assert.Equal(t, map[string]any{"login": "user", "password": "pa,ss\nword"}, got[0]["data"])
+		assert.Nil(t, got[1]["updated_at"])
+		assert.Equal(t, float64(6), got[1]["data"].(map[string]any)["digits"])
+		assert.Equal(t, "template", got[2]["type"])
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		var got []map[string]any
+		require.NoError(t, yaml.Unmarshal(write(t, FormatYAML, records), &got))
+		require.Len(t, got, 3)
+		assert.Equal(t, "mail account", got[0]["description"])
+		assert.Equal(t, map[string]any{"login": "user", "password": "pa,ss\nword"}, got[0]["data"])
+		assert.Equal(t, 6, got[1]["data"].(map[string]any)["digits"])
+		assert.Equal(t, "wifi", got[2]["data"].(map[string]any)["template"])
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		for _, format := range []string{FormatJSON, FormatYAML} {
+			var got []map[string]any
+			require.NoError(t, yaml.Unmarshal(write(t, format, nil), &got), format)
+			assert.Empty(t, got, format)
+		}
+		rows, err := csv.NewReader(bytes.NewReader(write(t, FormatCSV, nil))).ReadAll()
+		require.NoError(t, err)
+		assert.Len(t, rows, 1)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := NewWriter("xml", &bytes.Buffer{}, nil)
+		require.ErrorIs(t, err, ErrFormat)
+	})
+}
@@ -0,0 +1,235 @@
+/*
+Package generate creates random passwords and diceware passphrases by crypto/rand.
+
+The passwords are of the chosen length and character classes, every chosen class is used at least once,
+the look-alike characters can be excluded. The passphrases are the words of the EFF large wordlist,
+https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases, embedded to the client.
+
+The entropy is estimated by the size of the alphabet or the wordlist, it is the upper bound
+of the secret strength for the attacker knowing the policy.
+*/
+package generate
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// Character classes of passwords
+const (
+	Lower   = "abcdefghijklmnopqrstuvwxyz"
+	Upper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits  = "0123456789"
+	Symbols = "!#$%&()*+,-./:;<=>?@[]^_{}~"
+	// Similar the look-alike characters
+	Similar = "Il1|O0o"
+)
+
+// Default policy values
+const (
+	DefaultLength    = 20
+	DefaultWords     = 6
+	DefaultSeparator = "-"
+
+	// MinLength the shortest password allowed
+	MinLength = 4
+	// MaxLength the longest password or passphrase allowed, in characters or words
+	MaxLength = 1024
+)
+
+var (
+	ErrPolicy = errors.New("invalid generate policy")
+
+	//go:embed eff_large_wordlist.txt
+	wordlistText string
+	// wordlist the words of the embedded wordlist in the dice rolls order
+	wordlist = sync.OnceValue(func() []string {
+		words := make([]string, 0, 7776)
+		s := bufio.NewScanner(strings.NewReader(wordlistText))
+		for s.Scan() {
+			if _, word, ok := strings.Cut(s.Text(), "\t"); ok {
+				words = append(words, word)
+			}
+		}
+		return words
+	})
+)
+
+// Policy
+// the rules of generated secrets, Diceware selects the passphrase of Words words
+type Policy struct {
+	Length         int
+	Lower          bool
+	Upper          bool
+	Digits         bool
+	Symbols        bool
+	ExcludeSimilar bool
+	Diceware       bool
+	Words          int
+	Separator      string
+}
+
+// DefaultPolicy
+// the password of 20 characters of all classes
+func DefaultPolicy() Policy {
+	return Policy{
+		Length:    DefaultLength,
+		Lower:     true,
+		Upper:     true,
+		Digits:    true,
+		Symbols:   true,
+		Words:     DefaultWords,
+		Separator: DefaultSeparator,
+	}
+}
+
+// classes
+// the character sets of chosen classes, the look-alike characters are removed if excluded
+func (p Policy) classes() (classes []string) {
+	for _, c := range []struct {
+		chars  string
+		chosen bool
+	}{{Lower, p.Lower}, {Upper, p.Upper}, {Digits, p.Digits}, {Symbols, p.Symbols}} {
+		if !c.chosen {
+			continue
+		}
+		if p.ExcludeSimilar {
+			c.chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(Similar, r) {
+					return -1
+				}
+				return r
+			}, c.chars)
+		}
+		classes = append(classes, c.chars)
+	}
+	return
+}
+
+// Validate
+// check the length and the character classes of the password or the words of the passphrase
+func (p Policy) Validate() error {
+	if p.Diceware {
+		if p.Words < 1 || p.Words > MaxLength {
+			return fmt.Errorf("%w: words must be from 1 to %d", ErrPolicy, MaxLength)
+		}
+		return nil
+	}
+	classes := p.classes()
+	if len(classes) == 0 {
+		return fmt.Errorf("%w: no character classes chosen", ErrPolicy)
+	}
+	if p.Length < MinLength || p.Length > MaxLength || p.Length < len(classes) {
+		return fmt.Errorf("%w: length must be from %d to %d", ErrPolicy, max(MinLength, len(classes)), MaxLength)
+	}
+	return nil
+}
+
+// Entropy
+// the estimated entropy in bits of the secret of the policy
+func (p Policy) Entropy() float64 {
+	if p.Diceware {
+		return float64(p.Words) * math.Log2(float64(len(wordlist())))
+	}
+	return float64(p.Length) * math.Log2(float64(len(strings.Join(p.classes(), ""))))
+}
+
+// Generate
+// the password or passphrase of the policy
+func (p Policy) Generate() (secret string, err error) {
+	if err = p.Validate(); err != nil {
+		return
+	}
+	if p.Diceware {
+		return Passphrase(p.Words, p.Separator)
+	}
+	classes := p.classes()
+	all := []rune(strings.Join(classes, ""))
+	password := make([]rune, 0, p.Length)
+	// a character of every class, the rest of any class, then shuffled
+	for _, class := range classes {
+		var c rune
+		if c, err = pick([]rune(class)); err != nil {
+			return
+		}
+		password = append(password, c)
+	}
+	for len(password) < p.Length {
+		var c rune
+		if c, err = pick(all); err != nil {
+			return
+		}
+		password = append(password, c)
+	}
+	if err = shuffle(password); err != nil {
+		return
+	}
+	return string(password), nil
+}
+
+// Passphrase
+// the random words of the embedded wordlist joined by the separator
+func Passphrase(words int, separator string) (string, error) {
+	list := wordlist()
+	phrase := make([]string, words)
+	for i := range phrase {
+		n, err := randInt(len(list))
+		if err != nil {
+			return "", err
+		}
+		phrase[i] = list[n]
+	}
+	return strings.Join(phrase, separator), nil
+}
+
+// Strength
+// the rating of the entropy in bits
+func Strength(entropy float64) string {
+	switch {
+	case entropy < 50:
+		return "weak"
+	case entropy < 80:
+		return "fair"
+	case entropy < 128:
+		return "strong"
+	}
+	return "very strong"
+}
+
+// randInt
+// the uniform random number in [0, n) of crypto/rand
+func randInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+func pick(chars []rune) (rune, error) {
+	n, err := randInt(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[n], nil
+}
+
+// shuffle
+// the Fisher-Yates shuffle by crypto/rand
+func shuffle(r []rune) error {
+	for i := len(r) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return err
+		}
+		r[i], r[j] = r[j], r[i]
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package generate
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordlist(t *testing.T) {
+	words := wordlist()
+	require.Len(t, words, 7776)
+	assert.Equal(t, "abacus", words[0])
+	assert.Equal(t, "zoom", words[len(words)-1])
+}
+
+func TestPolicyGenerate(t *testing.T) {
+	p := DefaultPolicy()
+	p.Length = 32
+	p.ExcludeSimilar = true
+	for i := 0; i < 50; i++ {
+		password, err := p.Generate()
+		require.NoError(t, err)
+		require.Len(t, password, 32)
+		assert.True(t, strings.ContainsAny(password, Lower))
+		assert.True(t, strings.ContainsAny(password, Upper))
+		assert.True(t, strings.ContainsAny(password, Digits))
+		assert.True(t, strings.ContainsAny(password, Symbols))
+		assert.False(t, strings.ContainsAny(password, Similar))
+	}
+
+	password, err := Policy{Length: 12, Digits: true}.Generate()
+	require.NoError(t, err)
+	assert.Empty(t, strings.Trim(password, Digits))
+
+	_, err = Policy{Length: 12}.Generate()
+	require.ErrorIs(t, err, ErrPolicy)
+	_, err = Policy{Length: 3, Lower: true}.Generate()
+	require.ErrorIs(t, err, ErrPolicy)
+}
+
+func TestPolicyDiceware(t *testing.T) {
+	p := Policy{Diceware: true, Words: 5, Separator: " "}
+	phrase, err := p.Generate()
+	require.NoError(t, err)
+	words := strings.Split(phrase, " ")
+	require.Len(t, words, 5)
+	for _, w := range words {
+		assert.Contains(t, wordlist(), w)
+	}
+	assert.InDelta(t, 64.6, p.Entropy(), 0.1)
+
+	_, err = Policy{Diceware: true}.Generate()
+	require.ErrorIs(t, err, ErrPolicy)
+}
+
+func TestEntropy(t *testing.T) {
+	assert.InDelta(t, 20*math.Log2(float64(len(Lower+Upper+Digits+Symbols))), DefaultPolicy().Entropy(), 0.001)
+	assert.Equal(t, "weak", Strength(40))
+	assert.Equal(t, "strong", Strength(100))
+	assert.Equal(t, "very strong", Strength(128))
+}
@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"gophKeeper/internal/client/model"
+)
+
+// Bitwarden item types
+const (
+	bitwardenLogin    = 1
+	bitwardenCard     = 3
+	bitwardenIdentity = 4
+)
+
+// bitwardenMatch
+// the match rules of the Bitwarden uri match detection
+var bitwardenMatch = []string{model.MatchDomain, model.MatchHost, model.MatchPrefix, model.MatchExact, model.MatchRegexp, model.MatchNever}
+
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Items []bitwardenItem `json:"items"`
+}
+
+type bitwardenItem struct {
+	FolderID string `json:"folderId"`
+	Type     int    `json:"type"`
+	Name     string `json:"name"`
+	Notes    string `json:"notes"`
+	Fields   []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+		Type  int    `json:"type"`
+	} `json:"fields"`
+	Login *struct {
+		URIs []struct {
+			URI   string `json:"uri"`
+			Match *int   `json:"match"`
+		} `json:"uris"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		TOTP     string `json:"totp"`
+	} `json:"login"`
+	Card *struct {
+		CardholderName string `json:"cardholderName"`
+		Number         string `json:"number"`
+		ExpMonth       string `json:"expMonth"`
+		ExpYear        string `json:"expYear"`
+		Code           string `json:"code"`
+	} `json:"card"`
+	Identity map[string]any `json:"identity"`
+}
+
+// bitwardenIdentityFields
+// the fields of the identity item in the order of Bitwarden
+var bitwardenIdentityFields = []string{
+	"title", "firstName", "middleName", "lastName", "username", "company",
+	"ssn", "passportNumber", "licenseNumber", "email", "phone",
+	"address1", "address2", "address3", "city", "state", "postalCode", "country",
+}
+
+// parseBitwarden
+// the entries of the unencrypted Bitwarden JSON export, the identities are the text entries
+func parseBitwarden(r io.Reader, _ Options) (entries []Entry, err error) {
+	var export bitwardenExport
+	if err = json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("%w: bitwarden json: %w", ErrFormat, err)
+	}
+	if export.Encrypted {
+		return nil, fmt.Errorf("%w: the encrypted bitwarden export, export it unencrypted", ErrUnsupported)
+	}
+	folders := make(map[string]string, len(export.Folders))
+	for _, f := range export.Folders {
+		folders[f.ID] = f.Name
+	}
+	for _, item := range export.Items {
+		e := Entry{Kind: KindText, Title: item.Name, Folder: folders[item.FolderID], Notes: item.Notes}
+		switch {
+		case item.Type == bitwardenLogin && item.Login != nil:
+			e.Kind, e.Login, e.Password, e.TOTP = KindAuth, item.Login.Username, item.Login.Password, item.Login.TOTP
+			for _, u := range item.Login.URIs {
+				match := ""
+				if u.Match != nil && *u.Match >= 0 && *u.Match < len(bitwardenMatch) {
+					match = bitwardenMatch[*u.Match]
+				}
+				e.AddURL(u.URI, match)
+			}
+		case item.Type == bitwardenCard && item.Card != nil:
+			e.Kind = KindCard
+			e.Card = Card{Number: item.Card.Number, CVV: item.Card.Code, Name: item.Card.CardholderName}
+			if month, er := strconv.Atoi(item.Card.ExpMonth); er == nil && len(item.Card.ExpYear) >= 2 {
+				e.Card.Exp = fmt.Sprintf("%02d/%s", month, item.Card.ExpYear[len(item.Card.ExpYear)-2:])
+			}
+		case item.Type == bitwardenIdentity:
+			for _, name := range bitwardenIdentityFields {
+				if v, ok := item.Identity[name].(string); ok {
+					e.AddField(name, v, name == "ssn" || name == "passportNumber" || name == "licenseNumber")
+				}
+			}
+		}
+		for _, f := range item.Fields {
+			// the hidden fields are secret, the linked fields have no value
+			e.AddField(f.Name, f.Value, f.Type == 1)
+		}
+		if e.Kind == KindText && e.Notes == "" && len(e.Fields) == 0 {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return
+}
@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvColumns
+// the header names of the columns of the browser, 1Password and Bitwarden exports
+var csvColumns = map[string]string{
+	"title":          "title",
+	"name":           "title",
+	"url":            "url",
+	"uri":            "url",
+	"website":        "url",
+	"login_uri":      "url",
+	"username":       "login",
+	"user name":      "login",
+	"login":          "login",
+	"login_username": "login",
+	"password":       "password",
+	"login_password": "password",
+	"otpauth":        "totp",
+	"totp":           "totp",
+	"login_totp":     "totp",
+	"notes":          "notes",
+	"note":           "notes",
+	"extra":          "notes",
+	"folder":         "folder",
+	"group":          "folder",
+	"type":           "type",
+	// the columns of the export metadata are not imported
+	"favorite":            "",
+	"archived":            "",
+	"tags":                "",
+	"fields":              "",
+	"reprompt":            "",
+	"httprealm":           "",
+	"formactionorigin":    "",
+	"guid":                "",
+	"timecreated":         "",
+	"timelastused":        "",
+	"timepasswordchanged": "",
+}
+
+// parseCSV
+// the entries of the CSV with header, the unknown columns are the extra fields
+func parseCSV(r io.Reader, _ Options) (entries []Entry, err error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: csv header: %w", ErrFormat, err)
+	}
+	columns := make([]string, len(header))
+	known := false
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		column, ok := csvColumns[h]
+		if !ok {
+			column = "field:" + strings.TrimSpace(header[i])
+		}
+		known = known || column == "password" || column == "url"
+		columns[i] = column
+	}
+	if !known {
+		return nil, fmt.Errorf("%w: csv header has no password and url columns", ErrFormat)
+	}
+	for line := 2; ; line++ {
+		var record []string
+		if record, err = cr.Read(); err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: csv line %d: %w", ErrFormat, line, err)
+		}
+		var (
+			e    Entry
+			kind string
+		)
+		for i, value := range record {
+			if i >= len(columns) {
+				break
+			}
+			switch column := columns[i]; column {
+			case "title":
+				e.Title = value
+			case "url":
+				e.AddURL(value, "")
+			case "login":
+				e.Login = value
+			case "password":
+				e.Password = value
+			case "totp":
+				e.TOTP = value
+			case "notes":
+				e.Notes = value
+			case "folder":
+				e.Folder = value
+			case "type":
+				kind = value
+			case "":
+			default:
+				e.AddField(strings.TrimPrefix(column, "field:"), value, false)
+			}
+		}
+		switch {
+		case kind == "note" || kind == "" && e.Login == "" && e.Password == "" && len(e.URLs) == 0 && e.Notes != "":
+			e.Kind = KindText
+		case e.Login == "" && e.Password == "" && len(e.URLs) == 0 && e.Notes == "" && e.Title == "":
+			continue
+		default:
+			e.Kind = KindAuth
+		}
+		entries = append(entries, e)
+	}
+}
@@ -0,0 +1,355 @@
+/*
+Package importer reads the exports of other password managers into the records of the client.
+
+The formats are:
+  - keepass-xml - the KeePass 2.x XML export
+  - kdbx - the KeePass KDBX 4 database, opened by its password, the key files are not supported
+  - bitwarden - the unencrypted Bitwarden JSON export
+  - 1pux - the 1Password export
+  - csv - the CSV exports of Chrome, Firefox, 1Password and Bitwarden, the columns are found by their header,
+    chrome, firefox, 1password-csv and bitwarden-csv are its aliases
+
+The logins are imported as auth records, the cards as card, the notes and the other items as text,
+the attachments and documents as bin. The folders are the record descriptions,
+the notes, the custom fields, the urls and the TOTP secrets are the extra fields and urls of the records.
+*/
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/type/auth"
+	"gophKeeper/internal/client/model/type/bin"
+	"gophKeeper/internal/client/model/type/card"
+	"gophKeeper/internal/client/model/type/text"
+)
+
+// Formats of the imported files
+const (
+	FormatKeePassXML   = "keepass-xml"
+	FormatKDBX         = "kdbx"
+	FormatBitwarden    = "bitwarden"
+	Format1PUX         = "1pux"
+	FormatCSV          = "csv"
+	FormatChrome       = "chrome"
+	FormatFirefox      = "firefox"
+	Format1PasswordCSV = "1password-csv"
+	FormatBitwardenCSV = "bitwarden-csv"
+)
+
+// Kinds of the imported entries, they are the record types
+const (
+	KindAuth = "auth"
+	KindCard = "card"
+	KindText = "text"
+	KindBin  = "bin"
+)
+
+// Strategies of the keys of existing records
+const (
+	// Skip the entry is not imported
+	Skip = "skip"
+	// Rename the entry is imported with the free key of " (2)", " (3)" suffix
+	Rename = "rename"
+	// Overwrite the record is replaced by the entry
+	Overwrite = "overwrite"
+)
+
+// Operations of the import plan
+const (
+	OpCreate    = "create"
+	OpSkip      = "skip"
+	OpRename    = "rename"
+	OpOverwrite = "overwrite"
+)
+
+// Names of the extra fields of the imported entries
+const (
+	FieldNotes = "notes"
+	FieldTOTP  = "totp"
+	FieldURL   = "url"
+)
+
+var (
+	Strategies = []string{Skip, Rename, Overwrite}
+
+	ErrFormat      = errors.New("wrong import format")
+	ErrUnsupported = errors.New("unsupported import")
+	ErrPassword    = errors.New("wrong database password")
+
+	parsers = map[string]Parser{
+		FormatKeePassXML:   parseKeePassXML,
+		FormatKDBX:         parseKDBX,
+		FormatBitwarden:    parseBitwarden,
+		Format1PUX:         parse1PUX,
+		FormatCSV:          parseCSV,
+		FormatChrome:       parseCSV,
+		FormatFirefox:      parseCSV,
+		Format1PasswordCSV: parseCSV,
+		FormatBitwardenCSV: parseCSV,
+	}
+)
+
+// Options
+// of the import, Password opens the kdbx database
+type Options struct {
+	Password string
+}
+
+// Parser
+// reads the entries of the format
+type Parser func(r io.Reader, opts Options) ([]Entry, error)
+
+// Card
+// the card data of the entry
+type Card struct {
+	Number string
+	Exp    string
+	CVV    string
+	Name   string
+}
+
+// Entry
+// the imported item of any format
+type Entry struct {
+	Kind     string
+	Title    string
+	Folder   string
+	Login    string
+	Password string
+	TOTP     string
+	Notes    string
+	Card     Card
+	// Content of the attachment of bin entry, FileName is its name
+	Content  []byte
+	FileName string
+	URLs     []model.URL
+	Fields   []model.Field
+}
+
+// Formats
+// the names of the supported formats
+func Formats() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Detect
+// the format of the file by its extension, the csv is detected by its header at parse
+func Detect(fileName string) (format string, err error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".kdbx":
+		return FormatKDBX, nil
+	case ".xml":
+		return FormatKeePassXML, nil
+	case ".json":
+		return FormatBitwarden, nil
+	case ".1pux":
+		return Format1PUX, nil
+	case ".csv":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("%w: the format of %s is not detected, set it", ErrFormat, fileName)
+}
+
+// Parse
+// the entries of the format
+func Parse(format string, r io.Reader, opts Options) (entries []Entry, err error) {
+	parse, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s, supported: %s", ErrFormat, format, strings.Join(Formats(), ", "))
+	}
+	return parse(r, opts)
+}
+
+// AddURL
+// add the url of the match rule, the url which is not valid is kept as the extra field
+func (e *Entry) AddURL(s, match string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return
+	}
+	if match != "" {
+		s = match + "=" + s
+	}
+	u, err := model.ParseURL(s)
+	if err != nil {
+		e.AddField(FieldURL, s, false)
+		return
+	}
+	e.URLs = append(e.URLs, u)
+}
+
+// AddField
+// add the extra field of not empty value, the names are made unique
+func (e *Entry) AddField(name, value string, secret bool) {
+	name = strings.TrimSpace(name)
+	if value == "" {
+		return
+	}
+	if name == "" {
+		name = "field"
+	}
+	unique := name
+	for i := 2; slices.ContainsFunc(e.Fields, func(f model.Field) bool { return f.Name == unique }); i++ {
+		unique = fmt.Sprintf("%s %d", name, i)
+	}
+	e.Fields = append(e.Fields, model.Field{Name: unique, Value: value, Secret: secret})
+}
+
+// Key
+// the record key of the entry: its title, the host of its url, or its login
+func (e *Entry) Key() string {
+	if key := strings.TrimSpace(e.Title); key != "" {
+		return key
+	}
+	for _, u := range e.URLs {
+		if p, err := url.Parse(u.URL); err == nil && p.Hostname() != "" {
+			return p.Hostname()
+		}
+	}
+	if e.Login != "" {
+		return e.Login
+	}
+	if e.FileName != "" {
+		return e.FileName
+	}
+	return e.Kind
+}
+
+// text
+// the content of the text entry: the notes or the extra fields of the entry without notes
+func (e *Entry) text() (content string, fields []model.Field) {
+	if e.Notes != "" || len(e.Fields) == 0 {
+		return e.Notes, e.Fields
+	}
+	lines := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		lines[i] = f.Name + ": " + f.Value
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Model
+// the record model of the entry by the key.
+// The content of the bin entry is written to the file of the dir, so the large content is streamed at save,
+// it is kept in the model if the dir is empty
+func (e *Entry) Model(key, dir string) (m model.Model, err error) {
+	var (
+		extra = model.Extra{Fields: slices.Clone(e.Fields), URLs: e.URLs}
+		notes = e.Notes
+	)
+	switch e.Kind {
+	case KindAuth:
+		m = &auth.Model{Data: &auth.Data{Login: e.Login, Password: e.Password}}
+	case KindCard:
+		d := &card.Data{Number: e.Card.Number, Exp: e.Card.Exp, CVV: e.Card.CVV, Name: e.Card.Name}
+		d.Sanitize()
+		m = &card.Model{Data: d}
+	case KindText:
+		d := &text.Data{}
+		d.Text, extra.Fields = e.text()
+		m, notes = &text.Model{Data: d}, ""
+	case KindBin:
+		mb := &bin.Model{Data: &bin.Data{Bin: e.Content}}
+		if dir != "" {
+			mb.Data.Bin = nil
+			mb.FileName = filepath.Join(dir, attachmentName(e.FileName, key))
+			if err = os.WriteFile(mb.FileName, e.Content, 0600); err != nil {
+				return
+			}
+		}
+		m = mb
+	default:
+		return nil, fmt.Errorf("%w: the entry of kind %q", ErrUnsupported, e.Kind)
+	}
+	if notes != "" {
+		extra.Fields = append([]model.Field{{Name: FieldNotes, Value: notes}}, extra.Fields...)
+	}
+	if e.TOTP != "" {
+		extra.Fields = append(extra.Fields, model.Field{Name: FieldTOTP, Value: e.TOTP, Secret: true})
+	}
+	base := m.GetBase()
+	base.Key, base.Description, base.Extra = key, e.Folder, extra
+	return
+}
+
+// attachmentName
+// the base name of the attachment file, the attachment without name is named by the key
+func attachmentName(name, key string) string {
+	for _, n := range []string{name, key} {
+		if base := filepath.Base(n); base != "." && base != ".." && base != string(filepath.Separator) {
+			return base
+		}
+	}
+	return "attachment"
+}
+
+// Action
+// the planned import of the entry by the key
+type Action struct {
+	Entry *Entry
+	Key   string
+	Op    string
+}
+
+// Plan
+// the actions of the entries by the strategy of the existing keys.
+// The entries of the same key are renamed, whatever the strategy is
+func Plan(entries []Entry, strategy string, exists func(key string) (bool, error)) (actions []Action, err error) {
+	if !slices.Contains(Strategies, strategy) {
+		return nil, fmt.Errorf("%w: strategy %q, supported: %s", ErrFormat, strategy, strings.Join(Strategies, ", "))
+	}
+	taken := make(map[string]bool)
+	isFree := func(key string) (bool, error) {
+		if taken[key] {
+			return false, nil
+		}
+		found, er := exists(key)
+		return !found, er
+	}
+	actions = make([]Action, 0, len(entries))
+	for i := range entries {
+		a := Action{Entry: &entries[i], Key: entries[i].Key(), Op: OpCreate}
+		var found bool
+		if found, err = exists(a.Key); err != nil {
+			return
+		}
+		switch {
+		case taken[a.Key] || found && strategy == Rename:
+			a.Op = OpRename
+			base := a.Key
+			for n := 2; ; n++ {
+				a.Key = fmt.Sprintf("%s (%d)", base, n)
+				var free bool
+				if free, err = isFree(a.Key); err != nil {
+					return
+				}
+				if free {
+					break
+				}
+			}
+		case found && strategy == Skip:
+			a.Op = OpSkip
+		case found:
+			a.Op = OpOverwrite
+		}
+		if a.Op != OpSkip {
+			taken[a.Key] = true
+		}
+		actions = append(actions, a)
+	}
+	return
+}
@@ -0,0 +1,264 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/type/auth"
+	"gophKeeper/internal/client/model/type/bin"
+	"gophKeeper/internal/client/model/type/card"
+	"gophKeeper/internal/client/model/type/text"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	for name, format := range map[string]string{
+		"db.kdbx": FormatKDBX, "export.XML": FormatKeePassXML, "bitwarden.json": FormatBitwarden,
+		"export.1pux": Format1PUX, "passwords.csv": FormatCSV,
+	} {
+		got, err := Detect(name)
+		require.NoError(t, err)
+		assert.Equal(t, format, got)
+	}
+	_, err := Detect("export.txt")
+	require.ErrorIs(t, err, ErrFormat)
+	_, err = Parse("lastpass", strings.NewReader(""), Options{})
+	require.ErrorIs(t, err, ErrFormat)
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   []Entry
+	}{
+		{"chrome", FormatChrome, "name,url,username,password,note\n" +
+			"github.com,https://github.com/login,user,pass,\n" +
+			"secure note,,,,the note\n",
+			[]Entry{
+				{Kind: KindAuth, Title: "github.com", Login: "user", Password: "pass", URLs: []model.URL{{URL: "https://github.com/login"}}},
+				{Kind: KindText, Title: "secure note", Notes: "the note"},
+			}},
+		{"firefox", FormatFirefox, `"url","username","password","httpRealm","formActionOrigin","guid","timeCreated","timeLastUsed","timePasswordChanged"` + "\n" +
+			`"https://example.com","user","pass",,"https://example.com","{1}","1","2","3"` + "\n",
+			[]Entry{{Kind: KindAuth, Login: "user", Password: "pass", URLs: []model.URL{{URL: "https://example.com"}}}}},
+		{"1password", Format1PasswordCSV, "Title,Url,Username,Password,OTPAuth,Favorite,Archived,Tags,Notes,PIN\n" +
+			"bank,bank.com,user,pass,otpauth://totp/bank?secret=ABC,false,false,,note,1234\n",
+			[]Entry{{Kind: KindAuth, Title: "bank", Login: "user", Password: "pass", TOTP: "otpauth://totp/bank?secret=ABC",
+				Notes: "note", URLs: []model.URL{{URL: "https://bank.com"}}, Fields: []model.Field{{Name: "PIN", Value: "1234"}}}}},
+		{"bitwarden", FormatBitwardenCSV, "folder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp\n" +
+			"Work,,login,mail,,,0,mail.com,user,pass,\n" +
+			",,note,memo,text,,0,,,,\n",
+			[]Entry{
+				{Kind: KindAuth, Title: "mail", Folder: "Work", Login: "user", Password: "pass", URLs: []model.URL{{URL: "https://mail.com"}}},
+				{Kind: KindText, Title: "memo", Notes: "text"},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.format, strings.NewReader(tt.data), Options{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := Parse(FormatCSV, strings.NewReader("a,b\n1,2\n"), Options{})
+	require.ErrorIs(t, err, ErrFormat)
+}
+
+func TestParseBitwarden(t *testing.T) {
+	data := `{"encrypted": false,
+"folders": [{"id": "f1", "name": "Work"}],
+"items": [
+	{"folderId": "f1", "type": 1, "name": "github", "notes": null,
+		"fields": [{"name": "pin", "value": "1234", "type": 1}, {"name": "linked", "value": null, "type": 3}],
+		"login": {"uris": [{"match": 3, "uri": "https://github.com/login"}, {"match": null, "uri": "github.io"}],
+			"username": "user", "password": "pass", "totp": "JBSWY3DPEHPK3PXP"}},
+	{"folderId": null, "type": 2, "name": "memo", "notes": "the note", "secureNote": {"type": 0}},
+	{"type": 3, "name": "visa", "card": {"cardholderName": "John Doe", "number": "4111111111111111",
+		"expMonth": "1", "expYear": "2030", "code": "123"}},
+	{"type": 4, "name": "me", "identity": {"firstName": "John", "lastName": "Doe", "ssn": "000-00-0000"}}
+]}`
+	got, err := Parse(FormatBitwarden, strings.NewReader(data), Options{})
+	require.NoError(t, err)
+	require.Len(t, got, 4)
+	assert.Equal(t, Entry{Kind: KindAuth, Title: "github", Folder: "Work", Login: "user", Password: "pass", TOTP: "JBSWY3DPEHPK3PXP",
+		URLs:   []model.URL{{URL: "https://github.com/login", Match: model.MatchExact}, {URL: "https://github.io"}},
+		Fields: []model.Field{{Name: "pin", Value: "1234", Secret: true}}}, got[0])
+	assert.Equal(t, Entry{Kind: KindText, Title: "memo", Notes: "the note"}, got[1])
+	assert.Equal(t, Card{Number: "4111111111111111", Exp: "01/30", CVV: "123", Name: "John Doe"}, got[2].Card)
+	assert.Equal(t, KindText, got[3].Kind)
+	assert.Equal(t, []model.Field{{Name: "firstName", Value: "John"}, {Name: "lastName", Value: "Doe"},
+		{Name: "ssn", Value: "000-00-0000", Secret: true}}, got[3].Fields)
+
+	_, err = Parse(FormatBitwarden, strings.NewReader(`{"encrypted": true, "items": []}`), Options{})
+	require.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestParseKeePassXML(t *testing.T) {
+	var zipped bytes.Buffer
+	zw := gzip.NewWriter(&zipped)
+	_, err := zw.Write([]byte("attached content"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	data := `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta>
+		<Binaries><Binary ID="0" Compressed="True">` + base64.StdEncoding.EncodeToString(zipped.Bytes()) + `</Binary></Binaries>
+	</Meta>
+	<Root>
+		<Group>
+			<Name>Database</Name>
+			<Group>
+				<Name>Internet</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>github</Value></String>
+					<String><Key>UserName</Key><Value>user</Value></String>
+					<String><Key>Password</Key><Value ProtectInMemory="True">pass</Value></String>
+					<String><Key>otp</Key><Value>otpauth://totp/github?secret=ABC</Value></String>
+				</Entry>
+				<Entry>
+					<String><Key>Title</Key><Value>memo</Value></String>
+					<String><Key>Notes</Key><Value>the note</Value></String>
+				</Entry>
+				<Entry>
+					<String><Key>Title</Key><Value>files</Value></String>
+					<Binary><Key>key.txt</Key><Value Ref="0"/></Binary>
+				</Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`
+	got, err := Parse(FormatKeePassXML, strings.NewReader(data), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Kind: KindAuth, Title: "github", Folder: "Internet", Login: "user", Password: "pass", TOTP: "otpauth://totp/github?secret=ABC"},
+		{Kind: KindText, Title: "memo", Folder: "Internet", Notes: "the note"},
+		{Kind: KindBin, Title: "files - key.txt", Folder: "Internet", Content: []byte("attached content"), FileName: "key.txt"},
+	}, got)
+}
+
+func TestParse1PUX(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	w, err := zw.Create(onePasswordData)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(`{"accounts": [{"vaults": [{"attrs": {"name": "Personal"}, "items": [
+	{"categoryUuid": "001", "state": "active",
+		"overview": {"title": "github", "url": "https://github.com", "urls": []},
+		"details": {"loginFields": [
+			{"value": "user", "name": "username", "fieldType": "T", "designation": "username"},
+			{"value": "pass", "name": "password", "fieldType": "P", "designation": "password"}],
+			"notesPlain": "note",
+			"sections": [{"fields": [{"title": "one-time password", "id": "otp", "value": {"totp": "otpauth://totp/github?secret=ABC"}}]}]}},
+	{"categoryUuid": "002", "state": "active", "overview": {"title": "visa"},
+		"details": {"sections": [{"fields": [
+			{"title": "cardholder name", "id": "cardholder", "value": {"string": "John Doe"}},
+			{"title": "number", "id": "ccnum", "value": {"creditCardNumber": "4111111111111111"}},
+			{"title": "verification number", "id": "cvv", "value": {"concealed": "123"}},
+			{"title": "expiry date", "id": "expiry", "value": {"monthYear": 203001}},
+			{"title": "address", "id": "address", "value": {"address": {"city": "Nowhere"}}}]}]}},
+	{"categoryUuid": "006", "state": "active", "overview": {"title": "passport scan"},
+		"details": {"documentAttributes": {"fileName": "scan.pdf", "documentId": "doc1"}}},
+	{"categoryUuid": "003", "state": "trashed", "overview": {"title": "old"}, "details": {"notesPlain": "old"}}
+]}]}]}`))
+	require.NoError(t, err)
+	w, err = zw.Create("files/doc1__scan.pdf")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("%PDF"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	got, err := Parse(Format1PUX, &archive, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Kind: KindAuth, Title: "github", Folder: "Personal", Login: "user", Password: "pass", Notes: "note",
+			TOTP: "otpauth://totp/github?secret=ABC", URLs: []model.URL{{URL: "https://github.com"}}},
+		{Kind: KindCard, Title: "visa", Folder: "Personal", Card: Card{Number: "4111111111111111", Exp: "01/30", CVV: "123", Name: "John Doe"}},
+		{Kind: KindBin, Title: "passport scan", Folder: "Personal", Content: []byte("%PDF"), FileName: "scan.pdf"},
+	}, got)
+}
+
+func TestEntryModel(t *testing.T) {
+	e := Entry{Kind: KindAuth, Title: "github", Folder: "Work", Login: "user", Password: "pass", TOTP: "ABC", Notes: "note",
+		Fields: []model.Field{{Name: "pin", Value: "1234", Secret: true}}}
+	m, err := e.Model("github (2)", "")
+	require.NoError(t, err)
+	require.IsType(t, &auth.Model{}, m)
+	assert.Equal(t, &auth.Data{Login: "user", Password: "pass"}, m.(*auth.Model).Data)
+	assert.Equal(t, "github (2)", m.GetBase().Key)
+	assert.Equal(t, "Work", m.GetBase().Description)
+	assert.Equal(t, []model.Field{{Name: FieldNotes, Value: "note"}, {Name: "pin", Value: "1234", Secret: true},
+		{Name: FieldTOTP, Value: "ABC", Secret: true}}, m.GetBase().Fields)
+	require.NoError(t, m.Validate())
+
+	m, err = (&Entry{Kind: KindCard, Card: Card{Number: "4111111111111111", Exp: "01/30", CVV: "123"}}).Model("visa", "")
+	require.NoError(t, err)
+	assert.Equal(t, "4111 1111 1111 1111", m.(*card.Model).Data.Number)
+	require.NoError(t, m.Validate())
+
+	m, err = (&Entry{Kind: KindText, Fields: []model.Field{{Name: "firstName", Value: "John"}, {Name: "ssn", Value: "1"}}}).Model("me", "")
+	require.NoError(t, err)
+	assert.Equal(t, "firstName: John\nssn: 1", m.(*text.Model).Data.Text)
+	assert.Empty(t, m.GetBase().Fields)
+
+	dir := t.TempDir()
+	m, err = (&Entry{Kind: KindBin, Content: []byte("content"), FileName: "../key.txt"}).Model("key", dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "key.txt"), m.GetFileName())
+	assert.Empty(t, m.(*bin.Model).Data.Bin)
+	b, err := os.ReadFile(m.GetFileName())
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(b))
+
+	// the attachment without name is named by the key
+	for name, want := range map[string]string{"": "files - key", "..": "files - key", "/": "files - key"} {
+		m, err = (&Entry{Kind: KindBin, Content: []byte("content"), FileName: name}).Model("work/files - key", dir)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, want), m.GetFileName())
+	}
+	m, err = (&Entry{Kind: KindBin, Content: []byte("content")}).Model("..", dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "attachment"), m.GetFileName())
+
+	_, err = (&Entry{Kind: "ssh"}).Model("key", "")
+	require.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestPlan(t *testing.T) {
+	existing := map[string]bool{"github": true, "github (2)": true}
+	exists := func(key string) (bool, error) { return existing[key], nil }
+	entries := []Entry{{Title: "github"}, {Title: "mail"}, {Title: "mail"}, {URLs: []model.URL{{URL: "https://example.com/login"}}}}
+	tests := []struct {
+		strategy string
+		want     []Action
+	}{
+		{Skip, []Action{{Key: "github", Op: OpSkip}, {Key: "mail", Op: OpCreate}, {Key: "mail (2)", Op: OpRename}, {Key: "example.com", Op: OpCreate}}},
+		{Rename, []Action{{Key: "github (3)", Op: OpRename}, {Key: "mail", Op: OpCreate}, {Key: "mail (2)", Op: OpRename}, {Key: "example.com", Op: OpCreate}}},
+		{Overwrite, []Action{{Key: "github", Op: OpOverwrite}, {Key: "mail", Op: OpCreate}, {Key: "mail (2)", Op: OpRename}, {Key: "example.com", Op: OpCreate}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			actions, err := Plan(entries, tt.strategy, exists)
+			require.NoError(t, err)
+			require.Len(t, actions, len(tt.want))
+			for i, a := range actions {
+				assert.Same(t, &entries[i], a.Entry)
+				assert.Equal(t, tt.want[i].Key, a.Key)
+				assert.Equal(t, tt.want[i].Op, a.Op)
+			}
+		})
+	}
+	_, err := Plan(entries, "merge", exists)
+	require.ErrorIs(t, err, ErrFormat)
+}
@@ -0,0 +1,474 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+)
+
+// KDBX file signatures and the supported major version
+const (
+	kdbxSignature1 = 0x9AA2D903
+	kdbxSignature2 = 0xB54BFB67
+	kdbxVersion    = 4
+)
+
+// KDBX outer header fields
+const (
+	kdbxEndOfHeader   = 0
+	kdbxCipherID      = 2
+	kdbxCompression   = 3
+	kdbxMasterSeed    = 4
+	kdbxEncryptionIV  = 7
+	kdbxKDFParameters = 11
+)
+
+// KDBX inner header fields
+const (
+	kdbxInnerEnd       = 0
+	kdbxInnerStreamID  = 1
+	kdbxInnerStreamKey = 2
+	kdbxInnerBinary    = 3
+)
+
+// KDBX cipher and key derivation UUIDs
+const (
+	kdbxAES256   = "31c1f2e6bf714350be5805216afc5aff"
+	kdbxChaCha20 = "d6038a2b8b6f4cb5a524339a31dbb59a"
+	kdbxArgon2d  = "ef636ddf8c29444b91f7a9a403e30a0c"
+	kdbxArgon2id = "9e298b1956db4773b23dfc3ec6f0a1e6"
+	kdbxAESKDF   = "c9d9f39a628a4460bf740d08c18a4fea"
+)
+
+// The limits of the key derivation costs, the costs of the file are run before its password is checked
+const (
+	// kdbxMaxArgon2Memory the memory of argon2 in bytes
+	kdbxMaxArgon2Memory = 2 << 30
+	// kdbxMaxArgon2Iterations the passes of argon2
+	kdbxMaxArgon2Iterations = 1000
+	// kdbxMaxAESRounds the rounds of aes kdf
+	kdbxMaxAESRounds = 100_000_000
+)
+
+const (
+	// kdbxInnerChaCha20 the inner random stream of the protected values
+	kdbxInnerChaCha20 = 3
+	// kdbxHeaderIndex the block index of the header HMAC
+	kdbxHeaderIndex = math.MaxUint64
+)
+
+// kdbxHeader
+// the outer header of the database
+type kdbxHeader struct {
+	cipher     string
+	compressed bool
+	seed       []byte
+	iv         []byte
+	kdf        map[string]any
+}
+
+// parseKDBX
+// the entries of the KeePass KDBX 4 database, it is opened by the password only
+func parseKDBX(r io.Reader, opts Options) (entries []Entry, err error) {
+	h, raw, err := readKDBXHeader(r)
+	if err != nil {
+		return
+	}
+	var hash, mac [sha256.Size]byte
+	if _, err = io.ReadFull(r, hash[:]); err == nil {
+		_, err = io.ReadFull(r, mac[:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: kdbx header: %w", ErrFormat, err)
+	}
+	if sum := sha256.Sum256(raw); !hmac.Equal(sum[:], hash[:]) {
+		return nil, fmt.Errorf("%w: kdbx header is corrupted", ErrFormat)
+	}
+	password := sha256.Sum256([]byte(opts.Password))
+	composite := sha256.Sum256(password[:])
+	transformed, err := kdbxTransformKey(h.kdf, composite[:])
+	if err != nil {
+		return
+	}
+	hmacKey := sha512.Sum512(append(append(append([]byte{}, h.seed...), transformed...), 1))
+	hm := hmac.New(sha256.New, kdbxBlockKey(kdbxHeaderIndex, hmacKey[:]))
+	hm.Write(raw)
+	if !hmac.Equal(hm.Sum(nil), mac[:]) {
+		return nil, ErrPassword
+	}
+	payload, err := readKDBXBlocks(r, hmacKey[:])
+	if err != nil {
+		return
+	}
+	key := sha256.Sum256(append(append([]byte{}, h.seed...), transformed...))
+	if payload, err = kdbxDecrypt(h, key[:], payload); err != nil {
+		return
+	}
+	if h.compressed {
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(bytes.NewReader(payload)); err != nil {
+			return nil, fmt.Errorf("%w: kdbx content: %w", ErrFormat, err)
+		}
+		if payload, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("%w: kdbx content: %w", ErrFormat, err)
+		}
+	}
+	content := bytes.NewReader(payload)
+	stream, blobs, err := readKDBXInnerHeader(content)
+	if err != nil {
+		return
+	}
+	plain, err := kdbxUnprotect(content, stream)
+	if err != nil {
+		return
+	}
+	var f keepassFile
+	if err = xml.Unmarshal(plain, &f); err != nil {
+		return nil, fmt.Errorf("%w: kdbx xml: %w", ErrFormat, err)
+	}
+	return f.entries(blobs), nil
+}
+
+// readKDBXHeader
+// the outer header and its raw bytes
+func readKDBXHeader(r io.Reader) (h kdbxHeader, raw []byte, err error) {
+	var buf bytes.Buffer
+	tr := io.TeeReader(r, &buf)
+	var sig struct {
+		Signature1, Signature2 uint32
+		Minor, Major           uint16
+	}
+	if err = binary.Read(tr, binary.LittleEndian, &sig); err != nil || sig.Signature1 != kdbxSignature1 || sig.Signature2 != kdbxSignature2 {
+		err = fmt.Errorf("%w: not a kdbx database", ErrFormat)
+		return
+	}
+	if sig.Major != kdbxVersion {
+		err = fmt.Errorf("%w: kdbx version %d, only version %d is supported", ErrUnsupported, sig.Major, kdbxVersion)
+		return
+	}
+	for {
+		var field struct {
+			ID   uint8
+			Size uint32
+		}
+		if err = binary.Read(tr, binary.LittleEndian, &field); err != nil {
+			err = fmt.Errorf("%w: kdbx header: %w", ErrFormat, err)
+			return
+		}
+		var data []byte
+		if data, err = readN(tr, field.Size); err != nil {
+			err = fmt.Errorf("%w: kdbx header: %w", ErrFormat, err)
+			return
+		}
+		switch field.ID {
+		case kdbxEndOfHeader:
+			if h.cipher == "" || len(h.seed) != 32 || h.kdf == nil {
+				err = fmt.Errorf("%w: kdbx header is not complete", ErrFormat)
+			}
+			return h, buf.Bytes(), err
+		case kdbxCipherID:
+			h.cipher = hex.EncodeToString(data)
+		case kdbxCompression:
+			h.compressed = len(data) == 4 && binary.LittleEndian.Uint32(data) == 1
+		case kdbxMasterSeed:
+			h.seed = data
+		case kdbxEncryptionIV:
+			h.iv = data
+		case kdbxKDFParameters:
+			if h.kdf, err = parseVariantDict(data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readN
+// n bytes of r, the buffer grows by the read bytes, so the wrong size does not allocate the memory
+func readN(r io.Reader, n uint32) (b []byte, err error) {
+	if b, err = io.ReadAll(io.LimitReader(r, int64(n))); err == nil && len(b) != int(n) {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
+// parseVariantDict
+// the KDF parameters of the variant dictionary
+func parseVariantDict(b []byte) (dict map[string]any, err error) {
+	errDict := fmt.Errorf("%w: kdbx kdf parameters", ErrFormat)
+	if len(b) < 2 || b[1] != 1 {
+		return nil, errDict
+	}
+	b = b[2:]
+	take := func(n uint32) (v []byte) {
+		if uint32(len(b)) < n {
+			err = errDict
+			return nil
+		}
+		v, b = b[:n], b[n:]
+		return
+	}
+	size := func() uint32 {
+		if v := take(4); v != nil {
+			return binary.LittleEndian.Uint32(v)
+		}
+		return 0
+	}
+	dict = make(map[string]any)
+	for {
+		t := take(1)
+		if err != nil || t[0] == 0 {
+			return
+		}
+		name := take(size())
+		value := take(size())
+		if err != nil {
+			return
+		}
+		switch t[0] {
+		case 0x04:
+			if len(value) == 4 {
+				dict[string(name)] = uint64(binary.LittleEndian.Uint32(value))
+			}
+		case 0x05:
+			if len(value) == 8 {
+				dict[string(name)] = binary.LittleEndian.Uint64(value)
+			}
+		case 0x08:
+			dict[string(name)] = len(value) == 1 && value[0] != 0
+		case 0x18:
+			dict[string(name)] = string(value)
+		case 0x42:
+			dict[string(name)] = value
+		}
+	}
+}
+
+// kdbxTransformKey
+// the transformed composite key by the KDF of the parameters
+func kdbxTransformKey(kdf map[string]any, composite []byte) (key []byte, err error) {
+	uuid, _ := kdf["$UUID"].([]byte)
+	salt, _ := kdf["S"].([]byte)
+	switch id := hex.EncodeToString(uuid); id {
+	case kdbxArgon2d, kdbxArgon2id:
+		parallelism, _ := kdf["P"].(uint64)
+		memory, _ := kdf["M"].(uint64)
+		iterations, _ := kdf["I"].(uint64)
+		version, _ := kdf["V"].(uint64)
+		secret, _ := kdf["K"].([]byte)
+		data, _ := kdf["A"].([]byte)
+		if parallelism == 0 || parallelism > math.MaxUint8 || iterations == 0 || iterations > math.MaxUint32 ||
+			memory/1024 == 0 || memory/1024 > math.MaxUint32 {
+			return nil, fmt.Errorf("%w: kdbx argon2 parameters", ErrFormat)
+		}
+		if memory > kdbxMaxArgon2Memory || iterations > kdbxMaxArgon2Iterations {
+			return nil, fmt.Errorf("%w: kdbx argon2 costs %d KiB, %d iterations", ErrUnsupported, memory/1024, iterations)
+		}
+		if version != argon2Version {
+			return nil, fmt.Errorf("%w: kdbx argon2 version %#x", ErrUnsupported, version)
+		}
+		if id == kdbxArgon2d {
+			return argon2dKey(composite, salt, secret, data, uint32(iterations), uint32(memory/1024), uint32(parallelism), 32), nil
+		}
+		if len(secret)+len(data) > 0 {
+			return nil, fmt.Errorf("%w: kdbx argon2id secret", ErrUnsupported)
+		}
+		return argon2.IDKey(composite, salt, uint32(iterations), uint32(memory/1024), uint8(parallelism), 32), nil
+	case kdbxAESKDF:
+		rounds, _ := kdf["R"].(uint64)
+		if rounds > kdbxMaxAESRounds {
+			return nil, fmt.Errorf("%w: kdbx aes kdf %d rounds", ErrUnsupported, rounds)
+		}
+		var block cipher.Block
+		if block, err = aes.NewCipher(salt); err != nil {
+			return nil, fmt.Errorf("%w: kdbx aes kdf: %w", ErrFormat, err)
+		}
+		key = bytes.Clone(composite)
+		for i := uint64(0); i < rounds; i++ {
+			block.Encrypt(key[:aes.BlockSize], key[:aes.BlockSize])
+			block.Encrypt(key[aes.BlockSize:], key[aes.BlockSize:])
+		}
+		sum := sha256.Sum256(key)
+		return sum[:], nil
+	default:
+		return nil, fmt.Errorf("%w: kdbx kdf %s", ErrUnsupported, id)
+	}
+}
+
+// kdbxBlockKey
+// the HMAC key of the block of the index
+func kdbxBlockKey(index uint64, hmacKey []byte) []byte {
+	h := sha512.New()
+	_ = binary.Write(h, binary.LittleEndian, index)
+	h.Write(hmacKey)
+	return h.Sum(nil)
+}
+
+// readKDBXBlocks
+// the encrypted content of the HMAC blocks, the blocks are verified
+func readKDBXBlocks(r io.Reader, hmacKey []byte) (content []byte, err error) {
+	var buf bytes.Buffer
+	for index := uint64(0); ; index++ {
+		var block struct {
+			MAC  [sha256.Size]byte
+			Size uint32
+		}
+		if err = binary.Read(r, binary.LittleEndian, &block); err != nil {
+			return nil, fmt.Errorf("%w: kdbx block %d: %w", ErrFormat, index, err)
+		}
+		var data []byte
+		if data, err = readN(r, block.Size); err != nil {
+			return nil, fmt.Errorf("%w: kdbx block %d: %w", ErrFormat, index, err)
+		}
+		hm := hmac.New(sha256.New, kdbxBlockKey(index, hmacKey))
+		_ = binary.Write(hm, binary.LittleEndian, index)
+		_ = binary.Write(hm, binary.LittleEndian, block.Size)
+		hm.Write(data)
+		if !hmac.Equal(hm.Sum(nil), block.MAC[:]) {
+			return nil, fmt.Errorf("%w: kdbx block %d is corrupted", ErrFormat, index)
+		}
+		if block.Size == 0 {
+			return buf.Bytes(), nil
+		}
+		buf.Write(data)
+	}
+}
+
+// kdbxDecrypt
+// the content decrypted by the cipher of the header
+func kdbxDecrypt(h kdbxHeader, key, content []byte) ([]byte, error) {
+	switch h.cipher {
+	case kdbxAES256:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(h.iv) != aes.BlockSize || len(content) == 0 || len(content)%aes.BlockSize != 0 {
+			return nil, fmt.Errorf("%w: kdbx aes content", ErrFormat)
+		}
+		cipher.NewCBCDecrypter(block, h.iv).CryptBlocks(content, content)
+		pad := int(content[len(content)-1])
+		if pad == 0 || pad > aes.BlockSize {
+			return nil, fmt.Errorf("%w: kdbx aes padding", ErrFormat)
+		}
+		return content[:len(content)-pad], nil
+	case kdbxChaCha20:
+		c, err := chacha20.NewUnauthenticatedCipher(key, h.iv)
+		if err != nil {
+			return nil, fmt.Errorf("%w: kdbx chacha20: %w", ErrFormat, err)
+		}
+		c.XORKeyStream(content, content)
+		return content, nil
+	}
+	return nil, fmt.Errorf("%w: kdbx cipher %s", ErrUnsupported, h.cipher)
+}
+
+// readKDBXInnerHeader
+// the stream of the protected values and the attachments of the inner header,
+// the attachments are referenced by their index
+func readKDBXInnerHeader(r io.Reader) (stream cipher.Stream, blobs map[string][]byte, err error) {
+	var (
+		streamID  uint32
+		streamKey []byte
+	)
+	blobs = make(map[string][]byte)
+	for {
+		var field struct {
+			ID   uint8
+			Size uint32
+		}
+		if err = binary.Read(r, binary.LittleEndian, &field); err != nil {
+			err = fmt.Errorf("%w: kdbx inner header: %w", ErrFormat, err)
+			return
+		}
+		var data []byte
+		if data, err = readN(r, field.Size); err != nil {
+			err = fmt.Errorf("%w: kdbx inner header: %w", ErrFormat, err)
+			return
+		}
+		switch field.ID {
+		case kdbxInnerEnd:
+			if streamID != kdbxInnerChaCha20 {
+				err = fmt.Errorf("%w: kdbx inner stream %d", ErrUnsupported, streamID)
+				return
+			}
+			sum := sha512.Sum512(streamKey)
+			stream, err = chacha20.NewUnauthenticatedCipher(sum[:32], sum[32:44])
+			return
+		case kdbxInnerStreamID:
+			if len(data) == 4 {
+				streamID = binary.LittleEndian.Uint32(data)
+			}
+		case kdbxInnerStreamKey:
+			streamKey = data
+		case kdbxInnerBinary:
+			// the first byte is the protection flag
+			if len(data) > 0 {
+				blobs[strconv.Itoa(len(blobs))] = data[1:]
+			}
+		}
+	}
+}
+
+// kdbxUnprotect
+// the XML of the database with the protected values decrypted by the stream in the document order
+func kdbxUnprotect(r io.Reader, stream cipher.Stream) ([]byte, error) {
+	var (
+		buf       bytes.Buffer
+		protected bool
+	)
+	d := xml.NewDecoder(r)
+	enc := xml.NewEncoder(&buf)
+	for {
+		t, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: kdbx xml: %w", ErrFormat, err)
+		}
+		switch v := t.(type) {
+		case xml.ProcInst:
+			continue
+		case xml.StartElement:
+			protected = false
+			for _, a := range v.Attr {
+				protected = protected || v.Name.Local == "Value" && a.Name.Local == "Protected" && strings.EqualFold(a.Value, "true")
+			}
+		case xml.EndElement:
+			protected = false
+		case xml.CharData:
+			if protected {
+				b, er := base64.StdEncoding.DecodeString(strings.TrimSpace(string(v)))
+				if er != nil {
+					return nil, fmt.Errorf("%w: kdbx protected value: %w", ErrFormat, er)
+				}
+				stream.XORKeyStream(b, b)
+				t = xml.CharData(b)
+			}
+		}
+		if err = enc.EncodeToken(t); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
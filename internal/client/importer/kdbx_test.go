@@ -0,0 +1,257 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20"
+)
+
+func TestArgon2d(t *testing.T) {
+	// RFC 9106, 5.1 Argon2d test vector
+	key := argon2dKey(bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 16), bytes.Repeat([]byte{3}, 8),
+		bytes.Repeat([]byte{4}, 12), 3, 32, 4, 32)
+	assert.Equal(t, "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb", hex.EncodeToString(key))
+}
+
+// variantDict
+// the variant dictionary of the byte arrays and the uint64 values
+func variantDict(values map[string]any) []byte {
+	b := []byte{0, 1}
+	for name, v := range values {
+		var (
+			t     byte
+			value []byte
+		)
+		switch v := v.(type) {
+		case []byte:
+			t, value = 0x42, v
+		case uint64:
+			t, value = 0x05, binary.LittleEndian.AppendUint64(nil, v)
+		}
+		b = append(b, t)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(name)))
+		b = append(b, name...)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(value)))
+		b = append(b, value...)
+	}
+	return append(b, 0)
+}
+
+// tlv
+// the header field of the id
+func tlv(id byte, data []byte) []byte {
+	return append(binary.LittleEndian.AppendUint32([]byte{id}, uint32(len(data))), data...)
+}
+
+func random(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
+
+// writeKDBX
+// the KDBX 4 database of the cipher and the kdf, the protected values of the XML are of the "%s" verbs
+func writeKDBX(t *testing.T, password, cipherID string, kdf map[string]any, xmlFormat string, protected ...string) []byte {
+	seed, streamKey := random(t, 32), random(t, 64)
+	iv := random(t, 12)
+	if cipherID == kdbxAES256 {
+		iv = random(t, 16)
+	}
+	cipherUUID, _ := hex.DecodeString(cipherID)
+
+	header := binary.LittleEndian.AppendUint32(nil, kdbxSignature1)
+	header = binary.LittleEndian.AppendUint32(header, kdbxSignature2)
+	header = binary.LittleEndian.AppendUint32(header, kdbxVersion<<16)
+	header = append(header, tlv(kdbxCipherID, cipherUUID)...)
+	header = append(header, tlv(kdbxCompression, binary.LittleEndian.AppendUint32(nil, 1))...)
+	header = append(header, tlv(kdbxMasterSeed, seed)...)
+	header = append(header, tlv(kdbxEncryptionIV, iv)...)
+	header = append(header, tlv(kdbxKDFParameters, variantDict(kdf))...)
+	header = append(header, tlv(kdbxEndOfHeader, []byte("\r\n\r\n"))...)
+
+	sum := sha512.Sum512(streamKey)
+	stream, err := chacha20.NewUnauthenticatedCipher(sum[:32], sum[32:44])
+	require.NoError(t, err)
+	values := make([]any, len(protected))
+	for i, p := range protected {
+		b := []byte(p)
+		stream.XORKeyStream(b, b)
+		values[i] = base64.StdEncoding.EncodeToString(b)
+	}
+	var inner bytes.Buffer
+	inner.Write(tlv(kdbxInnerStreamID, binary.LittleEndian.AppendUint32(nil, kdbxInnerChaCha20)))
+	inner.Write(tlv(kdbxInnerStreamKey, streamKey))
+	inner.Write(tlv(kdbxInnerBinary, append([]byte{1}, "attached content"...)))
+	inner.Write(tlv(kdbxInnerEnd, nil))
+	inner.WriteString(fmt.Sprintf(xmlFormat, values...))
+
+	var payload bytes.Buffer
+	zw := gzip.NewWriter(&payload)
+	_, err = zw.Write(inner.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	pw := sha256.Sum256([]byte(password))
+	composite := sha256.Sum256(pw[:])
+	transformed, err := kdbxTransformKey(kdf, composite[:])
+	require.NoError(t, err)
+	key := sha256.Sum256(append(append([]byte{}, seed...), transformed...))
+	hmacKey := sha512.Sum512(append(append(append([]byte{}, seed...), transformed...), 1))
+
+	content := payload.Bytes()
+	if cipherID == kdbxAES256 {
+		pad := aes.BlockSize - len(content)%aes.BlockSize
+		content = append(content, bytes.Repeat([]byte{byte(pad)}, pad)...)
+		block, er := aes.NewCipher(key[:])
+		require.NoError(t, er)
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(content, content)
+	} else {
+		c, er := chacha20.NewUnauthenticatedCipher(key[:], iv)
+		require.NoError(t, er)
+		c.XORKeyStream(content, content)
+	}
+
+	out := bytes.NewBuffer(bytes.Clone(header))
+	headerHash := sha256.Sum256(header)
+	out.Write(headerHash[:])
+	hm := hmac.New(sha256.New, kdbxBlockKey(kdbxHeaderIndex, hmacKey[:]))
+	hm.Write(header)
+	out.Write(hm.Sum(nil))
+	for i, data := range [][]byte{content, nil} {
+		hm = hmac.New(sha256.New, kdbxBlockKey(uint64(i), hmacKey[:]))
+		hm.Write(binary.LittleEndian.AppendUint64(nil, uint64(i)))
+		hm.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(data))))
+		hm.Write(data)
+		out.Write(hm.Sum(nil))
+		out.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(data))))
+		out.Write(data)
+	}
+	return out.Bytes()
+}
+
+const kdbxXML = `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta><RecycleBinEnabled>True</RecycleBinEnabled><RecycleBinUUID>cmVjeWNsZQ==</RecycleBinUUID></Meta>
+	<Root>
+		<Group>
+			<UUID>cm9vdA==</UUID><Name>Database</Name>
+			<Entry>
+				<String><Key>Title</Key><Value>github</Value></String>
+				<String><Key>UserName</Key><Value>user</Value></String>
+				<String><Key>Password</Key><Value Protected="True">%s</Value></String>
+				<String><Key>URL</Key><Value>https://github.com/login</Value></String>
+				<String><Key>PIN</Key><Value Protected="True">%s</Value></String>
+				<Binary><Key>key.txt</Key><Value Ref="0"/></Binary>
+				<History>
+					<Entry>
+						<String><Key>Password</Key><Value Protected="True">%s</Value></String>
+					</Entry>
+				</History>
+			</Entry>
+			<Group>
+				<UUID>d29yaw==</UUID><Name>Work</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>mail</Value></String>
+					<String><Key>Password</Key><Value Protected="True">%s</Value></String>
+				</Entry>
+			</Group>
+			<Group>
+				<UUID>cmVjeWNsZQ==</UUID><Name>Recycle Bin</Name>
+				<Entry><String><Key>Title</Key><Value>deleted</Value></String></Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`
+
+func TestParseKDBX(t *testing.T) {
+	argon2d, _ := hex.DecodeString(kdbxArgon2d)
+	aesKDF, _ := hex.DecodeString(kdbxAESKDF)
+	tests := []struct {
+		name   string
+		cipher string
+		kdf    map[string]any
+	}{
+		{"argon2d chacha20", kdbxChaCha20, map[string]any{
+			"$UUID": argon2d, "S": random(t, 32), "P": uint64(2), "M": uint64(64 * 1024), "I": uint64(2), "V": uint64(argon2Version),
+		}},
+		{"aes kdf aes", kdbxAES256, map[string]any{"$UUID": aesKDF, "S": random(t, 32), "R": uint64(100)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := writeKDBX(t, "secret", tt.cipher, tt.kdf, kdbxXML, "p@ss", "1234", "old", "mail-pass")
+
+			entries, err := Parse(FormatKDBX, bytes.NewReader(db), Options{Password: "secret"})
+			require.NoError(t, err)
+			require.Len(t, entries, 3)
+			assert.Equal(t, KindAuth, entries[0].Kind)
+			assert.Equal(t, "github", entries[0].Title)
+			assert.Equal(t, "user", entries[0].Login)
+			assert.Equal(t, "p@ss", entries[0].Password)
+			assert.Equal(t, "https://github.com/login", entries[0].URLs[0].URL)
+			require.Len(t, entries[0].Fields, 1)
+			assert.Equal(t, "PIN", entries[0].Fields[0].Name)
+			assert.Equal(t, "1234", entries[0].Fields[0].Value)
+			assert.True(t, entries[0].Fields[0].Secret)
+
+			assert.Equal(t, KindBin, entries[1].Kind)
+			assert.Equal(t, "github - key.txt", entries[1].Title)
+			assert.Equal(t, "attached content", string(entries[1].Content))
+
+			assert.Equal(t, "mail", entries[2].Title)
+			assert.Equal(t, "Work", entries[2].Folder)
+			assert.Equal(t, "mail-pass", entries[2].Password)
+
+			_, err = Parse(FormatKDBX, bytes.NewReader(db), Options{Password: "wrong"})
+			require.ErrorIs(t, err, ErrPassword)
+		})
+	}
+
+	_, err := Parse(FormatKDBX, bytes.NewReader([]byte("not a database")), Options{})
+	require.ErrorIs(t, err, ErrFormat)
+}
+
+func TestKDBXTransformKeyCosts(t *testing.T) {
+	argon2d, _ := hex.DecodeString(kdbxArgon2d)
+	aesKDF, _ := hex.DecodeString(kdbxAESKDF)
+	argon2Params := func(memory, iterations uint64) map[string]any {
+		return map[string]any{
+			"$UUID": argon2d, "S": random(t, 32), "P": uint64(1), "M": memory, "I": iterations, "V": uint64(argon2Version),
+		}
+	}
+	tests := []struct {
+		name string
+		kdf  map[string]any
+	}{
+		{"argon2 memory", argon2Params(kdbxMaxArgon2Memory+1024, 1)},
+		{"argon2 memory of uint32 KiB", argon2Params(math.MaxUint32*1024, 1)},
+		{"argon2 iterations", argon2Params(1024, kdbxMaxArgon2Iterations+1)},
+		{"aes kdf rounds", map[string]any{"$UUID": aesKDF, "S": random(t, 32), "R": uint64(math.MaxUint64)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := kdbxTransformKey(tt.kdf, random(t, 32))
+			require.ErrorIs(t, err, ErrUnsupported)
+		})
+	}
+	// the costs within the limits are run
+	_, err := kdbxTransformKey(argon2Params(1024, 2), random(t, 32))
+	require.NoError(t, err)
+	_, err = kdbxTransformKey(map[string]any{"$UUID": aesKDF, "S": random(t, 32), "R": uint64(100)}, random(t, 32))
+	require.NoError(t, err)
+}
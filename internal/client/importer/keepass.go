@@ -0,0 +1,166 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KeePass standard fields of entries
+const (
+	keepassTitle    = "Title"
+	keepassUserName = "UserName"
+	keepassPassword = "Password"
+	keepassURL      = "URL"
+	keepassNotes    = "Notes"
+	keepassOTP      = "otp"
+)
+
+type keepassFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    struct {
+		RecycleBinEnabled bool          `xml:"RecycleBinEnabled"`
+		RecycleBinUUID    string        `xml:"RecycleBinUUID"`
+		Binaries          []keepassBlob `xml:"Binaries>Binary"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keepassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+// keepassBlob
+// the attachment content of the XML export, it is base64 of the gzip compressed content
+type keepassBlob struct {
+	ID         string `xml:"ID,attr"`
+	Compressed bool   `xml:"Compressed,attr"`
+	Value      string `xml:",chardata"`
+}
+
+type keepassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keepassEntry `xml:"Entry"`
+	Groups  []keepassGroup `xml:"Group"`
+}
+
+type keepassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value struct {
+			Value           string `xml:",chardata"`
+			Protected       bool   `xml:"Protected,attr"`
+			ProtectInMemory bool   `xml:"ProtectInMemory,attr"`
+		} `xml:"Value"`
+	} `xml:"String"`
+	Binaries []struct {
+		Key   string `xml:"Key"`
+		Value struct {
+			Ref string `xml:"Ref,attr"`
+		} `xml:"Value"`
+	} `xml:"Binary"`
+}
+
+// parseKeePassXML
+// the entries of the KeePass 2.x XML export
+func parseKeePassXML(r io.Reader, _ Options) (entries []Entry, err error) {
+	var f keepassFile
+	if err = xml.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("%w: keepass xml: %w", ErrFormat, err)
+	}
+	blobs := make(map[string][]byte, len(f.Meta.Binaries))
+	for _, b := range f.Meta.Binaries {
+		if blobs[b.ID], err = b.content(); err != nil {
+			return nil, fmt.Errorf("%w: keepass attachment %s: %w", ErrFormat, b.ID, err)
+		}
+	}
+	return f.entries(blobs), nil
+}
+
+// content
+// the decoded attachment content
+func (b keepassBlob) content() (content []byte, err error) {
+	if content, err = base64.StdEncoding.DecodeString(strings.TrimSpace(b.Value)); err != nil || !b.Compressed {
+		return
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return
+	}
+	return io.ReadAll(zr)
+}
+
+// entries
+// the entries of the groups, the attachments are found at blobs by their reference,
+// the root group is not the folder, the recycle bin is not imported
+func (f *keepassFile) entries(blobs map[string][]byte) (entries []Entry) {
+	var walk func(g keepassGroup, folder string)
+	walk = func(g keepassGroup, folder string) {
+		if f.Meta.RecycleBinEnabled && g.UUID != "" && g.UUID == f.Meta.RecycleBinUUID {
+			return
+		}
+		for _, ke := range g.Entries {
+			entries = append(entries, keepassEntries(ke, folder, blobs)...)
+		}
+		for _, sub := range g.Groups {
+			path := sub.Name
+			if folder != "" {
+				path = folder + "/" + sub.Name
+			}
+			walk(sub, path)
+		}
+	}
+	for _, g := range f.Root.Groups {
+		walk(g, "")
+	}
+	return
+}
+
+// keepassEntries
+// the entry of KeePass entry and the bin entries of its attachments
+func keepassEntries(ke keepassEntry, folder string, blobs map[string][]byte) (entries []Entry) {
+	e := Entry{Folder: folder}
+	for _, s := range ke.Strings {
+		v := s.Value.Value
+		switch s.Key {
+		case keepassTitle:
+			e.Title = v
+		case keepassUserName:
+			e.Login = v
+		case keepassPassword:
+			e.Password = v
+		case keepassURL:
+			e.AddURL(v, "")
+		case keepassNotes:
+			e.Notes = v
+		case keepassOTP:
+			e.TOTP = v
+		default:
+			e.AddField(s.Key, v, s.Value.Protected || s.Value.ProtectInMemory)
+		}
+	}
+	e.Kind = KindAuth
+	if e.Login == "" && e.Password == "" && len(e.URLs) == 0 && e.Notes != "" {
+		e.Kind = KindText
+	}
+	// the entry of the attachments only is not imported
+	if len(ke.Binaries) == 0 || e.Kind == KindText || e.Login != "" || e.Password != "" || len(e.URLs)+len(e.Fields) > 0 {
+		entries = append(entries, e)
+	}
+	for _, b := range ke.Binaries {
+		content, ok := blobs[b.Value.Ref]
+		if !ok {
+			continue
+		}
+		// the key is not a path, the attachment name is joined by " - "
+		title := e.Title + " - " + b.Key
+		if e.Title == "" {
+			title = b.Key
+		}
+		entries = append(entries, Entry{Kind: KindBin, Title: title, Folder: folder, Content: content, FileName: b.Key})
+	}
+	return
+}
@@ -0,0 +1,211 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 1Password item categories
+const (
+	onePasswordLogin    = "001"
+	onePasswordCard     = "002"
+	onePasswordPassword = "005"
+	onePasswordDocument = "006"
+)
+
+// onePasswordData the export data of the 1pux archive
+const onePasswordData = "export.data"
+
+type onePasswordExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePasswordItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePasswordItem struct {
+	CategoryUUID string `json:"categoryUuid"`
+	State        string `json:"state"`
+	Overview     struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		URLs  []struct {
+			URL string `json:"url"`
+		} `json:"urls"`
+	} `json:"overview"`
+	Details struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Name        string `json:"name"`
+			FieldType   string `json:"fieldType"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Title  string `json:"title"`
+			Fields []struct {
+				Title string                     `json:"title"`
+				ID    string                     `json:"id"`
+				Value map[string]json.RawMessage `json:"value"`
+			} `json:"fields"`
+		} `json:"sections"`
+		DocumentAttributes *struct {
+			FileName   string `json:"fileName"`
+			DocumentID string `json:"documentId"`
+		} `json:"documentAttributes"`
+	} `json:"details"`
+}
+
+// parse1PUX
+// the entries of the 1Password 1pux archive, the vaults are the folders, the trashed items are not imported
+func parse1PUX(r io.Reader, _ Options) (entries []Entry, err error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: 1pux: %w", ErrFormat, err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	data, ok := files[onePasswordData]
+	if !ok {
+		return nil, fmt.Errorf("%w: 1pux has no %s", ErrFormat, onePasswordData)
+	}
+	var export onePasswordExport
+	if err = readZipJSON(data, &export); err != nil {
+		return nil, fmt.Errorf("%w: 1pux: %w", ErrFormat, err)
+	}
+	for _, account := range export.Accounts {
+		for _, vault := range account.Vaults {
+			for _, item := range vault.Items {
+				if item.State == "trashed" {
+					continue
+				}
+				var e Entry
+				if e, err = onePasswordEntry(item, vault.Attrs.Name, files); err != nil {
+					return
+				}
+				entries = append(entries, e)
+			}
+		}
+	}
+	return
+}
+
+// readZipJSON
+// decode the JSON file of the archive
+func readZipJSON(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+	return json.NewDecoder(rc).Decode(v)
+}
+
+// onePasswordEntry
+// the entry of the item, the files of the documents are found in the archive by their id
+func onePasswordEntry(item onePasswordItem, folder string, files map[string]*zip.File) (e Entry, err error) {
+	e = Entry{Kind: KindText, Title: item.Overview.Title, Folder: folder, Notes: item.Details.NotesPlain}
+	switch item.CategoryUUID {
+	case onePasswordLogin, onePasswordPassword:
+		e.Kind, e.Password = KindAuth, item.Details.Password
+	case onePasswordCard:
+		e.Kind = KindCard
+	case onePasswordDocument:
+		if doc := item.Details.DocumentAttributes; doc != nil {
+			for name, f := range files {
+				if strings.HasPrefix(name, "files/"+doc.DocumentID) {
+					if e.Content, err = readZipFile(f); err != nil {
+						return e, fmt.Errorf("%w: 1pux document %s: %w", ErrFormat, doc.FileName, err)
+					}
+					e.Kind, e.FileName = KindBin, doc.FileName
+					break
+				}
+			}
+		}
+	}
+	for _, u := range item.Overview.URLs {
+		e.AddURL(u.URL, "")
+	}
+	if len(item.Overview.URLs) == 0 {
+		e.AddURL(item.Overview.URL, "")
+	}
+	for _, f := range item.Details.LoginFields {
+		switch f.Designation {
+		case "username":
+			e.Login = f.Value
+		case "password":
+			e.Password = f.Value
+		default:
+			e.AddField(f.Name, f.Value, f.FieldType == "P")
+		}
+	}
+	for _, s := range item.Details.Sections {
+		for _, f := range s.Fields {
+			onePasswordField(&e, f.ID, f.Title, f.Value)
+		}
+	}
+	return
+}
+
+// onePasswordField
+// set the section field to the entry, the card fields to the card, the others are the extra fields
+func onePasswordField(e *Entry, id, title string, value map[string]json.RawMessage) {
+	for kind, raw := range value {
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			// the numbers, as the month of year, are kept, the objects, as the address, are skipped
+			var n json.Number
+			if json.Unmarshal(raw, &n) != nil {
+				continue
+			}
+			v = n.String()
+		}
+		switch {
+		case kind == "totp":
+			e.TOTP = v
+		case e.Kind == KindCard && kind == "creditCardNumber":
+			e.Card.Number = v
+		case e.Kind == KindCard && id == "cvv":
+			e.Card.CVV = v
+		case e.Kind == KindCard && id == "cardholder":
+			e.Card.Name = v
+		case e.Kind == KindCard && kind == "monthYear" && len(v) == 6:
+			e.Card.Exp = v[4:] + "/" + v[2:4]
+		case kind == "date":
+			if t, err := strconv.ParseInt(v, 10, 64); err == nil {
+				v = time.Unix(t, 0).UTC().Format(time.DateOnly)
+			}
+			e.AddField(title, v, false)
+		default:
+			e.AddField(title, v, kind == "concealed")
+		}
+	}
+}
+
+// readZipFile
+// the content of the archive file
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,249 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	errs "gophKeeper/internal/client/errors"
+)
+
+// The pinentry program speaks the Assuan protocol: the client sends a command line,
+// the server answers with data lines "D <escaped data>", status lines "S ...",
+// comments "# ...", and finishes the answer with "OK [text]" or "ERR <code> <text>".
+
+const (
+	pinentryTitle = "GophKeeper"
+	// assuanLineLen the longest line of Assuan protocol
+	assuanLineLen = 1000
+)
+
+var ErrPinentryCancel = errors.New("pinentry is cancelled")
+
+// assuanError the error answer of Assuan server
+type assuanError struct {
+	Code int
+	Text string
+}
+
+func (e *assuanError) Error() string {
+	return fmt.Sprintf("pinentry error %d: %s", e.Code, e.Text)
+}
+
+// assuanEscape escapes the command argument
+func assuanEscape(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// assuanUnescape decodes the data line
+func assuanUnescape(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", errors.New("wrong assuan escape")
+		}
+		c, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", errors.New("wrong assuan escape")
+		}
+		b.WriteByte(byte(c))
+		i += 2
+	}
+	return b.String(), nil
+}
+
+type assuan struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// response reads the answer up to OK or ERR, returns the joined data lines
+func (a *assuan) response() (data string, err error) {
+	for {
+		var line string
+		if line, err = a.r.ReadString('\n'); err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "OK" || strings.HasPrefix(line, "OK "):
+			return
+		case strings.HasPrefix(line, "ERR "):
+			e := &assuanError{}
+			code, text, _ := strings.Cut(strings.TrimPrefix(line, "ERR "), " ")
+			e.Code, _ = strconv.Atoi(code)
+			e.Text = text
+			if strings.Contains(strings.ToLower(text), "cancel") {
+				err = errors.Join(ErrPinentryCancel, e)
+				return
+			}
+			err = e
+			return
+		case strings.HasPrefix(line, "D "):
+			var d string
+			if d, err = assuanUnescape(line[2:]); err != nil {
+				return
+			}
+			data += d
+		case strings.HasPrefix(line, "INQUIRE"):
+			// nothing to send on inquiry
+			if _, err = io.WriteString(a.w, "END\n"); err != nil {
+				return
+			}
+		}
+		// status lines and comments are skipped
+	}
+}
+
+// command sends the command with escaped argument and reads the answer
+func (a *assuan) command(name string, arg ...string) (data string, err error) {
+	line := name
+	if len(arg) > 0 {
+		line += " " + assuanEscape(strings.Join(arg, " "))
+	}
+	if len(line) > assuanLineLen {
+		line = line[:assuanLineLen]
+	}
+	if _, err = io.WriteString(a.w, line+"\n"); err != nil {
+		return
+	}
+	return a.response()
+}
+
+// getPin asks the pin by the prompts, twice if confirm
+func (a *assuan) getPin(confirm bool, prompts ...string) (pin string, err error) {
+	// greeting of the server
+	if _, err = a.response(); err != nil {
+		return
+	}
+	if tty := os.Getenv("GPG_TTY"); tty != "" {
+		_, _ = a.command("OPTION", "ttyname="+tty)
+	}
+	if _, err = a.command("SETTITLE", pinentryTitle); err != nil {
+		return
+	}
+	if _, err = a.command("SETPROMPT", "Passphrase:"); err != nil {
+		return
+	}
+	desc := PromptPs
+	if len(prompts) > 0 {
+		desc = prompts[0]
+	}
+	if _, err = a.command("SETDESC", strings.TrimSpace(desc)); err != nil {
+		return
+	}
+	if pin, err = a.command("GETPIN"); err != nil {
+		return
+	}
+	if pin == "" {
+		err = ErrEmptyPassphrase
+		return
+	}
+	if !confirm {
+		return
+	}
+	desc = PromptConfirmPs
+	if len(prompts) > 1 {
+		desc = prompts[1]
+	}
+	if _, err = a.command("SETDESC", strings.TrimSpace(desc)); err != nil {
+		return
+	}
+	var repeated string
+	if repeated, err = a.command("GETPIN"); err != nil {
+		return
+	}
+	if repeated != pin {
+		pin = ""
+		err = errs.ErrPasswordConfirm
+	}
+	return
+}
+
+type pinentrySource string
+
+// PinentrySource
+// the passphrase is asked by the pinentry program
+func PinentrySource(program string) Source {
+	return pinentrySource(program)
+}
+
+func (s pinentrySource) Passphrase(confirm bool, prompts ...string) (pass string, err error) {
+	err = runPinentry(string(s), func(a *assuan) (err error) {
+		pass, err = a.getPin(confirm, prompts...)
+		return
+	})
+	return
+}
+
+// PinentryConfirm
+// ask the user to allow the action described by desc with the pinentry program, the cancel is a denial
+func PinentryConfirm(program, desc string) (ok bool, err error) {
+	err = runPinentry(program, func(a *assuan) (err error) {
+		ok, err = a.confirm(desc)
+		return
+	})
+	return
+}
+
+// confirm asks the user to allow the action
+func (a *assuan) confirm(desc string) (ok bool, err error) {
+	// greeting of the server
+	if _, err = a.response(); err != nil {
+		return
+	}
+	if tty := os.Getenv("GPG_TTY"); tty != "" {
+		_, _ = a.command("OPTION", "ttyname="+tty)
+	}
+	if _, err = a.command("SETTITLE", pinentryTitle); err != nil {
+		return
+	}
+	if _, err = a.command("SETDESC", strings.TrimSpace(desc)); err != nil {
+		return
+	}
+	_, err = a.command("CONFIRM")
+	if errors.Is(err, ErrPinentryCancel) {
+		return false, nil
+	}
+	ok = err == nil
+	return
+}
+
+// runPinentry
+// start the pinentry program and talk to it by fn, the program is finished after
+func runPinentry(program string, fn func(a *assuan) error) (err error) {
+	cmd := exec.Command(program)
+	var (
+		stdin  io.WriteCloser
+		stdout io.ReadCloser
+	)
+	if stdin, err = cmd.StdinPipe(); err != nil {
+		return
+	}
+	if stdout, err = cmd.StdoutPipe(); err != nil {
+		return
+	}
+	if err = cmd.Start(); err != nil {
+		err = fmt.Errorf("pinentry %s: %w", program, err)
+		return
+	}
+	a := &assuan{r: bufio.NewReader(stdout), w: stdin}
+	err = fn(a)
+	if err == nil {
+		_, _ = a.command("BYE")
+	}
+	_ = stdin.Close()
+	if er := cmd.Wait(); er != nil && err == nil {
+		err = fmt.Errorf("pinentry %s: %w", program, er)
+	}
+	return
+}
@@ -0,0 +1,131 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+var (
+	ErrEmptyPassphrase = errors.New("empty passphrase")
+	ErrPassphraseFile  = errors.New("passphrase file is accessible by others")
+
+	// fdPassphrases the descriptor can be read once, the passphrase is kept for the next requests
+	fdPassphrases   = make(map[int]string)
+	fdPassphrasesMu sync.Mutex
+)
+
+// Source
+// the passphrase source other than the terminal prompt
+type Source interface {
+	// Passphrase returns the passphrase, prompts are used by interactive sources,
+	// confirm asks the passphrase twice
+	Passphrase(confirm bool, prompts ...string) (pass string, err error)
+}
+
+// GetPassphrase
+// get the passphrase from src, or from user by GetRawPass if src is nil
+func GetPassphrase(src Source, confirm bool, prompts ...string) (pass string, err error) {
+	if src == nil {
+		return GetRawPass(confirm, prompts...)
+	}
+	return src.Passphrase(confirm, prompts...)
+}
+
+// readLine
+// the first line of r without line end
+func readLine(r io.Reader) (pass string, err error) {
+	line, err := bufio.NewReader(io.LimitReader(r, 1025)).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return
+	}
+	err = nil
+	line = bytes.TrimRight(line, "\r\n")
+	switch {
+	case len(line) == 0:
+		err = ErrEmptyPassphrase
+	case len(line) > 1024:
+		err = errors.New("password too long")
+	default:
+		pass = string(line)
+	}
+	return
+}
+
+type fdSource int
+
+// FDSource
+// the passphrase is the first line read from the file descriptor
+func FDSource(fd int) Source {
+	return fdSource(fd)
+}
+
+func (s fdSource) Passphrase(_ bool, _ ...string) (pass string, err error) {
+	fdPassphrasesMu.Lock()
+	defer fdPassphrasesMu.Unlock()
+	if pass, ok := fdPassphrases[int(s)]; ok {
+		return pass, nil
+	}
+	f := os.NewFile(uintptr(s), fmt.Sprintf("fd%d", int(s)))
+	if f == nil {
+		err = fmt.Errorf("wrong passphrase descriptor %d", int(s))
+		return
+	}
+	defer func() { _ = f.Close() }()
+	if pass, err = readLine(f); err != nil {
+		err = fmt.Errorf("passphrase descriptor %d: %w", int(s), err)
+		return
+	}
+	fdPassphrases[int(s)] = pass
+	return
+}
+
+type fileSource string
+
+// FileSource
+// the passphrase is the first line of the file, which must not be accessible by others
+func FileSource(path string) Source {
+	return fileSource(path)
+}
+
+func (s fileSource) Passphrase(_ bool, _ ...string) (pass string, err error) {
+	var f *os.File
+	if f, err = os.Open(string(s)); err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	if runtime.GOOS != "windows" {
+		var fi os.FileInfo
+		if fi, err = f.Stat(); err != nil {
+			return
+		}
+		if fi.Mode().Perm()&0077 != 0 {
+			err = fmt.Errorf("%w: %s, %s", ErrPassphraseFile, s, fi.Mode().Perm())
+			return
+		}
+	}
+	if pass, err = readLine(f); err != nil {
+		err = fmt.Errorf("passphrase file %s: %w", s, err)
+	}
+	return
+}
+
+type envSource string
+
+// EnvSource
+// the passphrase is the value of the environment variable
+func EnvSource(name string) Source {
+	return envSource(name)
+}
+
+func (s envSource) Passphrase(_ bool, _ ...string) (pass string, err error) {
+	if pass = os.Getenv(string(s)); pass == "" {
+		err = fmt.Errorf("%w: environment variable %s", ErrEmptyPassphrase, s)
+	}
+	return
+}
@@ -0,0 +1,158 @@
+package password
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	errs "gophKeeper/internal/client/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pass")
+	require.NoError(t, os.WriteFile(path, []byte("secret pass\nnext line\n"), 0600))
+
+	pass, err := FileSource(path).Passphrase(false)
+	require.NoError(t, err)
+	assert.Equal(t, "secret pass", pass)
+
+	t.Run("open to others", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file mode is not checked on windows")
+		}
+		require.NoError(t, os.Chmod(path, 0644))
+		_, err := FileSource(path).Passphrase(false)
+		assert.ErrorIs(t, err, ErrPassphraseFile)
+	})
+	t.Run("empty", func(t *testing.T) {
+		empty := filepath.Join(dir, "empty")
+		require.NoError(t, os.WriteFile(empty, []byte("\n"), 0600))
+		_, err := FileSource(empty).Passphrase(false)
+		assert.ErrorIs(t, err, ErrEmptyPassphrase)
+	})
+	t.Run("missing", func(t *testing.T) {
+		_, err := FileSource(filepath.Join(dir, "missing")).Passphrase(false)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestEnvSource(t *testing.T) {
+	t.Setenv("GK_TEST_PASS", "env pass")
+	pass, err := EnvSource("GK_TEST_PASS").Passphrase(false)
+	require.NoError(t, err)
+	assert.Equal(t, "env pass", pass)
+
+	_, err = EnvSource("GK_TEST_PASS_UNSET").Passphrase(false)
+	assert.ErrorIs(t, err, ErrEmptyPassphrase)
+}
+
+// fakePinentry serves the Assuan requests, answering GETPIN by pins in order
+func fakePinentry(t *testing.T, r io.Reader, w io.Writer, pins ...string) {
+	t.Helper()
+	_, _ = io.WriteString(w, "OK Pleased to meet you\n")
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		cmd, _, _ := strings.Cut(sc.Text(), " ")
+		switch cmd {
+		case "GETPIN":
+			if len(pins) == 0 {
+				_, _ = io.WriteString(w, "ERR 83886179 Operation cancelled <Pinentry>\n")
+				continue
+			}
+			_, _ = io.WriteString(w, "S some status\nD "+assuanEscape(pins[0])+"\nOK\n")
+			pins = pins[1:]
+		case "CONFIRM":
+			// any pin left is the confirmation
+			if len(pins) == 0 {
+				_, _ = io.WriteString(w, "ERR 83886179 Operation cancelled <Pinentry>\n")
+				continue
+			}
+			_, _ = io.WriteString(w, "OK\n")
+			pins = pins[1:]
+		case "BYE":
+			_, _ = io.WriteString(w, "OK closing connection\n")
+			return
+		default:
+			_, _ = io.WriteString(w, "OK\n")
+		}
+	}
+}
+
+func TestAssuanGetPin(t *testing.T) {
+	tests := []struct {
+		name    string
+		pins    []string
+		confirm bool
+		want    string
+		wantErr error
+	}{
+		{name: "pin", pins: []string{"pin 100%"}, want: "pin 100%"},
+		{name: "confirm", pins: []string{"pin", "pin"}, confirm: true, want: "pin"},
+		{name: "confirm mismatch", pins: []string{"pin", "other"}, confirm: true, wantErr: errs.ErrPasswordConfirm},
+		{name: "cancel", wantErr: ErrPinentryCancel},
+		{name: "empty", pins: []string{""}, wantErr: ErrEmptyPassphrase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr, sw := io.Pipe()
+			sr, cw := io.Pipe()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				fakePinentry(t, sr, sw, tt.pins...)
+				_ = sw.Close()
+			}()
+			a := &assuan{r: bufio.NewReader(cr), w: cw}
+			pin, err := a.getPin(tt.confirm)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, pin)
+			}
+			_ = cw.Close()
+			<-done
+		})
+	}
+}
+
+func TestAssuanConfirm(t *testing.T) {
+	for _, allow := range []bool{true, false} {
+		cr, sw := io.Pipe()
+		sr, cw := io.Pipe()
+		done := make(chan struct{})
+		var pins []string
+		if allow {
+			pins = []string{"yes"}
+		}
+		go func() {
+			defer close(done)
+			fakePinentry(t, sr, sw, pins...)
+			_ = sw.Close()
+		}()
+		a := &assuan{r: bufio.NewReader(cr), w: cw}
+		ok, err := a.confirm("Allow?")
+		require.NoError(t, err)
+		assert.Equal(t, allow, ok)
+		_ = cw.Close()
+		<-done
+	}
+}
+
+func TestAssuanUnescape(t *testing.T) {
+	s, err := assuanUnescape("a%25b%0Ac")
+	require.NoError(t, err)
+	assert.Equal(t, "a%b\nc", s)
+
+	_, err = assuanUnescape("a%2")
+	assert.Error(t, err)
+	assert.Equal(t, "a%25b%0Ac", assuanEscape("a%b\nc"))
+}
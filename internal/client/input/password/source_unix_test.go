@@ -0,0 +1,35 @@
+//go:build unix
+
+package password
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFDSource(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	_, err = w.WriteString("fd pass\r\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	// the source closes its descriptor, so it gets a duplicate
+	fd, err := syscall.Dup(int(r.Fd()))
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	src := FDSource(fd)
+	pass, err := src.Passphrase(false)
+	require.NoError(t, err)
+	assert.Equal(t, "fd pass", pass)
+
+	// the descriptor is read once
+	pass, err = src.Passphrase(true)
+	require.NoError(t, err)
+	assert.Equal(t, "fd pass", pass)
+}
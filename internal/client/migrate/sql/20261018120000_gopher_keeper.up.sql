@@ -0,0 +1,10 @@
+create table storage_index
+(
+ key   TEXT not null,
+ token TEXT not null,
+ constraint storage_index_pk
+  primary key (key, token)
+);
+
+create index storage_index_token_index
+ on storage_index (token);
@@ -0,0 +1,13 @@
+create table storage_history
+(
+ key         TEXT    not null,
+ version     INTEGER not null,
+ description TEXT     DEFAULT '' not null,
+ created_at  datetime,
+ updated_at  datetime,
+ filename    text,
+ blob        BLOB,
+ saved_at    datetime DEFAULT (datetime('now', 'localtime')),
+ constraint storage_history_pk
+  primary key (key, version)
+);
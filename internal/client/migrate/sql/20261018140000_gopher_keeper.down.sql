@@ -0,0 +1,2 @@
+alter table storage
+ drop deleted_at;
@@ -0,0 +1,7 @@
+alter table storage
+ add deleted_at datetime;
+
+update storage
+set deleted_at = ifnull(updated_at, created_at)
+where blob is null
+  and filename is null;
@@ -11,12 +11,21 @@ type Common struct {
 	Key         string `json:"key" validate:"required" flag:"key,k" usage:"set your entry key-identifier"`
 	Description string `json:"description" flag:"description,d" usage:"description, will be displayed in the list of entries list"`
 	FileName    string `json:"fileName" flag:"file,f" usage:"read from file"`
+	Extra
+	// FieldArgs, SecretFieldArgs and URLArgs are the flag values of extra fields and urls, see SetExtra
+	FieldArgs       []string `json:"-" flag:"field" usage:"extra field name=value, repeat for more fields"`
+	SecretFieldArgs []string `json:"-" flag:"secret-field" usage:"extra secret field name=value, repeat for more fields"`
+	URLArgs         []string `json:"-" flag:"url" usage:"url [rule=]url, the rules: domain (default), host, prefix, exact, regexp, never, repeat for more urls"`
 }
 
 func (c *Common) Reset() {
 	c.Key = ""
 	c.Description = ""
 	c.FileName = ""
+	c.Extra = Extra{}
+	c.FieldArgs = nil
+	c.SecretFieldArgs = nil
+	c.URLArgs = nil
 }
 
 func (c *Common) GetKey() string {
@@ -42,6 +51,7 @@ type Packed struct {
 	Type     string `json:"type"`
 	Data     any    `json:"data"`
 	FileName string `json:"fileName,omitempty"`
+	Extra
 }
 
 func NewPackedBytes(m Model) ([]byte, error) {
@@ -52,8 +62,9 @@ func NewPackedBytes(m Model) ([]byte, error) {
 		}
 	}
 	p := Packed{
-		Type: GetName(m),
-		Data: m.GetPacked(),
+		Type:  GetName(m),
+		Data:  m.GetPacked(),
+		Extra: m.GetBase().Extra,
 	}
 	if m.GetFileName() != "" {
 		p.FileName = filepath.Base(m.GetFileName())
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	pb "gophKeeper/internal/proto"
@@ -15,17 +16,52 @@ type DBItem struct {
 	UpdatedAt   *time.Time `db:"updated_at" json:"updated_at"`
 	SyncAt      *time.Time `db:"sync_at" json:"sync_at"`
 	Description string     `db:"description" json:"description"`
+	// DeletedAt the time the record is moved to the trash, its payload is kept until purged
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
 }
 
+// EncryptedDescriptionPrefix
+// marks the description encrypted by the profile key, it is searched by the blind index
+const EncryptedDescriptionPrefix = "gk-enc:"
+
 type DBRecord struct {
 	DBItem
 	Blob     []byte  `db:"blob" json:"blob"`
 	Filename *string `db:"filename,omitempty"`
+	// IndexTokens blind index tokens of the encrypted description, nil if not indexed
+	IndexTokens []string `db:"-" json:"-"`
+}
+
+// DBVersion
+// the previous version of the record kept at the history on overwrite,
+// the versions of a key are numbered from 1, SavedAt is the time it was replaced
+type DBVersion struct {
+	DBRecord
+	Version int       `db:"version" json:"version"`
+	SavedAt time.Time `db:"saved_at" json:"saved_at"`
+}
+
+// HasEncryptedDescription checks if the description is encrypted
+func (d *DBItem) HasEncryptedDescription() bool {
+	return strings.HasPrefix(d.Description, EncryptedDescriptionPrefix)
+}
+
+// AssociatedData
+// binds encrypted data to the record key and the data type,
+// the blob moved to another key or labeled with another type fails the authentication
+func (d *DBItem) AssociatedData(dataType string) []byte {
+	return []byte("gophkeeper/record\x00" + d.Key + "\x00" + dataType)
 }
 
 // IsDeleted checks if the DBRecord is considered deleted.
-// A record is considered deleted if its Blob is empty and its Filename is nil.
+// A record is considered deleted if it is at the trash or it is a tombstone.
 func (d *DBRecord) IsDeleted() bool {
+	return d.DeletedAt != nil || d.IsTombstone()
+}
+
+// IsTombstone checks if the DBRecord is deleted without its payload, as purged or received by synchronization.
+// A record is a tombstone if its Blob is empty and its Filename is nil.
+func (d *DBRecord) IsTombstone() bool {
 	return len(d.Blob) == 0 && d.Filename == nil
 }
 
@@ -43,6 +79,7 @@ func (d *DBRecord) FromItemSync(p *pb.ItemSync) {
 		d.CreatedAt = p.CreatedAt.AsTime().Local()
 	}
 	d.Blob = p.Blob
+	d.IndexTokens = p.DescriptionTokens
 	d.SyncAt = &[]time.Time{time.Now()}[0]
 }
 
@@ -58,9 +95,15 @@ func (d *DBRecord) ToItemSync() (p *pb.ItemSync) {
 		Description: d.Description,
 		CreatedAt:   timestamppb.New(d.CreatedAt.Add(-time.Duration(z) * time.Second)),
 		Blob:        d.Blob,
+		// the tokens are sent if the profile keeps the index at server
+		DescriptionTokens: d.IndexTokens,
 	}
 	if d.UpdatedAt != nil {
 		p.UpdatedAt = timestamppb.New(d.UpdatedAt.Add(-time.Duration(z) * time.Second))
 	}
+	if d.DeletedAt != nil {
+		// the record at the trash is sent as the tombstone, its payload stays local
+		p.Description, p.Blob, p.DescriptionTokens = "", nil, nil
+	}
 	return
 }
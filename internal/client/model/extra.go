@@ -0,0 +1,218 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Match rules of the record urls
+const (
+	// MatchDomain the url of the same registered domain, it is the default rule
+	MatchDomain = "domain"
+	// MatchHost the url of the same host and port
+	MatchHost = "host"
+	// MatchPrefix the url starting with the record url
+	MatchPrefix = "prefix"
+	// MatchExact the same url
+	MatchExact = "exact"
+	// MatchRegexp the url matching the regular expression of the record url
+	MatchRegexp = "regexp"
+	// MatchNever the url is kept, but never matched
+	MatchNever = "never"
+)
+
+var (
+	MatchRules = []string{MatchDomain, MatchHost, MatchPrefix, MatchExact, MatchRegexp, MatchNever}
+
+	ErrExtra = errors.New("wrong extra")
+)
+
+// Field
+// the extra named field of record, the secret value is masked at output
+type Field struct {
+	Name   string `json:"name" validate:"required,max=100"`
+	Value  string `json:"value" validate:"max=5000"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// URL
+// the url of record with the rule of matching other urls, MatchDomain by default
+type URL struct {
+	URL   string `json:"url" validate:"required,max=2000"`
+	Match string `json:"match,omitempty" validate:"omitempty,oneof=domain host prefix exact regexp never"`
+}
+
+// Extra
+// the extra fields and urls of any record, they are encrypted with the record data
+type Extra struct {
+	Fields []Field `json:"fields,omitempty" validate:"dive"`
+	URLs   []URL   `json:"urls,omitempty" validate:"dive"`
+}
+
+// ParseField
+// the field of "name=value"
+func ParseField(s string, secret bool) (f Field, err error) {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		err = fmt.Errorf("%w: field %q is not name=value", ErrExtra, s)
+		return
+	}
+	return Field{Name: name, Value: value, Secret: secret}, nil
+}
+
+// ParseURL
+// the url of "[rule=]url", the url without scheme is of https, except of the regular expression
+func ParseURL(s string) (u URL, err error) {
+	s = strings.TrimSpace(s)
+	if rule, v, ok := strings.Cut(s, "="); ok && slices.Contains(MatchRules, rule) {
+		u.Match, s = rule, strings.TrimSpace(v)
+	}
+	if s == "" {
+		err = fmt.Errorf("%w: empty url", ErrExtra)
+		return
+	}
+	if u.Match == MatchRegexp {
+		if _, err = regexp.Compile(s); err != nil {
+			err = fmt.Errorf("%w: url %q: %w", ErrExtra, s, err)
+			return
+		}
+		u.URL = s
+		return
+	}
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+	var p *url.URL
+	if p, err = url.Parse(s); err != nil || Validator.Var(p.Hostname(), "required,hostname_rfc1123|ip") != nil {
+		err = fmt.Errorf("%w: url %q", ErrExtra, s)
+		return
+	}
+	u.URL = s
+	return
+}
+
+// GetMatch the match rule of url, MatchDomain by default
+func (u URL) GetMatch() string {
+	if u.Match == "" {
+		return MatchDomain
+	}
+	return u.Match
+}
+
+// Matches
+// the target url matches the url by its rule, the target without scheme is of https
+func (u URL) Matches(target string) bool {
+	target = strings.TrimSpace(target)
+	match := u.GetMatch()
+	switch match {
+	case MatchNever:
+		return false
+	case MatchRegexp:
+		re, err := regexp.Compile(u.URL)
+		return err == nil && re.MatchString(target)
+	}
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+	switch match {
+	case MatchExact:
+		return target == u.URL
+	case MatchPrefix:
+		return strings.HasPrefix(target, u.URL)
+	}
+	t, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	p, err := url.Parse(u.URL)
+	if err != nil {
+		return false
+	}
+	if match == MatchHost {
+		return strings.EqualFold(t.Host, p.Host)
+	}
+	return registeredDomain(t.Hostname()) == registeredDomain(p.Hostname())
+}
+
+// registeredDomain
+// the domain of host at the public suffix list, the ip address and unlisted host are as is
+func registeredDomain(host string) string {
+	host = strings.ToLower(host)
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if d, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return d
+	}
+	return host
+}
+
+// SetExtra
+// set the fields and urls from the flag values, the fields and urls are kept if there are no values of them
+func (c *Common) SetExtra() (err error) {
+	if len(c.FieldArgs)+len(c.SecretFieldArgs) > 0 {
+		fields := make([]Field, 0, len(c.FieldArgs)+len(c.SecretFieldArgs))
+		names := make(map[string]bool)
+		for i, s := range append(slices.Clone(c.FieldArgs), c.SecretFieldArgs...) {
+			f, er := ParseField(s, i >= len(c.FieldArgs))
+			if er == nil && names[f.Name] {
+				er = fmt.Errorf("%w: duplicate field %s", ErrExtra, f.Name)
+			}
+			if er != nil {
+				err = errors.Join(err, er)
+				continue
+			}
+			names[f.Name] = true
+			fields = append(fields, f)
+		}
+		c.Fields = fields
+	}
+	if len(c.URLArgs) > 0 {
+		urls := make([]URL, 0, len(c.URLArgs))
+		for _, s := range c.URLArgs {
+			u, er := ParseURL(s)
+			if er != nil {
+				err = errors.Join(err, er)
+				continue
+			}
+			urls = append(urls, u)
+		}
+		c.URLs = urls
+	}
+	return
+}
+
+// HasField
+// the record has the field of "name" or "name=value", the name is equal and the value is contained ignoring case
+func (e Extra) HasField(s string) bool {
+	name, value, withValue := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	for _, f := range e.Fields {
+		if !strings.EqualFold(f.Name, name) {
+			continue
+		}
+		if !withValue || strings.Contains(strings.ToLower(f.Value), strings.ToLower(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesURL
+// any url of the record matches the target url
+func (e Extra) MatchesURL(target string) bool {
+	for _, u := range e.URLs {
+		if u.Matches(target) {
+			return true
+		}
+	}
+	return false
+}
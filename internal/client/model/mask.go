@@ -0,0 +1,133 @@
+package model
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MaskChar the character of masked letters and digits
+const MaskChar = '*'
+
+// Maskable
+// data with the secret values not marked by mask tags, they are masked at output unless revealed
+type Maskable interface {
+	Mask()
+}
+
+// SecretFielder
+// data with the secret fields not marked by mask tags, the name of the first not empty one
+type SecretFielder interface {
+	SecretField() (name string, ok bool)
+}
+
+// mask the string fields of the struct pointed by v by their mask tags:
+//   - secret - the whole value is masked by the same mask
+//   - words - each word is masked, the number of words is kept
+//   - first=N,last=N - the letters and digits are masked except the first and the last N ones
+func MaskStruct(v any) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return
+	}
+	maskStruct(rv.Elem())
+}
+
+func maskStruct(rv reflect.Value) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		fv := rv.Field(i)
+		if !fv.CanSet() {
+			continue
+		}
+		if fv.Kind() == reflect.Struct {
+			maskStruct(fv)
+			continue
+		}
+		tag := rt.Field(i).Tag.Get("mask")
+		if tag == "" || fv.Kind() != reflect.String {
+			continue
+		}
+		fv.SetString(MaskValue(fv.String(), tag))
+	}
+}
+
+// MaskValue
+// the value masked by the mask tag, see MaskStruct
+func MaskValue(s, tag string) string {
+	switch tag {
+	case "secret":
+		return MaskSecret(s)
+	case "words":
+		words := strings.Fields(s)
+		for i := range words {
+			words[i] = strings.Repeat(string(MaskChar), 4)
+		}
+		return strings.Join(words, " ")
+	}
+	var first, last int
+	for _, opt := range strings.Split(tag, ",") {
+		k, v, _ := strings.Cut(opt, "=")
+		n, _ := strconv.Atoi(v)
+		switch k {
+		case "first":
+			first = n
+		case "last":
+			last = n
+		}
+	}
+	return MaskString(s, first, last)
+}
+
+// MaskString
+// the letters and digits of s are masked except the first and the last ones, the separators are kept
+func MaskString(s string, first, last int) string {
+	r := []rune(s)
+	var n int
+	for _, c := range r {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			n++
+		}
+	}
+	var i int
+	for j, c := range r {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			continue
+		}
+		if i >= first && i < n-last {
+			r[j] = MaskChar
+		}
+		i++
+	}
+	return string(r)
+}
+
+// MaskSecret
+// the secret of any length is masked by the same mask, empty is kept
+func MaskSecret(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.Repeat(string(MaskChar), 8)
+}
+
+// SecretField
+// the JSON name of the first not empty string field with the mask tag of the struct pointed by v
+func SecretField(v any) (name string, ok bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return
+	}
+	rv = rv.Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if f.Tag.Get("mask") == "" || rv.Field(i).Kind() != reflect.String || rv.Field(i).String() == "" {
+			continue
+		}
+		if name, _, _ = strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+			return name, true
+		}
+	}
+	return "", false
+}
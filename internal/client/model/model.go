@@ -4,14 +4,44 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 )
 
-var models = map[string]any{}
+var (
+	models       = map[string]any{}
+	constructors = map[string]func() Model{}
+)
 
 type FromFile interface {
 	DataFromFile() error
 }
 
+// Streamable
+// model data from the file can be stored as a stream, not loaded into memory
+type Streamable interface {
+	Model
+	// StreamData returns the data stored before the file content of the size
+	StreamData(size int64) Data
+}
+
+// Content
+// data with the raw content, it can be extracted to file
+type Content interface {
+	GetContent() []byte
+}
+
+// Fielded
+// data with the fields not of its struct, as of the template records
+type Fielded interface {
+	Get(name string) (v any, ok bool)
+}
+
+// Expiring
+// data with the expiry time, the zero time is of no expiry
+type Expiring interface {
+	ExpiresAt() time.Time
+}
+
 type Data interface {
 	GetPacked() any
 	GetDst() any
@@ -39,8 +69,24 @@ type Sanitisable interface {
 	Sanitize()
 }
 
-func RegisterModel(model Data) {
-	models[GetName(model)] = model
+// RegisterModel
+// register the data type by its name with the constructor of its record model,
+// the type without constructor has its model built otherwise, as the record of template
+func RegisterModel[M Model](model Data, newModel func() M) {
+	name := GetName(model)
+	models[name] = model
+	if newModel != nil {
+		constructors[name] = func() Model { return newModel() }
+	}
+}
+
+// GetNewModel
+// the new empty record model of the data type name
+func GetNewModel(name string) (Model, error) {
+	if newModel, ok := constructors[name]; ok {
+		return newModel(), nil
+	}
+	return nil, fmt.Errorf("model not found: %s", name)
 }
 
 func GetNewDataModel(name string) (Data, error) {
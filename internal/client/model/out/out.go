@@ -2,6 +2,10 @@ package out
 
 import (
 	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+
 	"gophKeeper/internal/client/model"
 )
 
@@ -15,10 +19,12 @@ func (d *dTypeRaw) UnmarshalJSON(b []byte) (err error) {
 type dRaw struct {
 	Type string   `json:"type"`
 	Data dTypeRaw `json:"data"`
+	model.Extra
 }
 
 type Item struct {
 	Data model.Data `json:"data"`
+	model.Extra
 	model.DBItem
 }
 
@@ -31,6 +37,7 @@ func (i *Item) UnmarshalJSON(b []byte) (err error) {
 	if err != nil {
 		return
 	}
+	i.Extra = t.Extra
 	err = json.Unmarshal(t.Data, &i.Data)
 
 	return
@@ -39,4 +46,97 @@ func (i *Item) UnmarshalJSON(b []byte) (err error) {
 type List struct {
 	Items []model.DBItem `json:"items"`
 	Total uint64         `json:"total"`
+	// Skipped the keys of the records failed to decrypt by the search of the decrypted data
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// Masked
+// the copy of item with the secret values of data and extra fields masked for output,
+// the data fields are masked by their mask tags, see model.MaskStruct
+func (i Item) Masked() Item {
+	if i.Data != nil {
+		v := reflect.New(reflect.TypeOf(i.Data).Elem())
+		v.Elem().Set(reflect.ValueOf(i.Data).Elem())
+		i.Data = v.Interface().(model.Data)
+		model.MaskStruct(i.Data.GetDst())
+		if d, ok := i.Data.(model.Maskable); ok {
+			d.Mask()
+		}
+	}
+	i.Fields = slices.Clone(i.Fields)
+	for n, f := range i.Fields {
+		if f.Secret {
+			i.Fields[n].Value = model.MaskSecret(f.Value)
+		}
+	}
+	return i
+}
+
+// Field
+// the value of data field by its JSON name, of the field of data by name or of the extra field,
+// the value which is not a string is in JSON
+func (i Item) Field(name string) (value string, ok bool) {
+	var v any
+	if d, isFielded := i.Data.(model.Fielded); isFielded {
+		v, ok = d.Get(name)
+	}
+	if !ok && i.Data != nil {
+		v, ok = jsonField(i.Data.GetDst(), name)
+	}
+	if !ok {
+		for _, f := range i.Fields {
+			if f.Name == name {
+				return f.Value, true
+			}
+		}
+		return
+	}
+	if s, isString := v.(string); isString {
+		return s, true
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	// the JSON string as of time is unquoted
+	if err = json.Unmarshal(b, &value); err != nil {
+		value = string(b)
+	}
+	return value, true
+}
+
+// SecretField
+// the name of the first secret field of data, or of the first secret extra field
+func (i Item) SecretField() (name string, ok bool) {
+	if d, isSecretFielder := i.Data.(model.SecretFielder); isSecretFielder {
+		name, ok = d.SecretField()
+	} else if i.Data != nil {
+		name, ok = model.SecretField(i.Data.GetDst())
+	}
+	if ok {
+		return
+	}
+	for _, f := range i.Fields {
+		if f.Secret && f.Value != "" {
+			return f.Name, true
+		}
+	}
+	return
+}
+
+// jsonField
+// the value of the struct field pointed by v by its JSON name
+func jsonField(v any, name string) (value any, ok bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return
+	}
+	rv = rv.Elem()
+	for n := 0; n < rv.NumField(); n++ {
+		tag, _, _ := strings.Cut(rv.Type().Field(n).Tag.Get("json"), ",")
+		if tag == name && tag != "-" && rv.Field(n).CanInterface() {
+			return rv.Field(n).Interface(), true
+		}
+	}
+	return
 }
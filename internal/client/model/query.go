@@ -10,6 +10,12 @@ type ListQuery struct {
 	Offset      uint64 `json:"offset" validate:"omitempty" flag:"offset,o" usage:"set offset"`
 	OrderBy     string `json:"orderBy" validate:"omitempty,oneof=key created_at updated_at sync_at 'key desc' 'created_at desc' 'updated_at desc' 'sync_at desc'" flag:"order-by,b" usage:"set order by"`
 	Deleted     bool   `json:"deleted" flag:"deleted" usage:"show deleted"`
+	// Field, URL and Expiring are searched at the decrypted records
+	Field    string `json:"-" validate:"omitempty,max=5000" flag:"field" usage:"search by extra field name or name=value"`
+	URL      string `json:"-" validate:"omitempty,max=2000" flag:"url" usage:"search the records with urls matching the url"`
+	Expiring string `json:"-" validate:"omitempty,max=20" flag:"expiring" usage:"search the records expired or expiring in the time, 30d or 12h"`
+	// DescriptionTokens blind index tokens of Description words, encrypted descriptions are searched by them
+	DescriptionTokens []string `json:"-" validate:"omitempty,max=100"`
 }
 
 func (m *ListQuery) Validate() (err error) {
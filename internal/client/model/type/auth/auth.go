@@ -24,7 +24,7 @@ func New() *Model {
 }
 
 func init() {
-	model.RegisterModel(&Data{})
+	model.RegisterModel(&Data{}, New)
 }
 
 func (m *Model) Reset() {
@@ -57,7 +57,7 @@ func (m *Model) GetDst() any {
 
 type Data struct {
 	Login    string `json:"login" flag:"login,l" default:"" usage:"login field"`
-	Password string `json:"password" flag:"password,p" default:"" usage:"password field"`
+	Password string `json:"password" flag:"password,p" default:"" usage:"password field" mask:"secret"`
 }
 
 func (m *Data) GetPacked() any {
@@ -0,0 +1,142 @@
+package bank
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"gophKeeper/internal/client/model"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	_ model.Model = (*Model)(nil)
+	_ model.Data  = (*Data)(nil)
+
+	ibanRegexp = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+)
+
+func init() {
+	model.RegisterModel(&Data{}, New)
+	validators := map[string]func(s string) bool{
+		"iban":     ValidIBAN,
+		"bank_bic": ValidBIC,
+	}
+	for k, v := range validators {
+		err := model.Validator.RegisterValidation(k, func(fl validator.FieldLevel) bool {
+			return v(fl.Field().String())
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+type Model struct {
+	Data *Data `json:"data" validate:"required"`
+	model.Common
+}
+
+func New() *Model {
+	return &Model{
+		Common: model.Common{},
+		Data:   &Data{},
+	}
+}
+
+func (m *Model) Reset() {
+	m.Common.Reset()
+	m.Data.Reset()
+}
+
+func (m *Model) GetKey() string {
+	if m.Key == "" {
+		m.Key = fmt.Sprintf("%s-%s", model.GetName(m), time.Now().Format("2006-01-02-15-04-05"))
+	}
+	return m.Key
+}
+
+func (m *Model) Validate(fields ...string) error {
+	if len(fields) == 0 {
+		return model.Validator.Struct(m)
+	} else {
+		return model.Validator.StructPartial(m, fields...)
+	}
+}
+
+func (m *Model) GetPacked() any {
+	return m.Data.GetPacked()
+}
+
+func (m *Model) GetDst() any {
+	return m.Data.GetDst()
+}
+
+type Data struct {
+	IBAN string `json:"iban" validate:"required,iban" flag:"iban,i" default:"" usage:"international bank account number DE89 3704 0044 0532 0130 00" mask:"first=4,last=4"`
+	BIC  string `json:"bic,omitempty" validate:"omitempty,bank_bic" flag:"bic,b" default:"" usage:"bank identifier code, SWIFT"`
+	Name string `json:"name,omitempty" validate:"omitempty,max=200" flag:"owner,o" default:"" usage:"account holder    Firstname Lastname"`
+	Bank string `json:"bank,omitempty" validate:"omitempty,max=200" flag:"bank" default:"" usage:"bank name"`
+}
+
+func (m *Data) GetPacked() any {
+	return m
+}
+
+func (m *Data) GetDst() any {
+	return m
+}
+
+func (m *Data) Reset() {
+	*m = Data{}
+}
+
+// Sanitize
+// the IBAN is upper case by groups of 4 characters, the BIC is upper case
+func (m *Data) Sanitize() {
+	iban := compact(m.IBAN)
+	groups := make([]string, 0, len(iban)/4+1)
+	for len(iban) > 4 {
+		groups = append(groups, iban[:4])
+		iban = iban[4:]
+	}
+	m.IBAN = strings.Join(append(groups, iban), " ")
+	m.BIC = compact(m.BIC)
+	m.Name = strings.TrimSpace(m.Name)
+	m.Bank = strings.TrimSpace(m.Bank)
+}
+
+// compact
+// the upper case string without spaces
+func compact(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+}
+
+// ValidIBAN
+// the IBAN of any case with spaces has the valid format and the check digits by ISO 13616, mod 97
+func ValidIBAN(s string) bool {
+	s = compact(s)
+	if !ibanRegexp.MatchString(s) {
+		return false
+	}
+	var digits strings.Builder
+	for _, r := range s[4:] + s[:4] {
+		if r >= 'A' && r <= 'Z' {
+			digits.WriteString(fmt.Sprint(r - 'A' + 10))
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && n.Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// ValidBIC
+// the BIC has the valid format by ISO 9362 and the country code
+func ValidBIC(s string) bool {
+	s = compact(s)
+	return model.Validator.Var(s, "bic") == nil && model.Validator.Var(s[4:6], "iso3166_1_alpha2") == nil
+}
@@ -0,0 +1,57 @@
+package _type
+
+import (
+	"testing"
+
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/type/bank"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBank(t *testing.T) {
+	for _, iban := range []string{"DE89370400440532013000", "de89 3704 0044 0532 0130 00", "GB82WEST12345698765432", "NL91ABNA0417164300"} {
+		require.True(t, bank.ValidIBAN(iban), iban)
+	}
+	for _, iban := range []string{"", "DE89370400440532013001", "DE8937040044", "1289370400440532013000", "DE89-3704-0044-0532-0130-00"} {
+		require.False(t, bank.ValidIBAN(iban), iban)
+	}
+	for _, bic := range []string{"COBADEFFXXX", "deutdeff", "NWBK GB2L"} {
+		require.True(t, bank.ValidBIC(bic), bic)
+	}
+	for _, bic := range []string{"", "COBA", "COBAXXFF", "COBADEFFXX"} {
+		require.False(t, bank.ValidBIC(bic), bic)
+	}
+
+	tests := []struct {
+		name    string
+		data    bank.Data
+		wantErr bool
+	}{
+		{name: "account", data: bank.Data{IBAN: "de89 3704 0044 0532 0130 00", BIC: "cobadeffxxx", Name: "Max Space"}},
+		{name: "no bic", data: bank.Data{IBAN: "GB82WEST12345698765432"}},
+		{name: "no iban", data: bank.Data{BIC: "COBADEFFXXX"}, wantErr: true},
+		{name: "wrong check digits", data: bank.Data{IBAN: "DE88370400440532013000"}, wantErr: true},
+		{name: "wrong bic", data: bank.Data{IBAN: "DE89370400440532013000", BIC: "COBA1EFF"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.data
+			data.Sanitize()
+			m := &bank.Model{Common: model.Common{Key: "some account"}, Data: &data}
+			err := m.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	d := &bank.Data{IBAN: "de89370400440532013000", BIC: " cobadeffxxx"}
+	d.Sanitize()
+	require.Equal(t, "DE89 3704 0044 0532 0130 00", d.IBAN)
+	require.Equal(t, "COBADEFFXXX", d.BIC)
+	model.MaskStruct(d)
+	require.Equal(t, "DE89 **** **** **** **30 00", d.IBAN)
+}
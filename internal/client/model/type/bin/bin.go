@@ -8,12 +8,14 @@ import (
 )
 
 var (
-	_ model.Model = (*Model)(nil)
-	_ model.Data  = (*Data)(nil)
+	_ model.Model      = (*Model)(nil)
+	_ model.Streamable = (*Model)(nil)
+	_ model.Data       = (*Data)(nil)
+	_ model.Content    = (*Data)(nil)
 )
 
 func init() {
-	model.RegisterModel(&Data{})
+	model.RegisterModel(&Data{}, New)
 }
 
 type Model struct {
@@ -44,6 +46,12 @@ func (m *Model) DataFromFile() (err error) {
 	return
 }
 
+// StreamData
+// the file content is not loaded, only its size is kept with data
+func (m *Model) StreamData(size int64) model.Data {
+	return &Data{Size: size}
+}
+
 func (m *Model) GetKey() string {
 	if m.Key == "" {
 		m.Key = fmt.Sprintf("%s-%s", model.GetName(m), time.Now().Format("2006-01-02-15-04-05"))
@@ -52,6 +60,10 @@ func (m *Model) GetKey() string {
 }
 
 func (m *Model) Validate(_ ...string) error {
+	if m.FileName != "" {
+		// the content is read from file at save
+		return model.Validator.StructExcept(m, "Data.Bin")
+	}
 	return model.Validator.Struct(m)
 }
 
@@ -65,6 +77,8 @@ func (m *Model) GetDst() any {
 
 type Data struct {
 	Bin []byte `json:"bin" validate:"required"`
+	// Size of the content stored as a stream, Bin is empty then
+	Size int64 `json:"size,omitempty"`
 }
 
 func (m *Data) GetPacked() any {
@@ -75,6 +89,11 @@ func (m *Data) GetDst() any {
 	return m
 }
 
+func (m *Data) GetContent() []byte {
+	return m.Bin
+}
+
 func (m *Data) Reset() {
 	(*m).Bin = nil
+	(*m).Size = 0
 }
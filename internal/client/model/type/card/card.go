@@ -23,7 +23,7 @@ var (
 )
 
 func init() {
-	model.RegisterModel(&Data{})
+	model.RegisterModel(&Data{}, New)
 	validators := map[string]string{
 		"credit_card_exp_date": expDateRegexp,
 		"credit_card_cvv":      cvvRegexp,
@@ -81,9 +81,9 @@ func (m *Model) GetDst() any {
 }
 
 type Data struct {
-	Number string `json:"number" validate:"required,credit_card" flag:"num,n" default:"" usage:"long card number 0000-0000-0000-0000"`
+	Number string `json:"number" validate:"required,credit_card" flag:"num,n" default:"" usage:"long card number 0000-0000-0000-0000" mask:"last=4"`
 	Exp    string `json:"exp" validate:"omitempty,credit_card_exp_date" flag:"exp,e" default:"" usage:"expiry           MM/YY"`
-	CVV    string `json:"cvv,omitempty" validate:"omitempty,credit_card_cvv" flag:"cvv,c" default:"" usage:"cvv value        000"`
+	CVV    string `json:"cvv,omitempty" validate:"omitempty,credit_card_cvv" flag:"cvv,c" default:"" usage:"cvv value        000" mask:"secret"`
 	Name   string `json:"name,omitempty" validate:"omitempty" flag:"owner,o" default:"" usage:"owner, card holder     Firstname Lastname"`
 }
 
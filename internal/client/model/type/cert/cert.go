@@ -0,0 +1,259 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gophKeeper/internal/client/model"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	_ model.Model    = (*Model)(nil)
+	_ model.Data     = (*Data)(nil)
+	_ model.Expiring = (*Data)(nil)
+
+	ErrNoCertificate = errors.New("no certificate")
+)
+
+func init() {
+	model.RegisterModel(&Data{}, New)
+	err := model.Validator.RegisterValidation("cert_chain", func(fl validator.FieldLevel) bool {
+		_, err := Chain(fl.Field().String())
+		return err == nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	// the private key must match the leaf certificate
+	err = model.Validator.RegisterValidation("cert_key", func(fl validator.FieldLevel) bool {
+		var certificate string
+		if c := fl.Parent().FieldByName("Certificate"); c.IsValid() {
+			certificate = c.String()
+		}
+		_, err := tls.X509KeyPair([]byte(certificate), []byte(fl.Field().String()))
+		return err == nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type Model struct {
+	Data *Data `json:"data" validate:"required"`
+	model.Common
+}
+
+func New() *Model {
+	return &Model{
+		Common: model.Common{},
+		Data:   &Data{},
+	}
+}
+
+func (m *Model) Reset() {
+	m.Common.Reset()
+	m.Data.Reset()
+}
+
+// DataFromFile
+// the certificates and the private key are read from the PEM bundle or the DER certificate of the file,
+// the private key is read from the key file too
+func (m *Model) DataFromFile() (err error) {
+	if m.Data == nil {
+		m.Data = &Data{}
+	}
+	if m.FileName != "" {
+		var b []byte
+		if b, err = os.ReadFile(m.FileName); err != nil {
+			return
+		}
+		var certificate, key string
+		if certificate, key, err = SplitBundle(b); err != nil {
+			return
+		}
+		m.Data.Certificate = certificate
+		if key != "" && m.Data.PrivateKey == "" {
+			m.Data.PrivateKey = key
+		}
+	}
+	if m.Data.KeyFile != "" {
+		var b []byte
+		if b, err = os.ReadFile(m.Data.KeyFile); err != nil {
+			return
+		}
+		m.Data.PrivateKey = string(b)
+	}
+	m.Data.Sanitize()
+	return
+}
+
+func (m *Model) GetKey() string {
+	if m.Key == "" {
+		m.Key = fmt.Sprintf("%s-%s", model.GetName(m), time.Now().Format("2006-01-02-15-04-05"))
+	}
+	return m.Key
+}
+
+// Validate
+// the files are read before, so the certificates and key from files are validated too
+func (m *Model) Validate(fields ...string) (err error) {
+	if err = m.DataFromFile(); err != nil {
+		return
+	}
+	if len(fields) == 0 {
+		return model.Validator.Struct(m)
+	} else {
+		return model.Validator.StructPartial(m, fields...)
+	}
+}
+
+func (m *Model) GetPacked() any {
+	return m.Data.GetPacked()
+}
+
+func (m *Model) GetDst() any {
+	return m.Data.GetDst()
+}
+
+type Data struct {
+	Certificate string `json:"certificate" validate:"required,cert_chain" flag:"cert,c" default:"" usage:"certificate chain in PEM, the leaf first, -f reads the PEM bundle or DER certificate from file"`
+	PrivateKey  string `json:"private_key,omitempty" validate:"omitempty,cert_key" flag:"private-key" default:"" usage:"private key in PEM, it must match the leaf certificate" mask:"secret"`
+	KeyFile     string `json:"-" flag:"key-file" default:"" usage:"read the private key from file"`
+	// Subject, SANs, Issuer, NotBefore, NotAfter and Fingerprint are of the leaf certificate
+	Subject     string    `json:"subject,omitempty"`
+	SANs        []string  `json:"sans,omitempty"`
+	Issuer      string    `json:"issuer,omitempty"`
+	NotBefore   time.Time `json:"not_before,omitempty"`
+	NotAfter    time.Time `json:"not_after,omitempty"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+}
+
+func (m *Data) GetPacked() any {
+	return m
+}
+
+func (m *Data) GetDst() any {
+	return m
+}
+
+func (m *Data) Reset() {
+	*m = Data{}
+}
+
+// ExpiresAt the expiry time of the leaf certificate
+func (m *Data) ExpiresAt() time.Time {
+	return m.NotAfter
+}
+
+// Sanitize
+// normalize the PEM blocks and set the details of the leaf certificate,
+// they are cleared if the chain is not valid
+func (m *Data) Sanitize() {
+	m.Certificate = normalizePEM(m.Certificate)
+	m.PrivateKey = normalizePEM(m.PrivateKey)
+	m.Subject, m.SANs, m.Issuer, m.Fingerprint = "", nil, "", ""
+	m.NotBefore, m.NotAfter = time.Time{}, time.Time{}
+	chain, err := Chain(m.Certificate)
+	if err != nil {
+		return
+	}
+	leaf := chain[0]
+	m.Subject = leaf.Subject.String()
+	m.Issuer = leaf.Issuer.String()
+	m.SANs = append(m.SANs, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		m.SANs = append(m.SANs, ip.String())
+	}
+	m.SANs = append(m.SANs, leaf.EmailAddresses...)
+	for _, u := range leaf.URIs {
+		m.SANs = append(m.SANs, u.String())
+	}
+	m.NotBefore, m.NotAfter = leaf.NotBefore.UTC(), leaf.NotAfter.UTC()
+	sum := sha256.Sum256(leaf.Raw)
+	m.Fingerprint = hex.EncodeToString(sum[:])
+}
+
+// normalizePEM
+// the line ends of PEM text are normalized, it ends with the line end
+func normalizePEM(s string) string {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+	if s == "" {
+		return s
+	}
+	return s + "\n"
+}
+
+// Chain
+// the certificates of PEM text, the leaf is the first one
+func Chain(certificate string) (chain []*x509.Certificate, err error) {
+	rest := []byte(certificate)
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected PEM block %s", block.Type)
+		}
+		var c *x509.Certificate
+		if c, err = x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, err
+		}
+		chain = append(chain, c)
+	}
+	if len(chain) == 0 {
+		err = ErrNoCertificate
+	}
+	return
+}
+
+// SplitBundle
+// the certificates and private key of the PEM bundle, the DER certificates are converted to PEM.
+// The other PEM blocks are skipped
+func SplitBundle(b []byte) (certificate, key string, err error) {
+	var certs, keys bytes.Buffer
+	rest := b
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		switch {
+		case block.Type == "CERTIFICATE":
+			err = pem.Encode(&certs, block)
+		case strings.HasSuffix(block.Type, "PRIVATE KEY") && keys.Len() == 0:
+			err = pem.Encode(&keys, block)
+		}
+		if err != nil {
+			return
+		}
+	}
+	if certs.Len() == 0 && keys.Len() == 0 {
+		var chain []*x509.Certificate
+		if chain, err = x509.ParseCertificates(b); err != nil {
+			err = errors.Join(ErrNoCertificate, err)
+			return
+		}
+		for _, c := range chain {
+			if err = pem.Encode(&certs, &pem.Block{Type: "CERTIFICATE", Bytes: c.Raw}); err != nil {
+				return
+			}
+		}
+	}
+	if certs.Len() == 0 {
+		err = ErrNoCertificate
+		return
+	}
+	return certs.String(), keys.String(), nil
+}
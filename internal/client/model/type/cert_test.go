@@ -0,0 +1,136 @@
+package _type
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/type/cert"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testCert
+// the PEM of the leaf certificate issued by the test CA, expiring at notAfter, and the PEM of its key
+func testCert(t *testing.T, notAfter time.Time) (leafPEM, caPEM, keyPEM string, leafDER []byte) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, caKey.Public(), caKey)
+	require.NoError(t, err)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "example.com", Organization: []string{"Example"}},
+		DNSNames:     []string{"example.com", "www.example.com"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour).Truncate(time.Second),
+		NotAfter:     notAfter.Truncate(time.Second),
+	}
+	leafDER, err = x509.CreateCertificate(rand.Reader, leaf, ca, key.Public(), caKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	leafPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}))
+	caPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	return
+}
+
+func TestCert(t *testing.T) {
+	notAfter := time.Now().Add(10 * 24 * time.Hour).UTC().Truncate(time.Second)
+	leafPEM, caPEM, keyPEM, leafDER := testCert(t, notAfter)
+	_, _, otherKeyPEM, _ := testCert(t, notAfter)
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	t.Run("bundle", func(t *testing.T) {
+		m := cert.New()
+		m.Key = "some cert"
+		m.FileName = write("bundle.pem", strings.ReplaceAll(leafPEM+caPEM+keyPEM, "\n", "\r\n"))
+		require.NoError(t, m.Validate())
+		require.Equal(t, leafPEM+caPEM, m.Data.Certificate)
+		require.Equal(t, keyPEM, m.Data.PrivateKey)
+		require.Equal(t, "CN=example.com,O=Example", m.Data.Subject)
+		require.Equal(t, "CN=Test CA", m.Data.Issuer)
+		require.Equal(t, []string{"example.com", "www.example.com", "127.0.0.1"}, m.Data.SANs)
+		require.Equal(t, notAfter, m.Data.NotAfter)
+		require.Equal(t, notAfter, m.Data.ExpiresAt())
+		require.Len(t, m.Data.Fingerprint, 64)
+
+		chain, err := cert.Chain(m.Data.Certificate)
+		require.NoError(t, err)
+		require.Len(t, chain, 2)
+	})
+
+	t.Run("der with key file", func(t *testing.T) {
+		m := cert.New()
+		m.Key = "some cert"
+		m.FileName = write("cert.der", string(leafDER))
+		m.Data.KeyFile = write("key.pem", keyPEM)
+		require.NoError(t, m.Validate())
+		require.Equal(t, leafPEM, m.Data.Certificate)
+		require.Equal(t, keyPEM, m.Data.PrivateKey)
+		require.Equal(t, notAfter, m.Data.NotAfter)
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			data    cert.Data
+			wantErr bool
+		}{
+			{name: "certificate only", data: cert.Data{Certificate: leafPEM}},
+			{name: "certificate with key", data: cert.Data{Certificate: leafPEM, PrivateKey: keyPEM}},
+			{name: "other key", data: cert.Data{Certificate: leafPEM, PrivateKey: otherKeyPEM}, wantErr: true},
+			{name: "key of not leaf", data: cert.Data{Certificate: caPEM + leafPEM, PrivateKey: keyPEM}, wantErr: true},
+			{name: "no certificate", data: cert.Data{PrivateKey: keyPEM}, wantErr: true},
+			{name: "key as certificate", data: cert.Data{Certificate: keyPEM}, wantErr: true},
+			{name: "not a certificate", data: cert.Data{Certificate: "not a certificate"}, wantErr: true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				data := tt.data
+				m := &cert.Model{Common: model.Common{Key: "some cert"}, Data: &data}
+				err := m.Validate()
+				if tt.wantErr {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				require.Equal(t, "CN=example.com,O=Example", m.Data.Subject)
+			})
+		}
+	})
+
+	t.Run("not a bundle", func(t *testing.T) {
+		_, _, err := cert.SplitBundle([]byte("not a certificate"))
+		require.ErrorIs(t, err, cert.ErrNoCertificate)
+		_, _, err = cert.SplitBundle([]byte(keyPEM))
+		require.ErrorIs(t, err, cert.ErrNoCertificate)
+	})
+}
@@ -0,0 +1,77 @@
+package _type
+
+import (
+	"testing"
+
+	"gophKeeper/internal/client/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtraURL(t *testing.T) {
+	parse := func(s string) model.URL {
+		u, err := model.ParseURL(s)
+		require.NoError(t, err, s)
+		return u
+	}
+	require.Equal(t, model.URL{URL: "https://example.com"}, parse(" example.com "))
+	require.Equal(t, model.URL{URL: "http://a.example.com/login", Match: model.MatchPrefix}, parse("prefix=http://a.example.com/login"))
+	require.Equal(t, model.URL{URL: `^https://a\.example\.com/`, Match: model.MatchRegexp}, parse(`regexp=^https://a\.example\.com/`))
+	for _, s := range []string{"", "host=", "https://", "bogus=(", "regexp=("} {
+		_, err := model.ParseURL(s)
+		require.ErrorIs(t, err, model.ErrExtra, s)
+	}
+
+	tests := []struct {
+		url    string
+		target string
+		want   bool
+	}{
+		{url: "example.com", target: "https://www.example.com/login", want: true},
+		{url: "a.example.co.uk", target: "b.example.co.uk", want: true},
+		{url: "a.example.co.uk", target: "other.co.uk"},
+		{url: "example.com", target: "example.org"},
+		{url: "127.0.0.1", target: "http://127.0.0.1:8080/", want: true},
+		{url: "host=a.example.com:8443", target: "https://A.example.com:8443/login", want: true},
+		{url: "host=a.example.com:8443", target: "https://a.example.com/login"},
+		{url: "prefix=https://example.com/app", target: "https://example.com/app/login", want: true},
+		{url: "prefix=https://example.com/app", target: "https://example.com/"},
+		{url: "exact=https://example.com/app", target: "https://example.com/app", want: true},
+		{url: "exact=https://example.com/app", target: "https://example.com/app/"},
+		{url: `regexp=^https://(a|b)\.example\.com/`, target: "https://b.example.com/x", want: true},
+		{url: `regexp=^https://(a|b)\.example\.com/`, target: "https://c.example.com/x"},
+		{url: "never=example.com", target: "https://example.com"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, parse(tt.url).Matches(tt.target), tt.url+" "+tt.target)
+	}
+}
+
+func TestExtraFields(t *testing.T) {
+	c := model.Common{
+		FieldArgs:       []string{"site=example.com", "empty="},
+		SecretFieldArgs: []string{"pin = 1234"},
+	}
+	require.NoError(t, c.SetExtra())
+	require.Equal(t, []model.Field{
+		{Name: "site", Value: "example.com"},
+		{Name: "empty"},
+		{Name: "pin", Value: " 1234", Secret: true},
+	}, c.Fields)
+	require.True(t, c.HasField("PIN"))
+	require.True(t, c.HasField("site=EXAMPLE"))
+	require.False(t, c.HasField("site=example.org"))
+	require.False(t, c.HasField("other"))
+
+	// the fields are kept without the flag values
+	c.FieldArgs, c.SecretFieldArgs = nil, nil
+	require.NoError(t, c.SetExtra())
+	require.Len(t, c.Fields, 3)
+
+	c.FieldArgs = []string{"=value"}
+	require.ErrorIs(t, c.SetExtra(), model.ErrExtra)
+
+	c.Reset()
+	require.Empty(t, c.Fields)
+	require.Empty(t, c.FieldArgs)
+}
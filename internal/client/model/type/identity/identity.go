@@ -0,0 +1,115 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gophKeeper/internal/client/model"
+)
+
+// Types of identity documents
+const (
+	TypePassport = "passport"
+	TypeIDCard   = "id_card"
+
+	// DateLayout the layout of issue and expiry dates
+	DateLayout = "2006-01-02"
+)
+
+var (
+	_ model.Model    = (*Model)(nil)
+	_ model.Data     = (*Data)(nil)
+	_ model.Expiring = (*Data)(nil)
+)
+
+func init() {
+	model.RegisterModel(&Data{}, New)
+}
+
+type Model struct {
+	Data *Data `json:"data" validate:"required"`
+	model.Common
+}
+
+func New() *Model {
+	return &Model{
+		Common: model.Common{},
+		Data:   &Data{Type: TypePassport},
+	}
+}
+
+func (m *Model) Reset() {
+	m.Common.Reset()
+	m.Data.Reset()
+}
+
+func (m *Model) GetKey() string {
+	if m.Key == "" {
+		m.Key = fmt.Sprintf("%s-%s", model.GetName(m), time.Now().Format("2006-01-02-15-04-05"))
+	}
+	return m.Key
+}
+
+// Validate
+// the expiry date must be after the issue date
+func (m *Model) Validate(fields ...string) (err error) {
+	if len(fields) == 0 {
+		err = model.Validator.Struct(m)
+	} else {
+		err = model.Validator.StructPartial(m, fields...)
+	}
+	if err == nil && m.Data.Issued != "" && m.Data.Expires != "" && m.Data.Expires <= m.Data.Issued {
+		err = errors.New("the expiry date must be after the issue date")
+	}
+	return
+}
+
+func (m *Model) GetPacked() any {
+	return m.Data.GetPacked()
+}
+
+func (m *Model) GetDst() any {
+	return m.Data.GetDst()
+}
+
+type Data struct {
+	Type      string `json:"type" validate:"required,oneof=passport id_card" flag:"type,t" default:"passport" usage:"document type: passport or id_card"`
+	Number    string `json:"number" validate:"required,max=30" flag:"num,n" default:"" usage:"document number" mask:"last=4"`
+	Country   string `json:"country" validate:"required,iso3166_1_alpha2|iso3166_1_alpha3" flag:"country,c" default:"" usage:"issuing country code, DE or DEU"`
+	Name      string `json:"name,omitempty" validate:"omitempty,max=200" flag:"owner,o" default:"" usage:"holder    Firstname Lastname"`
+	Authority string `json:"authority,omitempty" validate:"omitempty,max=200" flag:"authority,a" default:"" usage:"issuing authority"`
+	Issued    string `json:"issued,omitempty" validate:"omitempty,datetime=2006-01-02" flag:"issued,i" default:"" usage:"issue date  YYYY-MM-DD"`
+	Expires   string `json:"expires,omitempty" validate:"omitempty,datetime=2006-01-02" flag:"expires,e" default:"" usage:"expiry date YYYY-MM-DD"`
+}
+
+func (m *Data) GetPacked() any {
+	return m
+}
+
+func (m *Data) GetDst() any {
+	return m
+}
+
+func (m *Data) Reset() {
+	*m = Data{Type: TypePassport}
+}
+
+// Sanitize
+// the number and country are upper case, the spaces of number are removed
+func (m *Data) Sanitize() {
+	m.Type = strings.ToLower(strings.TrimSpace(m.Type))
+	m.Number = strings.ToUpper(strings.Join(strings.Fields(m.Number), ""))
+	m.Country = strings.ToUpper(strings.TrimSpace(m.Country))
+	m.Name = strings.TrimSpace(m.Name)
+	m.Authority = strings.TrimSpace(m.Authority)
+	m.Issued = strings.TrimSpace(m.Issued)
+	m.Expires = strings.TrimSpace(m.Expires)
+}
+
+// ExpiresAt the expiry date, zero if it is not set
+func (m *Data) ExpiresAt() time.Time {
+	t, _ := time.Parse(DateLayout, m.Expires)
+	return t
+}
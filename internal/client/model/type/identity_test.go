@@ -0,0 +1,55 @@
+package _type
+
+import (
+	"testing"
+	"time"
+
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/type/identity"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentity(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    identity.Data
+		wantErr bool
+	}{
+		{name: "passport", data: identity.Data{Type: "passport", Number: "c01x 00t47", Country: "deu", Name: "Erika Mustermann", Issued: "2020-01-31", Expires: "2030-01-30"}},
+		{name: "id card", data: identity.Data{Type: "id_card", Number: "T22000129", Country: "DE"}},
+		{name: "wrong type", data: identity.Data{Type: "visa", Number: "T22000129", Country: "DE"}, wantErr: true},
+		{name: "no number", data: identity.Data{Type: "passport", Country: "DE"}, wantErr: true},
+		{name: "wrong country", data: identity.Data{Type: "passport", Number: "T22000129", Country: "XX"}, wantErr: true},
+		{name: "wrong date", data: identity.Data{Type: "passport", Number: "T22000129", Country: "DE", Expires: "30.01.2030"}, wantErr: true},
+		{name: "expires before issued", data: identity.Data{Type: "passport", Number: "T22000129", Country: "DE", Issued: "2030-01-30", Expires: "2020-01-31"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.data
+			data.Sanitize()
+			m := &identity.Model{Common: model.Common{Key: "some document"}, Data: &data}
+			err := m.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	d := &identity.Data{Type: "passport", Number: "c01x 00t47", Country: "deu", Expires: "2030-01-30"}
+	d.Sanitize()
+	require.Equal(t, "C01X00T47", d.Number)
+	require.Equal(t, "DEU", d.Country)
+	require.Equal(t, time.Date(2030, 1, 30, 0, 0, 0, 0, time.UTC), d.ExpiresAt())
+	model.MaskStruct(d)
+	require.Equal(t, "*****0T47", d.Number)
+	require.True(t, (&identity.Data{}).ExpiresAt().IsZero())
+
+	m := identity.New()
+	m.Data.Number = "T22000129"
+	m.Reset()
+	require.Equal(t, identity.TypePassport, m.Data.Type)
+	require.Empty(t, m.Data.Number)
+}
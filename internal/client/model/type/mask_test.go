@@ -0,0 +1,115 @@
+package _type
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/out"
+	"gophKeeper/internal/client/model/type/auth"
+	"gophKeeper/internal/client/model/type/card"
+	"gophKeeper/internal/client/model/type/cert"
+	"gophKeeper/internal/client/model/type/template"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMask(t *testing.T) {
+	require.Equal(t, "**** **** **** 1111", model.MaskString("2222 4444 5555 1111", 0, 4))
+	require.Equal(t, "DE89 **** **30 00", model.MaskString("DE89 3704 0130 00", 4, 4))
+	require.Equal(t, "abc", model.MaskString("abc", 0, 4))
+	require.Equal(t, "**.*", model.MaskString("ab.c", 0, 0))
+	require.Equal(t, "********", model.MaskSecret("x"))
+	require.Empty(t, model.MaskSecret(""))
+	require.Equal(t, "**** ****", model.MaskValue("some words", "words"))
+	require.Equal(t, "so** ***", model.MaskValue("some key", "first=2"))
+}
+
+func TestItemMasked(t *testing.T) {
+	d := &card.Data{Number: "2222-4444-5555-1111", Exp: "10/29", CVV: "123", Name: "Max Space"}
+	d.Sanitize()
+	item := out.Item{
+		Data: d,
+		Extra: model.Extra{Fields: []model.Field{
+			{Name: "note", Value: "plain"},
+			{Name: "pin", Value: "1234", Secret: true},
+		}},
+	}
+	masked := item.Masked()
+	require.Equal(t, &card.Data{Number: "**** **** **** 1111", Exp: "10/29", CVV: "********", Name: "Max Space"}, masked.Data)
+	require.Equal(t, "plain", masked.Fields[0].Value)
+	require.Equal(t, "********", masked.Fields[1].Value)
+	// the item is not changed
+	require.Equal(t, "2222 4444 5555 1111", d.Number)
+	require.Equal(t, "1234", item.Fields[1].Value)
+
+	masked = out.Item{Data: &auth.Data{Login: "alice", Password: "secret"}}.Masked()
+	require.Equal(t, &auth.Data{Login: "alice", Password: "********"}, masked.Data)
+
+	values := &template.Data{Template: "database", Fields: []template.Value{
+		{Name: "host", Kind: template.KindString, Value: "db.example.com"},
+		{Name: "password", Kind: template.KindSecret, Value: "secret"},
+	}}
+	masked = out.Item{Data: values}.Masked()
+	v, _ := masked.Data.(*template.Data).Get("password")
+	require.Equal(t, "********", v)
+	v, _ = values.Get("password")
+	require.Equal(t, "secret", v)
+}
+
+func TestItemField(t *testing.T) {
+	item := out.Item{
+		Data:  &auth.Data{Login: "alice", Password: "secret"},
+		Extra: model.Extra{Fields: []model.Field{{Name: "pin", Value: "1234", Secret: true}}},
+	}
+	for name, want := range map[string]string{"password": "secret", "login": "alice", "pin": "1234"} {
+		v, ok := item.Field(name)
+		require.True(t, ok, name)
+		require.Equal(t, want, v)
+	}
+	_, ok := item.Field("Password")
+	require.False(t, ok)
+
+	d := &cert.Data{SANs: []string{"example.com"}}
+	require.NoError(t, json.Unmarshal([]byte(`{"not_after":"2030-01-02T03:04:05Z"}`), d))
+	item = out.Item{Data: d}
+	v, ok := item.Field("not_after")
+	require.True(t, ok)
+	require.Equal(t, "2030-01-02T03:04:05Z", v)
+	v, ok = item.Field("sans")
+	require.True(t, ok)
+	require.Equal(t, `["example.com"]`, v)
+
+	item = out.Item{Data: &template.Data{Template: "database", Fields: []template.Value{{Name: "port", Kind: template.KindNumber, Value: json.Number("5432")}}}}
+	v, ok = item.Field("port")
+	require.True(t, ok)
+	require.Equal(t, "5432", v)
+}
+
+func TestItemSecretField(t *testing.T) {
+	name, ok := out.Item{Data: &auth.Data{Login: "alice", Password: "secret"}}.SecretField()
+	require.True(t, ok)
+	require.Equal(t, "password", name)
+
+	name, ok = out.Item{Data: &card.Data{Number: "2222 4444 5555 1111", CVV: "123"}}.SecretField()
+	require.True(t, ok)
+	require.Equal(t, "number", name)
+
+	name, ok = out.Item{Data: &template.Data{Template: "database", Fields: []template.Value{
+		{Name: "host", Kind: template.KindString, Value: "db.example.com"},
+		{Name: "password", Kind: template.KindSecret, Value: "secret"},
+	}}}.SecretField()
+	require.True(t, ok)
+	require.Equal(t, "password", name)
+
+	// the empty secret is skipped, the secret extra field is taken
+	name, ok = out.Item{
+		Data:  &auth.Data{Login: "alice"},
+		Extra: model.Extra{Fields: []model.Field{{Name: "pin", Value: "1234", Secret: true}}},
+	}.SecretField()
+	require.True(t, ok)
+	require.Equal(t, "pin", name)
+
+	_, ok = out.Item{Data: &auth.Data{Login: "alice"}}.SecretField()
+	require.False(t, ok)
+}
@@ -11,6 +11,7 @@ import (
 	"gophKeeper/internal/client/model/type/auth"
 	"gophKeeper/internal/client/model/type/bin"
 	"gophKeeper/internal/client/model/type/card"
+	"gophKeeper/internal/client/model/type/otp"
 	"gophKeeper/internal/client/model/type/text"
 
 	"github.com/stretchr/testify/assert"
@@ -287,6 +288,38 @@ func TestModel(t *testing.T) {
 			validate:            []string{},
 			validateWantErrKeys: []string{"Key"},
 		},
+		{
+			name: "otp",
+			m: &otp.Model{
+				Common: model.Common{Key: "some otp"},
+				Data: &otp.Data{
+					Type:      "totp",
+					Secret:    "JBSWY3DPEHPK3PXP",
+					Issuer:    "Example",
+					Account:   "alice@example.com",
+					Algorithm: "SHA1",
+					Digits:    6,
+					Period:    30,
+				},
+			},
+			wantBytes: []byte(`{"type":"otp","data":{"type":"totp","secret":"JBSWY3DPEHPK3PXP","issuer":"Example","account":"alice@example.com","algorithm":"SHA1","digits":6,"period":30}}`),
+		},
+		{
+			name: "otp bad secret",
+			m: &otp.Model{
+				Common: model.Common{Key: "some otp 2"},
+				Data:   &otp.Data{Secret: "not base32 !"},
+			},
+			validateWantErrKeys: []string{"Secret"},
+		},
+		{
+			name: "otp bad uri",
+			m: &otp.Model{
+				Common: model.Common{Key: "some otp 3"},
+				Data:   &otp.Data{URI: "https://example.com", Secret: "JBSWY3DPEHPK3PXP"},
+			},
+			validateWantErrKeys: []string{"URI"},
+		},
 		{
 			name:     "card can no exp",
 			validate: []string{},
@@ -434,6 +467,23 @@ func TestGetNewDataModel(t *testing.T) {
 	}
 }
 
+func TestGetNewModel(t *testing.T) {
+	for _, name := range []string{"auth", "text", "bin", "card", "otp"} {
+		t.Run(name, func(t *testing.T) {
+			m, err := model.GetNewModel(name)
+			require.NoError(t, err)
+			data, err := model.GetNewDataModel(name)
+			require.NoError(t, err)
+			require.IsType(t, data, m.GetDst())
+		})
+	}
+	// the template record model is of its template
+	_, err := model.GetNewModel("template")
+	require.Error(t, err)
+	_, err = model.GetNewModel("unknown")
+	require.Error(t, err)
+}
+
 func containStrInErr(err error, str ...string) bool {
 	if err == nil {
 		return false
@@ -0,0 +1,64 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// normalizeSecret
+// upper case base32 without spaces, dashes and padding
+func normalizeSecret(s string) string {
+	s = strings.NewReplacer(" ", "", "-", "", "=", "").Replace(s)
+	return strings.ToUpper(s)
+}
+
+func decodeSecret(s string) (key []byte, err error) {
+	key, err = base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(normalizeSecret(s))
+	if err == nil && len(key) == 0 {
+		err = fmt.Errorf("empty secret")
+	}
+	return
+}
+
+// HOTP
+// the code of counter by RFC 4226, the algorithm is SHA1, SHA256 or SHA512
+func HOTP(secret string, counter uint64, algorithm string, digits int) (code string, err error) {
+	var key []byte
+	if key, err = decodeSecret(secret); err != nil {
+		return
+	}
+	var h func() hash.Hash
+	switch strings.ToUpper(algorithm) {
+	case "", "SHA1":
+		h = sha1.New
+	case "SHA256":
+		h = sha256.New
+	case "SHA512":
+		h = sha512.New
+	default:
+		err = fmt.Errorf("unknown otp algorithm %s", algorithm)
+		return
+	}
+	if digits < 6 || digits > 8 {
+		err = fmt.Errorf("wrong otp digits %d", digits)
+		return
+	}
+	mac := hmac.New(h, key)
+	_ = binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	code = fmt.Sprintf("%0*d", digits, value%mod)
+	return
+}
@@ -0,0 +1,212 @@
+package otp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gophKeeper/internal/client/model"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	TypeTOTP = "totp"
+	TypeHOTP = "hotp"
+
+	DefaultAlgorithm = "SHA1"
+	DefaultDigits    = 6
+	DefaultPeriod    = 30
+)
+
+var (
+	_ model.Model = (*Model)(nil)
+	_ model.Data  = (*Data)(nil)
+
+	ErrURI = errors.New("wrong otpauth uri")
+)
+
+func init() {
+	model.RegisterModel(&Data{}, New)
+	err := model.Validator.RegisterValidation("otp_secret", func(fl validator.FieldLevel) bool {
+		_, err := decodeSecret(fl.Field().String())
+		return err == nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	err = model.Validator.RegisterValidation("otpauth_uri", func(fl validator.FieldLevel) bool {
+		_, err := ParseURI(fl.Field().String())
+		return err == nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type Model struct {
+	Data *Data `json:"data" validate:"required"`
+	model.Common
+}
+
+func New() *Model {
+	return &Model{
+		Common: model.Common{},
+		Data:   &Data{},
+	}
+}
+
+func (m *Model) Reset() {
+	m.Common.Reset()
+	m.Data.Reset()
+}
+
+func (m *Model) GetKey() string {
+	if m.Key == "" {
+		m.Key = fmt.Sprintf("%s-%s", model.GetName(m), time.Now().Format("2006-01-02-15-04-05"))
+	}
+	return m.Key
+}
+
+func (m *Model) Validate(fields ...string) error {
+	if len(fields) == 0 {
+		return model.Validator.Struct(m)
+	} else {
+		return model.Validator.StructPartial(m, fields...)
+	}
+}
+
+func (m *Model) GetPacked() any {
+	return m.Data.GetPacked()
+}
+
+func (m *Model) GetDst() any {
+	return m.Data.GetDst()
+}
+
+type Data struct {
+	// URI the otpauth:// uri, it sets the other fields and is not stored
+	URI       string `json:"-" validate:"omitempty,otpauth_uri" flag:"uri,u" default:"" usage:"otpauth:// uri, sets all other fields"`
+	Type      string `json:"type" validate:"omitempty,oneof=totp hotp" flag:"type,t" default:"" usage:"totp (default) or hotp"`
+	Secret    string `json:"secret" validate:"required,otp_secret" flag:"secret,s" default:"" usage:"base32 secret" mask:"secret"`
+	Issuer    string `json:"issuer,omitempty" flag:"issuer,i" default:"" usage:"issuer, service name"`
+	Account   string `json:"account,omitempty" flag:"account,a" default:"" usage:"account name"`
+	Algorithm string `json:"algorithm" validate:"omitempty,oneof=SHA1 SHA256 SHA512" flag:"algorithm" default:"" usage:"SHA1 (default), SHA256 or SHA512"`
+	Digits    int    `json:"digits" validate:"omitempty,min=6,max=8" flag:"digits" default:"" usage:"code length, 6 by default"`
+	Period    int    `json:"period,omitempty" validate:"omitempty,min=1" flag:"period" default:"" usage:"totp period in seconds, 30 by default"`
+	Counter   uint64 `json:"counter,omitempty" flag:"counter" default:"" usage:"hotp counter"`
+}
+
+func (m *Data) GetPacked() any {
+	return m
+}
+
+func (m *Data) GetDst() any {
+	return m
+}
+
+func (m *Data) Reset() {
+	*m = Data{}
+}
+
+// Sanitize
+// set the fields from URI, normalize the secret and set the defaults
+func (m *Data) Sanitize() {
+	if m.URI != "" {
+		if d, err := ParseURI(m.URI); err == nil {
+			d.URI = m.URI
+			*m = d
+		}
+	}
+	m.Secret = normalizeSecret(m.Secret)
+	m.Type = strings.ToLower(m.Type)
+	m.Algorithm = strings.ToUpper(m.Algorithm)
+	if m.Type == "" {
+		m.Type = TypeTOTP
+	}
+	if m.Algorithm == "" {
+		m.Algorithm = DefaultAlgorithm
+	}
+	if m.Digits == 0 {
+		m.Digits = DefaultDigits
+	}
+	if m.Type == TypeTOTP && m.Period == 0 {
+		m.Period = DefaultPeriod
+	}
+}
+
+// ParseURI
+// the data of otpauth://TYPE/LABEL?secret=...&issuer=...&algorithm=...&digits=...&period=...&counter=...
+func ParseURI(s string) (d Data, err error) {
+	var u *url.URL
+	if u, err = url.Parse(s); err != nil {
+		err = fmt.Errorf("%w: %w", ErrURI, err)
+		return
+	}
+	if u.Scheme != "otpauth" {
+		err = fmt.Errorf("%w: scheme %q", ErrURI, u.Scheme)
+		return
+	}
+	d.Type = strings.ToLower(u.Host)
+	if d.Type != TypeTOTP && d.Type != TypeHOTP {
+		err = fmt.Errorf("%w: type %q", ErrURI, u.Host)
+		return
+	}
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		d.Issuer, d.Account = strings.TrimSpace(issuer), strings.TrimSpace(account)
+	} else {
+		d.Account = strings.TrimSpace(label)
+	}
+	q := u.Query()
+	if d.Secret = normalizeSecret(q.Get("secret")); d.Secret == "" {
+		err = fmt.Errorf("%w: no secret", ErrURI)
+		return
+	}
+	if _, err = decodeSecret(d.Secret); err != nil {
+		err = fmt.Errorf("%w: %w", ErrURI, err)
+		return
+	}
+	// the issuer parameter is preferred to the label prefix
+	if issuer := q.Get("issuer"); issuer != "" {
+		d.Issuer = issuer
+	}
+	d.Algorithm = strings.ToUpper(q.Get("algorithm"))
+	for name, dst := range map[string]*int{"digits": &d.Digits, "period": &d.Period} {
+		if v := q.Get(name); v != "" {
+			if *dst, err = strconv.Atoi(v); err != nil {
+				err = fmt.Errorf("%w: %s %q", ErrURI, name, v)
+				return
+			}
+		}
+	}
+	if v := q.Get("counter"); v != "" {
+		if d.Counter, err = strconv.ParseUint(v, 10, 64); err != nil {
+			err = fmt.Errorf("%w: counter %q", ErrURI, v)
+			return
+		}
+	}
+	if d.Type == TypeHOTP && q.Get("counter") == "" {
+		err = fmt.Errorf("%w: hotp needs counter", ErrURI)
+	}
+	return
+}
+
+// Code
+// the current code and the time it is valid for, hotp code is of the counter and not expired
+func (m *Data) Code(now time.Time) (code string, left time.Duration, err error) {
+	d := *m
+	d.Sanitize()
+	if d.Type == TypeHOTP {
+		code, err = HOTP(d.Secret, d.Counter, d.Algorithm, d.Digits)
+		return
+	}
+	period := time.Duration(d.Period) * time.Second
+	counter := uint64(now.Unix()) / uint64(d.Period)
+	left = period - now.Sub(time.Unix(int64(counter)*int64(d.Period), 0))
+	code, err = HOTP(d.Secret, counter, d.Algorithm, d.Digits)
+	return
+}
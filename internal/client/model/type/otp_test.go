@@ -0,0 +1,107 @@
+package _type
+
+import (
+	"testing"
+	"time"
+
+	"gophKeeper/internal/client/model/type/otp"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 appendix D
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range want {
+		got, err := otp.HOTP(secret, uint64(counter), "SHA1", 6)
+		require.NoError(t, err)
+		require.Equal(t, code, got, "counter %d", counter)
+	}
+	_, err := otp.HOTP(secret, 0, "MD5", 6)
+	require.Error(t, err)
+}
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 appendix B
+	secrets := map[string]string{
+		"SHA1":   "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"SHA256": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA",
+		"SHA512": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA",
+	}
+	tests := []struct {
+		unix int64
+		want map[string]string
+	}{
+		{unix: 59, want: map[string]string{"SHA1": "94287082", "SHA256": "46119246", "SHA512": "90693936"}},
+		{unix: 1111111109, want: map[string]string{"SHA1": "07081804", "SHA256": "68084774", "SHA512": "25091201"}},
+		{unix: 20000000000, want: map[string]string{"SHA1": "65353130", "SHA256": "77737706", "SHA512": "47863826"}},
+	}
+	for _, tt := range tests {
+		for alg, code := range tt.want {
+			d := otp.Data{Secret: secrets[alg], Algorithm: alg, Digits: 8}
+			got, left, err := d.Code(time.Unix(tt.unix, 0))
+			require.NoError(t, err)
+			require.Equal(t, code, got, "%s at %d", alg, tt.unix)
+			require.Equal(t, time.Duration(30-tt.unix%30)*time.Second, left)
+		}
+	}
+}
+
+func TestParseURI(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		want    otp.Data
+		wantErr bool
+	}{
+		{
+			name: "totp",
+			uri:  "otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example&algorithm=SHA256&digits=8&period=60",
+			want: otp.Data{Type: "totp", Secret: "JBSWY3DPEHPK3PXP", Issuer: "Example", Account: "alice@example.com",
+				Algorithm: "SHA256", Digits: 8, Period: 60},
+		},
+		{
+			name: "escaped label, lower case secret",
+			uri:  "otpauth://totp/ACME%20Co:john.doe%40email.com?secret=jbsw%20y3dp",
+			want: otp.Data{Type: "totp", Secret: "JBSWY3DP", Issuer: "ACME Co", Account: "john.doe@email.com"},
+		},
+		{
+			name: "hotp",
+			uri:  "otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP&counter=5",
+			want: otp.Data{Type: "hotp", Secret: "JBSWY3DPEHPK3PXP", Account: "alice", Counter: 5},
+		},
+		{name: "hotp no counter", uri: "otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP", wantErr: true},
+		{name: "no secret", uri: "otpauth://totp/alice", wantErr: true},
+		{name: "bad secret", uri: "otpauth://totp/alice?secret=1111", wantErr: true},
+		{name: "bad scheme", uri: "https://totp/alice?secret=JBSWY3DPEHPK3PXP", wantErr: true},
+		{name: "bad type", uri: "otpauth://motp/alice?secret=JBSWY3DPEHPK3PXP", wantErr: true},
+		{name: "bad digits", uri: "otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&digits=x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := otp.ParseURI(tt.uri)
+			if tt.wantErr {
+				require.ErrorIs(t, err, otp.ErrURI)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOTPSanitize(t *testing.T) {
+	d := &otp.Data{URI: "otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP"}
+	d.Sanitize()
+	require.Equal(t, "Example", d.Issuer)
+	require.Equal(t, "alice", d.Account)
+	require.Equal(t, otp.DefaultAlgorithm, d.Algorithm)
+	require.Equal(t, otp.DefaultDigits, d.Digits)
+	require.Equal(t, otp.DefaultPeriod, d.Period)
+
+	d = &otp.Data{Secret: "jbsw y3dp-ehpk 3pxp"}
+	d.Sanitize()
+	require.Equal(t, "JBSWY3DPEHPK3PXP", d.Secret)
+	require.Equal(t, otp.TypeTOTP, d.Type)
+}
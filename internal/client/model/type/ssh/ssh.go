@@ -0,0 +1,184 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gophKeeper/internal/client/model"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	_ model.Model = (*Model)(nil)
+	_ model.Data  = (*Data)(nil)
+
+	ErrPassphrase = errors.New("ssh key is encrypted, the passphrase is needed")
+)
+
+func init() {
+	model.RegisterModel(&Data{}, New)
+	err := model.Validator.RegisterValidation("ssh_private_key", func(fl validator.FieldLevel) bool {
+		var passphrase string
+		if p := fl.Parent().FieldByName("Passphrase"); p.IsValid() {
+			passphrase = p.String()
+		}
+		_, err := PublicKey(fl.Field().String(), passphrase)
+		return err == nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type Model struct {
+	Data *Data `json:"data" validate:"required"`
+	model.Common
+}
+
+func New() *Model {
+	return &Model{
+		Common: model.Common{},
+		Data:   &Data{},
+	}
+}
+
+func (m *Model) Reset() {
+	m.Common.Reset()
+	m.Data.Reset()
+}
+
+// DataFromFile
+// the private key is read from the file
+func (m *Model) DataFromFile() (err error) {
+	if m.FileName != "" {
+		if m.Data == nil {
+			m.Data = &Data{}
+		}
+		var b []byte
+		if b, err = os.ReadFile(m.FileName); err != nil {
+			return
+		}
+		m.Data.PrivateKey = string(b)
+		m.Data.Sanitize()
+	}
+	return
+}
+
+func (m *Model) GetKey() string {
+	if m.Key == "" {
+		m.Key = fmt.Sprintf("%s-%s", model.GetName(m), time.Now().Format("2006-01-02-15-04-05"))
+	}
+	return m.Key
+}
+
+// Validate
+// the key file is read before, so the key from file is validated too
+func (m *Model) Validate(fields ...string) (err error) {
+	if err = m.DataFromFile(); err != nil {
+		return
+	}
+	if len(fields) == 0 {
+		return model.Validator.Struct(m)
+	} else {
+		return model.Validator.StructPartial(m, fields...)
+	}
+}
+
+func (m *Model) GetPacked() any {
+	return m.Data.GetPacked()
+}
+
+func (m *Model) GetDst() any {
+	return m.Data.GetDst()
+}
+
+type Data struct {
+	PrivateKey string `json:"private_key" validate:"required,ssh_private_key" flag:"private-key" default:"" usage:"private key in OpenSSH or PEM format, -f reads it from file" mask:"secret"`
+	Passphrase string `json:"passphrase,omitempty" flag:"passphrase,p" default:"" usage:"passphrase of the private key, it is asked by ssh-agent if not saved" mask:"secret"`
+	Comment    string `json:"comment,omitempty" flag:"comment,c" default:"" usage:"comment of the public key"`
+	// Confirm and Lifetime are the constraints of the key at ssh-agent
+	Confirm  bool          `json:"confirm,omitempty" flag:"confirm" default:"" usage:"ssh-agent asks to confirm each use of the key"`
+	Lifetime time.Duration `json:"lifetime,omitempty" validate:"min=0" flag:"lifetime" default:"" usage:"ssh-agent drops the key after the time, 1h"`
+	// PublicKey and Fingerprint are derived from the private key
+	PublicKey   string `json:"public_key,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+func (m *Data) GetPacked() any {
+	return m
+}
+
+func (m *Data) GetDst() any {
+	return m
+}
+
+func (m *Data) Reset() {
+	*m = Data{}
+}
+
+// Sanitize
+// normalize the line ends of the key and set the public key with its fingerprint,
+// they are cleared if the key is not valid
+func (m *Data) Sanitize() {
+	if m.PrivateKey != "" {
+		m.PrivateKey = strings.TrimSpace(strings.ReplaceAll(m.PrivateKey, "\r\n", "\n")) + "\n"
+	}
+	m.PublicKey, m.Fingerprint = "", ""
+	pub, err := PublicKey(m.PrivateKey, m.Passphrase)
+	if err != nil {
+		return
+	}
+	m.PublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	if m.Comment != "" {
+		m.PublicKey += " " + m.Comment
+	}
+	m.Fingerprint = ssh.FingerprintSHA256(pub)
+}
+
+// RawKey
+// the parsed private key, it is decrypted by passphrase or by the saved one if it is empty.
+// The encrypted key without passphrase returns ErrPassphrase joined with *ssh.PassphraseMissingError
+func (m *Data) RawKey(passphrase string) (key any, err error) {
+	if passphrase == "" {
+		passphrase = m.Passphrase
+	}
+	key, err = ssh.ParseRawPrivateKey([]byte(m.PrivateKey))
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if passphrase == "" {
+			err = errors.Join(ErrPassphrase, err)
+			return
+		}
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase([]byte(m.PrivateKey), []byte(passphrase))
+	}
+	return
+}
+
+// PublicKey
+// the public key of the private key. The public key of encrypted OpenSSH key is known without passphrase,
+// the encrypted PEM key needs it
+func PublicKey(privateKey, passphrase string) (pub ssh.PublicKey, err error) {
+	key, err := (&Data{PrivateKey: privateKey, Passphrase: passphrase}).RawKey("")
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			if missing.PublicKey == nil {
+				err = ErrPassphrase
+				return
+			}
+			return missing.PublicKey, nil
+		}
+		return
+	}
+	var signer ssh.Signer
+	if signer, err = ssh.NewSignerFromKey(key); err != nil {
+		return
+	}
+	pub = signer.PublicKey()
+	return
+}
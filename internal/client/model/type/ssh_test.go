@@ -0,0 +1,123 @@
+package _type
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gophKeeper/internal/client/model"
+	sshType "gophKeeper/internal/client/model/type/ssh"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSH(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKey(edKey, "")
+	require.NoError(t, err)
+	plain := string(pem.EncodeToMemory(block))
+	block, err = ssh.MarshalPrivateKeyWithPassphrase(edKey, "", []byte("secret"))
+	require.NoError(t, err)
+	encrypted := string(pem.EncodeToMemory(block))
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	// legacy encrypted PEM keys are still in use
+	block, err = x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), []byte("secret"), x509.PEMCipherAES256)
+	require.NoError(t, err)
+	encryptedPEM := string(pem.EncodeToMemory(block))
+
+	edPub, err := ssh.NewPublicKey(edKey.Public())
+	require.NoError(t, err)
+	rsaPub, err := ssh.NewPublicKey(rsaKey.Public())
+	require.NoError(t, err)
+
+	t.Run("public key", func(t *testing.T) {
+		for _, key := range []string{plain, encrypted} {
+			pub, err := sshType.PublicKey(key, "")
+			require.NoError(t, err)
+			require.Equal(t, edPub.Marshal(), pub.Marshal())
+		}
+		_, err := sshType.PublicKey(encryptedPEM, "")
+		require.ErrorIs(t, err, sshType.ErrPassphrase)
+		pub, err := sshType.PublicKey(encryptedPEM, "secret")
+		require.NoError(t, err)
+		require.Equal(t, rsaPub.Marshal(), pub.Marshal())
+		_, err = sshType.PublicKey("not a key", "")
+		require.Error(t, err)
+	})
+
+	t.Run("raw key", func(t *testing.T) {
+		d := &sshType.Data{PrivateKey: encrypted}
+		_, err := d.RawKey("")
+		var missing *ssh.PassphraseMissingError
+		require.ErrorAs(t, err, &missing)
+		require.ErrorIs(t, err, sshType.ErrPassphrase)
+		_, err = d.RawKey("wrong")
+		require.Error(t, err)
+		key, err := d.RawKey("secret")
+		require.NoError(t, err)
+		signer, err := ssh.NewSignerFromKey(key)
+		require.NoError(t, err)
+		require.Equal(t, edPub.Marshal(), signer.PublicKey().Marshal())
+		// the passphrase of not encrypted key is ignored
+		_, err = (&sshType.Data{PrivateKey: plain, Passphrase: "secret"}).RawKey("")
+		require.NoError(t, err)
+	})
+
+	t.Run("sanitize", func(t *testing.T) {
+		d := &sshType.Data{PrivateKey: strings.ReplaceAll(plain, "\n", "\r\n") + "\r\n", Comment: "alice@host"}
+		d.Sanitize()
+		require.Equal(t, plain, d.PrivateKey)
+		require.Equal(t, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(edPub)))+" alice@host", d.PublicKey)
+		require.Equal(t, ssh.FingerprintSHA256(edPub), d.Fingerprint)
+		d.PrivateKey = "not a key"
+		d.Sanitize()
+		require.Empty(t, d.PublicKey)
+		require.Empty(t, d.Fingerprint)
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			data    sshType.Data
+			wantErr bool
+		}{
+			{name: "plain", data: sshType.Data{PrivateKey: plain}},
+			{name: "encrypted without passphrase", data: sshType.Data{PrivateKey: encrypted}},
+			{name: "encrypted", data: sshType.Data{PrivateKey: encrypted, Passphrase: "secret"}},
+			{name: "wrong passphrase", data: sshType.Data{PrivateKey: encrypted, Passphrase: "wrong"}, wantErr: true},
+			{name: "pem without passphrase", data: sshType.Data{PrivateKey: encryptedPEM}, wantErr: true},
+			{name: "pem", data: sshType.Data{PrivateKey: encryptedPEM, Passphrase: "secret"}},
+			{name: "not a key", data: sshType.Data{PrivateKey: "not a key"}, wantErr: true},
+			{name: "empty", data: sshType.Data{}, wantErr: true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				data := tt.data
+				m := &sshType.Model{Common: model.Common{Key: "some ssh"}, Data: &data}
+				if err := m.Validate(); tt.wantErr {
+					require.Error(t, err)
+				} else {
+					require.NoError(t, err)
+				}
+			})
+		}
+	})
+
+	t.Run("from file", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "id_ed25519")
+		require.NoError(t, os.WriteFile(name, []byte(plain), 0600))
+		m := &sshType.Model{Common: model.Common{Key: "some ssh", FileName: name}, Data: &sshType.Data{}}
+		require.NoError(t, m.Validate())
+		require.Equal(t, plain, m.Data.PrivateKey)
+		require.Equal(t, ssh.FingerprintSHA256(edPub), m.Data.Fingerprint)
+	})
+}
@@ -0,0 +1,264 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gophKeeper/internal/client/model"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/pflag"
+)
+
+var (
+	_ model.Model         = (*Model)(nil)
+	_ model.Data          = (*Data)(nil)
+	_ model.Maskable      = (*Data)(nil)
+	_ model.SecretFielder = (*Data)(nil)
+)
+
+func init() {
+	// the model of record is of its template, see New
+	model.RegisterModel[*Model](&Data{}, nil)
+}
+
+// Model
+// the record of template, the field values are set by the flags of template fields
+type Model struct {
+	Data *Data `json:"data" validate:"required"`
+	model.Common
+	def    Template
+	values []string
+}
+
+// New returns the record model of template
+func New(def Template) *Model {
+	return &Model{
+		Common: model.Common{},
+		Data:   &Data{},
+		def:    def,
+		values: make([]string, len(def.Fields)),
+	}
+}
+
+func (m *Model) Reset() {
+	m.Common.Reset()
+	m.Data.Reset()
+	clear(m.values)
+}
+
+// GenerateFlags
+// add the flags of template fields
+func (m *Model) GenerateFlags(fs *pflag.FlagSet) {
+	for i, f := range m.def.Fields {
+		name, short := f.FlagNames()
+		usage := f.Usage
+		if usage == "" {
+			usage = f.Name
+		}
+		usage += " (" + f.GetKind() + ")"
+		fs.StringVarP(&m.values[i], name, short, "", usage)
+	}
+}
+
+// SetData
+// set the data and the flag values of template fields by the values of data,
+// so the record is changed by the given flags only. The fields are copied, the data is not changed by Validate
+func (m *Model) SetData(d *Data) {
+	*m.Data = Data{Template: d.Template, Fields: slices.Clone(d.Fields)}
+	for i, f := range m.def.Fields {
+		m.values[i] = ""
+		if v, ok := d.Get(f.Name); ok && v != nil {
+			m.values[i] = fmt.Sprint(v)
+		}
+	}
+}
+
+// fileValues
+// the field values, the file content is the value of the first empty multiline field
+func (m *Model) fileValues() (values []string, err error) {
+	values = slices.Clone(m.values)
+	if m.FileName == "" {
+		return
+	}
+	for i, f := range m.def.Fields {
+		if f.GetKind() != KindMultiline || values[i] != "" {
+			continue
+		}
+		var b []byte
+		if b, err = os.ReadFile(m.FileName); err != nil {
+			return
+		}
+		values[i] = string(b)
+		return
+	}
+	err = fmt.Errorf("%w %s: no multiline field for the file", ErrTemplate, m.def.Name)
+	return
+}
+
+func (m *Model) GetKey() string {
+	if m.Key == "" {
+		m.Key = fmt.Sprintf("%s-%s", m.def.Name, time.Now().Format("2006-01-02-15-04-05"))
+	}
+	return m.Key
+}
+
+// Validate
+// validate the field values by their kinds and validators, the data fields are set by the typed values
+func (m *Model) Validate(_ ...string) (err error) {
+	values, err := m.fileValues()
+	if err != nil {
+		return
+	}
+	m.Data.Template = m.def.Name
+	m.Data.Fields = m.Data.Fields[:0]
+	for i, f := range m.def.Fields {
+		v, er := fieldValue(f, values[i])
+		if er != nil {
+			err = errors.Join(err, fmt.Errorf("%s: %w", f.Name, er))
+			continue
+		}
+		if v != nil {
+			m.Data.Fields = append(m.Data.Fields, Value{Name: f.Name, Kind: f.GetKind(), Value: v})
+		}
+	}
+	if err != nil {
+		return
+	}
+	return model.Validator.Struct(m)
+}
+
+// fieldValue
+// the typed value of field, nil for the empty value
+func fieldValue(f Field, s string) (v any, err error) {
+	if f.GetKind() == KindMultiline {
+		s = strings.TrimRight(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	} else {
+		s = strings.TrimSpace(s)
+	}
+	if f.Validate != "" {
+		if err = validateVar(s, f.Validate); err != nil {
+			return
+		}
+	}
+	if s == "" {
+		return
+	}
+	switch f.GetKind() {
+	case KindURL:
+		if err = validateVar(s, "url"); err != nil {
+			return
+		}
+	case KindDate:
+		var d time.Time
+		if d, err = time.Parse(DateLayout, s); err != nil {
+			err = fmt.Errorf("date must be %s", DateLayout)
+			return
+		}
+		s = d.Format(DateLayout)
+	case KindNumber:
+		if _, err = strconv.ParseFloat(s, 64); err != nil {
+			err = errors.New("not a number")
+			return
+		}
+		return json.Number(s), nil
+	}
+	return s, nil
+}
+
+// validateVar
+// validate the value by the tag, the error names the failed validator
+func validateVar(s, tag string) (err error) {
+	if err = model.Validator.Var(s, tag); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) && len(ve) > 0 {
+			err = fmt.Errorf("failed on the '%s' validation", ve[0].Tag())
+		}
+	}
+	return
+}
+
+func (m *Model) GetPacked() any {
+	return m.Data.GetPacked()
+}
+
+func (m *Model) GetDst() any {
+	return m.Data.GetDst()
+}
+
+// Value
+// the typed value of template field, the number is a JSON number
+type Value struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Value any    `json:"value"`
+}
+
+// Data
+// the values of template fields by the template order, the kinds are kept with values,
+// so the record is shown typed without its template
+type Data struct {
+	Template string  `json:"template" validate:"required"`
+	Fields   []Value `json:"fields"`
+}
+
+func (m *Data) GetPacked() any {
+	return m
+}
+
+func (m *Data) GetDst() any {
+	return m
+}
+
+func (m *Data) Reset() {
+	*m = Data{}
+}
+
+// UnmarshalJSON
+// numbers are decoded as JSON numbers, so they are kept as saved
+func (m *Data) UnmarshalJSON(b []byte) error {
+	type data Data
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return d.Decode((*data)(m))
+}
+
+// Mask
+// the values of secret fields are masked, the fields are copied, so the masked data can share them
+func (m *Data) Mask() {
+	m.Fields = slices.Clone(m.Fields)
+	for i, f := range m.Fields {
+		if s, ok := f.Value.(string); ok && f.Kind == KindSecret {
+			m.Fields[i].Value = model.MaskSecret(s)
+		}
+	}
+}
+
+// SecretField
+// the name of the first not empty secret field
+func (m *Data) SecretField() (name string, ok bool) {
+	for _, f := range m.Fields {
+		if s, isString := f.Value.(string); isString && s != "" && f.Kind == KindSecret {
+			return f.Name, true
+		}
+	}
+	return
+}
+
+// Get
+// the value of field by name
+func (m *Data) Get(name string) (v any, ok bool) {
+	for _, f := range m.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return
+}
@@ -0,0 +1,235 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gophKeeper/internal/client/model"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of template fields
+const (
+	KindString    = "string"
+	KindSecret    = "secret"
+	KindURL       = "url"
+	KindDate      = "date"
+	KindNumber    = "number"
+	KindMultiline = "multiline"
+
+	// DateLayout the layout of date fields
+	DateLayout = "2006-01-02"
+)
+
+var (
+	// FileNames the names of templates file at the profile directory, the first existing one is used
+	FileNames = []string{"templates.yaml", "templates.yml", "templates.json"}
+
+	Kinds = []string{KindString, KindSecret, KindURL, KindDate, KindNumber, KindMultiline}
+
+	ErrTemplate = errors.New("wrong template")
+
+	nameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+	flagRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*(,[a-zA-Z])?$`)
+	// reservedFlags the flags of save commands and the persistent ones
+	reservedFlags = []string{"key", "k", "description", "d", "file", "f", "field", "secret-field", "url", "debug", "help", "h", "passphrase-fd", "passphrase-file"}
+)
+
+// File
+// the templates file content
+type File struct {
+	Templates []Template `json:"templates" yaml:"templates"`
+}
+
+// Template
+// the user defined record type, it is saved by `save <name>`
+type Template struct {
+	Name        string  `json:"name" yaml:"name"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Fields      []Field `json:"fields" yaml:"fields"`
+}
+
+// Field
+// the field of template. Kind is string by default, Flag is "name,shorthand" of the save flag,
+// the field name by default, Validate is the validator tag of the value
+type Field struct {
+	Name     string `json:"name" yaml:"name"`
+	Kind     string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Flag     string `json:"flag,omitempty" yaml:"flag,omitempty"`
+	Usage    string `json:"usage,omitempty" yaml:"usage,omitempty"`
+	Validate string `json:"validate,omitempty" yaml:"validate,omitempty"`
+}
+
+// FlagNames the flag name and shorthand of the field
+func (f Field) FlagNames() (name, short string) {
+	flag := f.Flag
+	if flag == "" {
+		flag = f.Name
+	}
+	name, short, _ = strings.Cut(flag, ",")
+	return
+}
+
+// GetKind the kind of field, string by default
+func (f Field) GetKind() string {
+	if f.Kind == "" {
+		return KindString
+	}
+	return f.Kind
+}
+
+// Check
+// the template name must not be of the built-in type, the field names and flags must be unique
+func (t Template) Check() (err error) {
+	if !nameRegexp.MatchString(t.Name) {
+		return fmt.Errorf("%w: name %q", ErrTemplate, t.Name)
+	}
+	if _, er := model.GetNewDataModel(t.Name); er == nil {
+		return fmt.Errorf("%w: %s is the built-in type", ErrTemplate, t.Name)
+	}
+	if len(t.Fields) == 0 {
+		return fmt.Errorf("%w %s: no fields", ErrTemplate, t.Name)
+	}
+	names := make(map[string]bool)
+	flags := make(map[string]bool)
+	for _, f := range t.Fields {
+		if !nameRegexp.MatchString(f.Name) || names[f.Name] {
+			return fmt.Errorf("%w %s: field name %q", ErrTemplate, t.Name, f.Name)
+		}
+		names[f.Name] = true
+		if !slices.Contains(Kinds, f.GetKind()) {
+			return fmt.Errorf("%w %s: field %s kind %q", ErrTemplate, t.Name, f.Name, f.Kind)
+		}
+		if f.Flag != "" && !flagRegexp.MatchString(f.Flag) {
+			return fmt.Errorf("%w %s: field %s flag %q", ErrTemplate, t.Name, f.Name, f.Flag)
+		}
+		name, short := f.FlagNames()
+		for _, flag := range []string{name, short} {
+			if flag == "" {
+				continue
+			}
+			if flags[flag] || slices.Contains(reservedFlags, flag) {
+				return fmt.Errorf("%w %s: field %s flag %q is used", ErrTemplate, t.Name, f.Name, flag)
+			}
+			flags[flag] = true
+		}
+		if err = checkTag(f.Validate); err != nil {
+			return fmt.Errorf("%w %s: field %s validate %q: %w", ErrTemplate, t.Name, f.Name, f.Validate, err)
+		}
+	}
+	return
+}
+
+// checkTag
+// the validator panics on the wrong tag
+func checkTag(tag string) (err error) {
+	if tag == "" {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	_ = model.Validator.Var("", tag)
+	return
+}
+
+// Parse
+// the templates of YAML or JSON content, they are checked and must have unique names
+func Parse(b []byte) (templates []Template, err error) {
+	var f File
+	if err = yaml.Unmarshal(b, &f); err != nil {
+		err = fmt.Errorf("%w: %w", ErrTemplate, err)
+		return
+	}
+	names := make(map[string]bool)
+	for _, t := range f.Templates {
+		if err = t.Check(); err != nil {
+			return
+		}
+		if names[t.Name] {
+			err = fmt.Errorf("%w: duplicate %s", ErrTemplate, t.Name)
+			return
+		}
+		names[t.Name] = true
+	}
+	templates = f.Templates
+	if templates == nil {
+		templates = []Template{}
+	}
+	return
+}
+
+// Load
+// the templates of the first existing templates file at dir and its path,
+// templates are nil if there is no file
+func Load(dir string) (templates []Template, path string, err error) {
+	for _, name := range FileNames {
+		p := filepath.Join(dir, name)
+		b, er := os.ReadFile(p)
+		if os.IsNotExist(er) {
+			continue
+		}
+		if er != nil {
+			err = er
+			return
+		}
+		path = p
+		if templates, err = Parse(b); err != nil {
+			err = fmt.Errorf("%s: %w", p, err)
+		}
+		return
+	}
+	return
+}
+
+// Save
+// write the templates to the file of path by its format, to the YAML file at dir if path is empty
+func Save(dir, path string, templates []Template) (err error) {
+	if path == "" {
+		path = filepath.Join(dir, FileNames[0])
+	}
+	if templates == nil {
+		templates = []Template{}
+	}
+	var b []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		b, err = json.MarshalIndent(File{Templates: templates}, "", "  ")
+	default:
+		b, err = yaml.Marshal(File{Templates: templates})
+	}
+	if err != nil {
+		return
+	}
+	return os.WriteFile(path, b, 0600)
+}
+
+// Marshal
+// the canonical form of templates to synchronize, nil templates are nil
+func Marshal(templates []Template) ([]byte, error) {
+	if templates == nil {
+		return nil, nil
+	}
+	return json.Marshal(File{Templates: templates})
+}
+
+// Hash
+// the hash of the canonical templates, empty for nil
+func Hash(b []byte) string {
+	if b == nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
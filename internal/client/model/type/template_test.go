@@ -0,0 +1,190 @@
+package _type
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/out"
+	"gophKeeper/internal/client/model/type/template"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+const templatesYAML = `
+templates:
+  - name: database
+    description: Database credentials
+    fields:
+      - name: host
+        flag: host,H
+        validate: required,hostname
+      - name: port
+        kind: number
+      - name: password
+        kind: secret
+        validate: required
+      - name: console
+        kind: url
+      - name: expires
+        kind: date
+      - name: notes
+        kind: multiline
+  - name: license
+    fields:
+      - name: code
+        kind: secret
+`
+
+func TestTemplateParse(t *testing.T) {
+	templates, err := template.Parse([]byte(templatesYAML))
+	require.NoError(t, err)
+	require.Len(t, templates, 2)
+	require.Equal(t, "database", templates[0].Name)
+	require.Equal(t, template.KindString, templates[0].Fields[0].GetKind())
+	name, short := templates[0].Fields[0].FlagNames()
+	require.Equal(t, "host", name)
+	require.Equal(t, "H", short)
+
+	// JSON is parsed too
+	b, err := template.Marshal(templates)
+	require.NoError(t, err)
+	again, err := template.Parse(b)
+	require.NoError(t, err)
+	require.Equal(t, templates, again)
+
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{name: "built-in name", yaml: `{templates: [{name: auth, fields: [{name: a}]}]}`},
+		{name: "wrong name", yaml: `{templates: [{name: "Some Name", fields: [{name: a}]}]}`},
+		{name: "no fields", yaml: `{templates: [{name: some}]}`},
+		{name: "duplicate template", yaml: `{templates: [{name: some, fields: [{name: a}]}, {name: some, fields: [{name: a}]}]}`},
+		{name: "duplicate field", yaml: `{templates: [{name: some, fields: [{name: a}, {name: a}]}]}`},
+		{name: "wrong kind", yaml: `{templates: [{name: some, fields: [{name: a, kind: blob}]}]}`},
+		{name: "duplicate flag", yaml: `{templates: [{name: some, fields: [{name: a, flag: "x,x"}, {name: b, flag: "y,x"}]}]}`},
+		{name: "reserved flag", yaml: `{templates: [{name: some, fields: [{name: key}]}]}`},
+		{name: "reserved short flag", yaml: `{templates: [{name: some, fields: [{name: a, flag: "a,k"}]}]}`},
+		{name: "wrong validator", yaml: `{templates: [{name: some, fields: [{name: a, validate: "no_such_validator"}]}]}`},
+		{name: "not yaml", yaml: `templates: [`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := template.Parse([]byte(tt.yaml))
+			require.ErrorIs(t, err, template.ErrTemplate)
+		})
+	}
+}
+
+func TestTemplateLoadSave(t *testing.T) {
+	dir := t.TempDir()
+	templates, path, err := template.Load(dir)
+	require.NoError(t, err)
+	require.Nil(t, templates)
+	require.Empty(t, path)
+	b, err := template.Marshal(templates)
+	require.NoError(t, err)
+	require.Empty(t, template.Hash(b))
+
+	want, err := template.Parse([]byte(templatesYAML))
+	require.NoError(t, err)
+	for _, name := range []string{"templates.json", "templates.yaml"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, template.Save(dir, path, want))
+		templates, got, err := template.Load(dir)
+		require.NoError(t, err)
+		require.Equal(t, path, got)
+		require.Equal(t, want, templates)
+		require.NoError(t, os.Remove(path))
+	}
+
+	// the empty list is kept to remove the templates
+	require.NoError(t, template.Save(dir, "", []template.Template{}))
+	templates, _, err = template.Load(dir)
+	require.NoError(t, err)
+	require.NotNil(t, templates)
+	require.Empty(t, templates)
+}
+
+func TestTemplateRecord(t *testing.T) {
+	templates, err := template.Parse([]byte(templatesYAML))
+	require.NoError(t, err)
+	m := template.New(templates[0])
+	fs := pflag.NewFlagSet("save", pflag.ContinueOnError)
+	m.GenerateFlags(fs)
+	m.Key = "db"
+
+	require.Error(t, m.Validate())
+
+	notes := filepath.Join(t.TempDir(), "notes")
+	require.NoError(t, os.WriteFile(notes, []byte("line 1\r\nline 2\n"), 0600))
+	m.FileName = notes
+	require.NoError(t, fs.Parse([]string{"-H", "db.example.com", "--port", "5432", "--password", "secret",
+		"--console", "https://db.example.com", "--expires", "2027-01-31"}))
+	require.NoError(t, m.Validate())
+	require.Equal(t, "database", m.Data.Template)
+	v, ok := m.Data.Get("port")
+	require.True(t, ok)
+	require.Equal(t, json.Number("5432"), v)
+	v, _ = m.Data.Get("notes")
+	require.Equal(t, "line 1\nline 2", v)
+
+	b, err := model.NewPackedBytes(m)
+	require.NoError(t, err)
+	var item out.Item
+	require.NoError(t, json.Unmarshal(b, &item))
+	d, ok := item.Data.(*template.Data)
+	require.True(t, ok)
+	require.Equal(t, m.Data, d)
+	b, err = json.Marshal(d)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `{"name":"port","kind":"number","value":5432}`)
+
+	for _, args := range [][]string{
+		{"--port", "not a number"},
+		{"--console", "not a url"},
+		{"--expires", "31.01.2027"},
+		{"--host", "not a host name!"},
+	} {
+		require.NoError(t, fs.Parse(args))
+		require.Error(t, m.Validate(), args)
+		require.NoError(t, fs.Parse([]string{"-H", "db.example.com", "--port", "5432", "--console", "https://db.example.com", "--expires", "2027-01-31"}))
+	}
+
+	m.Reset()
+	require.Empty(t, m.Key)
+	require.Empty(t, m.Data.Fields)
+	require.Error(t, m.Validate())
+}
+
+func TestTemplateSetData(t *testing.T) {
+	templates, err := template.Parse([]byte(templatesYAML))
+	require.NoError(t, err)
+	d := &template.Data{Template: "database", Fields: []template.Value{
+		{Name: "host", Kind: template.KindString, Value: "db.example.com"},
+		{Name: "port", Kind: template.KindNumber, Value: json.Number("5432")},
+		{Name: "password", Kind: template.KindSecret, Value: "secret"},
+	}}
+	m := template.New(templates[0])
+	fs := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+	m.GenerateFlags(fs)
+	m.Key = "db"
+	m.SetData(d)
+
+	// only the given field is changed
+	require.NoError(t, fs.Parse([]string{"--port", "6543"}))
+	require.NoError(t, m.Validate())
+	v, _ := m.Data.Get("port")
+	require.Equal(t, json.Number("6543"), v)
+	v, _ = m.Data.Get("host")
+	require.Equal(t, "db.example.com", v)
+	v, _ = m.Data.Get("password")
+	require.Equal(t, "secret", v)
+	// the data of record is not changed
+	v, _ = d.Get("port")
+	require.Equal(t, json.Number("5432"), v)
+}
@@ -26,7 +26,7 @@ func (m *Model) Reset() {
 }
 
 func init() {
-	model.RegisterModel(&Data{})
+	model.RegisterModel(&Data{}, New)
 }
 
 type Model struct {
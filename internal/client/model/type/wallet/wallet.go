@@ -0,0 +1,138 @@
+package wallet
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gophKeeper/internal/client/model"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/tyler-smith/go-bip39"
+)
+
+var (
+	_ model.Model = (*Model)(nil)
+	_ model.Data  = (*Data)(nil)
+
+	pathRegexp = regexp.MustCompile(`^m(/[0-9]+'?)*$`)
+)
+
+func init() {
+	model.RegisterModel(&Data{}, New)
+	validators := map[string]func(s string) bool{
+		"bip39":      ValidMnemonic,
+		"bip32_path": pathRegexp.MatchString,
+	}
+	for k, v := range validators {
+		err := model.Validator.RegisterValidation(k, func(fl validator.FieldLevel) bool {
+			return v(fl.Field().String())
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+type Model struct {
+	Data *Data `json:"data" validate:"required"`
+	model.Common
+}
+
+func New() *Model {
+	return &Model{
+		Common: model.Common{},
+		Data:   &Data{},
+	}
+}
+
+func (m *Model) Reset() {
+	m.Common.Reset()
+	m.Data.Reset()
+}
+
+// DataFromFile
+// the seed phrase is read from the file, so it is not kept in the shell history
+func (m *Model) DataFromFile() (err error) {
+	if m.FileName != "" {
+		if m.Data == nil {
+			m.Data = &Data{}
+		}
+		var b []byte
+		if b, err = os.ReadFile(m.FileName); err != nil {
+			return
+		}
+		m.Data.Mnemonic = string(b)
+		m.Data.Sanitize()
+	}
+	return
+}
+
+func (m *Model) GetKey() string {
+	if m.Key == "" {
+		m.Key = fmt.Sprintf("%s-%s", model.GetName(m), time.Now().Format("2006-01-02-15-04-05"))
+	}
+	return m.Key
+}
+
+// Validate
+// the seed phrase file is read before, so the phrase from file is validated too
+func (m *Model) Validate(fields ...string) (err error) {
+	if err = m.DataFromFile(); err != nil {
+		return
+	}
+	if len(fields) == 0 {
+		return model.Validator.Struct(m)
+	} else {
+		return model.Validator.StructPartial(m, fields...)
+	}
+}
+
+func (m *Model) GetPacked() any {
+	return m.Data.GetPacked()
+}
+
+func (m *Model) GetDst() any {
+	return m.Data.GetDst()
+}
+
+type Data struct {
+	Mnemonic   string `json:"mnemonic" validate:"required,bip39" flag:"mnemonic,m" default:"" usage:"BIP-39 seed phrase of 12 to 24 english words, -f reads it from file" mask:"words"`
+	Passphrase string `json:"passphrase,omitempty" flag:"passphrase,p" default:"" usage:"BIP-39 passphrase, the 25th word" mask:"secret"`
+	Coin       string `json:"coin,omitempty" validate:"omitempty,max=50" flag:"coin,c" default:"" usage:"coin or network    BTC"`
+	Path       string `json:"path,omitempty" validate:"omitempty,bip32_path" flag:"path" default:"" usage:"derivation path    m/84'/0'/0'"`
+}
+
+func (m *Data) GetPacked() any {
+	return m
+}
+
+func (m *Data) GetDst() any {
+	return m
+}
+
+func (m *Data) Reset() {
+	*m = Data{}
+}
+
+// Sanitize
+// the words of seed phrase are lower case separated by single spaces
+func (m *Data) Sanitize() {
+	m.Mnemonic = normalize(m.Mnemonic)
+	m.Coin = strings.TrimSpace(m.Coin)
+	m.Path = strings.TrimSpace(m.Path)
+}
+
+// normalize
+// the lower case words separated by single spaces
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// ValidMnemonic
+// the seed phrase has words of the BIP-39 english list, their number and checksum are valid
+func ValidMnemonic(s string) bool {
+	return bip39.IsMnemonicValid(normalize(s))
+}
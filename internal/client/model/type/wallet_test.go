@@ -0,0 +1,67 @@
+package _type
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/type/wallet"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestWallet(t *testing.T) {
+	require.True(t, wallet.ValidMnemonic(testMnemonic))
+	require.True(t, wallet.ValidMnemonic(" Abandon  abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon ABOUT\n"))
+	for _, s := range []string{
+		"",
+		// wrong checksum
+		strings.Repeat("abandon ", 12),
+		// not a word of the list
+		strings.Replace(testMnemonic, "about", "abouts", 1),
+		// wrong number of words
+		strings.TrimPrefix(testMnemonic, "abandon "),
+	} {
+		require.False(t, wallet.ValidMnemonic(s), s)
+	}
+
+	tests := []struct {
+		name    string
+		data    wallet.Data
+		wantErr bool
+	}{
+		{name: "seed", data: wallet.Data{Mnemonic: testMnemonic, Passphrase: "secret", Coin: "BTC", Path: "m/84'/0'/0'"}},
+		{name: "wrong seed", data: wallet.Data{Mnemonic: strings.Repeat("abandon ", 12)}, wantErr: true},
+		{name: "wrong path", data: wallet.Data{Mnemonic: testMnemonic, Path: "84/0/0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.data
+			m := &wallet.Model{Common: model.Common{Key: "some wallet"}, Data: &data}
+			err := m.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("from file", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "seed.txt")
+		require.NoError(t, os.WriteFile(name, []byte(strings.ToUpper(testMnemonic)+"\r\n"), 0600))
+		m := wallet.New()
+		m.Key, m.FileName = "some wallet", name
+		require.NoError(t, m.Validate())
+		require.Equal(t, testMnemonic, m.Data.Mnemonic)
+	})
+
+	d := &wallet.Data{Mnemonic: testMnemonic, Passphrase: "secret"}
+	model.MaskStruct(d)
+	require.Equal(t, strings.TrimSpace(strings.Repeat("**** ", 12)), d.Mnemonic)
+	require.Equal(t, "********", d.Passphrase)
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"encoding/base64"
+	"strings"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/crypt"
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/storage"
+)
+
+// undecryptableDescription is listed instead of the description encrypted by another key
+const undecryptableDescription = "<encrypted>"
+
+// descriptionAD
+// binds the encrypted description to the record key
+func descriptionAD(key string) []byte {
+	return []byte("gophkeeper/description\x00" + key)
+}
+
+// encryptDescription
+// the description encrypted by token and the blind index tokens of its words,
+// the empty description is kept empty
+func encryptDescription(token, key, desc string, opts []crypt.Option) (enc string, tokens []string, err error) {
+	tokens = crypt.BlindTokens(crypt.BlindIndexKey(token), crypt.IndexWords(desc))
+	if desc == "" {
+		return
+	}
+	var b []byte
+	if b, err = crypt.Encode([]byte(desc), token, append(opts, crypt.WithAssociatedData(descriptionAD(key)))...); err != nil {
+		return
+	}
+	enc = model.EncryptedDescriptionPrefix + base64.RawStdEncoding.EncodeToString(b)
+	return
+}
+
+// decryptDescription
+// the plain description, not encrypted one is returned as is
+func decryptDescription(token, key, desc string) (plain string, err error) {
+	if !strings.HasPrefix(desc, model.EncryptedDescriptionPrefix) {
+		return desc, nil
+	}
+	var b []byte
+	if b, err = base64.RawStdEncoding.DecodeString(desc[len(model.EncryptedDescriptionPrefix):]); err != nil {
+		return
+	}
+	if b, err = crypt.Decode(b, token, crypt.WithAssociatedData(descriptionAD(key))); err != nil {
+		return
+	}
+	plain = string(b)
+	return
+}
+
+// recordDescription
+// the description to store by the profile mode, with the blind index tokens if it is encrypted
+func recordDescription(token, key, desc string, opts []crypt.Option) (string, []string, error) {
+	if !cfg.User.GetBool("description.encrypt") {
+		return desc, nil, nil
+	}
+	return encryptDescription(token, key, desc, opts)
+}
+
+// saveRecord
+// save the record with the blind index of its description, its previous version is kept by the history retention.
+// Returns the files of the removed versions to delete after commit
+func saveRecord(db storage.DB, r model.DBRecord) (files []string, err error) {
+	err = db.Transaction(func(db storage.DB) (err error) {
+		if err = db.Save(r); err != nil {
+			return
+		}
+		if err = db.SaveIndex(r.Key, r.IndexTokens); err != nil {
+			return
+		}
+		files, err = pruneHistory(db, r)
+		return
+	})
+	return
+}
+
+// rewriteRecord
+// update the record in place with the blind index of its description, its previous version is not kept
+func rewriteRecord(db storage.DB, r model.DBRecord) (err error) {
+	return db.Transaction(func(db storage.DB) (err error) {
+		if err = db.Rewrite(r); err != nil {
+			return
+		}
+		return db.SaveIndex(r.Key, r.IndexTokens)
+	})
+}
+
+// searchTokens
+// set the blind index tokens of the searched description words, if there are encrypted descriptions.
+// The records received without index are indexed before
+func (s *service) searchTokens(query *model.ListQuery) (err error) {
+	var n uint64
+	if n, err = s.r.DB.CountEncrypted(); err != nil || n == 0 {
+		return
+	}
+	var token string
+	if token, err = s.GetToken(); err != nil {
+		err = tokenError(err)
+		return
+	}
+	if err = s.indexDescriptions(token); err != nil {
+		return
+	}
+	query.DescriptionTokens = crypt.BlindTokens(crypt.BlindIndexKey(token), crypt.IndexWords(query.Description))
+	return
+}
+
+// indexDescriptions
+// build the blind index of encrypted descriptions received without it,
+// the descriptions encrypted by another key are skipped
+func (s *service) indexDescriptions(token string) (err error) {
+	var items []model.DBItem
+	if items, err = s.r.DB.ListUnindexed(); err != nil {
+		return
+	}
+	key := crypt.BlindIndexKey(token)
+	for _, item := range items {
+		plain, er := decryptDescription(token, item.Key, item.Description)
+		if er != nil {
+			continue
+		}
+		if err = s.r.DB.SaveIndex(item.Key, crypt.BlindTokens(key, crypt.IndexWords(plain))); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// decryptDescriptions
+// decrypt the encrypted descriptions of listed items, the token is asked only if there are some
+func (s *service) decryptDescriptions(items []model.DBItem) (err error) {
+	var token string
+	for i := range items {
+		if !items[i].HasEncryptedDescription() {
+			continue
+		}
+		if token == "" {
+			if token, err = s.GetToken(); err != nil {
+				err = tokenError(err)
+				return
+			}
+		}
+		plain, er := decryptDescription(token, items[i].Key, items[i].Description)
+		if er != nil {
+			plain = undecryptableDescription
+		}
+		items[i].Description = plain
+	}
+	return
+}
+
+// CountEncryptedDescriptions
+// number of records with encrypted description
+func (s *service) CountEncryptedDescriptions() (n uint64, err error) {
+	return s.r.DB.CountEncrypted()
+}
+
+// SetDescriptionMode
+// encrypt the descriptions of all records and index them by blind tokens, or decrypt them back.
+// serverIndex sends the tokens to server with the records, so they are searchable there too.
+// The changed records are marked updated to be synchronized, n is their number
+func (s *service) SetDescriptionMode(encrypt, serverIndex bool) (n int, err error) {
+	serverIndex = encrypt && serverIndex
+	var token string
+	if token, err = s.GetToken(); err != nil {
+		err = tokenError(err)
+		return
+	}
+	var opts []crypt.Option
+	if opts, err = cryptOptions(); err != nil {
+		return
+	}
+	// the index at server is updated by sending the records again
+	resend := serverIndex != cfg.User.GetBool("description.server_index")
+	err = s.r.DB.Transaction(func(db storage.DB) (err error) {
+		n = 0
+		var items []model.DBItem
+		if items, err = db.List(model.ListQuery{Deleted: true}); err != nil {
+			return
+		}
+		for _, item := range items {
+			if item.Description == "" || encrypt == item.HasEncryptedDescription() && !(encrypt && resend) {
+				continue
+			}
+			var r model.DBRecord
+			if r, err = db.Get(item.Key); err != nil {
+				return
+			}
+			var plain string
+			if plain, err = decryptDescription(token, r.Key, r.Description); err != nil {
+				err = decodeError(err, "")
+				return
+			}
+			if encrypt {
+				if r.Description, r.IndexTokens, err = encryptDescription(token, r.Key, plain, opts); err != nil {
+					return
+				}
+			} else {
+				r.Description, r.IndexTokens = plain, nil
+			}
+			r.UpdatedAt = nil
+			if err = rewriteRecord(db, r); err != nil {
+				return
+			}
+			n++
+		}
+		return setVersionsDescription(db, token, encrypt, opts)
+	})
+	if err != nil {
+		return
+	}
+	cfg.User.Set("description.encrypt", encrypt)
+	cfg.User.Set("description.server_index", serverIndex)
+	return
+}
+
+// setVersionsDescription
+// encrypt or decrypt the descriptions of the kept versions of all records by the mode
+func setVersionsDescription(db storage.DB, token string, encrypt bool, opts []crypt.Option) (err error) {
+	var versions []model.DBVersion
+	if versions, err = db.ListVersions(""); err != nil {
+		return
+	}
+	for _, item := range versions {
+		if item.Description == "" || encrypt == item.HasEncryptedDescription() {
+			continue
+		}
+		var v model.DBVersion
+		if v, err = db.GetVersion(item.Key, item.Version); err != nil {
+			return
+		}
+		var plain string
+		if plain, err = decryptDescription(token, v.Key, v.Description); err != nil {
+			err = decodeError(err, "")
+			return
+		}
+		v.Description = plain
+		if encrypt {
+			if v.Description, _, err = encryptDescription(token, v.Key, plain, opts); err != nil {
+				return
+			}
+		}
+		if err = db.UpdateVersion(v); err != nil {
+			return
+		}
+	}
+	return
+}
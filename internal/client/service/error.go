@@ -1,6 +1,10 @@
 package service
 
 import (
+	"io"
+	"time"
+
+	"gophKeeper/internal/client/crypt"
 	"gophKeeper/internal/client/model"
 	"gophKeeper/internal/client/model/out"
 )
@@ -15,7 +19,107 @@ func NewServiceError(e error) *serviceError {
 	return &serviceError{e: e}
 }
 
-func (s *serviceError) ChangePasswd() (err error) {
+func (s *serviceError) ChangePasswd(_ crypt.KDFParams) (err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) RotateKey(_ func(done, total int)) (err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) CreateRecovery(_, _ int) (codes []string, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) UseRecovery() (err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) SetKeyFile(_ string) (created bool, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) RemoveKeyFile() (err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) SetDescriptionMode(_, _ bool) (n int, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) CountEncryptedDescriptions() (n uint64, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) Expiring(_ time.Duration) (items []out.Item, skipped []string, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) ExportVault(_ io.Writer, _ string) (n int, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) RestoreVault(_ io.Reader, _ string, _ bool) (res VaultRestore, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) History(_ string) (data []model.DBVersion, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) GetVersion(_ string, _ int, _ io.Writer) (data out.Item, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) RestoreVersion(_ string, _ int) (err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) Trash() (data []model.DBItem, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) RestoreTrash(_ string) (err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) PurgeTrash(_ time.Duration) (n int, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) NextHOTP(_ string) (code string, counter uint64, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) EncryptTemplates(_ []byte) (data []byte, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) DecryptTemplates(_ []byte) (plain []byte, err error) {
+	err = s.e
+	return
+}
+
+func (s *serviceError) CheckPassphrase() (err error) {
 	err = s.e
 	return
 }
@@ -35,6 +139,11 @@ func (s *serviceError) Get(_ string) (data out.Item, err error) {
 	return
 }
 
+func (s *serviceError) Extract(_ string, _ io.Writer) (data out.Item, err error) {
+	err = s.e
+	return
+}
+
 func (s *serviceError) GetRaw(_ string) (data model.DBRecord, err error) {
 	err = s.e
 	return
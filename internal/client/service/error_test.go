@@ -3,6 +3,7 @@ package service
 import (
 	"errors"
 	"fmt"
+	"gophKeeper/internal/client/crypt"
 	"gophKeeper/internal/client/model"
 	"gophKeeper/internal/client/model/type/card"
 	"testing"
@@ -55,11 +56,65 @@ func TestNewServiceError(t *testing.T) {
 			err = srv.Delete("")
 			assert.Equal(t, err, tt.args.e, "Delete()")
 
-			err = srv.ChangePasswd()
+			err = srv.ChangePasswd(crypt.KDFParams{})
 			assert.Equal(t, err, tt.args.e, "ChangePasswd()")
 
+			err = srv.RotateKey(nil)
+			assert.Equal(t, err, tt.args.e, "RotateKey()")
+
+			_, err = srv.CreateRecovery(0, 0)
+			assert.Equal(t, err, tt.args.e, "CreateRecovery()")
+
+			err = srv.UseRecovery()
+			assert.Equal(t, err, tt.args.e, "UseRecovery()")
+
+			_, err = srv.SetKeyFile("")
+			assert.Equal(t, err, tt.args.e, "SetKeyFile()")
+
+			err = srv.RemoveKeyFile()
+			assert.Equal(t, err, tt.args.e, "RemoveKeyFile()")
+
+			_, err = srv.ExportVault(nil, "")
+			assert.Equal(t, err, tt.args.e, "ExportVault()")
+
+			_, err = srv.RestoreVault(nil, "", false)
+			assert.Equal(t, err, tt.args.e, "RestoreVault()")
+
+			_, err = srv.History("")
+			assert.Equal(t, err, tt.args.e, "History()")
+
+			_, err = srv.GetVersion("", 1, nil)
+			assert.Equal(t, err, tt.args.e, "GetVersion()")
+
+			err = srv.RestoreVersion("", 1)
+			assert.Equal(t, err, tt.args.e, "RestoreVersion()")
+
+			_, err = srv.Trash()
+			assert.Equal(t, err, tt.args.e, "Trash()")
+
+			err = srv.RestoreTrash("")
+			assert.Equal(t, err, tt.args.e, "RestoreTrash()")
+
+			_, err = srv.PurgeTrash(0)
+			assert.Equal(t, err, tt.args.e, "PurgeTrash()")
+
+			_, _, err = srv.NextHOTP("")
+			assert.Equal(t, err, tt.args.e, "NextHOTP()")
+
+			_, _, err = srv.Expiring(0)
+			assert.Equal(t, err, tt.args.e, "Expiring()")
+
+			_, err = srv.EncryptTemplates(nil)
+			assert.Equal(t, err, tt.args.e, "EncryptTemplates()")
+
+			_, err = srv.DecryptTemplates(nil)
+			assert.Equal(t, err, tt.args.e, "DecryptTemplates()")
+
 			_, err = srv.GetToken()
 			assert.Equal(t, err, tt.args.e, "GetToken()")
+
+			err = srv.CheckPassphrase()
+			assert.Equal(t, err, tt.args.e, "CheckPassphrase()")
 		})
 	}
 }
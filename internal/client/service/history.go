@@ -0,0 +1,175 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/crypt"
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/out"
+	"gophKeeper/internal/client/storage"
+)
+
+// History
+// the current version of the record numbered 0, unless it is deleted, followed by its kept versions,
+// the newest first. The descriptions are decrypted
+func (s *service) History(key string) (data []model.DBVersion, err error) {
+	r, err := s.r.DB.Get(key)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err == nil && !r.IsDeleted() {
+		data = append(data, model.DBVersion{DBRecord: model.DBRecord{DBItem: r.DBItem, Filename: r.Filename}})
+	}
+	var versions []model.DBVersion
+	if versions, err = s.r.DB.ListVersions(key); err != nil {
+		return
+	}
+	if data = append(data, versions...); len(data) == 0 {
+		err = sql.ErrNoRows
+		return
+	}
+	items := make([]model.DBItem, len(data))
+	for i := range data {
+		items[i] = data[i].DBItem
+	}
+	if err = s.decryptDescriptions(items); err != nil {
+		return
+	}
+	for i := range data {
+		data[i].Description = items[i].Description
+	}
+	return
+}
+
+// GetVersion
+// decrypt the kept version of the record, the version 0 is the current one.
+// The content of data is written to content if set
+func (s *service) GetVersion(key string, version int, content io.Writer) (data out.Item, err error) {
+	if version == 0 {
+		return s.get(key, content)
+	}
+	var v model.DBVersion
+	if v, err = s.r.DB.GetVersion(key, version); err != nil {
+		return
+	}
+	return s.decodeItem(v.DBRecord, content)
+}
+
+// RestoreVersion
+// replace the record by its kept version, as a new update to be synchronized.
+// The replaced record is kept at the history as any overwritten one
+func (s *service) RestoreVersion(key string, version int) (err error) {
+	var v model.DBVersion
+	if v, err = s.r.DB.GetVersion(key, version); err != nil {
+		return
+	}
+	// the version must be decrypted by the current key to replace the record
+	if _, err = s.decodeItem(v.DBRecord, nil); err != nil {
+		return
+	}
+	var token string
+	if token, err = s.GetToken(); err != nil {
+		err = tokenError(err)
+		return
+	}
+	var opts []crypt.Option
+	if opts, err = cryptOptions(); err != nil {
+		return
+	}
+	var plain string
+	if plain, err = decryptDescription(token, key, v.Description); err != nil {
+		err = decodeError(err, "")
+		return
+	}
+	r := model.DBRecord{
+		DBItem:   model.DBItem{Key: key, CreatedAt: v.CreatedAt},
+		Blob:     v.Blob,
+		Filename: v.Filename,
+	}
+	if r.Description, r.IndexTokens, err = recordDescription(token, key, plain, opts); err != nil {
+		return
+	}
+	var files []string
+	if files, err = saveRecord(s.r.DB, r); err != nil {
+		return
+	}
+	err = s.deleteFiles(files)
+	return
+}
+
+// pruneHistory
+// remove the versions of the saved record beyond the history retention of profile,
+// all versions of the purged one. Returns the files of the removed versions to delete after commit
+func pruneHistory(db storage.DB, r model.DBRecord) (files []string, err error) {
+	if r.IsTombstone() {
+		return db.DeleteHistory(r.Key)
+	}
+	keep, maxAge, err := cfg.GetHistoryRetention()
+	if err != nil {
+		return
+	}
+	var before time.Time
+	if maxAge > 0 {
+		before = time.Now().Add(-maxAge)
+	}
+	return db.PruneHistory(r.Key, keep, before)
+}
+
+// rotateVersions
+// re-key the kept versions of all records from the old token to the new one,
+// the versions not decrypted by the old token are skipped.
+// Returns the names of created files at the file store and the names of files to delete after commit
+func (s *service) rotateVersions(db storage.DB, oldToken, newToken string, opts []crypt.Option) (newFiles, oldFiles []string, err error) {
+	var versions []model.DBVersion
+	if versions, err = db.ListVersions(""); err != nil {
+		return
+	}
+	for _, item := range versions {
+		var v model.DBVersion
+		if v, err = db.GetVersion(item.Key, item.Version); err != nil {
+			return
+		}
+		var newFile, oldFile string
+		v.DBRecord, newFile, oldFile, err = s.reKeyRecord(v.DBRecord, oldToken, newToken, opts)
+		if errors.Is(err, crypt.ErrAuthentication) {
+			// the version encrypted by another key is not readable by the old one either, it is kept as is
+			if newFile != "" {
+				if er := s.r.File.Delete(newFile); er != nil && !os.IsNotExist(er) {
+					err = er
+					return
+				}
+			}
+			err = nil
+			continue
+		}
+		if newFile != "" {
+			newFiles = append(newFiles, newFile)
+		}
+		if err != nil {
+			return
+		}
+		if oldFile != "" {
+			oldFiles = append(oldFiles, oldFile)
+		}
+		if err = db.UpdateVersion(v); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// deleteFiles
+// delete the files of the file store, the missing ones are skipped
+func (s *service) deleteFiles(files []string) (err error) {
+	for _, f := range files {
+		if er := s.r.File.Delete(f); er != nil && !os.IsNotExist(er) {
+			err = errors.Join(err, er)
+		}
+	}
+	return
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/crypt"
+	errs "gophKeeper/internal/client/errors"
+	"gophKeeper/internal/client/input/password"
+)
+
+// tokenError
+// hide the details of the encryption key unwrapping error,
+// key file and passphrase source errors are kept to tell them from a wrong password
+func tokenError(err error) error {
+	var pathErr *fs.PathError
+	switch {
+	case cfg.Glob.GetBool("debug"):
+		return err
+	case errors.Is(err, crypt.ErrKeyFileMissing), errors.Is(err, crypt.ErrKeyFileWrong),
+		errors.Is(err, crypt.ErrKeyFileSize):
+		return err
+	case errors.Is(err, password.ErrPassphraseFile), errors.Is(err, password.ErrEmptyPassphrase),
+		errors.Is(err, password.ErrPinentryCancel), errors.As(err, &pathErr):
+		return err
+	}
+	return errs.ErrPassword
+}
+
+// readKeyFile
+// content of the key file set at profile, nil if the key file is not set or not found
+func readKeyFile(path string) (content []byte, err error) {
+	if path == "" {
+		return
+	}
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	defer func() { _ = f.Close() }()
+	if content, err = io.ReadAll(io.LimitReader(f, crypt.MaxKeyFileLen+1)); err != nil {
+		return
+	}
+	if len(content) < crypt.MinKeyFileLen || len(content) > crypt.MaxKeyFileLen {
+		err = fmt.Errorf("%w: %s", crypt.ErrKeyFileSize, path)
+	}
+	return
+}
+
+// keyFileFingerprint
+// fingerprint of the key file set at profile, empty if the key file is not set
+func keyFileFingerprint() (fp string, err error) {
+	path := cfg.User.GetString("key_file")
+	if path == "" {
+		return
+	}
+	var content []byte
+	if content, err = readKeyFile(path); err != nil {
+		return
+	}
+	if content == nil {
+		err = fmt.Errorf("%w: %s", crypt.ErrKeyFileMissing, path)
+		return
+	}
+	fp = crypt.KeyFileFingerprint(content)
+	return
+}
+
+// deriveKey
+// derive the key wrapping the encryption token, with the key file of profile if params need it
+func deriveKey(keyPass string, p crypt.KDFParams) (key string, err error) {
+	var content []byte
+	if p.KeyFile != "" {
+		path := cfg.User.GetString("key_file")
+		if content, err = readKeyFile(path); err != nil {
+			return
+		}
+		if content == nil && path != "" {
+			err = fmt.Errorf("%w: %s", crypt.ErrKeyFileMissing, path)
+			return
+		}
+	}
+	return crypt.DeriveKey(keyPass, p, content)
+}
+
+// keyFilePath
+// absolute path of the key file, it must be outside the profile directory
+func keyFilePath(path string) (abs string, err error) {
+	if abs, err = filepath.Abs(path); err != nil {
+		return
+	}
+	var dir string
+	if dir, err = cfg.UsrCfgDir(); err != nil {
+		return
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return
+	}
+	if rel, er := filepath.Rel(dir, abs); er == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		err = errs.ErrKeyFileInProfile
+	}
+	return
+}
+
+// SetKeyFile
+// set the key file as the second factor of the encryption key unwrapping.
+// The not existing file is created with random content.
+// The key file with the same content only updates its path, as on another device,
+// other one re-wraps the encryption key, the passphrase and the current key file are asked
+func (s *service) SetKeyFile(path string) (created bool, err error) {
+	if path, err = keyFilePath(path); err != nil {
+		return
+	}
+	var content []byte
+	if content, err = readKeyFile(path); err != nil {
+		return
+	}
+	if content == nil {
+		if content, err = crypt.NewKeyFile(); err != nil {
+			return
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return
+		}
+		if err = os.WriteFile(path, content, 0400); err != nil {
+			return
+		}
+		created = true
+		defer func() {
+			if err != nil {
+				_ = os.Remove(path)
+				created = false
+			}
+		}()
+	}
+	fp := crypt.KeyFileFingerprint(content)
+	params, ok, err := cfg.GetKDFParams()
+	if err != nil {
+		return
+	}
+	packed := cfg.User.GetString("packed_key")
+	if packed == "" || ok && params.KeyFile == fp {
+		cfg.User.Set("key_file", path)
+		return
+	}
+	params.KeyFile = fp
+	err = s.rewrapToken(params, path)
+	return
+}
+
+// RemoveKeyFile
+// unwrap the encryption key with the passphrase and the key file, and wrap it by the passphrase only
+func (s *service) RemoveKeyFile() (err error) {
+	params, ok, err := cfg.GetKDFParams()
+	if err != nil {
+		return
+	}
+	if !ok || params.KeyFile == "" || cfg.User.GetString("packed_key") == "" {
+		cfg.User.Set("key_file", nil)
+		return
+	}
+	params.KeyFile = ""
+	return s.rewrapToken(params, "")
+}
+
+// rewrapToken
+// unwrap the encryption key by the current passphrase and key file,
+// and wrap it again for the key file of params at path
+func (s *service) rewrapToken(params crypt.KDFParams, path string) (err error) {
+	var passRaw string
+	if passRaw, err = getPassphrase(false, cfg.PromptMasterPs, cfg.PromptConfirmMasterPs); err != nil {
+		return
+	}
+	keyPass := masterKeyPass(passRaw)
+	var token []byte
+	if token, err = unpackToken(cfg.User.GetString("packed_key"), keyPass); err != nil {
+		err = tokenError(err)
+		return
+	}
+	if params, err = crypt.NewKDFParams(params); err != nil {
+		return
+	}
+	bakPath := cfg.User.Get("key_file")
+	if path == "" {
+		cfg.User.Set("key_file", nil)
+	} else {
+		cfg.User.Set("key_file", path)
+	}
+	if err = packToken(token, keyPass, params); err != nil {
+		cfg.User.Set("key_file", bakPath)
+		return
+	}
+	s.cacheToken(token)
+	return
+}
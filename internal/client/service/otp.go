@@ -0,0 +1,38 @@
+package service
+
+import (
+	"time"
+
+	errs "gophKeeper/internal/client/errors"
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/type/otp"
+)
+
+// NextHOTP
+// the code of the counter based one-time password record, the stored counter is advanced past it.
+// The record is updated in place, as a new update to be synchronized, the counter is not kept as a version
+func (s *service) NextHOTP(key string) (code string, counter uint64, err error) {
+	item, err := s.get(key, nil)
+	if err != nil {
+		return
+	}
+	d, ok := item.Data.(*otp.Data)
+	if !ok || d.Type != otp.TypeHOTP {
+		err = errs.ErrNotHOTP
+		return
+	}
+	if code, _, err = d.Code(time.Now()); err != nil {
+		return
+	}
+	counter = d.Counter
+	d.Counter++
+	m := otp.New()
+	m.Key, m.Description, m.Extra, m.Data = key, item.Description, item.Extra, d
+	var next model.DBRecord
+	if next, err = s.encodeRecord(m); err != nil {
+		return
+	}
+	next.CreatedAt = item.CreatedAt
+	err = rewriteRecord(s.r.DB, next)
+	return
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/input/password"
+)
+
+// passphraseSource
+// the source of the master password by the fixed precedence:
+// --passphrase-fd, --passphrase-file or the profile passphrase.file,
+// the environment variable named by the profile passphrase.env,
+// the profile passphrase.pinentry program.
+// nil means the terminal prompt.
+// The not interactive sources are skipped if interactiveOnly, as on asking a changed password
+func passphraseSource(interactiveOnly bool) password.Source {
+	if interactiveOnly {
+		if cfg.User.Viper != nil {
+			if program := cfg.User.GetString("passphrase.pinentry"); program != "" {
+				return password.PinentrySource(program)
+			}
+		}
+		return nil
+	}
+	if cfg.Glob.Viper.Get("passphrase_fd") != nil {
+		return password.FDSource(cfg.Glob.GetInt("passphrase_fd"))
+	}
+	if path := cfg.Glob.GetString("passphrase_file"); path != "" {
+		return password.FileSource(path)
+	}
+	if cfg.User.Viper == nil {
+		return nil
+	}
+	if path := cfg.User.GetString("passphrase.file"); path != "" {
+		return password.FileSource(path)
+	}
+	if name := cfg.User.GetString("passphrase.env"); name != "" {
+		return password.EnvSource(name)
+	}
+	if program := cfg.User.GetString("passphrase.pinentry"); program != "" {
+		return password.PinentrySource(program)
+	}
+	return nil
+}
+
+// getPassphrase
+// the master password from the configured source
+func getPassphrase(confirm bool, prompts ...string) (string, error) {
+	return password.GetPassphrase(passphraseSource(false), confirm, prompts...)
+}
+
+// getNewPassphrase
+// the changed master password, it is asked interactively,
+// as the not interactive source keeps the current one
+func getNewPassphrase(prompts ...string) (string, error) {
+	return password.GetPassphrase(passphraseSource(true), true, prompts...)
+}
+
+// recoverySource
+// the source of the recovery code or its shares: --passphrase-fd, --passphrase-file
+// or the profile passphrase.pinentry program, nil means the terminal prompt.
+// The profile passphrase.file and passphrase.env keep the master password, so they are skipped
+func recoverySource() password.Source {
+	if cfg.Glob.Viper.Get("passphrase_fd") != nil {
+		return password.FDSource(cfg.Glob.GetInt("passphrase_fd"))
+	}
+	if path := cfg.Glob.GetString("passphrase_file"); path != "" {
+		return password.FileSource(path)
+	}
+	return passphraseSource(true)
+}
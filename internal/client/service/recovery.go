@@ -0,0 +1,205 @@
+package service
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/crypt"
+	errs "gophKeeper/internal/client/errors"
+	"gophKeeper/internal/client/input/password"
+)
+
+// recoveryAD
+// associated data of the recovery wrapping, bound to the profile name
+func recoveryAD() []byte {
+	return []byte("gophkeeper/recovery\x00" + cfg.User.GetString("name"))
+}
+
+// packRecovery
+// wrap the token with the recovery secret, and the secret with the token to re-wrap it at key rotation
+func packRecovery(token, secret []byte, r *cfg.Recovery) (err error) {
+	var opts []crypt.Option
+	if opts, err = cryptOptions(); err != nil {
+		return
+	}
+	var packed, codeKey []byte
+	if packed, err = crypt.Encode(token, string(secret), append(opts, crypt.WithAssociatedData(recoveryAD()))...); err != nil {
+		return
+	}
+	if codeKey, err = crypt.Encode(secret, string(token), opts...); err != nil {
+		return
+	}
+	r.PackedKey = hex.EncodeToString(packed)
+	r.CodeKey = hex.EncodeToString(codeKey)
+	return
+}
+
+// CreateRecovery
+// create a new recovery code and store the encryption key wrapped by it to the profile,
+// the previous recovery code is no longer valid. If shares is set,
+// the code is split into shares, any threshold of them can be used instead of the code
+func (s *service) CreateRecovery(shares, threshold int) (codes []string, err error) {
+	if shares == 0 && threshold != 0 || shares != 0 && (threshold < 2 || threshold > shares) {
+		err = fmt.Errorf("%w: %d of %d", crypt.ErrShareParams, threshold, shares)
+		return
+	}
+	var token string
+	if token, err = s.GetToken(); err != nil {
+		err = tokenError(err)
+		return
+	}
+	var secret []byte
+	if secret, err = crypt.NewRecoverySecret(); err != nil {
+		return
+	}
+	if shares == 0 {
+		codes = []string{crypt.FormatRecoveryCode(secret)}
+	} else {
+		var split [][]byte
+		if split, err = crypt.SplitSecret(secret, shares, threshold); err != nil {
+			return
+		}
+		for _, share := range split {
+			codes = append(codes, crypt.FormatRecoveryShare(share))
+		}
+	}
+	r := cfg.Recovery{
+		Shares:    shares,
+		Threshold: threshold,
+		CreatedAt: time.Now().Format(time.DateTime),
+	}
+	if err = packRecovery([]byte(token), secret, &r); err != nil {
+		codes = nil
+		return
+	}
+	cfg.SetRecovery(&r)
+	return
+}
+
+// readRecoverySecret
+// read the recovery code or enough of its shares from the recovery source,
+// a line may have several shares separated by spaces, as the not interactive sources give one line
+func readRecoverySecret() (secret []byte, err error) {
+	src := recoverySource()
+	var text string
+	if text, err = password.GetPassphrase(src, false, cfg.PromptRecoveryCode); err != nil {
+		return
+	}
+	if secret, err = crypt.ParseRecoveryCode(text); err == nil {
+		return
+	}
+	var shares [][]byte
+	if shares, err = parseRecoveryShares(text, nil); err != nil {
+		err = errors.Join(crypt.ErrRecoveryCode, err)
+		return
+	}
+	for threshold := crypt.ShareThreshold(shares[0]); len(shares) < threshold; {
+		prompt := fmt.Sprintf(cfg.PromptRecoveryShare, len(shares), threshold)
+		if text, err = password.GetPassphrase(src, false, prompt); err != nil {
+			return
+		}
+		if shares, err = parseRecoveryShares(text, shares); err != nil {
+			return
+		}
+	}
+	return crypt.CombineShares(shares)
+}
+
+// parseRecoveryShares
+// appends the recovery shares of the text to the entered ones, the text is one share or the shares separated by spaces.
+// The share entered again is an error, so the not interactive source giving the same line does not repeat it
+func parseRecoveryShares(text string, entered [][]byte) (shares [][]byte, err error) {
+	fields := []string{text}
+	if _, err = crypt.ParseRecoveryShare(text); err != nil {
+		if fields = strings.Fields(text); len(fields) < 2 {
+			return
+		}
+	}
+	shares = entered
+	for _, f := range fields {
+		var share []byte
+		if share, err = crypt.ParseRecoveryShare(f); err != nil {
+			return
+		}
+		if slices.ContainsFunc(shares, func(s []byte) bool { return bytes.Equal(s, share) }) {
+			err = fmt.Errorf("%w: the share is entered again", crypt.ErrRecoveryShare)
+			return
+		}
+		shares = append(shares, share)
+	}
+	return
+}
+
+// UseRecovery
+// unwrap the encryption key by the recovery code or its shares and set a new master passphrase
+func (s *service) UseRecovery() (err error) {
+	r, ok, err := cfg.GetRecovery()
+	if err != nil {
+		return
+	}
+	if !ok {
+		err = errs.ErrNoRecovery
+		return
+	}
+	var packed []byte
+	if packed, err = hex.DecodeString(r.PackedKey); err != nil {
+		return
+	}
+	var secret, token []byte
+	if secret, err = readRecoverySecret(); err != nil {
+		return
+	}
+	if token, err = crypt.Decode(packed, string(secret), crypt.WithAssociatedData(recoveryAD())); err != nil {
+		err = fmt.Errorf("%w: %w", crypt.ErrRecoveryCode, err)
+		return
+	}
+
+	costs, ok, err := cfg.GetKDFParams()
+	if err != nil {
+		return
+	}
+	if !ok {
+		costs = crypt.DefaultKDFParams
+	}
+	var params crypt.KDFParams
+	if params, err = crypt.NewKDFParams(costs); err != nil {
+		return
+	}
+	var passRaw string
+	passRaw, err = getNewPassphrase(cfg.PromptNewMasterPs, cfg.PromptConfirmMasterPs)
+	if err != nil {
+		return
+	}
+	if err = packToken(token, masterKeyPass(passRaw), params); err != nil {
+		return
+	}
+	s.cacheToken(token)
+	return
+}
+
+// rotateRecovery
+// re-wrap the recovery wrapping for the new token, the recovery code is kept valid
+func rotateRecovery(oldToken, newToken string) (err error) {
+	r, ok, err := cfg.GetRecovery()
+	if err != nil || !ok {
+		return
+	}
+	var codeKey, secret []byte
+	if codeKey, err = hex.DecodeString(r.CodeKey); err != nil {
+		return
+	}
+	if secret, err = crypt.Decode(codeKey, oldToken); err != nil {
+		return
+	}
+	if err = packRecovery([]byte(newToken), secret, &r); err != nil {
+		return
+	}
+	cfg.SetRecovery(&r)
+	return
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/crypt"
+	errs "gophKeeper/internal/client/errors"
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/out"
+	"gophKeeper/internal/client/storage"
+)
+
+// RotateKey
+// replace the encryption key of profile with a new random one.
+// Data keys of records and their kept versions are re-wrapped with the new key in one transaction,
+// records saved without own data key are re-encrypted with a new one.
+// progress is called after each record, if set
+func (s *service) RotateKey(progress func(done, total int)) (err error) {
+	packed := cfg.User.GetString("packed_key")
+	if packed == "" {
+		err = errs.ErrNoEncryptionKey
+		return
+	}
+	var passRaw string
+	if passRaw, err = getPassphrase(false, cfg.PromptMasterPs, cfg.PromptConfirmMasterPs); err != nil {
+		return
+	}
+	keyPass := masterKeyPass(passRaw)
+	var oldToken []byte
+	if oldToken, err = unpackToken(packed, keyPass); err != nil {
+		err = tokenError(err)
+		return
+	}
+	newToken := make([]byte, 128)
+	if _, err = rand.Read(newToken); err != nil {
+		err = errors.Join(errors.New("error create new token"), err)
+		return
+	}
+	var opts []crypt.Option
+	if opts, err = cryptOptions(); err != nil {
+		return
+	}
+	params, _, err := cfg.GetKDFParams()
+	if err != nil {
+		return
+	}
+	if params, err = crypt.NewKDFParams(params); err != nil {
+		return
+	}
+
+	bak := make(map[string]any)
+	for _, k := range []string{"packed_key", "kdf", "sync.token", "recovery"} {
+		bak[k] = cfg.User.Get(k)
+	}
+	var newFiles, oldFiles []string
+	err = s.r.DB.Transaction(func(db storage.DB) (err error) {
+		var items []model.DBItem
+		// the records at the trash are re-keyed too, to be restored
+		if items, err = db.List(model.ListQuery{Deleted: true}); err != nil {
+			return
+		}
+		for i, item := range items {
+			var newFile, oldFile string
+			newFile, oldFile, err = s.rotateRecord(db, item.Key, string(oldToken), string(newToken), opts)
+			if newFile != "" {
+				newFiles = append(newFiles, newFile)
+			}
+			if err != nil {
+				return
+			}
+			if oldFile != "" {
+				oldFiles = append(oldFiles, oldFile)
+			}
+			if progress != nil {
+				progress(i+1, len(items))
+			}
+		}
+		var created, obsolete []string
+		created, obsolete, err = s.rotateVersions(db, string(oldToken), string(newToken), opts)
+		newFiles, oldFiles = append(newFiles, created...), append(oldFiles, obsolete...)
+		if err != nil {
+			return
+		}
+		if err = rotateSyncToken(string(oldToken), string(newToken), opts); err != nil {
+			return
+		}
+		if err = rotateRecovery(string(oldToken), string(newToken)); err != nil {
+			return
+		}
+		if err = packToken(newToken, keyPass, params); err != nil {
+			return
+		}
+		// the new key must be stored before commit, the old one is restored on failure
+		err = cfg.User.Save()
+		return
+	})
+	if err != nil {
+		for k, v := range bak {
+			cfg.User.Set(k, v)
+		}
+		err = errors.Join(err, cfg.User.Save())
+		for _, f := range newFiles {
+			err = errors.Join(err, s.r.File.Delete(f))
+		}
+		return
+	}
+	for _, f := range oldFiles {
+		if er := s.r.File.Delete(f); er != nil && !os.IsNotExist(er) {
+			err = errors.Join(err, er)
+		}
+	}
+	s.cacheToken(newToken)
+	return
+}
+
+// rotateRecord
+// re-key the record from the old token to the new one and save it.
+// Returns the name of created file at the file store and the name of file to delete after commit
+func (s *service) rotateRecord(db storage.DB, key, oldToken, newToken string, opts []crypt.Option) (newFile, oldFile string, err error) {
+	var r model.DBRecord
+	if r, err = db.Get(key); err != nil || r.IsTombstone() {
+		return
+	}
+	if r, newFile, oldFile, err = s.reKeyRecord(r, oldToken, newToken, opts); err != nil {
+		return
+	}
+	r.UpdatedAt = nil
+	err = rewriteRecord(db, r)
+	return
+}
+
+// reKeyRecord
+// re-wrap the data key of record blob from the old token to the new one,
+// the blob without data key is re-encrypted, as the encrypted description with its index.
+// Returns the record to save, the name of created file at the file store and the name of file to delete after commit
+func (s *service) reKeyRecord(r model.DBRecord, oldToken, newToken string, opts []crypt.Option) (_ model.DBRecord, newFile, oldFile string, err error) {
+	if r.HasEncryptedDescription() {
+		var plain string
+		if plain, err = decryptDescription(oldToken, r.Key, r.Description); err != nil {
+			err = decodeError(err, "")
+			return r, newFile, oldFile, err
+		}
+		if r.Description, r.IndexTokens, err = encryptDescription(newToken, r.Key, plain, opts); err != nil {
+			return r, newFile, oldFile, err
+		}
+	}
+	if len(r.Blob) == 0 && r.Filename != nil {
+		oldFile = *r.Filename
+		var rewrapped bool
+		if newFile, rewrapped, err = s.rewrapStored(r, oldToken, newToken, opts); err != nil || rewrapped {
+			if err == nil {
+				r.Filename = &newFile
+			}
+			return r, newFile, oldFile, err
+		}
+		if r.Blob, err = s.r.File.GetStored(oldFile); err != nil {
+			return r, newFile, oldFile, err
+		}
+		r.Filename = nil
+	}
+	if r.Blob, err = reKeyBlob(r, oldToken, newToken, opts); err != nil {
+		return r, newFile, oldFile, err
+	}
+	if len(r.Blob) > cfg.MaxBlobSize {
+		// a new file keeps the old one valid until commit
+		newFile = storeFileName(r.Key)
+		if err = s.r.File.SaveStore(newFile, r.Blob); err != nil {
+			return r, "", oldFile, err
+		}
+		r.Filename = &newFile
+		r.Blob = nil
+	}
+	return r, newFile, oldFile, err
+}
+
+// rewrapStored
+// re-wrap the data key of the stored file into a new file by parts,
+// the file without data key is not rewrapped
+func (s *service) rewrapStored(r model.DBRecord, oldToken, newToken string, opts []crypt.Option) (newFile string, rewrapped bool, err error) {
+	var src io.ReadSeekCloser
+	if src, err = s.r.File.OpenStored(*r.Filename); err != nil {
+		return
+	}
+	defer func() { _ = src.Close() }()
+	info, infoErr := crypt.ReadInfo(src)
+	if infoErr != nil || !info.HasDataKey() {
+		return
+	}
+	if _, err = src.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	// a new file keeps the old one valid until commit
+	newFile = storeFileName(r.Key)
+	var dst io.WriteCloser
+	if dst, err = s.r.File.CreateStore(newFile); err != nil {
+		newFile = ""
+		return
+	}
+	err = crypt.RewrapStream(src, dst, oldToken, newToken,
+		append(opts, crypt.WithAssociatedData(r.AssociatedData(info.ContentType)))...)
+	err = errors.Join(err, dst.Close())
+	rewrapped = err == nil
+	return
+}
+
+// reKeyBlob
+// re-wrap the data key of blob, blobs without data key are decoded and encoded with a new one
+func reKeyBlob(r model.DBRecord, oldToken, newToken string, opts []crypt.Option) (blob []byte, err error) {
+	dataType := crypt.ContentType(r.Blob)
+	if crypt.HasDataKey(r.Blob) {
+		return crypt.Rewrap(r.Blob, oldToken, newToken, append(opts, crypt.WithAssociatedData(r.AssociatedData(dataType)))...)
+	}
+	var decodeOpts []crypt.Option
+	if dataType != "" {
+		decodeOpts = append(decodeOpts, crypt.WithAssociatedData(r.AssociatedData(dataType)))
+	}
+	var plain []byte
+	if plain, err = crypt.Decode(r.Blob, oldToken, decodeOpts...); err != nil {
+		return
+	}
+	if dataType == "" {
+		var item out.Item
+		if err = json.Unmarshal(plain, &item); err != nil {
+			return
+		}
+		dataType = model.GetName(item.Data)
+	}
+	return crypt.Encode(plain, newToken, append(opts, crypt.WithDataKey(), crypt.WithContentType(dataType),
+		crypt.WithAssociatedData(r.AssociatedData(dataType)))...)
+}
+
+// rotateSyncToken
+// re-encrypt the synchronization token of profile with the new token
+func rotateSyncToken(oldToken, newToken string, opts []crypt.Option) (err error) {
+	encrypted := cfg.User.GetString("sync.token")
+	if encrypted == "" {
+		return
+	}
+	var b []byte
+	if b, err = hex.DecodeString(encrypted); err != nil {
+		return
+	}
+	if b, err = crypt.Decode(b, oldToken); err != nil {
+		return
+	}
+	if b, err = crypt.Encode(b, newToken, opts...); err != nil {
+		return
+	}
+	cfg.User.Set("sync.token", hex.EncodeToString(b))
+	return
+}
@@ -1,14 +1,23 @@
 package service
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
-
-	"gophKeeper/internal/client/input/password"
+	"unicode"
+	"unicode/utf8"
 
 	cfg "gophKeeper/internal/client/config"
 	"gophKeeper/internal/client/crypt"
@@ -16,30 +25,166 @@ import (
 	"gophKeeper/internal/client/model"
 	"gophKeeper/internal/client/model/out"
 	"gophKeeper/internal/client/storage"
+	"gophKeeper/internal/helper"
 )
 
 type Service interface {
 	List(query model.ListQuery) (data out.List, err error)
 	Get(key string) (data out.Item, err error)
+	Extract(key string, w io.Writer) (data out.Item, err error)
 	GetRaw(key string) (data model.DBRecord, err error)
 	Save(data model.Model) (err error)
 	SaveRaw(data model.DBRecord) (err error)
 	Delete(key string) (err error)
 	GetToken() (token string, err error)
-	ChangePasswd() (err error)
+	CheckPassphrase() (err error)
+	ChangePasswd(costs crypt.KDFParams) (err error)
+	RotateKey(progress func(done, total int)) (err error)
+	CreateRecovery(shares, threshold int) (codes []string, err error)
+	UseRecovery() (err error)
+	SetKeyFile(path string) (created bool, err error)
+	RemoveKeyFile() (err error)
+	SetDescriptionMode(encrypt, serverIndex bool) (n int, err error)
+	CountEncryptedDescriptions() (n uint64, err error)
+	Expiring(within time.Duration) (items []out.Item, skipped []string, err error)
+	ExportVault(w io.Writer, pass string) (n int, err error)
+	RestoreVault(r io.Reader, pass string, overwrite bool) (res VaultRestore, err error)
+	History(key string) (data []model.DBVersion, err error)
+	GetVersion(key string, version int, content io.Writer) (data out.Item, err error)
+	RestoreVersion(key string, version int) (err error)
+	Trash() (data []model.DBItem, err error)
+	RestoreTrash(key string) (err error)
+	PurgeTrash(olderThan time.Duration) (n int, err error)
+	NextHOTP(key string) (code string, counter uint64, err error)
+	EncryptTemplates(plain []byte) (data []byte, err error)
+	DecryptTemplates(data []byte) (plain []byte, err error)
 }
 
 var _ Service = (*service)(nil)
 
+// maxStoreKeyLen the length of the record key kept in the name of its file at the file store
+const maxStoreKeyLen = 64
+
 type service struct {
-	r *storage.Storage
+	r    *storage.Storage
+	keys KeyCache
 }
 
-func NewService(r *storage.Storage) *service {
-	return &service{r: r}
+// KeyCache
+// external store of the unwrapped encryption key shared by client processes, as the unlock agent
+type KeyCache interface {
+	GetKey(id string) (key []byte, err error)
+	PutKey(id string, key []byte) (err error)
+}
+
+type Option func(s *service)
+
+// WithKeyCache
+// the encryption key is taken from the cache before asking the password, and stored to it after
+func WithKeyCache(c KeyCache) Option {
+	return func(s *service) {
+		s.keys = c
+	}
+}
+
+func NewService(r *storage.Storage, opts ...Option) *service {
+	s := &service{r: r}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// keyCacheID
+// id of the encryption key at the key cache, a new packed key is cached separately
+func keyCacheID(packed string) string {
+	sum := sha256.Sum256([]byte(cfg.User.GetString("name") + string([]byte{0}) + packed))
+	return hex.EncodeToString(sum[:])
+}
+
+// cryptOptions
+// encryption options from user profile
+func cryptOptions() (opts []crypt.Option, err error) {
+	var alg crypt.Algorithm
+	if alg, err = crypt.ParseAlgorithm(cfg.User.GetString("crypt.algorithm")); err != nil {
+		return
+	}
+	opts = append(opts, crypt.WithAlgorithm(alg))
+	return
+}
+
+// masterKeyPass
+// the passphrase for key derivation, bound to the profile name
+func masterKeyPass(passRaw string) string {
+	return profileKeyPass(cfg.User.GetString("name"), passRaw)
+}
+
+// profileKeyPass
+// the passphrase for key derivation of the profile of name
+func profileKeyPass(name, passRaw string) string {
+	return name + string([]byte{9}) + passRaw
+}
+
+// packToken
+// wrap the encryption token with the key derived from the passphrase by p,
+// and store it with the derivation parameters to the profile
+func packToken(token []byte, keyPass string, p crypt.KDFParams) (err error) {
+	var (
+		key         string
+		opts        []crypt.Option
+		packedBytes []byte
+	)
+	if key, err = deriveKey(keyPass, p); err != nil {
+		return
+	}
+	if opts, err = cryptOptions(); err != nil {
+		return
+	}
+	packedBytes, err = crypt.Encode(token, key, append(opts, crypt.WithKDF(crypt.KDFArgon2id))...)
+	if err != nil {
+		return
+	}
+	cfg.User.Set("packed_key", hex.EncodeToString(packedBytes))
+	cfg.SetKDFParams(p)
+	return
 }
 
-func (s *service) ChangePasswd() (err error) {
+// unpackToken
+// unwrap the encryption token from the packed_key of profile,
+// legacy packed key, wrapped with sha256 of passphrase, is repacked with argon2id
+func unpackToken(packed, keyPass string) (token []byte, err error) {
+	var packedBytes []byte
+	packedBytes, err = hex.DecodeString(packed)
+	if err != nil {
+		err = errors.Join(errors.New("error hex.DecodeString"), err)
+		return
+	}
+	params, ok, err := cfg.GetKDFParams()
+	if err != nil {
+		return
+	}
+	if !ok {
+		if token, err = crypt.Decode(packedBytes, keyPass); err != nil {
+			return
+		}
+		if params, err = crypt.NewKDFParams(crypt.DefaultKDFParams); err != nil {
+			return
+		}
+		err = packToken(token, keyPass, params)
+		return
+	}
+	var key string
+	if key, err = deriveKey(keyPass, params); err != nil {
+		return
+	}
+	token, err = crypt.Decode(packedBytes, key)
+	return
+}
+
+// ChangePasswd
+// set new passphrase for encryption key, costs of the key derivation can be raised,
+// zero costs are kept from the current parameters
+func (s *service) ChangePasswd(costs crypt.KDFParams) (err error) {
 	var token string
 	isNew := cfg.User.GetString("packed_key") == ""
 	bakToken := cfg.User.GetString("encryption_key")
@@ -49,49 +194,105 @@ func (s *service) ChangePasswd() (err error) {
 			cfg.User.Set("encryption_key", bakToken)
 		}
 	}()
-	token, err = s.GetToken()
+	// the current password is asked, not taken from the key cache
+	token, err = s.getToken(false)
 	if err != nil {
 		return
 	}
 	if isNew {
 		return
 	}
-	var passRaw string
-	passRaw, err = password.GetRawPass(true, cfg.PromptNewMasterPs, cfg.PromptConfirmMasterPs)
-	userName := cfg.User.GetString("name")
-
-	var (
-		packedBytes  []byte
-		cryptKeyPass = userName + string([]byte{9}) + passRaw
-	)
+	current, ok, err := cfg.GetKDFParams()
+	if err != nil {
+		return
+	}
+	if !ok {
+		current = crypt.DefaultKDFParams
+	}
+	if costs.Time == 0 {
+		costs.Time = current.Time
+	}
+	if costs.Memory == 0 {
+		costs.Memory = current.Memory
+	}
+	if costs.Threads == 0 {
+		costs.Threads = current.Threads
+	}
+	costs.KeyFile = current.KeyFile
+	if costs.Weaker(current) {
+		err = crypt.ErrKDFWeaker
+		return
+	}
+	var params crypt.KDFParams
+	if params, err = crypt.NewKDFParams(costs); err != nil {
+		return
+	}
 
-	packedBytes, err = crypt.Encode([]byte(token), cryptKeyPass)
+	var passRaw string
+	passRaw, err = getNewPassphrase(cfg.PromptNewMasterPs, cfg.PromptConfirmMasterPs)
 	if err != nil {
 		return
 	}
-	cfg.User.Set("packed_key", hex.EncodeToString(packedBytes))
 
+	if err = packToken([]byte(token), masterKeyPass(passRaw), params); err != nil {
+		return
+	}
+	s.cacheToken([]byte(token))
 	return
 }
 
+// cacheToken
+// cache the unwrapped token in config, it must be excluded from saving, and at the key cache if set
+func (s *service) cacheToken(token []byte) {
+	cfg.User.Set("encryption_key", string(token))
+	if s.keys != nil {
+		// the cache is optional, as the agent can be not running
+		_ = s.keys.PutKey(keyCacheID(cfg.User.GetString("packed_key")), token)
+	}
+}
+
 func (s *service) GetToken() (token string, err error) {
+	return s.getToken(true)
+}
+
+// CheckPassphrase
+// ask the master passphrase and unwrap the encryption key by it, the key cache and the unwrapped key
+// are not used, as to confirm the plaintext export
+func (s *service) CheckPassphrase() (err error) {
+	if cfg.User.GetString("packed_key") == "" {
+		err = errs.ErrNoEncryptionKey
+		return
+	}
+	cfg.User.Set("encryption_key", nil)
+	if _, err = s.getToken(false); err != nil {
+		err = tokenError(err)
+	}
+	return
+}
+
+// getToken
+// the encryption token unwrapped by the password, useCache allows to take it from the key cache
+func (s *service) getToken(useCache bool) (token string, err error) {
 	token = cfg.User.GetString("encryption_key")
 	if token == "" {
 		packed := cfg.User.GetString("packed_key")
-		userName := cfg.User.GetString("name")
+		if packed != "" && useCache && s.keys != nil {
+			if key, er := s.keys.GetKey(keyCacheID(packed)); er == nil && len(key) > 0 {
+				token = string(key)
+				cfg.User.Set("encryption_key", token)
+				return
+			}
+		}
 		var passRaw string
 		if packed == "" {
-			passRaw, err = password.GetRawPass(true, cfg.PromptNewMasterPs, cfg.PromptConfirmMasterPs)
+			passRaw, err = getPassphrase(true, cfg.PromptNewMasterPs, cfg.PromptConfirmMasterPs)
 		} else {
-			passRaw, err = password.GetRawPass(false, cfg.PromptMasterPs, cfg.PromptConfirmMasterPs)
+			passRaw, err = getPassphrase(false, cfg.PromptMasterPs, cfg.PromptConfirmMasterPs)
 		}
 		if err != nil {
 			return
 		}
-		var (
-			packedBytes, tokenBytes []byte
-			cryptKeyPass            = userName + string([]byte{9}) + passRaw
-		)
+		var tokenBytes []byte
 		if packed == "" {
 			// fmt.Println("Creating new token... ")
 			tokenBytes = make([]byte, 128)
@@ -100,27 +301,26 @@ func (s *service) GetToken() (token string, err error) {
 				err = errors.Join(errors.New("error create new token"), err)
 				return
 			}
-			packedBytes, err = crypt.Encode(tokenBytes, cryptKeyPass)
-			if err != nil {
+			var params crypt.KDFParams
+			if params, err = crypt.NewKDFParams(crypt.DefaultKDFParams); err != nil {
 				return
 			}
-
-			cfg.User.Set("packed_key", hex.EncodeToString(packedBytes))
-		} else {
-			packedBytes, err = hex.DecodeString(packed)
-			if err != nil {
-				err = errors.Join(errors.New("error hex.DecodeString"), err)
+			// the key file set before the key is created becomes its second factor
+			if params.KeyFile, err = keyFileFingerprint(); err != nil {
 				return
 			}
-			tokenBytes, err = crypt.Decode(packedBytes, cryptKeyPass)
+			if err = packToken(tokenBytes, masterKeyPass(passRaw), params); err != nil {
+				return
+			}
+		} else {
+			tokenBytes, err = unpackToken(packed, masterKeyPass(passRaw))
 			if err != nil {
 				err = errors.Join(errors.New("error decode token"), err)
 				return
 			}
 		}
 		token = string(tokenBytes)
-		// cache token in config, it must be excluded from saving
-		cfg.User.Set("encryption_key", token)
+		s.cacheToken(tokenBytes)
 	}
 	return
 }
@@ -129,6 +329,14 @@ func (s *service) List(query model.ListQuery) (data out.List, err error) {
 	if err = query.Validate(); err != nil {
 		return
 	}
+	if query.Description != "" {
+		if err = s.searchTokens(&query); err != nil {
+			return
+		}
+	}
+	if query.Field != "" || query.URL != "" || query.Expiring != "" {
+		return s.searchDecrypted(query)
+	}
 	if data.Total, err = s.r.DB.Count(query); err != nil {
 		return
 	}
@@ -136,44 +344,232 @@ func (s *service) List(query model.ListQuery) (data out.List, err error) {
 	if err != nil {
 		return
 	}
+	err = s.decryptDescriptions(data.Items)
+	return
+}
+
+// searchDecrypted
+// the records of query are matched by their extra fields, urls and expiry time at the decrypted data,
+// the limit and offset are applied to the found ones
+func (s *service) searchDecrypted(query model.ListQuery) (data out.List, err error) {
+	var within time.Duration
+	if query.Expiring != "" {
+		if within, err = helper.ParseDuration(query.Expiring); err != nil {
+			return
+		}
+	}
+	limit, offset := query.Limit, query.Offset
+	query.Limit, query.Offset = 0, 0
+	now := time.Now()
+	items, skipped, err := s.decryptedItems(query, func(item out.Item) bool {
+		return (query.Field == "" || item.HasField(query.Field)) &&
+			(query.URL == "" || item.MatchesURL(query.URL)) &&
+			(query.Expiring == "" || expiresWithin(item, now, within))
+	})
+	if err != nil {
+		return
+	}
+	data.Total = uint64(len(items))
+	data.Skipped = skipped
+	data.Items = []model.DBItem{}
+	for i := offset; i < data.Total && (limit == 0 || uint64(len(data.Items)) < limit); i++ {
+		data.Items = append(data.Items, items[i].DBItem)
+	}
+	return
+}
+
+// Expiring
+// the records expired or expiring in the time, sorted by the expiry time, and the keys of the records failed to decrypt
+func (s *service) Expiring(within time.Duration) (items []out.Item, skipped []string, err error) {
+	now := time.Now()
+	if items, skipped, err = s.decryptedItems(model.ListQuery{}, func(item out.Item) bool {
+		return expiresWithin(item, now, within)
+	}); err != nil {
+		return
+	}
+	slices.SortStableFunc(items, func(a, b out.Item) int {
+		return a.Data.(model.Expiring).ExpiresAt().Compare(b.Data.(model.Expiring).ExpiresAt())
+	})
+	return
+}
+
+// expiresWithin
+// the data of item has the expiry time before now plus within
+func expiresWithin(item out.Item, now time.Time, within time.Duration) bool {
+	e, ok := item.Data.(model.Expiring)
+	return ok && !e.ExpiresAt().IsZero() && e.ExpiresAt().Before(now.Add(within))
+}
+
+// decryptedItems
+// the decrypted records of query matching by match, the deleted records are not searched.
+// The records failed to decrypt, as of a partial synchronization, are skipped, their keys are returned
+func (s *service) decryptedItems(query model.ListQuery, match func(item out.Item) bool) (items []out.Item, skipped []string, err error) {
+	// the deleted records have no data
+	query.Deleted = false
+	var list []model.DBItem
+	if list, err = s.r.DB.List(query); err != nil {
+		return
+	}
+	// the wrong passphrase fails all of them
+	if len(list) > 0 {
+		if _, err = s.GetToken(); err != nil {
+			err = tokenError(err)
+			return
+		}
+	}
+	for _, r := range list {
+		item, er := s.Get(r.Key)
+		if er != nil {
+			skipped = append(skipped, r.Key)
+			continue
+		}
+		if match(item) {
+			items = append(items, item)
+		}
+	}
 	return
 }
 
 func (s *service) Get(key string) (data out.Item, err error) {
+	return s.get(key, nil)
+}
+
+// Extract
+// get the record and write its raw content to w,
+// the content stored as a stream is decrypted by chunks, not loaded into memory
+func (s *service) Extract(key string, w io.Writer) (data out.Item, err error) {
+	return s.get(key, w)
+}
+
+func (s *service) get(key string, content io.Writer) (data out.Item, err error) {
 	var (
 		r model.DBRecord
 	)
-	if r, err = s.GetRaw(key); err != nil {
+	if r, err = s.r.DB.Get(key); err != nil {
 		return
 	}
 	if r.IsDeleted() {
 		err = sql.ErrNoRows
 		return
 	}
+	return s.decodeItem(r, content)
+}
+
+// decodeItem
+// decrypt the stored record, the content of data is written to content if set
+func (s *service) decodeItem(r model.DBRecord, content io.Writer) (data out.Item, err error) {
 	data.DBItem = r.DBItem
-	var (
-		deCrypted []byte
-		token     string
-	)
+	var token string
 	token, err = s.GetToken()
 	if err != nil {
-		if !cfg.Glob.GetBool("debug") {
-			err = errs.ErrPassword
+		err = tokenError(err)
+		return
+	}
+	if data.Description, err = decryptDescription(token, r.Key, r.Description); err != nil {
+		err = decodeError(err, "")
+		return
+	}
+	var blob io.ReadSeeker = bytes.NewReader(r.Blob)
+	if len(r.Blob) == 0 && r.Filename != nil {
+		var f io.ReadSeekCloser
+		if f, err = s.r.File.OpenStored(*r.Filename); err != nil {
+			return
 		}
+		defer func() { _ = f.Close() }()
+		blob = f
+	}
+	info, infoErr := crypt.ReadInfo(blob)
+	if _, err = blob.Seek(0, io.SeekStart); err != nil {
 		return
 	}
-	deCrypted, err = crypt.Decode(r.Blob, token)
-	if err != nil {
-		err = errs.ErrDecode
+	if infoErr == nil && info.IsStream() {
+		err = getStream(r, info.ContentType, blob, token, &data, content)
+		return
+	}
+	if r.Blob, err = io.ReadAll(blob); err != nil {
+		return
+	}
+	if err = decodeRecord(r, token, &data); err != nil {
+		return
+	}
+	if content != nil {
+		c, ok := data.Data.(model.Content)
+		if !ok {
+			err = errs.ErrNoContent
+			return
+		}
+		_, err = content.Write(c.GetContent())
+	}
+	return
+}
+
+// decodeError
+// authentication failure of the record bound to its key means the data of another record
+func decodeError(err error, dataType string) error {
+	if dataType != "" && errors.Is(err, crypt.ErrAuthentication) && !errors.Is(err, crypt.ErrTruncated) {
+		return fmt.Errorf("%w: %w", errs.ErrRecordBinding, err)
+	}
+	return fmt.Errorf("%w: %w", errs.ErrDecode, err)
+}
+
+// decodeRecord
+// decrypt the blob of record into data
+func decodeRecord(r model.DBRecord, token string, data *out.Item) (err error) {
+	// blobs saved before binding have no content type and are decoded as is
+	var opts []crypt.Option
+	dataType := crypt.ContentType(r.Blob)
+	if dataType != "" {
+		opts = append(opts, crypt.WithAssociatedData(r.AssociatedData(dataType)))
+	}
+	var deCrypted []byte
+	if deCrypted, err = crypt.Decode(r.Blob, token, opts...); err != nil {
+		err = decodeError(err, dataType)
+		return
+	}
+	if err = json.Unmarshal(deCrypted, data); err != nil {
+		return
+	}
+	if dataType != "" && model.GetName(data.Data) != dataType {
+		err = fmt.Errorf("%w: type %s labeled as %s", errs.ErrRecordBinding, model.GetName(data.Data), dataType)
 		return
 	}
-	err = json.Unmarshal(deCrypted, &data)
 	if dataSan, ok := data.Data.(model.Sanitisable); ok {
 		dataSan.Sanitize()
 	}
 	return
 }
 
+// getStream
+// decrypt the data stored as a stream: the packed data line followed by the raw content,
+// the content is written to w if set
+func getStream(r model.DBRecord, dataType string, blob io.Reader, token string, data *out.Item, w io.Writer) (err error) {
+	var dr io.Reader
+	dr, err = crypt.NewDecryptReader(blob, token, crypt.WithAssociatedData(r.AssociatedData(dataType)))
+	if err != nil {
+		err = decodeError(err, dataType)
+		return
+	}
+	br := bufio.NewReader(dr)
+	var line []byte
+	if line, err = br.ReadBytes('\n'); err != nil {
+		err = decodeError(err, dataType)
+		return
+	}
+	if err = json.Unmarshal(line, data); err != nil {
+		return
+	}
+	if model.GetName(data.Data) != dataType {
+		err = fmt.Errorf("%w: type %s labeled as %s", errs.ErrRecordBinding, model.GetName(data.Data), dataType)
+		return
+	}
+	if w != nil {
+		if _, err = io.Copy(w, br); err != nil {
+			err = decodeError(err, dataType)
+		}
+	}
+	return
+}
+
 func (s *service) GetRaw(key string) (data model.DBRecord, err error) {
 	if data, err = s.r.DB.Get(key); err != nil {
 		return
@@ -183,16 +579,39 @@ func (s *service) GetRaw(key string) (data model.DBRecord, err error) {
 			return
 		}
 	}
+	data.IndexTokens, err = s.r.DB.GetIndex(key)
 	return
 }
 
 func (s *service) Save(data model.Model) (err error) {
+	if err = data.GetBase().SetExtra(); err != nil {
+		return
+	}
 	if err = data.Validate(); err != nil {
 		return
 	}
+	if m, ok := data.(model.Streamable); ok && data.GetFileName() != "" {
+		var fi os.FileInfo
+		if fi, err = os.Stat(data.GetFileName()); err != nil {
+			return
+		}
+		if fi.Size() > cfg.MaxBlobSize {
+			return s.saveStream(m, fi.Size())
+		}
+	}
 	var r model.DBRecord
+	if r, err = s.encodeRecord(data); err != nil {
+		return
+	}
+	err = s.SaveRaw(r)
+
+	return
+}
+
+// encodeRecord
+// encrypt the data and its description into the record to save
+func (s *service) encodeRecord(data model.Model) (r model.DBRecord, err error) {
 	r.Key = data.GetKey()
-	r.Description = data.GetDescription()
 	var blob []byte
 	blob, err = model.NewPackedBytes(data)
 	if err != nil {
@@ -206,29 +625,158 @@ func (s *service) Save(data model.Model) (err error) {
 		}
 		return
 	}
-	if r.Blob, err = crypt.Encode(blob, token); err != nil {
+	var opts []crypt.Option
+	if opts, err = cryptOptions(); err != nil {
+		return
+	}
+	if r.Description, r.IndexTokens, err = recordDescription(token, r.Key, data.GetDescription(), opts); err != nil {
+		return
+	}
+	dataType := model.GetName(data)
+	opts = append(opts, crypt.WithDataKey(), crypt.WithContentType(dataType), crypt.WithAssociatedData(r.AssociatedData(dataType)))
+	r.Blob, err = crypt.Encode(blob, token, opts...)
+	return
+}
+
+// saveStream
+// encrypt the source file of data into the file store by chunks, the file is not loaded into memory.
+// The stream holds the packed data line followed by the file content
+func (s *service) saveStream(data model.Streamable, size int64) (err error) {
+	var token string
+	token, err = s.GetToken()
+	if err != nil {
+		if !cfg.Glob.GetBool("debug") {
+			err = errors.New("wrong password")
+		}
+		return
+	}
+	var opts []crypt.Option
+	if opts, err = cryptOptions(); err != nil {
+		return
+	}
+	var r model.DBRecord
+	r.Key = data.GetKey()
+	if r.Description, r.IndexTokens, err = recordDescription(token, r.Key, data.GetDescription(), opts); err != nil {
+		return
+	}
+	dataType := model.GetName(data)
+	var packed []byte
+	packed, err = json.Marshal(model.Packed{
+		Type:     dataType,
+		Data:     data.StreamData(size).GetPacked(),
+		FileName: filepath.Base(data.GetFileName()),
+		Extra:    data.GetBase().Extra,
+	})
+	if err != nil {
+		return
+	}
+	var src *os.File
+	if src, err = os.Open(data.GetFileName()); err != nil {
+		return
+	}
+	defer func() { _ = src.Close() }()
+
+	// the file of the previous version is kept by the history, so the name must be new
+	fileName := storeFileName(r.Key)
+	var dst io.WriteCloser
+	if dst, err = s.r.File.CreateStore(fileName); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = s.r.File.Delete(fileName)
+		}
+	}()
+	err = func() (err error) {
+		defer func() { err = errors.Join(err, dst.Close()) }()
+		var w io.WriteCloser
+		w, err = crypt.NewEncryptWriter(dst, token,
+			append(opts, crypt.WithContentType(dataType), crypt.WithAssociatedData(r.AssociatedData(dataType)))...)
+		if err != nil {
+			return
+		}
+		if _, err = w.Write(append(packed, '\n')); err != nil {
+			return
+		}
+		var n int64
+		if n, err = io.Copy(w, src); err != nil {
+			return
+		}
+		if n != size {
+			err = fmt.Errorf("file %s is changed while reading", data.GetFileName())
+			return
+		}
+		err = w.Close()
+		return
+	}()
+	if err != nil {
 		return
 	}
-	err = s.SaveRaw(r)
 
+	r.Filename = &fileName
+	var files []string
+	if files, err = saveRecord(s.r.DB, r); err != nil {
+		return
+	}
+	err = s.deleteFiles(files)
 	return
 }
 
 func (s *service) SaveRaw(data model.DBRecord) (err error) {
 	if len(data.Blob) > cfg.MaxBlobSize {
-		fileName := time.Now().Format("20060102150405") + "-" + data.Key
-		err = s.r.File.SaveStore(fileName, data.Blob)
-		if err != nil {
-			return
+		if old, er := s.r.DB.Get(data.Key); er == nil && old.Filename != nil && s.isStored(*old.Filename, data.Blob) {
+			// the unchanged content, as received back by synchronization, keeps its file,
+			// so it is not taken for a new version
+			data.Filename = old.Filename
+		} else {
+			fileName := storeFileName(data.Key)
+			err = s.r.File.SaveStore(fileName, data.Blob)
+			if err != nil {
+				return
+			}
+			data.Filename = &fileName
 		}
-		data.Filename = &fileName
 		data.Blob = nil
 	}
-	err = s.r.DB.Save(data)
+	if data.IsTombstone() {
+		// the deletion received keeps the local payload at the trash
+		if data, err = s.trashReceived(data); err != nil {
+			return
+		}
+	}
+	var files []string
+	if files, err = saveRecord(s.r.DB, data); err != nil {
+		return
+	}
+	err = s.deleteFiles(files)
 
 	return
 }
 
+// storeFileName
+// the name of the new file of the record at the file store, the key is reduced to the letters, digits, - and _
+func storeFileName(key string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			return r
+		}
+		return '_'
+	}, key)
+	if len(safe) > maxStoreKeyLen {
+		safe = safe[:maxStoreKeyLen]
+	}
+	return time.Now().Format("20060102150405.000000000") + "-" + safe
+}
+
+// isStored
+// checks the file of the file store holds the blob
+func (s *service) isStored(fileName string, blob []byte) bool {
+	b, err := s.r.File.GetStored(fileName)
+	return err == nil && bytes.Equal(b, blob)
+}
+
+// Delete
+// move the record to the trash, its payload and versions are kept until purged
 func (s *service) Delete(key string) (err error) {
 	var r model.DBRecord
 	if r, err = s.r.DB.Get(key); err != nil {
@@ -238,11 +786,6 @@ func (s *service) Delete(key string) (err error) {
 		err = sql.ErrNoRows
 		return
 	}
-	if r.Filename != nil && *r.Filename != "" {
-		if err = s.r.File.Delete(*r.Filename); err != nil {
-			return
-		}
-	}
 	err = s.r.DB.Delete(key)
 	return
 }
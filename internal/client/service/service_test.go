@@ -1,10 +1,21 @@
 package service
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	errs "gophKeeper/internal/client/errors"
+	"io"
+	"math/big"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -13,13 +24,19 @@ import (
 	"time"
 
 	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/crypt"
+	"gophKeeper/internal/client/importer"
 	clMigrate "gophKeeper/internal/client/migrate"
 	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/out"
 	"gophKeeper/internal/client/model/type/auth"
 	"gophKeeper/internal/client/model/type/bin"
 	"gophKeeper/internal/client/model/type/card"
+	"gophKeeper/internal/client/model/type/cert"
+	"gophKeeper/internal/client/model/type/otp"
 	"gophKeeper/internal/client/model/type/text"
 	"gophKeeper/internal/client/storage"
+	"gophKeeper/internal/client/vault"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/mattn/go-sqlite3"
@@ -38,6 +55,7 @@ type serviceStoreTestSuite struct {
 	user                       string
 	userBak                    string
 	pass                       string
+	storePath                  string
 }
 
 var testDataPath string = filepath.Join("..", "..", "..", "testdata")
@@ -46,6 +64,7 @@ func (s *serviceStoreTestSuite) SetupSuite() {
 	s.user = "test-" + time.Now().Format("20060102150405")
 
 	storePath := filepath.Join(s.T().TempDir(), cfg.AppName, s.user)
+	s.storePath = storePath
 	dbFile := filepath.Join(storePath, "store.db")
 	profiles := cfg.Glob.GetStringMap("profiles")
 	profiles[s.user] = cfg.NewGlobProfileItem(storePath)
@@ -123,6 +142,8 @@ func (s *serviceStoreTestSuite) Test_service() {
 		name    string
 		args    args
 		wantErr wantErr
+		// wantData expected data if it differs from saved
+		wantData model.Data
 	}{
 		{
 			name: "test auth",
@@ -211,6 +232,8 @@ func (s *serviceStoreTestSuite) Test_service() {
 				del:  true,
 			},
 			wantErr: wantErr{},
+			// large file is stored as a stream, the content is not loaded
+			wantData: &bin.Data{Size: 1201181},
 		},
 		{
 			name: "test text",
@@ -285,8 +308,12 @@ func (s *serviceStoreTestSuite) Test_service() {
 						assert.Equal(t, gotItemData.Key, tt.args.save.GetKey())
 						assert.Equal(t, gotItemData.Description, tt.args.save.GetDescription())
 
-						if !reflect.DeepEqual(gotItemData.Data.GetPacked(), tt.args.save.GetPacked()) {
-							t.Errorf("GetStored() gotData = %v, want %v", gotItemData.Data, tt.args.save.GetPacked())
+						want := tt.args.save.GetPacked()
+						if tt.wantData != nil {
+							want = tt.wantData.GetPacked()
+						}
+						if !reflect.DeepEqual(gotItemData.Data.GetPacked(), want) {
+							t.Errorf("GetStored() gotData = %v, want %v", gotItemData.Data, want)
 						}
 					}
 				})
@@ -328,6 +355,25 @@ func (s *serviceStoreTestSuite) Test_GetToken() {
 
 }
 
+func (s *serviceStoreTestSuite) Test_CheckPassphrase() {
+	t := s.T()
+	t.Run("Test CheckPassphrase", func(t *testing.T) {
+		_, err := s.srv.GetToken()
+		require.NoError(t, err)
+
+		// the passphrase is asked with the unwrapped key
+		s.input(s.pass)
+		require.NoError(t, s.srv.CheckPassphrase())
+
+		s.input("someWrongPass")
+		require.ErrorIs(t, s.srv.CheckPassphrase(), errs.ErrPassword)
+
+		s.input(s.pass)
+		_, err = s.srv.GetToken()
+		require.NoError(t, err)
+	})
+}
+
 func (s *serviceStoreTestSuite) Test_WrongPass() {
 	t := s.T()
 	t.Run("Test wrong pass", func(t *testing.T) {
@@ -381,3 +427,1281 @@ func (s *serviceStoreTestSuite) Test_WrongPassConfirm() {
 		require.Equal(s.T(), true, errors.Is(err, errs.ErrPasswordConfirm))
 	})
 }
+
+func (s *serviceStoreTestSuite) Test_KDFUpgrade() {
+	t := s.T()
+	token, err := s.srv.GetToken()
+	require.NoError(t, err)
+	packed := cfg.User.GetString("packed_key")
+	params, ok, err := cfg.GetKDFParams()
+	require.NoError(t, err)
+	require.True(t, ok)
+	defer func() {
+		cfg.User.Set("packed_key", packed)
+		cfg.SetKDFParams(params)
+		cfg.User.Set("encryption_key", token)
+	}()
+
+	t.Run("legacy packed key is upgraded", func(t *testing.T) {
+		legacy, err := crypt.Encode([]byte(token), cfg.User.GetString("name")+"\t"+s.pass)
+		require.NoError(t, err)
+		cfg.User.Set("packed_key", hex.EncodeToString(legacy))
+		cfg.User.Set("kdf", nil)
+		cfg.User.Set("encryption_key", "")
+
+		s.input(s.pass)
+		got, err := s.srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+
+		upgraded, ok, err := cfg.GetKDFParams()
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.NoError(t, upgraded.Validate())
+		require.NotEqual(t, hex.EncodeToString(legacy), cfg.User.GetString("packed_key"))
+
+		cfg.User.Set("encryption_key", "")
+		s.input(s.pass)
+		got, err = s.srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	})
+
+	t.Run("change password raise costs", func(t *testing.T) {
+		current, _, err := cfg.GetKDFParams()
+		require.NoError(t, err)
+
+		s.input(s.pass, s.pass, s.pass)
+		err = s.srv.ChangePasswd(crypt.KDFParams{Time: current.Time + 1})
+		require.NoError(t, err)
+		raised, _, err := cfg.GetKDFParams()
+		require.NoError(t, err)
+		require.Equal(t, current.Time+1, raised.Time)
+		require.Equal(t, current.Memory, raised.Memory)
+		require.NotEqual(t, current.Salt, raised.Salt)
+
+		s.input(s.pass)
+		err = s.srv.ChangePasswd(crypt.KDFParams{Time: current.Time})
+		require.ErrorIs(t, err, crypt.ErrKDFWeaker)
+
+		cfg.User.Set("encryption_key", "")
+		s.input(s.pass)
+		got, err := s.srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	})
+}
+
+func (s *serviceStoreTestSuite) Test_RecordBinding() {
+	t := s.T()
+	for _, key := range []string{"binding-key-1", "binding-key-2"} {
+		err := s.srv.Save(&text.Model{
+			Common: model.Common{Key: key},
+			Data:   &text.Data{Text: "text of " + key},
+		})
+		require.NoError(t, err)
+	}
+	r1, err := s.srv.GetRaw("binding-key-1")
+	require.NoError(t, err)
+	r2, err := s.srv.GetRaw("binding-key-2")
+	require.NoError(t, err)
+
+	// swap blobs between records
+	r1.Blob, r2.Blob = r2.Blob, r1.Blob
+	require.NoError(t, s.srv.SaveRaw(r1))
+	require.NoError(t, s.srv.SaveRaw(r2))
+
+	for _, key := range []string{"binding-key-1", "binding-key-2"} {
+		_, err = s.srv.Get(key)
+		require.ErrorIs(t, err, errs.ErrRecordBinding)
+	}
+
+	// put them back
+	r1.Blob, r2.Blob = r2.Blob, r1.Blob
+	require.NoError(t, s.srv.SaveRaw(r1))
+	require.NoError(t, s.srv.SaveRaw(r2))
+	got, err := s.srv.Get("binding-key-1")
+	require.NoError(t, err)
+	require.Equal(t, "text of binding-key-1", got.Data.(*text.Data).Text)
+}
+
+func (s *serviceStoreTestSuite) Test_RotateKey() {
+	t := s.T()
+	token, err := s.srv.GetToken()
+	require.NoError(t, err)
+
+	require.NoError(t, s.srv.Save(&text.Model{
+		Common: model.Common{Key: "rotate-new"},
+		Data:   &text.Data{Text: "record with own data key"},
+	}))
+	newRec, err := s.srv.GetRaw("rotate-new")
+	require.NoError(t, err)
+	require.True(t, crypt.HasDataKey(newRec.Blob))
+
+	// record of older version, encrypted with the encryption key directly and kept at file store
+	bigText := make([]byte, cfg.MaxBlobSize*3)
+	_, err = rand.Read(bigText)
+	require.NoError(t, err)
+	packed, err := model.NewPackedBytes(&text.Model{Data: &text.Data{Text: hex.EncodeToString(bigText)}})
+	require.NoError(t, err)
+	legacyBlob, err := crypt.Encode(packed, token)
+	require.NoError(t, err)
+	require.NoError(t, s.srv.SaveRaw(model.DBRecord{DBItem: model.DBItem{Key: "rotate-legacy"}, Blob: legacyBlob}))
+	legacyRec, err := s.srv.GetRaw("rotate-legacy")
+	require.NoError(t, err)
+	require.NotNil(t, legacyRec.Filename)
+	_, err = os.Stat(filepath.Join(s.storePath, *legacyRec.Filename))
+	require.NoError(t, err)
+
+	streamFile := filepath.Join(testDataPath, "SomeFile.pdf")
+	require.NoError(t, s.srv.Save(&bin.Model{Common: model.Common{Key: "rotate-stream", FileName: streamFile}}))
+	streamRec, err := s.srv.GetRaw("rotate-stream")
+	require.NoError(t, err)
+
+	syncToken, err := crypt.Encode([]byte("some sync token"), token)
+	require.NoError(t, err)
+	cfg.User.Set("sync.token", hex.EncodeToString(syncToken))
+	defer cfg.User.Set("sync.token", nil)
+
+	t.Run("wrong password", func(t *testing.T) {
+		packedKey := cfg.User.GetString("packed_key")
+		s.input("wrong" + s.pass)
+		err := s.srv.RotateKey(nil)
+		require.ErrorIs(t, err, errs.ErrPassword)
+		require.Equal(t, packedKey, cfg.User.GetString("packed_key"))
+		got, err := s.srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		var done, total int
+		s.input(s.pass)
+		err := s.srv.RotateKey(func(d, n int) { done, total = d, n })
+		require.NoError(t, err)
+		require.Greater(t, total, 1)
+		require.Equal(t, total, done)
+
+		newToken, err := s.srv.GetToken()
+		require.NoError(t, err)
+		require.NotEqual(t, token, newToken)
+
+		// data key is re-wrapped, the payload is kept
+		rotated, err := s.srv.GetRaw("rotate-new")
+		require.NoError(t, err)
+		require.True(t, crypt.HasDataKey(rotated.Blob))
+		require.NotEqual(t, newRec.Blob, rotated.Blob)
+		require.Equal(t, newRec.Blob[len(newRec.Blob)-64:], rotated.Blob[len(rotated.Blob)-64:])
+		require.NotNil(t, rotated.UpdatedAt)
+
+		rotatedLegacy, err := s.srv.GetRaw("rotate-legacy")
+		require.NoError(t, err)
+		require.True(t, crypt.HasDataKey(rotatedLegacy.Blob))
+		require.NotNil(t, rotatedLegacy.Filename)
+		require.NotEqual(t, *legacyRec.Filename, *rotatedLegacy.Filename)
+		_, err = os.Stat(filepath.Join(s.storePath, *legacyRec.Filename))
+		require.True(t, os.IsNotExist(err))
+
+		// the new key is unlocked by the same passphrase
+		cfg.User.Set("encryption_key", "")
+		s.input(s.pass)
+		item, err := s.srv.Get("rotate-new")
+		require.NoError(t, err)
+		require.Equal(t, "record with own data key", item.Data.(*text.Data).Text)
+		item, err = s.srv.Get("rotate-legacy")
+		require.NoError(t, err)
+		require.Equal(t, hex.EncodeToString(bigText), item.Data.(*text.Data).Text)
+
+		// the stream is re-wrapped by parts
+		rotatedStream, err := s.srv.GetRaw("rotate-stream")
+		require.NoError(t, err)
+		require.NotEqual(t, *streamRec.Filename, *rotatedStream.Filename)
+		require.Equal(t, streamRec.Blob[len(streamRec.Blob)-1000:], rotatedStream.Blob[len(rotatedStream.Blob)-1000:])
+		content, err := os.ReadFile(streamFile)
+		require.NoError(t, err)
+		buf := new(bytes.Buffer)
+		_, err = s.srv.Extract("rotate-stream", buf)
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(content, buf.Bytes()))
+
+		b, err := hex.DecodeString(cfg.User.GetString("sync.token"))
+		require.NoError(t, err)
+		b, err = crypt.Decode(b, newToken)
+		require.NoError(t, err)
+		require.Equal(t, "some sync token", string(b))
+	})
+}
+
+func (s *serviceStoreTestSuite) Test_BinStream() {
+	t := s.T()
+	fileName := filepath.Join(testDataPath, "SomeFile.pdf")
+	content, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+
+	m := bin.New()
+	m.Key, m.FileName, m.Description = "stream-bin", fileName, "stream description"
+	require.NoError(t, s.srv.Save(m))
+
+	r, err := s.srv.GetRaw("stream-bin")
+	require.NoError(t, err)
+	require.NotNil(t, r.Filename)
+	info, err := crypt.ReadInfo(bytes.NewReader(r.Blob))
+	require.NoError(t, err)
+	require.True(t, info.IsStream())
+	require.Equal(t, "bin", info.ContentType)
+
+	item, err := s.srv.Get("stream-bin")
+	require.NoError(t, err)
+	require.Equal(t, "stream description", item.Description)
+	require.Equal(t, &bin.Data{Size: int64(len(content))}, item.Data)
+
+	buf := new(bytes.Buffer)
+	item, err = s.srv.Extract("stream-bin", buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), item.Data.(*bin.Data).Size)
+	require.True(t, bytes.Equal(content, buf.Bytes()))
+
+	t.Run("small data is extracted too", func(t *testing.T) {
+		require.NoError(t, s.srv.Save(&bin.Model{
+			Common: model.Common{Key: "stream-bin-small"},
+			Data:   &bin.Data{Bin: []byte("small content")},
+		}))
+		buf := new(bytes.Buffer)
+		_, err := s.srv.Extract("stream-bin-small", buf)
+		require.NoError(t, err)
+		require.Equal(t, "small content", buf.String())
+
+		require.NoError(t, s.srv.Save(&auth.Model{
+			Common: model.Common{Key: "stream-auth"},
+			Data:   &auth.Data{Login: "login", Password: "password"},
+		}))
+		_, err = s.srv.Extract("stream-auth", buf)
+		require.ErrorIs(t, err, errs.ErrNoContent)
+	})
+
+	t.Run("modified stream", func(t *testing.T) {
+		path := filepath.Join(s.storePath, *r.Filename)
+		stored, err := os.ReadFile(path)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, os.WriteFile(path, stored, 0600)) }()
+
+		modified := bytes.Clone(stored)
+		modified[len(modified)-100] ^= 1
+		require.NoError(t, os.WriteFile(path, modified, 0600))
+		_, err = s.srv.Extract("stream-bin", io.Discard)
+		require.ErrorIs(t, err, crypt.ErrAuthentication)
+
+		require.NoError(t, os.WriteFile(path, stored[:len(stored)-100], 0600))
+		_, err = s.srv.Extract("stream-bin", io.Discard)
+		require.ErrorIs(t, err, crypt.ErrAuthentication)
+	})
+
+	t.Run("save again keeps stored file of version", func(t *testing.T) {
+		require.NoError(t, s.srv.Save(m))
+		r2, err := s.srv.GetRaw("stream-bin")
+		require.NoError(t, err)
+		require.NotEqual(t, *r.Filename, *r2.Filename)
+		_, err = os.Stat(filepath.Join(s.storePath, *r.Filename))
+		require.NoError(t, err)
+		buf := new(bytes.Buffer)
+		_, err = s.srv.GetVersion("stream-bin", 1, buf)
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(content, buf.Bytes()))
+	})
+
+	t.Run("key with path separators is stored at the file store", func(t *testing.T) {
+		m := bin.New()
+		m.Key, m.FileName = "../work/"+strings.Repeat("x", 100), fileName
+		require.NoError(t, s.srv.Save(m))
+		r, err := s.srv.GetRaw(m.Key)
+		require.NoError(t, err)
+		require.NotNil(t, r.Filename)
+		require.Equal(t, filepath.Base(*r.Filename), *r.Filename)
+		_, err = os.Stat(filepath.Join(s.storePath, *r.Filename))
+		require.NoError(t, err)
+		buf := new(bytes.Buffer)
+		_, err = s.srv.Extract(m.Key, buf)
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(content, buf.Bytes()))
+	})
+}
+
+func (s *serviceStoreTestSuite) Test_Recovery() {
+	t := s.T()
+	token, err := s.srv.GetToken()
+	require.NoError(t, err)
+	defer cfg.SetRecovery(nil)
+
+	// reset the password by the recovery code, the key is asked from the new one
+	recoverWith := func(t *testing.T, newPass string, codes ...string) {
+		cfg.User.Set("encryption_key", "")
+		s.input(append(codes, newPass, newPass)...)
+		require.NoError(t, s.srv.UseRecovery())
+		cfg.User.Set("encryption_key", "")
+		s.input(newPass)
+		got, err := s.srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	}
+
+	t.Run("not created", func(t *testing.T) {
+		require.ErrorIs(t, s.srv.UseRecovery(), errs.ErrNoRecovery)
+	})
+
+	t.Run("wrong shares params", func(t *testing.T) {
+		for _, p := range [][2]int{{0, 2}, {3, 1}, {2, 3}, {256, 2}} {
+			_, err := s.srv.CreateRecovery(p[0], p[1])
+			require.ErrorIs(t, err, crypt.ErrShareParams, p)
+		}
+	})
+
+	t.Run("code", func(t *testing.T) {
+		codes, err := s.srv.CreateRecovery(0, 0)
+		require.NoError(t, err)
+		require.Len(t, codes, 1)
+		recoverWith(t, "recovered"+s.pass, codes[0])
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		code, err := crypt.NewRecoverySecret()
+		require.NoError(t, err)
+		s.input(crypt.FormatRecoveryCode(code))
+		require.ErrorIs(t, s.srv.UseRecovery(), crypt.ErrRecoveryCode)
+		s.input("some typo")
+		require.ErrorIs(t, s.srv.UseRecovery(), crypt.ErrRecoveryCode)
+	})
+
+	t.Run("shares", func(t *testing.T) {
+		codes, err := s.srv.CreateRecovery(5, 3)
+		require.NoError(t, err)
+		require.Len(t, codes, 5)
+		cfg.User.Set("encryption_key", "")
+		s.input(codes[1], codes[1])
+		require.ErrorIs(t, s.srv.UseRecovery(), crypt.ErrRecoveryShare)
+
+		recoverWith(t, "shares"+s.pass, codes[4], codes[0], codes[2])
+		// the shares of one line
+		recoverWith(t, "shares"+s.pass, codes[1]+" "+codes[3], codes[0])
+	})
+
+	t.Run("passphrase file", func(t *testing.T) {
+		codes, err := s.srv.CreateRecovery(3, 2)
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), "recovery")
+		cfg.Glob.Viper.Set("passphrase_file", path)
+		defer cfg.Glob.Viper.Set("passphrase_file", nil)
+
+		// the profile source keeps the master password, it is not the recovery code
+		cfg.User.Set("passphrase.env", "GK_TEST_MASTER")
+		defer cfg.User.Set("passphrase", nil)
+		t.Setenv("GK_TEST_MASTER", s.pass)
+
+		// the same line is not taken for the next share
+		require.NoError(t, os.WriteFile(path, []byte(codes[1]+"\n"), 0600))
+		cfg.User.Set("encryption_key", "")
+		require.ErrorIs(t, s.srv.UseRecovery(), crypt.ErrRecoveryShare)
+
+		// the new password is asked at the terminal
+		require.NoError(t, os.WriteFile(path, []byte(codes[0]+" "+codes[2]+"\n"), 0600))
+		s.input("shares"+s.pass, "shares"+s.pass)
+		require.NoError(t, s.srv.UseRecovery())
+		got, err := s.srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+		cfg.User.Set("encryption_key", "")
+		require.NoError(t, os.WriteFile(path, []byte("shares"+s.pass+"\n"), 0600))
+		got, err = s.srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	})
+
+	t.Run("kept valid by key rotation", func(t *testing.T) {
+		cfg.User.Set("encryption_key", "")
+		s.input("shares" + s.pass)
+		codes, err := s.srv.CreateRecovery(0, 0)
+		require.NoError(t, err)
+		s.input("shares" + s.pass)
+		require.NoError(t, s.srv.RotateKey(nil))
+		token, err = s.srv.GetToken()
+		require.NoError(t, err)
+		recoverWith(t, s.pass, codes[0])
+	})
+}
+
+func (s *serviceStoreTestSuite) Test_KeyFile() {
+	t := s.T()
+	token, err := s.srv.GetToken()
+	require.NoError(t, err)
+	require.NoError(t, s.srv.Save(&text.Model{
+		Common: model.Common{Key: "keyfile-text"},
+		Data:   &text.Data{Text: "two factors"},
+	}))
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "keys", "gophkeeper.key")
+
+	lock := func() {
+		cfg.User.Set("encryption_key", "")
+		s.input(s.pass)
+	}
+
+	t.Run("inside profile", func(t *testing.T) {
+		_, err := s.srv.SetKeyFile(filepath.Join(s.storePath, "gophkeeper.key"))
+		require.ErrorIs(t, err, errs.ErrKeyFileInProfile)
+	})
+
+	t.Run("set", func(t *testing.T) {
+		s.input(s.pass)
+		created, err := s.srv.SetKeyFile(keyFile)
+		require.NoError(t, err)
+		require.True(t, created)
+		params, _, err := cfg.GetKDFParams()
+		require.NoError(t, err)
+		require.NotEmpty(t, params.KeyFile)
+
+		lock()
+		got, err := s.srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		cfg.User.Set("key_file", keyFile+".moved")
+		lock()
+		_, err := s.srv.Get("keyfile-text")
+		require.ErrorIs(t, err, crypt.ErrKeyFileMissing)
+		require.NotErrorIs(t, err, errs.ErrPassword)
+	})
+
+	t.Run("wrong", func(t *testing.T) {
+		other := filepath.Join(dir, "other.key")
+		content, err := crypt.NewKeyFile()
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(other, content, 0600))
+		cfg.User.Set("key_file", other)
+		lock()
+		_, err = s.srv.Get("keyfile-text")
+		require.ErrorIs(t, err, crypt.ErrKeyFileWrong)
+	})
+
+	t.Run("copy of key file", func(t *testing.T) {
+		content, err := os.ReadFile(keyFile)
+		require.NoError(t, err)
+		moved := filepath.Join(dir, "copy.key")
+		require.NoError(t, os.WriteFile(moved, content, 0600))
+		created, err := s.srv.SetKeyFile(moved)
+		require.NoError(t, err)
+		require.False(t, created)
+		require.Equal(t, moved, cfg.User.GetString("key_file"))
+
+		lock()
+		got, err := s.srv.Get("keyfile-text")
+		require.NoError(t, err)
+		require.Equal(t, "two factors", got.Data.(*text.Data).Text)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		s.input(s.pass)
+		require.NoError(t, s.srv.RemoveKeyFile())
+		require.Empty(t, cfg.User.GetString("key_file"))
+		params, _, err := cfg.GetKDFParams()
+		require.NoError(t, err)
+		require.Empty(t, params.KeyFile)
+
+		lock()
+		got, err := s.srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	})
+}
+
+type mapKeyCache map[string][]byte
+
+func (c mapKeyCache) GetKey(id string) ([]byte, error) {
+	key, ok := c[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return key, nil
+}
+
+func (c mapKeyCache) PutKey(id string, key []byte) error {
+	c[id] = key
+	return nil
+}
+
+func (s *serviceStoreTestSuite) Test_KeyCache() {
+	t := s.T()
+	token, err := s.srv.GetToken()
+	require.NoError(t, err)
+	cache := mapKeyCache{}
+	srv := NewService(storage.NewStorage(s.db, s.storePath), WithKeyCache(cache))
+
+	// the password is asked once, then the key is taken from the cache
+	cfg.User.Set("encryption_key", "")
+	s.input(s.pass)
+	got, err := srv.GetToken()
+	require.NoError(t, err)
+	require.Equal(t, token, got)
+	require.Len(t, cache, 1)
+
+	cfg.User.Set("encryption_key", "")
+	got, err = srv.GetToken()
+	require.NoError(t, err)
+	require.Equal(t, token, got)
+
+	// a new packed key is cached separately
+	s.input(s.pass, s.pass, s.pass)
+	require.NoError(t, srv.ChangePasswd(crypt.KDFParams{}))
+	require.Len(t, cache, 2)
+}
+
+func (s *serviceStoreTestSuite) Test_PassphraseSource() {
+	t := s.T()
+	token, err := s.srv.GetToken()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	defer func() {
+		cfg.Glob.Viper.Set("passphrase_file", nil)
+		cfg.User.Set("passphrase", nil)
+		cfg.User.Set("encryption_key", token)
+	}()
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(dir, "pass")
+		require.NoError(t, os.WriteFile(path, []byte(s.pass+"\n"), 0600))
+		cfg.Glob.Viper.Set("passphrase_file", path)
+		defer cfg.Glob.Viper.Set("passphrase_file", nil)
+
+		cfg.User.Set("encryption_key", "")
+		got, err := s.srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	})
+
+	t.Run("profile env", func(t *testing.T) {
+		t.Setenv("GK_TEST_MASTER", s.pass)
+		cfg.User.Set("passphrase.env", "GK_TEST_MASTER")
+		defer cfg.User.Set("passphrase", nil)
+
+		cfg.User.Set("encryption_key", "")
+		got, err := s.srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+
+		// the run flag takes precedence over the profile
+		path := filepath.Join(dir, "wrong")
+		require.NoError(t, os.WriteFile(path, []byte("wrongPass\n"), 0600))
+		cfg.Glob.Viper.Set("passphrase_file", path)
+		defer cfg.Glob.Viper.Set("passphrase_file", nil)
+		cfg.User.Set("encryption_key", "")
+		_, err = s.srv.GetToken()
+		require.Error(t, err)
+	})
+}
+
+func (s *serviceStoreTestSuite) Test_Description() {
+	t := s.T()
+	_, err := s.srv.GetToken()
+	require.NoError(t, err)
+	defer func() {
+		_, _ = s.srv.SetDescriptionMode(false, false)
+	}()
+	save := func(key, desc string) {
+		require.NoError(t, s.srv.Save(&text.Model{
+			Common: model.Common{Key: key, Description: desc},
+			Data:   &text.Data{Text: "text of " + key},
+		}))
+	}
+	search := func(desc string) (keys []string) {
+		list, err := s.srv.List(model.ListQuery{Key: "desc-", Description: desc})
+		require.NoError(t, err)
+		for _, item := range list.Items {
+			keys = append(keys, item.Key)
+		}
+		require.Equal(t, uint64(len(keys)), list.Total)
+		return
+	}
+	save("desc-plain", "Bank account of Alice")
+
+	// records of other tests are encrypted too
+	n, err := s.srv.SetDescriptionMode(true, false)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, n, 1)
+	save("desc-mail", "Mail of Alice, example.com")
+
+	t.Run("stored encrypted", func(t *testing.T) {
+		for _, key := range []string{"desc-plain", "desc-mail"} {
+			r, err := s.srv.GetRaw(key)
+			require.NoError(t, err)
+			require.True(t, r.HasEncryptedDescription())
+			require.NotContains(t, r.Description, "Alice")
+			require.NotEmpty(t, r.IndexTokens)
+		}
+		item, err := s.srv.Get("desc-mail")
+		require.NoError(t, err)
+		require.Equal(t, "Mail of Alice, example.com", item.Description)
+
+		list, err := s.srv.List(model.ListQuery{Key: "desc-plain"})
+		require.NoError(t, err)
+		require.Len(t, list.Items, 1)
+		require.Equal(t, "Bank account of Alice", list.Items[0].Description)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		require.ElementsMatch(t, []string{"desc-plain", "desc-mail"}, search("alice"))
+		require.Equal(t, []string{"desc-mail"}, search("EXAMPLE mail"))
+		require.Empty(t, search("bank mail"))
+		// whole words only
+		require.Empty(t, search("exam"))
+	})
+
+	t.Run("synchronized without index", func(t *testing.T) {
+		r, err := s.srv.GetRaw("desc-mail")
+		require.NoError(t, err)
+		r.IndexTokens = nil
+		require.NoError(t, s.srv.SaveRaw(r))
+		require.Equal(t, []string{"desc-mail"}, search("mail"))
+	})
+
+	t.Run("rotate key", func(t *testing.T) {
+		before, err := s.srv.GetRaw("desc-mail")
+		require.NoError(t, err)
+		s.input(s.pass)
+		require.NoError(t, s.srv.RotateKey(nil))
+		after, err := s.srv.GetRaw("desc-mail")
+		require.NoError(t, err)
+		require.NotEqual(t, before.Description, after.Description)
+		require.NotEqual(t, before.IndexTokens, after.IndexTokens)
+		require.Equal(t, []string{"desc-mail"}, search("mail"))
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		n, err := s.srv.SetDescriptionMode(false, false)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, n, 2)
+		r, err := s.srv.GetRaw("desc-plain")
+		require.NoError(t, err)
+		require.Equal(t, "Bank account of Alice", r.Description)
+		require.Nil(t, r.IndexTokens)
+		// substring search of plain descriptions
+		require.ElementsMatch(t, []string{"desc-plain", "desc-mail"}, search("lic"))
+	})
+}
+
+func (s *serviceStoreTestSuite) Test_Extra() {
+	t := s.T()
+	m := auth.New()
+	m.Key = "extra-mail"
+	m.Data.Login, m.Data.Password = "alice", "password"
+	m.FieldArgs = []string{"recovery email=alice@example.org"}
+	m.SecretFieldArgs = []string{"pin=1234"}
+	m.URLArgs = []string{"mail.example.com", "host=https://login.example.net:8443"}
+	require.NoError(t, s.srv.Save(m))
+
+	item, err := s.srv.Get("extra-mail")
+	require.NoError(t, err)
+	require.Equal(t, []model.Field{
+		{Name: "recovery email", Value: "alice@example.org"},
+		{Name: "pin", Value: "1234", Secret: true},
+	}, item.Fields)
+	require.Equal(t, []model.URL{
+		{URL: "https://mail.example.com"},
+		{URL: "https://login.example.net:8443", Match: model.MatchHost},
+	}, item.URLs)
+
+	r, err := s.srv.GetRaw("extra-mail")
+	require.NoError(t, err)
+	require.NotContains(t, string(r.Blob), "example")
+
+	t.Run("stream", func(t *testing.T) {
+		m := bin.New()
+		m.Key, m.FileName = "extra-stream", filepath.Join(testDataPath, "SomeFile.pdf")
+		m.URLArgs = []string{"files.example.com"}
+		require.NoError(t, s.srv.Save(m))
+		item, err := s.srv.Get("extra-stream")
+		require.NoError(t, err)
+		require.Equal(t, []model.URL{{URL: "https://files.example.com"}}, item.URLs)
+	})
+
+	t.Run("wrong", func(t *testing.T) {
+		for _, m := range []*auth.Model{
+			{Common: model.Common{Key: "extra-wrong", FieldArgs: []string{"no value"}}, Data: &auth.Data{Login: "a"}},
+			{Common: model.Common{Key: "extra-wrong", FieldArgs: []string{"a=1"}, SecretFieldArgs: []string{"a=2"}}, Data: &auth.Data{Login: "a"}},
+			{Common: model.Common{Key: "extra-wrong", URLArgs: []string{"regexp=("}}, Data: &auth.Data{Login: "a"}},
+		} {
+			require.ErrorIs(t, s.srv.Save(m), model.ErrExtra)
+		}
+	})
+
+	search := func(q model.ListQuery) (keys []string) {
+		q.Key = "extra-"
+		list, err := s.srv.List(q)
+		require.NoError(t, err)
+		for _, item := range list.Items {
+			keys = append(keys, item.Key)
+		}
+		return
+	}
+	require.Equal(t, []string{"extra-mail"}, search(model.ListQuery{Field: "Recovery Email"}))
+	require.Equal(t, []string{"extra-mail"}, search(model.ListQuery{Field: "pin=23"}))
+	require.Empty(t, search(model.ListQuery{Field: "pin=5"}))
+	require.ElementsMatch(t, []string{"extra-mail", "extra-stream"}, search(model.ListQuery{URL: "https://www.example.com/inbox"}))
+	require.Equal(t, []string{"extra-mail"}, search(model.ListQuery{URL: "https://login.example.net:8443/"}))
+	require.Empty(t, search(model.ListQuery{URL: "https://login.example.net/"}))
+	list, err := s.srv.List(model.ListQuery{Key: "extra-", URL: "example.com", Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), list.Total)
+	require.Len(t, list.Items, 1)
+}
+
+func (s *serviceStoreTestSuite) Test_Expiring() {
+	t := s.T()
+	save := func(key string, notAfter time.Time) {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: key},
+			NotBefore:    time.Now().Add(-48 * time.Hour),
+			NotAfter:     notAfter,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, priv.Public(), priv)
+		require.NoError(t, err)
+		m := cert.New()
+		m.Key = key
+		m.Data.Certificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+		require.NoError(t, s.srv.Save(m))
+	}
+	save("expiring-later", time.Now().Add(90*24*time.Hour))
+	save("expiring-soon", time.Now().Add(10*24*time.Hour))
+	save("expiring-expired", time.Now().Add(-24*time.Hour))
+
+	// the record failed to decrypt is skipped, it is rewritten in place, so no version is kept
+	save("expiring-broken", time.Now().Add(-24*time.Hour))
+	db := storage.NewStorage(s.db, s.storePath).DB
+	broken, err := s.srv.GetRaw("expiring-broken")
+	require.NoError(t, err)
+	stored := bytes.Clone(broken.Blob)
+	broken.Blob[len(broken.Blob)-1] ^= 1
+	require.NoError(t, rewriteRecord(db, broken))
+
+	items, skipped, err := s.srv.Expiring(30 * 24 * time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []string{"expiring-broken"}, skipped)
+	var keys []string
+	for _, item := range items {
+		keys = append(keys, item.Key)
+	}
+	require.Equal(t, []string{"expiring-expired", "expiring-soon"}, keys)
+
+	list, err := s.srv.List(model.ListQuery{Key: "expiring-", Expiring: "0d"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"expiring-broken"}, list.Skipped)
+	broken.Blob = stored
+	require.NoError(t, rewriteRecord(db, broken))
+	require.NoError(t, s.srv.Delete("expiring-broken"))
+
+	list, err = s.srv.List(model.ListQuery{Key: "expiring-", Expiring: "100d", OrderBy: "key"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), list.Total)
+	list, err = s.srv.List(model.ListQuery{Key: "expiring-", Expiring: "0d"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), list.Total)
+	require.Equal(t, "expiring-expired", list.Items[0].Key)
+
+	_, err = s.srv.List(model.ListQuery{Expiring: "month"})
+	require.Error(t, err)
+}
+
+func (s *serviceStoreTestSuite) Test_SaveChanged() {
+	t := s.T()
+	m := auth.New()
+	m.Key = "edited"
+	m.Data.Login, m.Data.Password = "alice", "password"
+	require.NoError(t, s.srv.Save(m))
+	r, err := s.srv.GetRaw("edited")
+	require.NoError(t, err)
+	require.Nil(t, r.UpdatedAt)
+	now := time.Now()
+	r.SyncAt = &now
+	require.NoError(t, s.srv.SaveRaw(r))
+
+	m.Data.Password = "changed"
+	require.NoError(t, s.srv.Save(m))
+	changed, err := s.srv.GetRaw("edited")
+	require.NoError(t, err)
+	// the changed record keeps the created time, gets the updated time and is synchronized again
+	require.Equal(t, r.CreatedAt, changed.CreatedAt)
+	require.NotNil(t, changed.UpdatedAt)
+	require.Nil(t, changed.SyncAt)
+	item, err := s.srv.Get("edited")
+	require.NoError(t, err)
+	require.Equal(t, "changed", item.Data.(*auth.Data).Password)
+}
+
+func (s *serviceStoreTestSuite) Test_ImportAttachment() {
+	t := s.T()
+	content := make([]byte, cfg.MaxBlobSize+1024)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+	data := `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta>
+		<Binaries><Binary ID="0">` + base64.StdEncoding.EncodeToString(content) + `</Binary></Binaries>
+	</Meta>
+	<Root>
+		<Group>
+			<Name>Database</Name>
+			<Entry>
+				<String><Key>Title</Key><Value>work/files</Value></String>
+				<Binary><Key>dump.bin</Key><Value Ref="0"/></Binary>
+				<Binary><Key></Key><Value Ref="0"/></Binary>
+			</Entry>
+		</Group>
+	</Root>
+</KeePassFile>`
+	entries, err := importer.Parse(importer.FormatKeePassXML, strings.NewReader(data), importer.Options{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	dir := t.TempDir()
+	for _, e := range entries {
+		m, err := e.Model(e.Title, dir)
+		require.NoError(t, err)
+		require.NoError(t, s.srv.Save(m))
+
+		r, err := s.srv.GetRaw(e.Title)
+		require.NoError(t, err)
+		require.NotNil(t, r.Filename)
+		require.Equal(t, filepath.Base(*r.Filename), *r.Filename)
+		var buf bytes.Buffer
+		_, err = s.srv.Extract(e.Title, &buf)
+		require.NoError(t, err)
+		require.Equal(t, content, buf.Bytes())
+	}
+}
+
+func (s *serviceStoreTestSuite) Test_NextHOTP() {
+	t := s.T()
+	m := otp.New()
+	m.Key, m.Description = "hotp", "counter code"
+	m.Data.Type, m.Data.Secret, m.Data.Counter = otp.TypeHOTP, "JBSWY3DPEHPK3PXP", 5
+	require.NoError(t, s.srv.Save(m))
+	versions, err := s.srv.History("hotp")
+	require.NoError(t, err)
+
+	for counter := uint64(5); counter < 7; counter++ {
+		want, err := otp.HOTP("JBSWY3DPEHPK3PXP", counter, otp.DefaultAlgorithm, otp.DefaultDigits)
+		require.NoError(t, err)
+		code, used, err := s.srv.NextHOTP("hotp")
+		require.NoError(t, err)
+		require.Equal(t, want, code)
+		require.Equal(t, counter, used)
+
+		item, err := s.srv.Get("hotp")
+		require.NoError(t, err)
+		require.Equal(t, counter+1, item.Data.(*otp.Data).Counter)
+		require.Equal(t, "counter code", item.Description)
+	}
+	r, err := s.srv.GetRaw("hotp")
+	require.NoError(t, err)
+	require.Nil(t, r.SyncAt)
+	// the advanced counter is not kept as a version
+	got, err := s.srv.History("hotp")
+	require.NoError(t, err)
+	require.Len(t, got, len(versions))
+
+	totp := otp.New()
+	totp.Key, totp.Data.Secret = "totp", "JBSWY3DPEHPK3PXP"
+	require.NoError(t, s.srv.Save(totp))
+	_, _, err = s.srv.NextHOTP("totp")
+	require.ErrorIs(t, err, errs.ErrNotHOTP)
+}
+
+func (s *serviceStoreTestSuite) Test_History() {
+	t := s.T()
+	save := func(text string) {
+		m := auth.New()
+		m.Key, m.Description = "history", "description of "+text
+		m.Data.Login, m.Data.Password = "alice", text
+		require.NoError(t, s.srv.Save(m))
+	}
+	password := func(item out.Item) string {
+		return item.Data.(*auth.Data).Password
+	}
+	save("first")
+	save("second")
+
+	t.Run("versions", func(t *testing.T) {
+		versions, err := s.srv.History("history")
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		require.Equal(t, 0, versions[0].Version)
+		require.Equal(t, "description of second", versions[0].Description)
+		require.Equal(t, 1, versions[1].Version)
+		require.Equal(t, "description of first", versions[1].Description)
+		require.False(t, versions[1].SavedAt.IsZero())
+
+		item, err := s.srv.GetVersion("history", 1, nil)
+		require.NoError(t, err)
+		require.Equal(t, "first", password(item))
+		item, err = s.srv.GetVersion("history", 0, nil)
+		require.NoError(t, err)
+		require.Equal(t, "second", password(item))
+		_, err = s.srv.GetVersion("history", 5, nil)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("synchronized copy is not a version", func(t *testing.T) {
+		r, err := s.srv.GetRaw("history")
+		require.NoError(t, err)
+		now := time.Now()
+		r.SyncAt = &now
+		require.NoError(t, s.srv.SaveRaw(r))
+		versions, err := s.srv.History("history")
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		require.NoError(t, s.srv.RestoreVersion("history", 1))
+		item, err := s.srv.Get("history")
+		require.NoError(t, err)
+		require.Equal(t, "first", password(item))
+		require.Equal(t, "description of first", item.Description)
+		// the restore is the new update to be synchronized, the replaced record is kept
+		r, err := s.srv.GetRaw("history")
+		require.NoError(t, err)
+		require.NotNil(t, r.UpdatedAt)
+		require.Nil(t, r.SyncAt)
+		versions, err := s.srv.History("history")
+		require.NoError(t, err)
+		require.Len(t, versions, 3)
+		require.Equal(t, 2, versions[1].Version)
+		item, err = s.srv.GetVersion("history", 2, nil)
+		require.NoError(t, err)
+		require.Equal(t, "second", password(item))
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		defer cfg.User.Set("history.keep", cfg.HistoryKeep)
+		cfg.User.Set("history.keep", 1)
+		save("third")
+		versions, err := s.srv.History("history")
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		require.Equal(t, 3, versions[1].Version)
+
+		defer cfg.User.Set("history.max_age", nil)
+		cfg.User.Set("history.keep", 10)
+		cfg.User.Set("history.max_age", "1s")
+		time.Sleep(time.Second * 2)
+		save("fourth")
+		versions, err = s.srv.History("history")
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		require.Equal(t, 4, versions[1].Version)
+	})
+
+	t.Run("stored file is removed with its last version", func(t *testing.T) {
+		defer cfg.User.Set("history.keep", cfg.HistoryKeep)
+		m := bin.New()
+		m.Key, m.FileName = "history-stream", filepath.Join(testDataPath, "SomeFile.pdf")
+		require.NoError(t, s.srv.Save(m))
+		r, err := s.srv.GetRaw("history-stream")
+		require.NoError(t, err)
+		require.NoError(t, s.srv.Save(m))
+		_, err = os.Stat(filepath.Join(s.storePath, *r.Filename))
+		require.NoError(t, err)
+
+		cfg.User.Set("history.keep", 0)
+		require.NoError(t, s.srv.Save(m))
+		_, err = os.Stat(filepath.Join(s.storePath, *r.Filename))
+		require.True(t, os.IsNotExist(err))
+		versions, err := s.srv.History("history-stream")
+		require.NoError(t, err)
+		require.Len(t, versions, 1)
+	})
+
+	t.Run("rotated key", func(t *testing.T) {
+		s.input(s.pass)
+		require.NoError(t, s.srv.RotateKey(nil))
+		item, err := s.srv.GetVersion("history", 4, nil)
+		require.NoError(t, err)
+		require.Equal(t, "third", password(item))
+	})
+
+	t.Run("delete keeps versions", func(t *testing.T) {
+		require.NoError(t, s.srv.Delete("history"))
+		versions, err := s.srv.History("history")
+		require.NoError(t, err)
+		require.NotEmpty(t, versions)
+		require.NotZero(t, versions[0].Version)
+	})
+
+	t.Run("purge removes versions", func(t *testing.T) {
+		_, err := s.srv.PurgeTrash(0)
+		require.NoError(t, err)
+		_, err = s.srv.History("history")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		require.ErrorIs(t, s.srv.RestoreVersion("history", 4), sql.ErrNoRows)
+	})
+}
+
+func (s *serviceStoreTestSuite) Test_Templates() {
+	t := s.T()
+	plain := []byte(`{"templates":[{"name":"license","fields":[{"name":"code","kind":"secret"}]}]}`)
+	data, err := s.srv.EncryptTemplates(plain)
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(data, []byte("license")))
+
+	got, err := s.srv.DecryptTemplates(data)
+	require.NoError(t, err)
+	require.Equal(t, plain, got)
+
+	data[len(data)-1] ^= 1
+	_, err = s.srv.DecryptTemplates(data)
+	require.ErrorIs(t, err, errs.ErrDecode)
+	_, err = s.srv.DecryptTemplates(plain)
+	require.ErrorIs(t, err, errs.ErrDecode)
+}
+
+func (s *serviceStoreTestSuite) Test_Trash() {
+	t := s.T()
+	m := auth.New()
+	m.Key, m.Description = "trash", "trashed account"
+	m.Data.Login, m.Data.Password = "alice", "secret"
+	require.NoError(t, s.srv.Save(m))
+	stream := bin.New()
+	stream.Key, stream.FileName = "trash-stream", filepath.Join(testDataPath, "SomeFile.pdf")
+	require.NoError(t, s.srv.Save(stream))
+	streamRaw, err := s.srv.GetRaw("trash-stream")
+	require.NoError(t, err)
+	require.NotNil(t, streamRaw.Filename)
+
+	t.Run("delete moves to trash", func(t *testing.T) {
+		require.NoError(t, s.srv.Delete("trash"))
+		require.ErrorIs(t, s.srv.Delete("trash"), sql.ErrNoRows)
+		_, err := s.srv.Get("trash")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+
+		items, err := s.srv.Trash()
+		require.NoError(t, err)
+		require.NotEmpty(t, items)
+		require.Equal(t, "trash", items[0].Key)
+		require.Equal(t, "trashed account", items[0].Description)
+		require.NotNil(t, items[0].DeletedAt)
+	})
+
+	t.Run("synchronized as tombstone", func(t *testing.T) {
+		r, err := s.srv.GetRaw("trash")
+		require.NoError(t, err)
+		require.NotEmpty(t, r.Blob)
+		p := r.ToItemSync()
+		require.Empty(t, p.Blob)
+		require.Empty(t, p.Description)
+
+		// the tombstone received back keeps the local payload at the trash
+		var received model.DBRecord
+		received.FromItemSync(p)
+		require.NoError(t, s.srv.SaveRaw(received))
+		r, err = s.srv.GetRaw("trash")
+		require.NoError(t, err)
+		require.NotEmpty(t, r.Blob)
+		require.NotNil(t, r.DeletedAt)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		require.NoError(t, s.srv.RestoreTrash("trash"))
+		item, err := s.srv.Get("trash")
+		require.NoError(t, err)
+		require.Equal(t, "secret", item.Data.(*auth.Data).Password)
+		require.Equal(t, "trashed account", item.Description)
+		r, err := s.srv.GetRaw("trash")
+		require.NoError(t, err)
+		require.Nil(t, r.DeletedAt)
+		require.Nil(t, r.SyncAt)
+		require.ErrorIs(t, s.srv.RestoreTrash("trash"), sql.ErrNoRows)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		require.NoError(t, s.srv.Delete("trash"))
+		require.NoError(t, s.srv.Delete("trash-stream"))
+		n, err := s.srv.PurgeTrash(time.Hour)
+		require.NoError(t, err)
+		require.Zero(t, n)
+
+		n, err = s.srv.PurgeTrash(0)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, n, 2)
+		items, err := s.srv.Trash()
+		require.NoError(t, err)
+		require.Empty(t, items)
+		require.ErrorIs(t, s.srv.RestoreTrash("trash"), sql.ErrNoRows)
+		_, err = os.Stat(filepath.Join(s.storePath, *streamRaw.Filename))
+		require.True(t, os.IsNotExist(err))
+
+		// the purged record is kept as the tombstone for synchronization
+		r, err := s.srv.GetRaw("trash")
+		require.NoError(t, err)
+		require.True(t, r.IsTombstone())
+	})
+}
+
+// newVaultTarget
+// the service of a new empty store of the same profile
+func (s *serviceStoreTestSuite) newVaultTarget(t *testing.T) (srv Service, storePath string) {
+	storePath = t.TempDir()
+	db, err := sqlx.Open("sqlite3", filepath.Join(storePath, "store.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+	_, err = clMigrate.Migrate(db.DB)
+	require.NoError(t, err)
+	return NewService(storage.NewStorage(db, storePath)), storePath
+}
+
+func (s *serviceStoreTestSuite) Test_Vault() {
+	t := s.T()
+	token, err := s.srv.GetToken()
+	require.NoError(t, err)
+	streamFile := filepath.Join(testDataPath, "SomeFile.pdf")
+	content, err := os.ReadFile(streamFile)
+	require.NoError(t, err)
+	vaultText := &text.Model{
+		Common: model.Common{Key: "vault-text", Description: "vault description"},
+		Data:   &text.Data{Text: "archived text"},
+	}
+	require.NoError(t, s.srv.Save(vaultText))
+	// saved again to have the updated time
+	require.NoError(t, s.srv.Save(vaultText))
+	require.NoError(t, s.srv.Save(&bin.Model{Common: model.Common{Key: "vault-stream", FileName: streamFile}}))
+	textRec, err := s.srv.GetRaw("vault-text")
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	n, err := s.srv.ExportVault(buf, "export pass")
+	require.NoError(t, err)
+	list, err := s.srv.List(model.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, int(list.Total), n)
+	archive := buf.Bytes()
+
+	checkRestored := func(t *testing.T, srv Service) {
+		item, err := srv.Get("vault-text")
+		require.NoError(t, err)
+		require.Equal(t, "archived text", item.Data.(*text.Data).Text)
+		require.Equal(t, "vault description", item.Description)
+		// the restored record keeps its time
+		r, err := srv.GetRaw("vault-text")
+		require.NoError(t, err)
+		require.Equal(t, textRec.CreatedAt, r.CreatedAt)
+		require.NotNil(t, r.UpdatedAt)
+		require.Equal(t, *textRec.UpdatedAt, *r.UpdatedAt)
+		require.Nil(t, r.SyncAt)
+		stream := new(bytes.Buffer)
+		_, err = srv.Extract("vault-stream", stream)
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(content, stream.Bytes()))
+	}
+
+	t.Run("restore", func(t *testing.T) {
+		srv, storePath := s.newVaultTarget(t)
+		res, err := srv.RestoreVault(bytes.NewReader(archive), "export pass", false)
+		require.NoError(t, err)
+		require.Equal(t, VaultRestore{Created: n}, res)
+		checkRestored(t, srv)
+
+		res, err = srv.RestoreVault(bytes.NewReader(archive), "export pass", false)
+		require.NoError(t, err)
+		require.Equal(t, VaultRestore{Skipped: n}, res)
+
+		stored, err := srv.GetRaw("vault-stream")
+		require.NoError(t, err)
+		res, err = srv.RestoreVault(bytes.NewReader(archive), "export pass", true)
+		require.NoError(t, err)
+		require.Equal(t, VaultRestore{Overwritten: n}, res)
+		checkRestored(t, srv)
+		// the overwritten stored file is kept by its version
+		overwritten, err := srv.GetRaw("vault-stream")
+		require.NoError(t, err)
+		require.NotEqual(t, *stored.Filename, *overwritten.Filename)
+		versions, err := srv.History("vault-stream")
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		require.Equal(t, stored.Filename, versions[1].Filename)
+		_, err = os.Stat(filepath.Join(storePath, *stored.Filename))
+		require.NoError(t, err)
+	})
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		srv, _ := s.newVaultTarget(t)
+		_, err := srv.RestoreVault(bytes.NewReader(archive), "wrong pass", false)
+		require.ErrorIs(t, err, vault.ErrPassphrase)
+	})
+
+	t.Run("damaged archive is not restored", func(t *testing.T) {
+		srv, storePath := s.newVaultTarget(t)
+		_, err := srv.RestoreVault(bytes.NewReader(archive[:len(archive)-100]), "export pass", false)
+		require.ErrorIs(t, err, vault.ErrIntegrity)
+		list, err := srv.List(model.ListQuery{Deleted: true})
+		require.NoError(t, err)
+		require.Zero(t, list.Total)
+		entries, err := os.ReadDir(storePath)
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "only the db file is kept")
+	})
+
+	t.Run("another key", func(t *testing.T) {
+		s.input(s.pass)
+		require.NoError(t, s.srv.RotateKey(nil))
+		srv, _ := s.newVaultTarget(t)
+		s.input(s.pass)
+		res, err := srv.RestoreVault(bytes.NewReader(archive), "export pass", false)
+		require.NoError(t, err)
+		require.True(t, res.ReKeyed)
+		require.Equal(t, n, res.Created)
+		checkRestored(t, srv)
+	})
+
+	t.Run("profile without key", func(t *testing.T) {
+		bak := make(map[string]any)
+		for _, k := range []string{"name", "packed_key", "kdf", "recovery", "encryption_key"} {
+			bak[k] = cfg.User.Get(k)
+		}
+		defer func() {
+			for k, v := range bak {
+				cfg.User.Set(k, v)
+			}
+		}()
+		m, err := vault.NewReader(bytes.NewReader(archive), "export pass")
+		require.NoError(t, err)
+
+		// the key of the same profile is taken as is
+		cfg.User.Set("packed_key", "")
+		cfg.User.Set("encryption_key", "")
+		srv, _ := s.newVaultTarget(t)
+		_, err = srv.RestoreVault(bytes.NewReader(archive), "export pass", false)
+		require.NoError(t, err)
+		require.Equal(t, m.Manifest.PackedKey, cfg.User.GetString("packed_key"))
+		s.input(s.pass)
+		got, err := srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+		checkRestored(t, srv)
+
+		// the key of another profile is wrapped for this one
+		cfg.User.Set("name", "vault-renamed")
+		cfg.User.Set("packed_key", "")
+		cfg.User.Set("encryption_key", "")
+		srv, _ = s.newVaultTarget(t)
+		s.input(s.pass)
+		_, err = srv.RestoreVault(bytes.NewReader(archive), "export pass", false)
+		require.NoError(t, err)
+		require.NotEqual(t, m.Manifest.PackedKey, cfg.User.GetString("packed_key"))
+		cfg.User.Set("encryption_key", "")
+		s.input(s.pass)
+		got, err = srv.GetToken()
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+		checkRestored(t, srv)
+	})
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"gophKeeper/internal/client/crypt"
+)
+
+// templatesType the content type of the synchronized templates envelope
+const templatesType = "templates"
+
+// templatesAD
+// associated data of the synchronized templates
+func templatesAD() []byte {
+	return []byte("gophkeeper/templates")
+}
+
+// EncryptTemplates
+// encrypt the canonical templates to synchronize by the profile key, the envelope is of the record blobs
+func (s *service) EncryptTemplates(plain []byte) (data []byte, err error) {
+	var token string
+	if token, err = s.GetToken(); err != nil {
+		err = tokenError(err)
+		return
+	}
+	var opts []crypt.Option
+	if opts, err = cryptOptions(); err != nil {
+		return
+	}
+	opts = append(opts, crypt.WithDataKey(), crypt.WithContentType(templatesType), crypt.WithAssociatedData(templatesAD()))
+	return crypt.Encode(plain, token, opts...)
+}
+
+// DecryptTemplates
+// decrypt the templates received by synchronization
+func (s *service) DecryptTemplates(data []byte) (plain []byte, err error) {
+	var token string
+	if token, err = s.GetToken(); err != nil {
+		err = tokenError(err)
+		return
+	}
+	if plain, err = crypt.Decode(data, token, crypt.WithAssociatedData(templatesAD())); err != nil {
+		err = decodeError(err, "")
+	}
+	return
+}
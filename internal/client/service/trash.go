@@ -0,0 +1,109 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/storage"
+)
+
+// Trash
+// the deleted records kept with their payload, the latest deleted first. The descriptions are decrypted
+func (s *service) Trash() (data []model.DBItem, err error) {
+	if data, err = s.r.DB.ListDeleted(time.Time{}); err != nil {
+		return
+	}
+	err = s.decryptDescriptions(data)
+	return
+}
+
+// RestoreTrash
+// bring the deleted record back from the trash, as a new update to be synchronized
+func (s *service) RestoreTrash(key string) (err error) {
+	var r model.DBRecord
+	if r, err = s.r.DB.Get(key); err != nil {
+		return
+	}
+	if r.DeletedAt == nil || r.IsTombstone() {
+		err = sql.ErrNoRows
+		return
+	}
+	if r.IndexTokens, err = s.r.DB.GetIndex(key); err != nil {
+		return
+	}
+	r.DeletedAt, r.UpdatedAt, r.SyncAt = nil, nil, nil
+	var files []string
+	if files, err = saveRecord(s.r.DB, r); err != nil {
+		return
+	}
+	err = s.deleteFiles(files)
+	return
+}
+
+// PurgeTrash
+// wipe the payload and versions of the records deleted earlier than olderThan ago, all of them if it is zero.
+// The records are kept as the tombstones for synchronization. Returns the number of purged records
+func (s *service) PurgeTrash(olderThan time.Duration) (n int, err error) {
+	var before time.Time
+	if olderThan > 0 {
+		before = time.Now().Add(-olderThan)
+	}
+	var files []string
+	err = s.r.DB.Transaction(func(db storage.DB) (err error) {
+		n, files = 0, nil
+		var items []model.DBItem
+		if items, err = db.ListDeleted(before); err != nil {
+			return
+		}
+		for _, item := range items {
+			var r model.DBRecord
+			if r, err = db.Get(item.Key); err != nil {
+				return
+			}
+			if err = db.Purge(item.Key); err != nil {
+				return
+			}
+			var versions []string
+			if versions, err = db.DeleteHistory(item.Key); err != nil {
+				return
+			}
+			files = append(files, versions...)
+			if r.Filename != nil && *r.Filename != "" && !slices.Contains(versions, *r.Filename) {
+				files = append(files, *r.Filename)
+			}
+			n++
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+	err = s.deleteFiles(files)
+	return
+}
+
+// trashReceived
+// the deletion received by synchronization for the record with the local payload keeps it at the trash,
+// to be restored until purged
+func (s *service) trashReceived(data model.DBRecord) (_ model.DBRecord, err error) {
+	data.DeletedAt = data.UpdatedAt
+	if data.DeletedAt == nil {
+		data.DeletedAt = &[]time.Time{time.Now()}[0]
+	}
+	var old model.DBRecord
+	if old, err = s.r.DB.Get(data.Key); err != nil || old.IsTombstone() {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+		return data, err
+	}
+	if old.DeletedAt != nil {
+		data.DeletedAt = old.DeletedAt
+	}
+	data.Description, data.Blob, data.Filename = old.Description, old.Blob, old.Filename
+	data.IndexTokens, err = s.r.DB.GetIndex(data.Key)
+	return data, err
+}
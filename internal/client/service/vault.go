@@ -0,0 +1,427 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/crypt"
+	errs "gophKeeper/internal/client/errors"
+	"gophKeeper/internal/client/model"
+	"gophKeeper/internal/client/model/type/template"
+	"gophKeeper/internal/client/storage"
+	"gophKeeper/internal/client/vault"
+)
+
+// vaultSettings
+// the profile settings of the records encryption kept at the vault archive
+var vaultSettings = []string{"description.encrypt", "description.server_index", "crypt.algorithm"}
+
+// VaultRestore
+// the result of the vault archive restore
+type VaultRestore struct {
+	Created     int
+	Overwritten int
+	Skipped     int
+	// ReKeyed the records are re-encrypted with the encryption key of profile
+	ReKeyed bool
+}
+
+// ExportVault
+// write the records of profile with the contents kept at the file store, the templates and the packed key
+// to the vault archive encrypted by pass, the records stay encrypted by the profile key.
+// The deleted records are not exported. Returns the number of exported records
+func (s *service) ExportVault(w io.Writer, pass string) (n int, err error) {
+	m := vault.Manifest{
+		Profile:   cfg.User.GetString("name"),
+		PackedKey: cfg.User.GetString("packed_key"),
+		Settings:  make(map[string]any),
+	}
+	if m.PackedKey == "" {
+		err = errs.ErrNoEncryptionKey
+		return
+	}
+	if params, ok, er := cfg.GetKDFParams(); er != nil {
+		err = er
+		return
+	} else if ok {
+		m.KDF = &params
+	}
+	if r, ok, er := cfg.GetRecovery(); er != nil {
+		err = er
+		return
+	} else if ok {
+		if m.Recovery, err = json.Marshal(r); err != nil {
+			return
+		}
+	}
+	for _, k := range vaultSettings {
+		if v := cfg.User.Get(k); v != nil {
+			m.Settings[k] = v
+		}
+	}
+	var dir string
+	if dir, err = cfg.UsrCfgDir(); err != nil {
+		return
+	}
+	var templates []template.Template
+	if templates, _, err = template.Load(dir); err != nil {
+		return
+	}
+	if m.Templates, err = template.Marshal(templates); err != nil {
+		return
+	}
+	// the records are read in one transaction to be consistent
+	err = s.r.DB.Transaction(func(db storage.DB) (err error) {
+		var items []model.DBItem
+		if items, err = db.List(model.ListQuery{}); err != nil {
+			return
+		}
+		m.Records = len(items)
+		var vw *vault.Writer
+		if vw, err = vault.NewWriter(w, pass, m); err != nil {
+			return
+		}
+		for _, item := range items {
+			if err = s.exportRecord(db, vw, item.Key); err != nil {
+				return fmt.Errorf("%s: %w", item.Key, err)
+			}
+		}
+		return vw.Close()
+	})
+	if err == nil {
+		n = m.Records
+	}
+	return
+}
+
+// exportRecord
+// write the record of key with its blind index to the archive, the stored content is written by parts
+func (s *service) exportRecord(db storage.DB, vw *vault.Writer, key string) (err error) {
+	var r model.DBRecord
+	if r, err = db.Get(key); err != nil {
+		return
+	}
+	if r.IndexTokens, err = db.GetIndex(key); err != nil {
+		return
+	}
+	rec := vault.Record{
+		Key:         r.Key,
+		Description: r.Description,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+		Blob:        r.Blob,
+		IndexTokens: r.IndexTokens,
+	}
+	if len(r.Blob) > 0 || r.Filename == nil {
+		return vw.Add(rec, nil, 0)
+	}
+	var f io.ReadSeekCloser
+	if f, err = s.r.File.OpenStored(*r.Filename); err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	var size int64
+	if size, err = f.Seek(0, io.SeekEnd); err != nil {
+		return
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	return vw.Add(rec, f, size)
+}
+
+// vaultToken
+// unwrap the packed key of the archive by the master passphrase of the exported profile,
+// the passphrase is returned to wrap the key for the profile
+func vaultToken(m vault.Manifest) (token []byte, passRaw string, err error) {
+	var packed []byte
+	if packed, err = hex.DecodeString(m.PackedKey); err != nil {
+		err = fmt.Errorf("%w: packed key: %w", vault.ErrIntegrity, err)
+		return
+	}
+	if passRaw, err = getPassphrase(false, cfg.PromptVaultMasterPs); err != nil {
+		return
+	}
+	keyPass := profileKeyPass(m.Profile, passRaw)
+	if m.KDF == nil {
+		token, err = crypt.Decode(packed, keyPass)
+	} else {
+		var key string
+		if key, err = deriveKey(keyPass, *m.KDF); err == nil {
+			token, err = crypt.Decode(packed, key)
+		}
+	}
+	if err != nil {
+		err = tokenError(err)
+	}
+	return
+}
+
+// RestoreVault
+// restore the records of the vault archive encrypted by pass with their created and updated time,
+// the archived templates missing at the profile are added. The profile without encryption key takes
+// the packed key of the archive, wrapped again by the master passphrase of the exported profile
+// if it has another name. The records of another key are re-encrypted with the key of profile.
+// The existing records are overwritten if overwrite is set, skipped otherwise.
+// Nothing is restored if the archive fails the integrity check
+func (s *service) RestoreVault(r io.Reader, pass string, overwrite bool) (res VaultRestore, err error) {
+	var vr *vault.Reader
+	if vr, err = vault.NewReader(r, pass); err != nil {
+		return
+	}
+	m := vr.Manifest
+	if m.PackedKey == "" {
+		err = fmt.Errorf("%w: no packed key", vault.ErrIntegrity)
+		return
+	}
+	var (
+		packed             = cfg.User.GetString("packed_key")
+		oldToken, newToken string
+		setKey             func() error
+	)
+	switch {
+	case packed == m.PackedKey:
+	case packed == "" && m.Profile == cfg.User.GetString("name"):
+		setKey = func() error { return adoptVaultKey(m) }
+	default:
+		var (
+			token   []byte
+			passRaw string
+		)
+		if token, passRaw, err = vaultToken(m); err != nil {
+			return
+		}
+		if packed == "" {
+			setKey = func() error { return repackVaultKey(m, token, passRaw) }
+			break
+		}
+		if newToken, err = s.GetToken(); err != nil {
+			err = tokenError(err)
+			return
+		}
+		oldToken, res.ReKeyed = string(token), true
+	}
+	var opts []crypt.Option
+	if opts, err = cryptOptions(); err != nil {
+		return
+	}
+
+	bak := make(map[string]any)
+	for _, k := range append([]string{"packed_key", "kdf", "recovery"}, vaultSettings...) {
+		bak[k] = cfg.User.Get(k)
+	}
+	var newFiles, oldFiles []string
+	err = s.r.DB.Transaction(func(db storage.DB) (err error) {
+		for {
+			var (
+				rec     vault.Record
+				content io.Reader
+			)
+			if rec, content, err = vr.Next(); err == io.EOF {
+				break
+			}
+			if err != nil {
+				return
+			}
+			var created, obsolete []string
+			created, obsolete, err = s.restoreRecord(db, rec, content, overwrite, oldToken, newToken, opts, &res)
+			newFiles = append(newFiles, created...)
+			if err != nil {
+				return fmt.Errorf("%s: %w", rec.Key, err)
+			}
+			oldFiles = append(oldFiles, obsolete...)
+		}
+		if setKey == nil {
+			return nil
+		}
+		if err = setKey(); err != nil {
+			return
+		}
+		// the key must be stored before commit, the previous one is restored on failure
+		return cfg.User.Save()
+	})
+	if err != nil {
+		if setKey != nil {
+			for k, v := range bak {
+				cfg.User.Set(k, v)
+			}
+			err = errors.Join(err, cfg.User.Save())
+		}
+		for _, f := range newFiles {
+			err = errors.Join(err, s.r.File.Delete(f))
+		}
+		res = VaultRestore{}
+		return
+	}
+	for _, f := range oldFiles {
+		if er := s.r.File.Delete(f); er != nil && !os.IsNotExist(er) {
+			err = errors.Join(err, er)
+		}
+	}
+	err = errors.Join(err, restoreTemplates(m.Templates))
+	return
+}
+
+// restoreRecord
+// save the archived record, it is re-keyed if the new token is set. Returns the names of created files
+// at the file store and the names of files to delete after commit
+func (s *service) restoreRecord(db storage.DB, rec vault.Record, content io.Reader, overwrite bool,
+	oldToken, newToken string, opts []crypt.Option, res *VaultRestore) (created, obsolete []string, err error) {
+	existing, err := db.Get(rec.Key)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	exists := err == nil && !existing.IsDeleted()
+	err = nil
+	if exists && !overwrite {
+		res.Skipped++
+		return
+	}
+	r := model.DBRecord{
+		DBItem: model.DBItem{
+			Key:         rec.Key,
+			CreatedAt:   rec.CreatedAt,
+			UpdatedAt:   rec.UpdatedAt,
+			Description: rec.Description,
+		},
+		Blob:        rec.Blob,
+		IndexTokens: rec.IndexTokens,
+	}
+	if exists && r.UpdatedAt == nil {
+		// the updated time of the overwritten record is set on saving, the created one is kept instead
+		r.UpdatedAt = &r.CreatedAt
+	}
+	if content != nil {
+		fileName := storeFileName(r.Key)
+		var dst io.WriteCloser
+		if dst, err = s.r.File.CreateStore(fileName); err != nil {
+			return
+		}
+		created = append(created, fileName)
+		_, err = io.Copy(dst, content)
+		if err = errors.Join(err, dst.Close()); err != nil {
+			return
+		}
+		r.Filename = &fileName
+	}
+	if newToken != "" {
+		var newFile, oldFile string
+		r, newFile, oldFile, err = s.reKeyRecord(r, oldToken, newToken, opts)
+		if newFile != "" {
+			created = append(created, newFile)
+		}
+		if err != nil {
+			return
+		}
+		if oldFile != "" {
+			obsolete = append(obsolete, oldFile)
+		}
+	}
+	// the overwritten record is kept at the history, the files of the removed versions are deleted after commit
+	var files []string
+	if files, err = saveRecord(db, r); err != nil {
+		return
+	}
+	obsolete = append(obsolete, files...)
+	if exists {
+		res.Overwritten++
+	} else {
+		res.Created++
+	}
+	return
+}
+
+// adoptVaultKey
+// store the packed key of the archive of the same profile name with its derivation parameters,
+// the recovery wrapping and the settings of the records encryption
+func adoptVaultKey(m vault.Manifest) (err error) {
+	cfg.User.Set("packed_key", m.PackedKey)
+	cfg.User.Set("kdf", nil)
+	if m.KDF != nil {
+		cfg.SetKDFParams(*m.KDF)
+	}
+	cfg.SetRecovery(nil)
+	if len(m.Recovery) > 0 {
+		var r cfg.Recovery
+		if err = json.Unmarshal(m.Recovery, &r); err != nil {
+			return
+		}
+		cfg.SetRecovery(&r)
+	}
+	setVaultSettings(m)
+	return
+}
+
+// repackVaultKey
+// wrap the key of the archive by the master passphrase of the exported profile for this profile,
+// as the packed key is bound to the profile name. The recovery wrapping is bound to it too and is not restored
+func repackVaultKey(m vault.Manifest, token []byte, passRaw string) (err error) {
+	costs := crypt.DefaultKDFParams
+	if m.KDF != nil {
+		costs = *m.KDF
+	}
+	var params crypt.KDFParams
+	if params, err = crypt.NewKDFParams(costs); err != nil {
+		return
+	}
+	if params.KeyFile, err = keyFileFingerprint(); err != nil {
+		return
+	}
+	setVaultSettings(m)
+	if err = packToken(token, profileKeyPass(cfg.User.GetString("name"), passRaw), params); err != nil {
+		return
+	}
+	cfg.SetRecovery(nil)
+	return
+}
+
+// setVaultSettings
+// set the archived settings of the records encryption
+func setVaultSettings(m vault.Manifest) {
+	for _, k := range vaultSettings {
+		if v, ok := m.Settings[k]; ok {
+			cfg.User.Set(k, v)
+		}
+	}
+}
+
+// restoreTemplates
+// add the archived templates missing at the templates file of profile by their names
+func restoreTemplates(b json.RawMessage) (err error) {
+	if len(b) == 0 || string(b) == "null" {
+		return
+	}
+	var archived []template.Template
+	if archived, err = template.Parse(b); err != nil {
+		return
+	}
+	var dir string
+	if dir, err = cfg.UsrCfgDir(); err != nil {
+		return
+	}
+	templates, path, err := template.Load(dir)
+	if err != nil {
+		return
+	}
+	names := make(map[string]bool, len(templates))
+	for _, t := range templates {
+		names[t.Name] = true
+	}
+	added := false
+	for _, t := range archived {
+		if !names[t.Name] {
+			templates = append(templates, t)
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+	return template.Save(dir, path, templates)
+}
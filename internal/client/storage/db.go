@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"database/sql"
+	"errors"
 	"time"
 
 	"gophKeeper/internal/client/model"
@@ -11,22 +13,64 @@ import (
 	sq "github.com/Masterminds/squirrel"
 )
 
+// sqlxDB common methods of sqlx.DB and sqlx.Tx
+type sqlxDB interface {
+	Get(dest interface{}, query string, args ...interface{}) error
+	Select(dest interface{}, query string, args ...interface{}) error
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
 type dbStore struct {
-	db *sqlx.DB
+	db sqlxDB
+	// conn is nil for the store bound to a transaction
+	conn *sqlx.DB
 }
 
 func NewDBStore(db *sqlx.DB) *dbStore {
 	return &dbStore{
-		db: db,
+		db:   db,
+		conn: db,
 	}
 }
 
+// Transaction
+// run fn with the store bound to one transaction, it is committed if fn returns no error,
+// a nested call runs fn in the current transaction
+func (s *dbStore) Transaction(fn func(db DB) error) (err error) {
+	if s.conn == nil {
+		return fn(s)
+	}
+	var tx *sqlx.Tx
+	if tx, err = s.conn.Beginx(); err != nil {
+		return
+	}
+	if err = fn(&dbStore{db: tx}); err != nil {
+		err = errors.Join(err, tx.Rollback())
+		return
+	}
+	err = tx.Commit()
+	return
+}
+
 func (s *dbStore) querySqlBuilder(b sq.SelectBuilder, query model.ListQuery) sq.SelectBuilder {
 	if query.Key != "" {
 		b = b.Where(sq.Like{"key": "%" + query.Key + "%"})
 	}
 	if query.Description != "" {
-		b = b.Where(sq.Like{"description": "%" + query.Description + "%"})
+		plain := sq.And{
+			sq.NotLike{"description": model.EncryptedDescriptionPrefix + "%"},
+			sq.Like{"description": "%" + query.Description + "%"},
+		}
+		if len(query.DescriptionTokens) == 0 {
+			b = b.Where(plain)
+		} else {
+			// all words of the query are in the index of the encrypted description
+			indexed := sq.Select("key").From("storage_index").
+				Where(sq.Eq{"token": query.DescriptionTokens}).
+				GroupBy("key").
+				Having("count(*) = ?", len(query.DescriptionTokens))
+			b = b.Where(sq.Or{plain, sq.Expr("key in (?)", indexed)})
+		}
 	}
 	if query.CreatedAt != "" {
 		b = b.Where(sq.Like{"created_at": "%" + query.CreatedAt + "%"})
@@ -38,7 +82,7 @@ func (s *dbStore) querySqlBuilder(b sq.SelectBuilder, query model.ListQuery) sq.
 		b = b.Where("sync_at is null or sync_at < ?", query.SyncAt)
 	}
 	if !query.Deleted {
-		b = b.Where("blob is not null or filename is not null")
+		b = b.Where(sq.Or{sq.NotEq{"blob": nil}, sq.NotEq{"filename": nil}}).Where(sq.Eq{"deleted_at": nil})
 	}
 	return b
 }
@@ -89,7 +133,7 @@ func (s *dbStore) Count(query model.ListQuery) (n uint64, err error) {
 func (s *dbStore) Get(key string) (model.DBRecord, error) {
 	var data model.DBRecord
 	err := s.db.Get(&data,
-		`SELECT key, description, created_at, updated_at, filename, blob, sync_at FROM storage where key = ?`,
+		`SELECT key, description, created_at, updated_at, filename, blob, sync_at, deleted_at FROM storage where key = ?`,
 		key)
 	if err != nil {
 		return model.DBRecord{}, err
@@ -97,31 +141,241 @@ func (s *dbStore) Get(key string) (model.DBRecord, error) {
 	return data, nil
 }
 
+// Save
+// insert or update the record, the previous version of the changed record is kept at the history
 func (s *dbStore) Save(data model.DBRecord) (err error) {
+	return s.Transaction(func(db DB) (err error) {
+		tx := db.(*dbStore)
+		if err = tx.keepVersion(data); err != nil {
+			return
+		}
+		return tx.save(data)
+	})
+}
+
+// Rewrite
+// update the record in place, as re-encrypted by another key, the previous version is not kept
+func (s *dbStore) Rewrite(data model.DBRecord) (err error) {
+	return s.save(data)
+}
+
+// keepVersion
+// copy the stored record to the history as its next version, if its data or description is changed by data.
+// The deleted record is not kept
+func (s *dbStore) keepVersion(data model.DBRecord) (err error) {
+	_, err = s.db.Exec(`insert into storage_history
+ (key, version, description, created_at, updated_at, filename, blob)
+ select key, ifnull((select max(version) from storage_history h where h.key = storage.key), 0) + 1,
+        description, created_at, updated_at, filename, blob
+ from storage
+ where key = ? and (blob is not null or filename is not null)
+   and (ifnull(blob, x'') != ifnull(?, x'') or filename is not ? or description != ?)`,
+		data.Key, data.Blob, data.Filename, data.Description)
+	return
+}
+
+func (s *dbStore) save(data model.DBRecord) (err error) {
 	createdAt := data.CreatedAt.Format(time.DateTime)
 	if data.CreatedAt.IsZero() {
 		createdAt = time.Now().Format(time.DateTime)
 	}
-	var updatedAt *string
+	var updatedAt, deletedAt *string
 	if data.UpdatedAt != nil {
 		updatedAt = &[]string{data.UpdatedAt.Format(time.DateTime)}[0]
 	}
+	if data.DeletedAt != nil {
+		deletedAt = &[]string{data.DeletedAt.Format(time.DateTime)}[0]
+	}
 	_, err = s.db.Exec(`insert into storage 
- (key, description, created_at, updated_at, filename, blob, sync_at)
- values(?,?,?,?,?,?,?)
+ (key, description, created_at, updated_at, filename, blob, sync_at, deleted_at)
+ values(?,?,?,?,?,?,?,?)
  on conflict (key) do update 
   set description=excluded.description,
       updated_at=case when excluded.updated_at is not null then excluded.updated_at else DATETIME('now','localtime') end,
       filename=excluded.filename,
       blob=excluded.blob,
-      sync_at=excluded.sync_at`,
-		data.Key, data.Description, createdAt, updatedAt, data.Filename, data.Blob, data.SyncAt)
+      sync_at=excluded.sync_at,
+      deleted_at=excluded.deleted_at`,
+		data.Key, data.Description, createdAt, updatedAt, data.Filename, data.Blob, data.SyncAt, deletedAt)
 	return
 }
 
+// Delete
+// move the record to the trash, its payload is kept until purged
 func (s *dbStore) Delete(key string) (err error) {
 	_, err = s.db.Exec(`update storage 
-set description = '', updated_at=DATETIME('now','localtime'), filename = null, blob=null
+set updated_at=DATETIME('now','localtime'), deleted_at=DATETIME('now','localtime')
 where key = ?`, key)
 	return
 }
+
+// Purge
+// wipe the description and payload of the deleted record with its index,
+// the record is kept as the tombstone for synchronization
+func (s *dbStore) Purge(key string) (err error) {
+	_, err = s.db.Exec(`update storage 
+set description = '', filename = null, blob=null, deleted_at=ifnull(deleted_at, DATETIME('now','localtime'))
+where key = ?`, key)
+	if err != nil {
+		return
+	}
+	err = s.SaveIndex(key, nil)
+	return
+}
+
+// ListDeleted
+// the records at the trash, moved to it before the time if it is not zero, the latest deleted first
+func (s *dbStore) ListDeleted(before time.Time) (data []model.DBItem, err error) {
+	builder := sq.Select("key", "description", "created_at", "updated_at", "sync_at", "deleted_at").
+		From("storage").
+		Where(sq.NotEq{"deleted_at": nil}).
+		Where(sq.Or{sq.NotEq{"blob": nil}, sq.NotEq{"filename": nil}}).
+		OrderBy("deleted_at desc", "key")
+	if !before.IsZero() {
+		builder = builder.Where(sq.Lt{"deleted_at": before.Format(time.DateTime)})
+	}
+	var (
+		sql  string
+		args []interface{}
+	)
+	if sql, args, err = builder.ToSql(); err != nil {
+		return
+	}
+	err = s.db.Select(&data, sql, args...)
+	return
+}
+
+// ListVersions
+// the kept versions of the record without their data, the newest first.
+// The versions of all records are listed for the empty key
+func (s *dbStore) ListVersions(key string) (data []model.DBVersion, err error) {
+	builder := sq.Select("key", "version", "description", "created_at", "updated_at", "filename", "saved_at").
+		From("storage_history").
+		OrderBy("key", "version desc")
+	if key != "" {
+		builder = builder.Where(sq.Eq{"key": key})
+	}
+	var (
+		sql  string
+		args []interface{}
+	)
+	if sql, args, err = builder.ToSql(); err != nil {
+		return
+	}
+	err = s.db.Select(&data, sql, args...)
+	return
+}
+
+// GetVersion
+// the kept version of the record with its data
+func (s *dbStore) GetVersion(key string, version int) (data model.DBVersion, err error) {
+	err = s.db.Get(&data, `SELECT key, version, description, created_at, updated_at, filename, blob, saved_at
+FROM storage_history where key = ? and version = ?`, key, version)
+	return
+}
+
+// UpdateVersion
+// replace the data and description of the kept version, as re-encrypted by another key
+func (s *dbStore) UpdateVersion(data model.DBVersion) (err error) {
+	_, err = s.db.Exec(`update storage_history set description = ?, filename = ?, blob = ?
+where key = ? and version = ?`, data.Description, data.Filename, data.Blob, data.Key, data.Version)
+	return
+}
+
+// PruneHistory
+// remove the versions of the record beyond the keep newest ones and the versions replaced before the time,
+// the zero time removes none by age. Returns the files of the removed versions not used by other records
+func (s *dbStore) PruneHistory(key string, keep int, before time.Time) (files []string, err error) {
+	if keep < 0 {
+		keep = 0
+	}
+	where := `key = ? and (version not in
+ (select version from storage_history where key = ? order by version desc limit ?)`
+	args := []any{key, key, keep}
+	if !before.IsZero() {
+		where += ` or saved_at < ?`
+		args = append(args, before.Format(time.DateTime))
+	}
+	return s.removeVersions(where+`)`, args...)
+}
+
+// DeleteHistory
+// remove all versions of the record. Returns the files of the removed versions not used by other records
+func (s *dbStore) DeleteHistory(key string) (files []string, err error) {
+	return s.removeVersions(`key = ?`, key)
+}
+
+// removeVersions
+// remove the versions matching the condition, returns their files not used by the records and other versions
+func (s *dbStore) removeVersions(where string, args ...any) (files []string, err error) {
+	var names []string
+	if err = s.db.Select(&names, `select distinct filename from storage_history
+where filename is not null and `+where, args...); err != nil {
+		return
+	}
+	if _, err = s.db.Exec(`delete from storage_history where `+where, args...); err != nil {
+		return
+	}
+	for _, name := range names {
+		var n int
+		if err = s.db.Get(&n, `select (select count(*) from storage where filename = ?) +
+ (select count(*) from storage_history where filename = ?)`, name, name); err != nil {
+			return
+		}
+		if n == 0 {
+			files = append(files, name)
+		}
+	}
+	return
+}
+
+// SaveIndex
+// replace the blind index tokens of the record description.
+// The empty token marks the indexed record, nil tokens remove the index
+func (s *dbStore) SaveIndex(key string, tokens []string) (err error) {
+	if _, err = s.db.Exec(`delete from storage_index where key = ?`, key); err != nil || tokens == nil {
+		return
+	}
+	for _, token := range append([]string{""}, tokens...) {
+		if _, err = s.db.Exec(`insert into storage_index (key, token) values(?,?) on conflict do nothing`,
+			key, token); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// GetIndex
+// the blind index tokens of the record description, nil if not indexed
+func (s *dbStore) GetIndex(key string) (tokens []string, err error) {
+	var rows []string
+	if err = s.db.Select(&rows, `select token from storage_index where key = ? order by token`, key); err != nil {
+		return
+	}
+	for _, token := range rows {
+		if tokens == nil {
+			tokens = []string{}
+		}
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return
+}
+
+// ListUnindexed
+// the records with encrypted description and no blind index, as received by synchronization
+func (s *dbStore) ListUnindexed() (data []model.DBItem, err error) {
+	err = s.db.Select(&data, `SELECT key, description, created_at, updated_at, sync_at FROM storage
+where description like ? and key not in (select key from storage_index)`,
+		model.EncryptedDescriptionPrefix+"%")
+	return
+}
+
+// CountEncrypted
+// number of records with encrypted description
+func (s *dbStore) CountEncrypted() (n uint64, err error) {
+	err = s.db.Get(&n, `SELECT count(*) FROM storage where description like ?`,
+		model.EncryptedDescriptionPrefix+"%")
+	return
+}
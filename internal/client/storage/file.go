@@ -2,6 +2,7 @@ package storage
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -19,22 +20,57 @@ func NewFileStore(path string) *fileStore {
 	}
 }
 
-func (s *fileStore) GetStored(fileName string) (b []byte, err error) {
+// storedPath
+// full path of the file in store, file name must not lead outside the store
+func (s *fileStore) storedPath(fileName string) (string, error) {
 	if strings.Contains(fileName, "..") || filepath.IsAbs(fileName) {
-		return nil, fmt.Errorf("invalid file name: %s", fileName)
+		return "", fmt.Errorf("invalid file name: %s", fileName)
 	}
 	fullPath := filepath.Join(s.path, fileName)
 	fullPath = filepath.Clean(fullPath)
 	relPath, err := filepath.Rel(s.path, fullPath)
 	if err != nil || strings.HasPrefix(relPath, "..") {
-		return nil, fmt.Errorf("attempt to access file outside of allowed directory: %s", fullPath)
+		return "", fmt.Errorf("attempt to access file outside of allowed directory: %s", fullPath)
+	}
+	return fullPath, nil
+}
+
+func (s *fileStore) GetStored(fileName string) (b []byte, err error) {
+	fullPath, err := s.storedPath(fileName)
+	if err != nil {
+		return nil, err
 	}
 
 	return os.ReadFile(fullPath)
 }
 
+// OpenStored
+// open the stored file for reading by parts
+func (s *fileStore) OpenStored(fileName string) (f io.ReadSeekCloser, err error) {
+	fullPath, err := s.storedPath(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(fullPath)
+}
+
+// CreateStore
+// create the stored file for writing by parts
+func (s *fileStore) CreateStore(fileName string) (f io.WriteCloser, err error) {
+	if err = os.Mkdir(s.path, 0700); err != nil && !os.IsExist(err) {
+		return
+	}
+	fullPath, err := s.storedPath(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+}
+
 func (s *fileStore) SaveStore(fileName string, b []byte) (err error) {
-	if err = os.Mkdir(s.path, 0600); err != nil && !os.IsExist(err) {
+	if err = os.Mkdir(s.path, 0700); err != nil && !os.IsExist(err) {
 		return
 	}
 	return os.WriteFile(filepath.Join(s.path, fileName), b, 0600)
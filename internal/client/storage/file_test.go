@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStoreDir(t *testing.T) {
+	// the new store directory must be entered to create its files
+	for name, create := range map[string]func(s *fileStore) error{
+		"create": func(s *fileStore) error {
+			f, err := s.CreateStore("streamed")
+			if err == nil {
+				err = f.Close()
+			}
+			return err
+		},
+		"save": func(s *fileStore) error { return s.SaveStore("saved", []byte("content")) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := NewFileStore(filepath.Join(t.TempDir(), "store"))
+			require.NoError(t, create(s))
+			fi, err := os.Stat(s.path)
+			require.NoError(t, err)
+			require.Equal(t, os.FileMode(0700), fi.Mode().Perm())
+		})
+	}
+}
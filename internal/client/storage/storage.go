@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"io"
+	"time"
+
 	"gophKeeper/internal/client/model"
 
 	"github.com/jmoiron/sqlx"
@@ -11,12 +14,27 @@ type DB interface {
 	Count(query model.ListQuery) (n uint64, err error)
 	Get(key string) (data model.DBRecord, err error)
 	Save(data model.DBRecord) (err error)
+	Rewrite(data model.DBRecord) (err error)
 	Delete(key string) (err error)
+	Purge(key string) (err error)
+	ListDeleted(before time.Time) (data []model.DBItem, err error)
+	SaveIndex(key string, tokens []string) (err error)
+	GetIndex(key string) (tokens []string, err error)
+	ListUnindexed() (data []model.DBItem, err error)
+	CountEncrypted() (n uint64, err error)
+	ListVersions(key string) (data []model.DBVersion, err error)
+	GetVersion(key string, version int) (data model.DBVersion, err error)
+	UpdateVersion(data model.DBVersion) (err error)
+	PruneHistory(key string, keep int, before time.Time) (files []string, err error)
+	DeleteHistory(key string) (files []string, err error)
+	Transaction(fn func(db DB) error) (err error)
 }
 
 type File interface {
 	GetStored(fileName string) (b []byte, err error)
 	SaveStore(fileName string, b []byte) (err error)
+	OpenStored(fileName string) (f io.ReadSeekCloser, err error)
+	CreateStore(fileName string) (f io.WriteCloser, err error)
 	Delete(fileName string) (err error)
 	GetOrigin(filePath string) (b []byte, err error)
 	SaveOrigin(filePath string, b []byte) (err error)
@@ -4,14 +4,17 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"runtime"
 	"sync"
 	"time"
 
 	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/crypt"
 	"gophKeeper/internal/client/model"
 	"gophKeeper/internal/client/model/out"
+	"gophKeeper/internal/client/model/type/template"
 	"gophKeeper/internal/client/service"
 	pb "gophKeeper/internal/proto"
 
@@ -116,6 +119,40 @@ func (sc syncService) SyncUser(ctx context.Context, newPass string) (updated boo
 		Description: cfg.User.GetString("sync.user.description"),
 		Password:    newPass,
 	}
+	if params, ok, er := cfg.GetKDFParams(); er != nil {
+		err = er
+		return
+	} else if ok {
+		if user.KdfParams, err = json.Marshal(params); err != nil {
+			return
+		}
+	}
+	if r, ok, er := cfg.GetRecovery(); er != nil {
+		err = er
+		return
+	} else if ok {
+		if user.Recovery, err = json.Marshal(r); err != nil {
+			return
+		}
+	}
+	templatesDir, err := cfg.UsrCfgDir()
+	if err != nil {
+		return
+	}
+	templates, templatesPath, err := localTemplates(templatesDir)
+	if err != nil {
+		return
+	}
+	// the changed templates file makes the local profile newer
+	if h := template.Hash(templates); h != cfg.User.GetString("templates_hash") {
+		cfg.User.Set("templates_hash", h)
+	}
+	// the templates are sent encrypted by the profile key, the profile without the key has none to send
+	if templates != nil && cfg.User.GetString("packed_key") != "" {
+		if user.Templates, err = sc.s.EncryptTemplates(templates); err != nil {
+			return
+		}
+	}
 	if createdAt := cfg.User.GetTime("sync.user.created_at"); !createdAt.IsZero() {
 		user.CreatedAt = timestamppb.New(createdAt)
 	}
@@ -132,9 +169,49 @@ func (sc syncService) SyncUser(ctx context.Context, newPass string) (updated boo
 		return
 	}
 	if getUser.PackedKey != nil && !bytes.Equal(user.PackedKey, getUser.PackedKey) {
+		// the rejected derivation parameters keep the current packed key
+		if _, err = parseKDFParams(getUser.KdfParams); err != nil {
+			return
+		}
 		user.PackedKey = getUser.PackedKey
 		cfg.User.Set("packed_key", user.PackedKey)
+		// the unwrapped key is of the replaced packed key
+		cfg.User.Set("encryption_key", nil)
+		// the derivation parameters are valid only with its packed key
+		if err = setKDFParams(getUser.KdfParams); err != nil {
+			return
+		}
+		// and so is the recovery wrapping
+		if err = setRecovery(getUser.Recovery); err != nil {
+			return
+		}
 		updated = true
+	} else {
+		if len(getUser.KdfParams) > 0 && !bytes.Equal(user.KdfParams, getUser.KdfParams) {
+			if err = setKDFParams(getUser.KdfParams); err != nil {
+				return
+			}
+			updated = true
+		}
+		if len(getUser.Recovery) > 0 && !bytes.Equal(user.Recovery, getUser.Recovery) {
+			if err = setRecovery(getUser.Recovery); err != nil {
+				return
+			}
+			updated = true
+		}
+	}
+	// the profile without templates at server keeps the local ones, the empty list removes them
+	if len(getUser.Templates) > 0 && !bytes.Equal(user.Templates, getUser.Templates) {
+		var received []byte
+		if received, err = sc.s.DecryptTemplates(getUser.Templates); err != nil {
+			return
+		}
+		if template.Hash(received) != cfg.User.GetString("templates_hash") {
+			if err = setTemplates(templatesDir, templatesPath, received); err != nil {
+				return
+			}
+			updated = true
+		}
 	}
 	if user.Description != getUser.Description {
 		cfg.User.Set("sync.user.description", getUser.Description)
@@ -158,6 +235,52 @@ func (sc syncService) SyncUser(ctx context.Context, newPass string) (updated boo
 	return
 }
 
+// setKDFParams
+// store the key derivation parameters received from server,
+// empty ones are of the legacy packed key
+func setKDFParams(b []byte) (err error) {
+	if len(b) == 0 {
+		cfg.User.Set("kdf", nil)
+		return
+	}
+	var params crypt.KDFParams
+	if params, err = parseKDFParams(b); err != nil {
+		return
+	}
+	cfg.SetKDFParams(params)
+	return
+}
+
+// parseKDFParams
+// the key derivation parameters received from server, the ones out of the accepted costs are rejected,
+// so a server can not make the unlock run out of memory, empty ones are zero
+func parseKDFParams(b []byte) (params crypt.KDFParams, err error) {
+	if len(b) == 0 {
+		return
+	}
+	if err = json.Unmarshal(b, &params); err != nil {
+		return
+	}
+	err = params.Validate()
+	return
+}
+
+// setRecovery
+// store the recovery wrapping of the encryption key received from server,
+// empty one removes the local wrapping
+func setRecovery(b []byte) (err error) {
+	if len(b) == 0 {
+		cfg.SetRecovery(nil)
+		return
+	}
+	var r cfg.Recovery
+	if err = json.Unmarshal(b, &r); err != nil {
+		return
+	}
+	cfg.SetRecovery(&r)
+	return
+}
+
 func (sc syncService) DeleteUser(ctx context.Context) (err error) {
 	client := pb.NewUserClient(sc.conn)
 	_, err = client.DeleteUser(ctx, &pb.NoMessage{}, sc.callOpt...)
@@ -259,6 +382,10 @@ errCh chan error*/) (lcItemQueue chan dbRecQueue) {
 				if item.Key == "" {
 					item.Key = key
 				}
+				if !cfg.User.GetBool("description.server_index") {
+					// the blind index is kept at server by opt-in only
+					item.IndexTokens = nil
+				}
 				lcItemQueue <- dbRecQueue{item, er}
 			}
 		}
@@ -0,0 +1,45 @@
+package sync
+
+import (
+	"encoding/json"
+	"testing"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/crypt"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetKDFParams(t *testing.T) {
+	// the profile of the test is at the temporary home
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	require.NoError(t, cfg.UserLoad(true))
+
+	params, err := crypt.NewKDFParams(crypt.MinKDFParams)
+	require.NoError(t, err)
+	b, err := json.Marshal(params)
+	require.NoError(t, err)
+	require.NoError(t, setKDFParams(b))
+	stored, ok, err := cfg.GetKDFParams()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, params, stored)
+
+	for name, change := range map[string]func(p *crypt.KDFParams){
+		"memory":  func(p *crypt.KDFParams) { p.Memory = crypt.MaxKDFParams.Memory + 1 },
+		"time":    func(p *crypt.KDFParams) { p.Time = crypt.MaxKDFParams.Time + 1 },
+		"threads": func(p *crypt.KDFParams) { p.Threads = crypt.MaxKDFParams.Threads + 1 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			huge := params
+			change(&huge)
+			b, err := json.Marshal(huge)
+			require.NoError(t, err)
+			require.ErrorIs(t, setKDFParams(b), crypt.ErrKDFParams)
+			stored, _, err := cfg.GetKDFParams()
+			require.NoError(t, err)
+			require.Equal(t, params, stored)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package sync
+
+import (
+	"os"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/model/type/template"
+)
+
+// localTemplates
+// the canonical templates of the profile file to synchronize and the path of file.
+// The file removed after its templates are synchronized is the empty list, so the removal is synchronized too,
+// the profile that never had templates has none and keeps the templates of server
+func localTemplates(dir string) (b []byte, path string, err error) {
+	var templates []template.Template
+	if templates, path, err = template.Load(dir); err != nil {
+		return
+	}
+	if templates == nil && cfg.User.GetString("templates_hash") != "" {
+		templates = []template.Template{}
+	}
+	b, err = template.Marshal(templates)
+	return
+}
+
+// setTemplates
+// write the templates received from server to the templates file of profile,
+// the empty list removes the file
+func setTemplates(dir, path string, b []byte) (err error) {
+	var templates []template.Template
+	if templates, err = template.Parse(b); err != nil {
+		return
+	}
+	if len(templates) > 0 {
+		err = template.Save(dir, path, templates)
+	} else if path != "" {
+		if err = os.Remove(path); os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return
+	}
+	if b, err = template.Marshal(templates); err != nil {
+		return
+	}
+	cfg.User.Set("templates_hash", template.Hash(b))
+	return
+}
@@ -0,0 +1,76 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	cfg "gophKeeper/internal/client/config"
+	"gophKeeper/internal/client/model/type/template"
+
+	"github.com/stretchr/testify/require"
+)
+
+const templatesYAML = `
+templates:
+  - name: license
+    fields:
+      - name: code
+        kind: secret
+`
+
+func TestTemplates(t *testing.T) {
+	// the profile of the test is at the temporary home
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	require.NoError(t, cfg.UserLoad(true))
+	dir := t.TempDir()
+	empty, err := template.Marshal([]template.Template{})
+	require.NoError(t, err)
+
+	t.Run("never had templates", func(t *testing.T) {
+		b, path, err := localTemplates(dir)
+		require.NoError(t, err)
+		require.Nil(t, b)
+		require.Empty(t, path)
+	})
+
+	var received []byte
+	t.Run("received", func(t *testing.T) {
+		templates, err := template.Parse([]byte(templatesYAML))
+		require.NoError(t, err)
+		received, err = template.Marshal(templates)
+		require.NoError(t, err)
+		require.NoError(t, setTemplates(dir, "", received))
+		require.Equal(t, template.Hash(received), cfg.User.GetString("templates_hash"))
+
+		b, path, err := localTemplates(dir)
+		require.NoError(t, err)
+		require.Equal(t, received, b)
+		require.Equal(t, filepath.Join(dir, template.FileNames[0]), path)
+	})
+
+	t.Run("removed file is empty list", func(t *testing.T) {
+		require.NoError(t, os.Remove(filepath.Join(dir, template.FileNames[0])))
+		b, path, err := localTemplates(dir)
+		require.NoError(t, err)
+		require.Empty(t, path)
+		require.Equal(t, empty, b)
+		require.NotEqual(t, template.Hash(b), cfg.User.GetString("templates_hash"))
+	})
+
+	t.Run("received empty list removes file", func(t *testing.T) {
+		require.NoError(t, setTemplates(dir, "", received))
+		path := filepath.Join(dir, template.FileNames[0])
+		require.NoError(t, setTemplates(dir, path, empty))
+		_, err := os.Stat(path)
+		require.True(t, os.IsNotExist(err))
+		require.Equal(t, template.Hash(empty), cfg.User.GetString("templates_hash"))
+
+		// the removed file is not changed since, the profile is not newer
+		b, _, err := localTemplates(dir)
+		require.NoError(t, err)
+		require.Equal(t, template.Hash(empty), template.Hash(b))
+		require.NoError(t, setTemplates(dir, "", empty))
+	})
+}
@@ -0,0 +1,317 @@
+/*
+Package vault writes and reads the encrypted vault archive of a profile, the .gkx file.
+
+The archive is the magic line, the clear header line with the key derivation parameters
+and the stream encrypted by the key derived from the export passphrase, the header is bound
+to the stream as its associated data. The stream is a tar of the manifest, followed by the records,
+the record kept at the file store is followed by its content. The records stay encrypted
+by the profile key, the manifest holds the packed key to unwrap it.
+*/
+
+package vault
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"gophKeeper/internal/client/crypt"
+)
+
+const (
+	// Format the import format name of the archive
+	Format = "gkx"
+	// Ext the file extension of the archive
+	Ext     = ".gkx"
+	Version = 1
+
+	contentType  = "gophkeeper/vault"
+	manifestName = "manifest.json"
+	recordPrefix = "records/"
+	filePrefix   = "files/"
+	// maxHeaderLen the limit of the clear header line
+	maxHeaderLen = 4096
+)
+
+var magic = []byte("GKX\n")
+
+var (
+	ErrFormat     = errors.New("not a vault archive")
+	ErrVersion    = errors.New("unsupported vault archive version")
+	ErrPassphrase = errors.New("wrong export passphrase")
+	ErrIntegrity  = errors.New("vault archive integrity check failed")
+)
+
+// Header
+// the clear part of the archive, the parameters to derive the key from the export passphrase
+type Header struct {
+	Version   int             `json:"version"`
+	KDF       crypt.KDFParams `json:"kdf"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Manifest
+// the exported profile: its name, the packed key of the records with the derivation parameters,
+// the recovery wrapping, the settings of the records encryption and the templates
+type Manifest struct {
+	Profile   string           `json:"profile"`
+	PackedKey string           `json:"packed_key"`
+	KDF       *crypt.KDFParams `json:"kdf,omitempty"`
+	Recovery  json.RawMessage  `json:"recovery,omitempty"`
+	Settings  map[string]any   `json:"settings,omitempty"`
+	// Templates the canonical templates of the profile, null if it has no templates file
+	Templates json.RawMessage `json:"templates,omitempty"`
+	// Records the number of archived records, checked at reading
+	Records int `json:"records"`
+}
+
+// Record
+// the record as it is stored, the content of the stored one follows it at the archive
+type Record struct {
+	Key         string     `json:"key"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	Blob        []byte     `json:"blob,omitempty"`
+	Stored      bool       `json:"stored,omitempty"`
+	// IndexTokens blind index tokens of the encrypted description, nil if not indexed
+	IndexTokens []string `json:"index_tokens"`
+}
+
+// Writer
+// writes the records to the archive, Close must be called to complete it
+type Writer struct {
+	enc     io.WriteCloser
+	tw      *tar.Writer
+	records int
+	n       int
+}
+
+// NewWriter
+// start the archive encrypted by pass at w and write the manifest to it
+func NewWriter(w io.Writer, pass string, m Manifest) (vw *Writer, err error) {
+	h := Header{Version: Version, CreatedAt: time.Now()}
+	if h.KDF, err = crypt.NewKDFParams(crypt.DefaultKDFParams); err != nil {
+		return
+	}
+	var line []byte
+	if line, err = json.Marshal(h); err != nil {
+		return
+	}
+	var key string
+	if key, err = crypt.DeriveKey(pass, h.KDF, nil); err != nil {
+		return
+	}
+	if _, err = w.Write(append(append(bytes.Clone(magic), line...), '\n')); err != nil {
+		return
+	}
+	vw = &Writer{records: m.Records}
+	if vw.enc, err = crypt.NewEncryptWriter(w, key, crypt.WithKDF(crypt.KDFArgon2id),
+		crypt.WithContentType(contentType), crypt.WithAssociatedData(line)); err != nil {
+		return nil, err
+	}
+	vw.tw = tar.NewWriter(vw.enc)
+	var b []byte
+	if b, err = json.Marshal(m); err != nil {
+		return nil, err
+	}
+	if err = vw.writeFile(manifestName, bytes.NewReader(b), int64(len(b))); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// writeFile
+// write the tar entry of the name
+func (w *Writer) writeFile(name string, r io.Reader, size int64) (err error) {
+	if err = w.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0600,
+		ModTime:  time.Now(),
+	}); err != nil {
+		return
+	}
+	var n int64
+	if n, err = io.Copy(w.tw, r); err == nil && n != size {
+		err = fmt.Errorf("%s: written %d of %d bytes", name, n, size)
+	}
+	return
+}
+
+// Add
+// write the record, the content of size is written after the record kept at the file store
+func (w *Writer) Add(r Record, content io.Reader, size int64) (err error) {
+	r.Stored = content != nil
+	if r.Stored {
+		r.Blob = nil
+	}
+	var b []byte
+	if b, err = json.Marshal(r); err != nil {
+		return
+	}
+	name := strconv.Itoa(w.n)
+	if err = w.writeFile(recordPrefix+name+".json", bytes.NewReader(b), int64(len(b))); err != nil {
+		return
+	}
+	if r.Stored {
+		if err = w.writeFile(filePrefix+name, content, size); err != nil {
+			return
+		}
+	}
+	w.n++
+	return
+}
+
+// Close
+// complete the archive, the number of written records must be the one of the manifest.
+// The underlying writer is not closed
+func (w *Writer) Close() (err error) {
+	err = errors.Join(w.tw.Close(), w.enc.Close())
+	if err == nil && w.n != w.records {
+		err = fmt.Errorf("%w: written %d of %d records", ErrIntegrity, w.n, w.records)
+	}
+	return
+}
+
+// Reader
+// reads the records of the archive, the manifest is read by NewReader
+type Reader struct {
+	Header   Header
+	Manifest Manifest
+	dr       io.Reader
+	tr       *tar.Reader
+	n        int
+}
+
+// NewReader
+// open the archive encrypted by pass and read its manifest
+func NewReader(r io.Reader, pass string) (vr *Reader, err error) {
+	br := bufio.NewReaderSize(r, maxHeaderLen)
+	head := make([]byte, len(magic))
+	if _, err = io.ReadFull(br, head); err != nil || !bytes.Equal(head, magic) {
+		return nil, ErrFormat
+	}
+	var line []byte
+	if line, err = br.ReadSlice('\n'); err != nil {
+		return nil, fmt.Errorf("%w: header: %w", ErrFormat, err)
+	}
+	line = bytes.Clone(line[:len(line)-1])
+	vr = &Reader{}
+	if err = json.Unmarshal(line, &vr.Header); err != nil {
+		return nil, fmt.Errorf("%w: header: %w", ErrFormat, err)
+	}
+	if vr.Header.Version != Version {
+		return nil, fmt.Errorf("%w: %d", ErrVersion, vr.Header.Version)
+	}
+	var key string
+	if key, err = crypt.DeriveKey(pass, vr.Header.KDF, nil); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFormat, err)
+	}
+	if vr.dr, err = crypt.NewDecryptReader(br, key, crypt.WithAssociatedData(line)); err != nil {
+		switch {
+		case errors.Is(err, crypt.ErrTruncated):
+			err = integrityError(err)
+		case errors.Is(err, crypt.ErrAuthentication):
+			// the first chunk is opened at once, its damage is not told from the wrong passphrase
+			err = ErrPassphrase
+		}
+		return nil, err
+	}
+	vr.tr = tar.NewReader(vr.dr)
+	var th *tar.Header
+	if th, err = vr.tr.Next(); err != nil {
+		return nil, integrityError(err)
+	}
+	if th.Name != manifestName {
+		return nil, fmt.Errorf("%w: %s is not the manifest", ErrIntegrity, th.Name)
+	}
+	if err = json.NewDecoder(vr.tr).Decode(&vr.Manifest); err != nil {
+		return nil, integrityError(err)
+	}
+	return
+}
+
+// integrityError
+// the error of the damaged archive
+func integrityError(err error) error {
+	if errors.Is(err, ErrIntegrity) {
+		return err
+	}
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("%w: %w", ErrIntegrity, err)
+}
+
+// contentReader
+// reads the stored content, the read errors are the integrity errors
+type contentReader struct {
+	r io.Reader
+}
+
+func (c contentReader) Read(p []byte) (n int, err error) {
+	if n, err = c.r.Read(p); err != nil && err != io.EOF {
+		err = integrityError(err)
+	}
+	return
+}
+
+// Next
+// the next record, the content of the stored one is read from content until the next call.
+// io.EOF is returned at the end of the archive, after the whole stream is authenticated
+// and the number of records is checked
+func (r *Reader) Next() (rec Record, content io.Reader, err error) {
+	var th *tar.Header
+	th, err = r.tr.Next()
+	if err == io.EOF {
+		// the last chunk of the stream is authenticated on reading it to the end
+		if _, err = io.Copy(io.Discard, r.dr); err != nil {
+			err = integrityError(err)
+			return
+		}
+		if r.n != r.Manifest.Records {
+			err = fmt.Errorf("%w: read %d of %d records", ErrIntegrity, r.n, r.Manifest.Records)
+			return
+		}
+		err = io.EOF
+		return
+	}
+	if err != nil {
+		err = integrityError(err)
+		return
+	}
+	name := strconv.Itoa(r.n)
+	if th.Name != recordPrefix+name+".json" {
+		err = fmt.Errorf("%w: unexpected %s", ErrIntegrity, th.Name)
+		return
+	}
+	if err = json.NewDecoder(r.tr).Decode(&rec); err != nil {
+		err = integrityError(err)
+		return
+	}
+	if rec.Key == "" {
+		err = fmt.Errorf("%w: record %s has no key", ErrIntegrity, name)
+		return
+	}
+	if rec.Stored {
+		if th, err = r.tr.Next(); err != nil {
+			err = integrityError(err)
+			return
+		}
+		if th.Name != filePrefix+name {
+			err = fmt.Errorf("%w: no content of record %s", ErrIntegrity, rec.Key)
+			return
+		}
+		content = contentReader{r.tr}
+	}
+	r.n++
+	return
+}
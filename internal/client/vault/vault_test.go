@@ -0,0 +1,126 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeArchive
+// the archive of the inline record and the stored one of not compressible random content
+func writeArchive(t *testing.T, pass string, random []byte) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, pass, Manifest{Profile: "user", PackedKey: "00ff", Records: 2})
+	require.NoError(t, err)
+	updated := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)
+	require.NoError(t, w.Add(Record{
+		Key:         "inline",
+		Description: "some description",
+		CreatedAt:   time.Date(2023, 1, 2, 3, 4, 5, 0, time.Local),
+		UpdatedAt:   &updated,
+		Blob:        []byte("encrypted blob"),
+		IndexTokens: []string{},
+	}, nil, 0))
+	content := hex.EncodeToString(random)
+	require.NoError(t, w.Add(Record{Key: "stored", CreatedAt: time.Now()}, strings.NewReader(content), int64(len(content))))
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestArchive(t *testing.T) {
+	random := make([]byte, 100*1024)
+	_, err := rand.Read(random)
+	require.NoError(t, err)
+	b := writeArchive(t, "export pass", random)
+
+	t.Run("read", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(b), "export pass")
+		require.NoError(t, err)
+		assert.Equal(t, Version, r.Header.Version)
+		assert.Equal(t, "user", r.Manifest.Profile)
+		assert.Equal(t, "00ff", r.Manifest.PackedKey)
+
+		rec, content, err := r.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "inline", rec.Key)
+		assert.Nil(t, content)
+		assert.Equal(t, "encrypted blob", string(rec.Blob))
+		assert.Equal(t, "2023-01-02 03:04:05", rec.CreatedAt.Format(time.DateTime))
+		require.NotNil(t, rec.UpdatedAt)
+		assert.Equal(t, "2024-05-06 07:08:09", rec.UpdatedAt.Format(time.DateTime))
+		assert.NotNil(t, rec.IndexTokens)
+
+		rec, content, err = r.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "stored", rec.Key)
+		assert.Nil(t, rec.IndexTokens)
+		require.NotNil(t, content)
+		got, err := io.ReadAll(content)
+		require.NoError(t, err)
+		assert.Equal(t, hex.EncodeToString(random), string(got))
+
+		_, _, err = r.Next()
+		require.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader(b), "wrong pass")
+		require.ErrorIs(t, err, ErrPassphrase)
+	})
+
+	t.Run("not archive", func(t *testing.T) {
+		_, err := NewReader(strings.NewReader("some text"), "export pass")
+		require.ErrorIs(t, err, ErrFormat)
+	})
+
+	t.Run("changed header", func(t *testing.T) {
+		changed := bytes.Replace(b, []byte(`"version":1`), []byte(`"version":2`), 1)
+		_, err := NewReader(bytes.NewReader(changed), "export pass")
+		require.ErrorIs(t, err, ErrVersion)
+		changed = bytes.Replace(b, []byte(`"created_at":"`), []byte(`"created_at":"1`), 1)
+		_, err = NewReader(bytes.NewReader(changed), "export pass")
+		require.Error(t, err)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(b[:len(b)-20]), "export pass")
+		require.NoError(t, err)
+		for err == nil {
+			_, _, err = r.Next()
+		}
+		require.ErrorIs(t, err, ErrIntegrity)
+	})
+
+	t.Run("changed stream", func(t *testing.T) {
+		changed := bytes.Clone(b)
+		changed[len(changed)-30] ^= 1
+		r, err := NewReader(bytes.NewReader(changed), "export pass")
+		require.NoError(t, err)
+		for err == nil {
+			_, _, err = r.Next()
+		}
+		require.ErrorIs(t, err, ErrIntegrity)
+	})
+
+	t.Run("records count", func(t *testing.T) {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, "export pass", Manifest{Records: 2})
+		require.NoError(t, err)
+		require.NoError(t, w.Add(Record{Key: "one"}, nil, 0))
+		require.ErrorIs(t, w.Close(), ErrIntegrity)
+
+		r, err := NewReader(bytes.NewReader(buf.Bytes()), "export pass")
+		require.NoError(t, err)
+		_, _, err = r.Next()
+		require.NoError(t, err)
+		_, _, err = r.Next()
+		require.ErrorIs(t, err, ErrIntegrity)
+	})
+}
@@ -0,0 +1,25 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	for s, want := range map[string]time.Duration{
+		"30d":   30 * 24 * time.Hour,
+		"0d":    0,
+		"12h":   12 * time.Hour,
+		"1h30m": 90 * time.Minute,
+	} {
+		d, err := ParseDuration(s)
+		require.NoError(t, err, s)
+		require.Equal(t, want, d, s)
+	}
+	for _, s := range []string{"", "d", "-1d", "1.5d", "30 days"} {
+		_, err := ParseDuration(s)
+		require.Error(t, err, s)
+	}
+}
@@ -61,6 +61,8 @@ func generateFlags(rv reflect.Value, fs *pflag.FlagSet) (err error) {
 			case *uint64:
 				defVal, _ := strconv.ParseUint(defVal, 10, 64)
 				fs.Uint64VarP(f, tagNames[0], tagNames[1], defVal, usage)
+			case *[]string:
+				fs.StringArrayVarP(f, tagNames[0], tagNames[1], nil, usage)
 			default:
 				err = errors.New("unknown type")
 			}
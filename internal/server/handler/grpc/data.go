@@ -63,6 +63,7 @@ func (g *data) List(ctx context.Context, in *pb.ListRequest) (out *pb.ListRespon
 	if in.GetOrderby() != "" {
 		q.OrderBy = in.GetOrderby()
 	}
+	q.DescriptionTokens = in.GetDescriptionTokens()
 	if err = q.Validate(); err != nil {
 		err = status.Error(codes.InvalidArgument, err.Error())
 		return
@@ -129,6 +130,7 @@ func (g *data) SyncItem(ctx context.Context, in *pb.ItemSync) (out *pb.ItemSync,
 		}
 		item.CreatedAt = in.GetCreatedAt().AsTime()
 		item.Blob = in.GetBlob()
+		item.DescriptionTokens = in.GetDescriptionTokens()
 		if in.GetUpdatedAt().IsValid() {
 			if item.UpdatedAt == nil {
 				item.UpdatedAt = new(time.Time)
@@ -143,6 +145,7 @@ func (g *data) SyncItem(ctx context.Context, in *pb.ItemSync) (out *pb.ItemSync,
 	}
 	// If incoming data is older or empty, return from server store
 	out.Blob = item.Blob
+	out.DescriptionTokens = item.DescriptionTokens
 	out.Description = ""
 	if item.Description != nil {
 		out.Description = *item.Description
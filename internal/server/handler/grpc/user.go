@@ -87,6 +87,9 @@ func (g *user) SyncUser(ctx context.Context, in *pb.UserSync) (out *pb.UserSync,
 		}
 		storedUser.CreatedAt = in.GetCreatedAt().AsTime()
 		storedUser.PackedKey = in.GetPackedKey()
+		storedUser.KDFParams = in.GetKdfParams()
+		storedUser.Recovery = in.GetRecovery()
+		storedUser.Templates = in.GetTemplates()
 		storedUser.Password = in.GetPassword()
 		storedUser.UpdatedAt = nil
 		if in.GetUpdatedAt().IsValid() {
@@ -101,6 +104,9 @@ func (g *user) SyncUser(ctx context.Context, in *pb.UserSync) (out *pb.UserSync,
 	}
 	// If incoming data is older, return from server store
 	out.PackedKey = storedUser.PackedKey
+	out.KdfParams = storedUser.KDFParams
+	out.Recovery = storedUser.Recovery
+	out.Templates = storedUser.Templates
 	out.Description = ""
 	if storedUser.Description != nil {
 		out.Description = *storedUser.Description
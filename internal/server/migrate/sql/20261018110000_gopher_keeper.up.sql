@@ -0,0 +1,2 @@
+alter table users
+ add recovery bytea;
@@ -0,0 +1 @@
+drop table storage_index;
@@ -0,0 +1,12 @@
+create table storage_index
+(
+ key     varchar(255) not null,
+ user_id uuid         not null,
+ token   varchar(64)  not null,
+ primary key (user_id, key, token),
+ constraint storage_index_storage_fk
+  foreign key (key, user_id) references storage (key, user_id) on delete cascade
+);
+
+create index storage_index_token_index
+ on storage_index (user_id, token);
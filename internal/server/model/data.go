@@ -16,6 +16,8 @@ type ItemShort struct {
 type Item struct {
 	ItemShort
 	Blob []byte `db:"blob" json:"blob"`
+	// DescriptionTokens blind index tokens of the encrypted description, sent by client opt-in
+	DescriptionTokens []string `db:"-" json:"description_tokens"`
 }
 
 type List struct {
@@ -28,6 +30,8 @@ type DBRecord struct {
 	UserID   uuid.UUID `db:"user_id" validate:"required"`
 	FileName *string   `db:"filename,omitempty"`
 	Blob     []byte    `db:"blob"`
+	// DescriptionTokens blind index tokens of the encrypted description
+	DescriptionTokens []string `db:"-"`
 }
 
 func (i *Item) IsNew() bool {
@@ -7,6 +7,8 @@ type ListQuery struct {
 	OrderBy string `json:"orderBy" validate:"omitempty,oneof=key created_at updated_at 'key desc' 'created_at desc' 'updated_at desc'"`
 	Offset  uint64 `json:"offset" validate:"omitempty"`
 	Limit   uint64 `json:"limit" validate:"omitempty" default:"10"`
+	// DescriptionTokens blind index tokens, the items indexed by all of them are listed
+	DescriptionTokens []string `json:"description_tokens" validate:"omitempty,max=100,dive,hexadecimal,max=64"`
 }
 
 func (m *ListQuery) Validate() error {
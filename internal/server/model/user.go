@@ -20,6 +20,9 @@ type User struct {
 	Password    string
 	Description *string
 	PackedKey   []byte
+	KDFParams   []byte
+	Recovery    []byte
+	Templates   []byte
 	CreatedAt   time.Time
 	UpdatedAt   *time.Time
 }
@@ -30,6 +33,9 @@ type DBUser struct {
 	Password    []byte     `db:"password"`
 	Description *string    `db:"description"`
 	PackedKey   []byte     `db:"packed_key"`
+	KDFParams   []byte     `db:"kdf_params"`
+	Recovery    []byte     `db:"recovery"`
+	Templates   []byte     `db:"templates"`
 	CreatedAt   time.Time  `db:"created_at"`
 	UpdatedAt   *time.Time `db:"updated_at"`
 }
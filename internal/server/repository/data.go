@@ -2,9 +2,12 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"gophKeeper/internal/server/config"
 	"gophKeeper/internal/server/model"
 
+	sqrl "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
@@ -33,10 +36,38 @@ func (s *dataStore) GetDataItem(ctx context.Context, userID uuid.UUID, key strin
 	if err != nil {
 		return
 	}
-	err = s.db.GetContext(ctx, &item, query, args...)
+	if err = s.db.GetContext(ctx, &item, query, args...); err != nil {
+		return
+	}
+	query, args, err = sq.Select("token").
+		From(indexTableName).
+		Where("key = ?", key).
+		Where("user_id = ?", userID).
+		OrderBy("token").
+		ToSql()
+	if err != nil {
+		return
+	}
+	err = s.db.SelectContext(ctx, &item.DescriptionTokens, query, args...)
 	return
 }
 
+// indexFilter
+// filter the items indexed by all blind index tokens of query
+func indexFilter(b sqrl.SelectBuilder, q *model.ListQuery) sqrl.SelectBuilder {
+	if len(q.DescriptionTokens) == 0 {
+		return b
+	}
+	indexed := sqrl.Select("key").From(indexTableName).
+		Where(sqrl.Eq{"token": q.DescriptionTokens}).
+		GroupBy("user_id", "key").
+		Having("count(*) = ?", len(q.DescriptionTokens))
+	if q.UserID != uuid.Nil {
+		indexed = indexed.Where("user_id = ?", q.UserID)
+	}
+	return b.Where(sqrl.Expr("key in (?)", indexed))
+}
+
 func (s *dataStore) ListDataItems(ctx context.Context, q *model.ListQuery) (list []model.ItemShort, err error) {
 	var (
 		query string
@@ -49,6 +80,7 @@ func (s *dataStore) ListDataItems(ctx context.Context, q *model.ListQuery) (list
 		if q.UserID != uuid.Nil {
 			sqlBuild = sqlBuild.Where("user_id = ?", q.UserID)
 		}
+		sqlBuild = indexFilter(sqlBuild, q)
 		if q.Limit != 0 {
 			sqlBuild = sqlBuild.Limit(q.Limit)
 		}
@@ -78,6 +110,7 @@ func (s *dataStore) CountDataItems(ctx context.Context, q *model.ListQuery) (tot
 		if q.UserID != uuid.Nil {
 			sqlBuild = sqlBuild.Where("user_id = ?", q.UserID)
 		}
+		sqlBuild = indexFilter(sqlBuild, q)
 	}
 	query, args, err = sqlBuild.ToSql()
 	if err != nil {
@@ -92,7 +125,19 @@ func (s *dataStore) SaveDataItem(ctx context.Context, item model.DBRecord) (err
 	var (
 		query string
 		args  []interface{}
+		tx    *sqlx.Tx
 	)
+	tx, err = s.db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return
+	}
+	defer func() {
+		rErr := tx.Rollback()
+		if rErr != nil && !errors.Is(rErr, sql.ErrTxDone) {
+			err = errors.Join(err, rErr)
+		}
+	}()
+
 	query, args, err = sq.Insert(storeTableName).
 		Columns(`key, user_id, description, created_at, updated_at, filename, blob`).
 		Values(item.Key, item.UserID, item.Description, item.CreatedAt, item.UpdatedAt, item.FileName, item.Blob).
@@ -105,6 +150,34 @@ func (s *dataStore) SaveDataItem(ctx context.Context, item model.DBRecord) (err
 	if err != nil {
 		return
 	}
-	_, err = s.db.ExecContext(ctx, query, args...)
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		return
+	}
+
+	// the blind index of the description is replaced by the sent one
+	query, args, err = sq.Delete(indexTableName).
+		Where("key = ?", item.Key).
+		Where("user_id = ?", item.UserID).
+		ToSql()
+	if err != nil {
+		return
+	}
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		return
+	}
+	if len(item.DescriptionTokens) > 0 {
+		insert := sq.Insert(indexTableName).Columns("key", "user_id", "token")
+		for _, token := range item.DescriptionTokens {
+			insert = insert.Values(item.Key, item.UserID, token)
+		}
+		if query, args, err = insert.Suffix("on conflict do nothing").ToSql(); err != nil {
+			return
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			return
+		}
+	}
+
+	err = tx.Commit()
 	return
 }
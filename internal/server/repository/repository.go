@@ -16,6 +16,7 @@ var sq = sqrl.StatementBuilder.PlaceholderFormat(sqrl.Dollar)
 
 const (
 	storeTableName  = "storage"
+	indexTableName  = "storage_index"
 	userTableName   = "users"
 	clientTableName = "clients"
 )
@@ -35,12 +35,18 @@ func (s *userStore) SaveUser(ctx context.Context, user *model.DBUser) (err error
 			"password":    user.Password,
 			"description": user.Description,
 			"packed_key":  user.PackedKey,
+			"kdf_params":  user.KDFParams,
+			"recovery":    user.Recovery,
+			"templates":   user.Templates,
 		}).
 		Suffix(`
 on conflict (email) do update
 set description=excluded.description,
       password=case when excluded.password <> '' then excluded.password else ` + userTableName + `.password end,
-      packed_key=excluded.packed_key
+      packed_key=excluded.packed_key,
+      kdf_params=excluded.kdf_params,
+      recovery=excluded.recovery,
+      templates=excluded.templates
 RETURNING id, created_at, updated_at`).
 		ToSql()
 	if err != nil {
@@ -61,7 +67,7 @@ func (s *userStore) GetUserSelf(ctx context.Context) (user model.DBUser, err err
 		return
 	}
 
-	query, args, err = sq.Select(`id, description, email, packed_key, created_at, updated_at`).
+	query, args, err = sq.Select(`id, description, email, packed_key, kdf_params, recovery, templates, created_at, updated_at`).
 		From(userTableName).
 		Where("id = ?", userID).ToSql()
 	if err != nil {
@@ -93,7 +99,7 @@ func (s *userStore) GetUserByEmail(ctx context.Context, email string) (user mode
 		query string
 		args  []interface{}
 	)
-	query, args, err = sq.Select(`id, email, password, description, created_at, updated_at, packed_key`).
+	query, args, err = sq.Select(`id, email, password, description, created_at, updated_at, packed_key, kdf_params, recovery`).
 		From(userTableName).
 		Where("email = ?", email).
 		ToSql()
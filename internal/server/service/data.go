@@ -47,6 +47,7 @@ func (s *serv) GetSelfItem(ctx context.Context, k string) (item *model.Item, err
 	}
 	item.ItemShort = dbItem.ItemShort
 	item.Blob = dbItem.Blob
+	item.DescriptionTokens = dbItem.DescriptionTokens
 	// if dbItem.FileName != nil && item.Blob == nil {
 	// item.Blob, err = s.r.GetFile(*dbItem.FileName)
 	// }
@@ -68,6 +69,7 @@ func (s *serv) SaveSelfItem(ctx context.Context, item *model.Item) (err error) {
 	// dbItem.Blob = nil
 	// dbItem.FileName = getFileStorageName
 	dbItem.Blob = item.Blob
+	dbItem.DescriptionTokens = item.DescriptionTokens
 
 	err = s.r.SaveDataItem(ctx, dbItem)
 
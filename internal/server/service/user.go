@@ -30,6 +30,9 @@ func (s *user) GetSelf(ctx context.Context) (user model.User, err error) {
 	}
 	user.Email = u.Email
 	user.PackedKey = u.PackedKey
+	user.KDFParams = u.KDFParams
+	user.Recovery = u.Recovery
+	user.Templates = u.Templates
 	user.Description = u.Description
 	user.CreatedAt = u.CreatedAt
 	user.UpdatedAt = u.UpdatedAt
@@ -43,6 +46,9 @@ func (s *user) SaveSelf(ctx context.Context, user *model.User) (err error) {
 		Email:       user.Email,
 		Description: user.Description,
 		PackedKey:   user.PackedKey,
+		KDFParams:   user.KDFParams,
+		Recovery:    user.Recovery,
+		Templates:   user.Templates,
 	}
 	if user.Password != "" {
 		u.Password, err = bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)